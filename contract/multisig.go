@@ -0,0 +1,136 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package contract
+
+import (
+	"fmt"
+	"math/big"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// Multisig represents the storage of the generic multisig contract shipped
+// with octez-client as
+// `pair (nat %stored_counter) (pair (nat %threshold) (list %keys key))`.
+// https://gitlab.com/tezos/tezos/-/blob/master/michelson_test_scripts/mini_scenarios/generic_multisig.tz
+type Multisig struct {
+	Counter   int64       // replay protection counter, expected by the next call
+	Threshold int64       // number of valid signatures required
+	Keys      []tezos.Key // keys allowed to sign
+}
+
+// multisigPayloadType is the type of the value the signers of a multisig
+// call sign: chain id and contract address followed by counter and action.
+var multisigPayloadType = micheline.NewType(micheline.NewPairType(
+	micheline.NewPairType(micheline.NewCode(micheline.T_CHAIN_ID), micheline.NewCode(micheline.T_ADDRESS)),
+	micheline.NewPairType(
+		micheline.NewCode(micheline.T_NAT),
+		micheline.NewCode(micheline.T_OR,
+			micheline.NewCode(micheline.T_LAMBDA,
+				micheline.NewCode(micheline.T_UNIT),
+				micheline.NewCode(micheline.T_LIST, micheline.NewCode(micheline.T_OPERATION)),
+			),
+			micheline.NewPairType(
+				micheline.NewCode(micheline.T_NAT),
+				micheline.NewCode(micheline.T_LIST, micheline.NewCode(micheline.T_KEY)),
+			),
+		),
+	),
+))
+
+// DecodeMultisigStorage decodes generic multisig contract storage. Keys may be
+// encoded in readable (string) or optimized (bytes) form and the storage may
+// use nested or comb pairs.
+func DecodeMultisigStorage(v micheline.Value) (*Multisig, error) {
+	p := v.Value
+	if !p.IsPair() {
+		return nil, fmt.Errorf("contract: unexpected multisig storage %s", p.DumpLimit(64))
+	}
+	args := p.Args
+	if len(args) == 2 && args[1].IsPair() {
+		args = append([]micheline.Prim{args[0]}, args[1].Args...)
+	}
+	if len(args) != 3 {
+		return nil, fmt.Errorf("contract: unexpected multisig storage %s", p.DumpLimit(64))
+	}
+	counter, err := multisigNat(args[0], "counter")
+	if err != nil {
+		return nil, err
+	}
+	threshold, err := multisigNat(args[1], "threshold")
+	if err != nil {
+		return nil, err
+	}
+	if args[2].Type != micheline.PrimSequence {
+		return nil, fmt.Errorf("contract: invalid multisig keys %s", args[2].DumpLimit(64))
+	}
+	ms := &Multisig{
+		Counter:   counter,
+		Threshold: threshold,
+		Keys:      make([]tezos.Key, 0, len(args[2].Args)),
+	}
+	for _, kp := range args[2].Args {
+		var (
+			key tezos.Key
+			err error
+		)
+		switch kp.Type {
+		case micheline.PrimString:
+			key, err = tezos.ParseKey(kp.String)
+		case micheline.PrimBytes:
+			key, err = tezos.DecodeKey(kp.Bytes)
+		default:
+			err = fmt.Errorf("unexpected prim %s", kp.DumpLimit(64))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("contract: invalid multisig key: %v", err)
+		}
+		ms.Keys = append(ms.Keys, key)
+	}
+	if ms.Threshold > int64(len(ms.Keys)) {
+		return nil, fmt.Errorf("contract: multisig threshold %d exceeds %d keys", ms.Threshold, len(ms.Keys))
+	}
+	return ms, nil
+}
+
+func multisigNat(p micheline.Prim, name string) (int64, error) {
+	if p.Type != micheline.PrimInt || p.Int == nil || p.Int.Sign() < 0 || !p.Int.IsInt64() {
+		return 0, fmt.Errorf("contract: invalid multisig %s %s", name, p.DumpLimit(64))
+	}
+	return p.Int.Int64(), nil
+}
+
+// NewMultisigLambdaAction returns a multisig action that runs code, a lambda
+// of type `lambda unit (list operation)`, to emit operations.
+func NewMultisigLambdaAction(code micheline.Prim) micheline.Prim {
+	return micheline.NewCode(micheline.D_LEFT, code)
+}
+
+// NewMultisigChangeKeysAction returns a multisig action that replaces the
+// threshold and signer keys.
+func NewMultisigChangeKeysAction(threshold int64, keys []tezos.Key) micheline.Prim {
+	list := micheline.NewSeq()
+	for _, k := range keys {
+		list.Args = append(list.Args, micheline.NewBytes(k.Bytes()))
+	}
+	return micheline.NewCode(micheline.D_RIGHT,
+		micheline.NewPairValue(micheline.NewBig(big.NewInt(threshold)), list),
+	)
+}
+
+// Payload returns the bytes signers have to sign to authorize action for
+// the multisig contract addr on chain. The payload is the PACKed value
+// `Pair (Pair chain_id addr) (Pair counter action)` the contract checks
+// signatures against, using the current counter of m.
+func (m *Multisig) Payload(chain tezos.ChainIdHash, addr tezos.Address, action micheline.Prim) ([]byte, error) {
+	if !addr.IsContract() {
+		return nil, fmt.Errorf("contract: invalid multisig address %q", addr)
+	}
+	val := micheline.NewPairValue(
+		micheline.NewPairValue(micheline.NewBytes(chain.Hash.Hash), micheline.NewBytes(addr.Bytes22())),
+		micheline.NewPairValue(micheline.NewBig(big.NewInt(m.Counter)), action),
+	)
+	return micheline.PackPrim(multisigPayloadType, val)
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package contract
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+const multisigStorageType = `{"prim":"pair","args":[{"prim":"nat","annots":["%stored_counter"]},{"prim":"pair","args":[{"prim":"nat","annots":["%threshold"]},{"prim":"list","annots":["%keys"],"args":[{"prim":"key"}]}]}]}`
+
+func TestDecodeMultisigStorage(t *testing.T) {
+	k1 := tezos.MustParseKey("edpkuBknW28nW72KG6RoHtYW7p12T6GKc7nAbwYX5m8Wd9sDVC9yav")
+	k2 := tezos.MustParseKey("edpkuZ7ERiU5B8knLqQsVMH86j9RLMUyHyL665oCXDkPQxF7HGqSeJ")
+
+	for _, v := range []string{
+		// readable form as returned by contracts/<id>/storage
+		`{"prim":"Pair","args":[{"int":"3"},{"prim":"Pair","args":[{"int":"2"},[{"string":"` + k1.String() + `"},{"string":"` + k2.String() + `"}]]}]}`,
+		// optimized comb form
+		`{"prim":"Pair","args":[{"int":"3"},{"int":"2"},[{"bytes":"` + hex.EncodeToString(k1.Bytes()) + `"},{"bytes":"` + hex.EncodeToString(k2.Bytes()) + `"}]]}`,
+	} {
+		ms, err := DecodeMultisigStorage(newTestValue(t, multisigStorageType, v))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ms.Counter != 3 || ms.Threshold != 2 || len(ms.Keys) != 2 {
+			t.Fatalf("unexpected multisig %#v", ms)
+		}
+		if !ms.Keys[0].IsEqual(k1) || !ms.Keys[1].IsEqual(k2) {
+			t.Errorf("key mismatch got=%s %s", ms.Keys[0], ms.Keys[1])
+		}
+	}
+
+	for _, v := range []string{
+		`{"int":"1"}`,
+		// threshold above key count
+		`{"prim":"Pair","args":[{"int":"0"},{"int":"2"},[{"string":"` + k1.String() + `"}]]}`,
+		// invalid key
+		`{"prim":"Pair","args":[{"int":"0"},{"int":"1"},[{"string":"edpk"}]]}`,
+	} {
+		if _, err := DecodeMultisigStorage(newTestValue(t, multisigStorageType, v)); err == nil {
+			t.Errorf("expected error for %s", v)
+		}
+	}
+
+	// integers without value or out of range
+	if _, err := multisigNat(micheline.Prim{Type: micheline.PrimInt}, "counter"); err == nil {
+		t.Errorf("expected error for missing int")
+	}
+	if _, err := multisigNat(micheline.NewBig(new(big.Int).Lsh(big.NewInt(1), 64)), "counter"); err == nil {
+		t.Errorf("expected error for int overflow")
+	}
+}
+
+func TestMultisigPayload(t *testing.T) {
+	key := tezos.MustParseKey("edpkuBknW28nW72KG6RoHtYW7p12T6GKc7nAbwYX5m8Wd9sDVC9yav")
+	chain := tezos.MustParseChainIdHash("NetXdQprcVkpaWU")
+	addr := tezos.MustParseAddress("KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu")
+	ms := &Multisig{Counter: 3, Threshold: 1, Keys: []tezos.Key{key}}
+
+	buf, err := ms.Payload(chain, addr, NewMultisigChangeKeysAction(1, []tezos.Key{key}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// PACK (Pair (Pair chain_id address) (Pair 3 (Right (Pair 1 {key}))))
+	want := "05" + "0707" +
+		"0707" + "0a00000004" + hex.EncodeToString(chain.Hash.Hash) + "0a00000016" + hex.EncodeToString(addr.Bytes22()) +
+		"0707" + "0003" + "0508" + "0707" + "0001" + "0200000026" + "0a00000021" + hex.EncodeToString(key.Bytes())
+	if got := hex.EncodeToString(buf); got != want {
+		t.Errorf("payload mismatch\n  want=%s\n  got= %s", want, got)
+	}
+
+	// lambda actions pack their code as is
+	lambda := micheline.NewSeq(micheline.NewCode(micheline.I_DROP), micheline.NewCode(micheline.I_NIL, micheline.NewCode(micheline.T_OPERATION)))
+	if _, err := ms.Payload(chain, addr, NewMultisigLambdaAction(lambda)); err != nil {
+		t.Errorf("lambda payload: %v", err)
+	}
+	if _, err := ms.Payload(chain, tezos.MustParseAddress("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"), NewMultisigLambdaAction(lambda)); err == nil {
+		t.Errorf("expected error for implicit address")
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package contract
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+const TezosStoragePrefix = "tezos-storage:"
+
+// TokenMetadata represents a TZIP-12 token metadata entry as stored in the
+// `token_metadata` bigmap as `pair (nat %token_id) (map %token_info string bytes)`.
+// https://gitlab.com/tzip/tzip/-/blob/master/proposals/tzip-12/tzip-12.md#token-metadata
+type TokenMetadata struct {
+	TokenId  *big.Int          // token id
+	URI      string            // "" key, off-chain metadata location (TZIP-16 URI)
+	Name     string            // name, optional
+	Symbol   string            // symbol, optional
+	Decimals int               // decimals, optional
+	Extra    map[string]string // all other keys, UTF-8 or hex if not valid UTF-8
+}
+
+// DecodeTokenMetadata decodes a TZIP-12 token metadata bigmap value. Byte values
+// with well-known keys must be valid UTF-8 and decimals must be a decimal number.
+func DecodeTokenMetadata(v micheline.Value) (*TokenMetadata, error) {
+	p := v.Value
+	if !p.IsPair() || len(p.Args) != 2 {
+		return nil, fmt.Errorf("contract: unexpected token metadata value %s", p.DumpLimit(64))
+	}
+	args := p.Args
+	if args[0].Type != micheline.PrimInt {
+		return nil, fmt.Errorf("contract: invalid token_id %s", args[0].DumpLimit(64))
+	}
+	info := args[1]
+	if info.Type != micheline.PrimSequence {
+		return nil, fmt.Errorf("contract: invalid token_info %s", info.DumpLimit(64))
+	}
+
+	meta := &TokenMetadata{
+		TokenId: new(big.Int).Set(args[0].Int),
+		Extra:   make(map[string]string),
+	}
+	for _, elt := range info.Args {
+		if elt.OpCode != micheline.D_ELT || len(elt.Args) != 2 {
+			return nil, fmt.Errorf("contract: invalid token_info element %s", elt.DumpLimit(64))
+		}
+		key, val := elt.Args[0], elt.Args[1]
+		if key.Type != micheline.PrimString || val.Type != micheline.PrimBytes {
+			return nil, fmt.Errorf("contract: invalid token_info element %s", elt.DumpLimit(64))
+		}
+		switch key.String {
+		case "", "name", "symbol", "decimals":
+			if !utf8.Valid(val.Bytes) {
+				return nil, fmt.Errorf("contract: token_info %q is not valid UTF-8", key.String)
+			}
+		}
+		s := string(val.Bytes)
+		switch key.String {
+		case "":
+			meta.URI = s
+		case "name":
+			meta.Name = s
+		case "symbol":
+			meta.Symbol = s
+		case "decimals":
+			d, err := strconv.Atoi(s)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("contract: invalid token decimals %q", s)
+			}
+			meta.Decimals = d
+		default:
+			if utf8.Valid(val.Bytes) {
+				meta.Extra[key.String] = s
+			} else {
+				meta.Extra[key.String] = hex.EncodeToString(val.Bytes)
+			}
+		}
+	}
+	return meta, nil
+}
+
+// IsStorageURI returns true when the off-chain metadata URI points to
+// contract storage via TZIP-16 `tezos-storage:` indirection.
+func (m TokenMetadata) IsStorageURI() bool {
+	return strings.HasPrefix(m.URI, TezosStoragePrefix)
+}
+
+// StorageKey resolves a `tezos-storage:` URI into the contract address and the
+// key into the contract's `%metadata` bigmap. The address is nil when the URI
+// refers to the current contract.
+func (m TokenMetadata) StorageKey() (*tezos.Address, string, error) {
+	return ParseStorageURI(m.URI)
+}
+
+// ParseStorageURI parses TZIP-16 storage URIs of the form `tezos-storage:<key>`
+// and `tezos-storage://<address>[.<chain>]/<key>`. Keys are percent-decoded.
+func ParseStorageURI(uri string) (*tezos.Address, string, error) {
+	if !strings.HasPrefix(uri, TezosStoragePrefix) {
+		return nil, "", fmt.Errorf("contract: not a tezos-storage URI %q", uri)
+	}
+	rest := strings.TrimPrefix(uri, TezosStoragePrefix)
+	var addr *tezos.Address
+	if strings.HasPrefix(rest, "//") {
+		rest = rest[2:]
+		i := strings.IndexByte(rest, '/')
+		if i < 0 {
+			return nil, "", fmt.Errorf("contract: missing key in tezos-storage URI %q", uri)
+		}
+		host := rest[:i]
+		if j := strings.IndexByte(host, '.'); j >= 0 {
+			host = host[:j] // strip chain id
+		}
+		a, err := tezos.ParseAddress(host)
+		if err != nil {
+			return nil, "", fmt.Errorf("contract: invalid address in tezos-storage URI %q: %w", uri, err)
+		}
+		addr = &a
+		rest = rest[i+1:]
+	}
+	key, err := url.PathUnescape(rest)
+	if err != nil {
+		return nil, "", fmt.Errorf("contract: invalid key in tezos-storage URI %q: %w", uri, err)
+	}
+	if key == "" {
+		return nil, "", fmt.Errorf("contract: missing key in tezos-storage URI %q", uri)
+	}
+	return addr, key, nil
+}
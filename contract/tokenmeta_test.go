@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package contract
+
+import (
+	"encoding/json"
+	"testing"
+
+	"blockwatch.cc/tzgo/micheline"
+)
+
+const tokenMetaType = `{"prim":"pair","args":[{"prim":"nat","annots":["%token_id"]},{"prim":"map","annots":["%token_info"],"args":[{"prim":"string"},{"prim":"bytes"}]}]}`
+
+// newTestValue returns a value of Micheline JSON type typ decoded from val.
+func newTestValue(t *testing.T, typ, val string) micheline.Value {
+	t.Helper()
+	var tp, vp micheline.Prim
+	if err := json.Unmarshal([]byte(typ), &tp); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(val), &vp); err != nil {
+		t.Fatal(err)
+	}
+	return micheline.NewValue(micheline.NewType(tp), vp)
+}
+
+func TestDecodeTokenMetadata(t *testing.T) {
+	// kUSD token_metadata entry
+	val := newTestValue(t, tokenMetaType, `{"prim":"Pair","args":[{"int":"0"},[
+		{"prim":"Elt","args":[{"string":""},{"bytes":"74657a6f732d73746f726167653a6b555344"}]},
+		{"prim":"Elt","args":[{"string":"decimals"},{"bytes":"3138"}]},
+		{"prim":"Elt","args":[{"string":"icon"},{"bytes":"68747470733a2f2f6b6f6c696272692e66696e616e63652f6b7573642e706e67"}]},
+		{"prim":"Elt","args":[{"string":"name"},{"bytes":"4b6f6c696272692055534420537461626c65636f696e"}]},
+		{"prim":"Elt","args":[{"string":"symbol"},{"bytes":"6b555344"}]}
+	]]}`)
+	meta, err := DecodeTokenMetadata(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.TokenId.Int64() != 0 {
+		t.Errorf("token_id mismatch got=%s", meta.TokenId)
+	}
+	if meta.Name != "Kolibri USD Stablecoin" || meta.Symbol != "kUSD" || meta.Decimals != 18 {
+		t.Errorf("unexpected metadata %#v", meta)
+	}
+	if meta.Extra["icon"] != "https://kolibri.finance/kusd.png" {
+		t.Errorf("unexpected extra fields %#v", meta.Extra)
+	}
+	if !meta.IsStorageURI() {
+		t.Fatalf("expected tezos-storage URI, got %q", meta.URI)
+	}
+	addr, key, err := meta.StorageKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != nil || key != "kUSD" {
+		t.Errorf("unexpected storage key %v %q", addr, key)
+	}
+}
+
+func TestDecodeTokenMetadataInvalid(t *testing.T) {
+	for _, v := range []string{
+		// not a pair
+		`{"int":"1"}`,
+		// invalid UTF-8 name
+		`{"prim":"Pair","args":[{"int":"1"},[{"prim":"Elt","args":[{"string":"name"},{"bytes":"ff"}]}]]}`,
+		// non-numeric decimals
+		`{"prim":"Pair","args":[{"int":"1"},[{"prim":"Elt","args":[{"string":"decimals"},{"bytes":"736978"}]}]]}`,
+	} {
+		if _, err := DecodeTokenMetadata(newTestValue(t, tokenMetaType, v)); err == nil {
+			t.Errorf("expected error for %s", v)
+		}
+	}
+}
+
+func TestParseStorageURI(t *testing.T) {
+	addr, key, err := ParseStorageURI("tezos-storage://KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu.NetXdQprcVkpaWU/my%2Fkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr == nil || addr.String() != "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu" || key != "my/key" {
+		t.Errorf("unexpected result %v %q", addr, key)
+	}
+	for _, v := range []string{"ipfs://QmX", "tezos-storage:", "tezos-storage://KT1/key", "tezos-storage://KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"} {
+		if _, _, err := ParseStorageURI(v); err == nil {
+			t.Errorf("expected error for %s", v)
+		}
+	}
+}
@@ -113,7 +113,7 @@ func run() error {
 		if err != nil {
 			return err
 		}
-		if addr.Type != tezos.AddressTypeContract {
+		if !addr.IsContract() {
 			return fmt.Errorf("%s is not a smart contract address", a)
 		}
 		return showContractInfo(ctx, c, addr)
@@ -148,12 +148,20 @@ func fetchHead(ctx context.Context, c *rpc.Client) error {
 	if err != nil {
 		return err
 	}
-	printHead(head)
+	con, err := c.GetConstants(ctx, *head.Hash)
+	if err != nil {
+		return err
+	}
+	printHead(head, con)
 	return nil
 }
 
-func printHead(h *rpc.BlockHeader) {
-	fmt.Printf("Block  %d (%d) %s %s\n", h.Level, h.Level/4096, h.Hash, h.Timestamp)
+func printHead(h *rpc.BlockHeader, con rpc.Constants) {
+	var cycle int64
+	if con.BlocksPerCycle > 0 {
+		cycle = h.Level / con.BlocksPerCycle
+	}
+	fmt.Printf("Block  %d (%d) %s %s\n", h.Level, cycle, h.Hash, h.Timestamp)
 }
 
 func printBlock(b *rpc.Block) {
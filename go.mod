@@ -3,10 +3,14 @@ module blockwatch.cc/tzgo
 go 1.16
 
 require (
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1
 	github.com/echa/log v1.0.3
 	github.com/go-bson/bson v0.0.0-20171017145622-6d291e839eca
 	github.com/pmezard/go-difflib v1.0.0
+	github.com/tyler-smith/go-bip39 v1.1.0
 	golang.org/x/crypto v0.0.0-20210317152858-513c2a44f670
+	golang.org/x/sync v0.1.0
+	golang.org/x/text v0.3.6
 	gopkg.in/bson.v2 v2.0.0-20171018101713-d8c8987b8862 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/mgo.v2 v2.0.0-20190816093944-a6b53ec6cb22 // indirect
@@ -0,0 +1,54 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// BlsPoint is a decoded bls12_381_g1, bls12_381_g2 or bls12_381_fr value.
+// G1 and G2 points are kept in the uncompressed form used by Michelson,
+// Fr scalars as 32 byte little endian. JSON output is the hex string.
+type BlsPoint struct {
+	Type  OpCode // T_BLS12_381_G1, T_BLS12_381_G2 or T_BLS12_381_FR
+	Bytes []byte
+}
+
+// blsSize returns the encoded size of a BLS12-381 type or zero for other types.
+func blsSize(typ OpCode) int {
+	switch typ {
+	case T_BLS12_381_G1:
+		return 96
+	case T_BLS12_381_G2:
+		return 192
+	case T_BLS12_381_FR:
+		return 32
+	default:
+		return 0
+	}
+}
+
+// DecodeBlsPoint decodes a bytes value of BLS12-381 type typ and checks its length.
+func DecodeBlsPoint(typ OpCode, buf []byte) (BlsPoint, error) {
+	n := blsSize(typ)
+	if n == 0 {
+		return BlsPoint{}, fmt.Errorf("micheline: %s is not a BLS12-381 type", typ)
+	}
+	if len(buf) != n {
+		return BlsPoint{}, fmt.Errorf("micheline: invalid %s length %d, expected %d", typ, len(buf), n)
+	}
+	b := make([]byte, n)
+	copy(b, buf)
+	return BlsPoint{Type: typ, Bytes: b}, nil
+}
+
+func (p BlsPoint) String() string {
+	return hex.EncodeToString(p.Bytes)
+}
+
+func (p BlsPoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
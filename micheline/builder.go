@@ -4,6 +4,7 @@
 package micheline
 
 import (
+	"fmt"
 	"math/big"
 )
 
@@ -75,6 +76,184 @@ func NewPrim(c OpCode, anno ...string) Prim {
 	return Prim{Type: typ, OpCode: c, Anno: anno}
 }
 
+// Builder is a fluent constructor for Micheline values. Containers are opened
+// with Pair, Left, Right, Some and Seq and receive all subsequently added
+// values as children. Containers with a fixed number of children close
+// automatically once complete, sequences must be closed with End. The first
+// error stops the builder and is returned from Build.
+//
+//	p, err := NewBuilder().Pair().String("a").Int(1).Build()
+type Builder struct {
+	stack []builderFrame
+	root  []Prim
+	err   error
+}
+
+type builderFrame struct {
+	code OpCode // D_PAIR, D_LEFT, D_RIGHT or D_SOME, unused for sequences
+	want int    // required number of children, -1 for sequences
+	args []Prim
+}
+
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Pair opens a pair that closes after exactly two children.
+func (b *Builder) Pair() *Builder {
+	return b.open(D_PAIR, 2)
+}
+
+// Left opens a Left branch that closes after one child.
+func (b *Builder) Left() *Builder {
+	return b.open(D_LEFT, 1)
+}
+
+// Right opens a Right branch that closes after one child.
+func (b *Builder) Right() *Builder {
+	return b.open(D_RIGHT, 1)
+}
+
+// Some opens an option value that closes after one child.
+func (b *Builder) Some() *Builder {
+	return b.open(D_SOME, 1)
+}
+
+// Seq opens a sequence which must be closed with End.
+func (b *Builder) Seq() *Builder {
+	return b.open(K_PARAMETER, -1)
+}
+
+// End closes the innermost open container. It is required for sequences
+// and fails for incomplete fixed-size containers.
+func (b *Builder) End() *Builder {
+	if b.err != nil {
+		return b
+	}
+	if len(b.stack) == 0 {
+		b.err = fmt.Errorf("micheline: builder End without open container")
+		return b
+	}
+	top := b.stack[len(b.stack)-1]
+	if top.want >= 0 && len(top.args) != top.want {
+		b.err = fmt.Errorf("micheline: builder %s needs exactly %d arguments, got %d",
+			top.code, top.want, len(top.args))
+		return b
+	}
+	return b.close()
+}
+
+func (b *Builder) None() *Builder {
+	return b.Add(NewCode(D_NONE))
+}
+
+func (b *Builder) Unit() *Builder {
+	return b.Add(NewCode(D_UNIT))
+}
+
+func (b *Builder) Bool(v bool) *Builder {
+	if v {
+		return b.Add(NewCode(D_TRUE))
+	}
+	return b.Add(NewCode(D_FALSE))
+}
+
+func (b *Builder) Int(i int64) *Builder {
+	return b.Add(NewInt64(i))
+}
+
+func (b *Builder) Big(i *big.Int) *Builder {
+	if i == nil {
+		b.fail(fmt.Errorf("micheline: builder nil integer"))
+		return b
+	}
+	return b.Add(NewBig(i))
+}
+
+func (b *Builder) String(s string) *Builder {
+	return b.Add(NewString(s))
+}
+
+func (b *Builder) Bytes(buf []byte) *Builder {
+	return b.Add(NewBytes(buf))
+}
+
+// Elt adds a map element. It is only valid inside a sequence.
+func (b *Builder) Elt(k, v Prim) *Builder {
+	if b.err == nil && (len(b.stack) == 0 || b.stack[len(b.stack)-1].want >= 0) {
+		b.err = fmt.Errorf("micheline: builder Elt outside of sequence")
+		return b
+	}
+	return b.Add(NewCode(D_ELT, k, v))
+}
+
+// Add appends a prebuilt value to the innermost open container.
+func (b *Builder) Add(p Prim) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if len(b.stack) == 0 {
+		if len(b.root) > 0 {
+			b.err = fmt.Errorf("micheline: builder requires a single root value")
+			return b
+		}
+		b.root = append(b.root, p)
+		return b
+	}
+	top := &b.stack[len(b.stack)-1]
+	top.args = append(top.args, p)
+	if top.want > 0 && len(top.args) == top.want {
+		return b.close()
+	}
+	return b
+}
+
+// Build returns the constructed value or the first error encountered.
+func (b *Builder) Build() (Prim, error) {
+	if b.err != nil {
+		return InvalidPrim, b.err
+	}
+	if n := len(b.stack); n > 0 {
+		top := b.stack[n-1]
+		if top.want < 0 {
+			return InvalidPrim, fmt.Errorf("micheline: builder has %d unclosed containers", n)
+		}
+		return InvalidPrim, fmt.Errorf("micheline: builder %s needs exactly %d arguments, got %d",
+			top.code, top.want, len(top.args))
+	}
+	if len(b.root) == 0 {
+		return InvalidPrim, fmt.Errorf("micheline: builder is empty")
+	}
+	return b.root[0], nil
+}
+
+func (b *Builder) open(code OpCode, want int) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if len(b.stack) == 0 && len(b.root) > 0 {
+		b.err = fmt.Errorf("micheline: builder requires a single root value")
+		return b
+	}
+	b.stack = append(b.stack, builderFrame{code: code, want: want})
+	return b
+}
+
+func (b *Builder) close() *Builder {
+	top := b.stack[len(b.stack)-1]
+	b.stack = b.stack[:len(b.stack)-1]
+	if top.want < 0 {
+		return b.Add(NewSeq(top.args...))
+	}
+	return b.Add(NewCode(top.code, top.args...))
+}
+
+func (b *Builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
 // Macros
 func ASSERT_CMPEQ() Prim {
 	return NewSeq(
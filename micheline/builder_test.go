@@ -0,0 +1,78 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package micheline
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBuilderFA2Transfer(t *testing.T) {
+	// [ Pair from [ Pair to (Pair token_id amount) ] ]
+	p, err := NewBuilder().
+		Seq().
+		Pair().
+		String("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb").
+		Seq().
+		Pair().String("KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu").Pair().Int(0).Int(100).
+		Pair().String("KT195Mo1aBBLvzMVGQhiSWRDngp5PoQANavy").Pair().Int(1).Int(5).
+		End().
+		End().
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"args":[{"string":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"},[` +
+		`{"args":[{"string":"KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"},{"args":[{"int":"0"},{"int":"100"}],"prim":"Pair"}],"prim":"Pair"},` +
+		`{"args":[{"string":"KT195Mo1aBBLvzMVGQhiSWRDngp5PoQANavy"},{"args":[{"int":"1"},{"int":"5"}],"prim":"Pair"}],"prim":"Pair"}` +
+		`]],"prim":"Pair"}]`
+	if string(buf) != want {
+		t.Errorf("mismatch\n  want=%s\n  got= %s", want, buf)
+	}
+}
+
+func TestBuilderMapAndOr(t *testing.T) {
+	p, err := NewBuilder().
+		Pair().
+		Left().Some().Bytes([]byte{0xca, 0xfe}).
+		Seq().Elt(NewString("a"), NewInt64(1)).Elt(NewString("b"), NewCode(D_UNIT)).End().
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, _ := json.Marshal(p)
+	want := `{"args":[{"args":[{"args":[{"bytes":"cafe"}],"prim":"Some"}],"prim":"Left"},[` +
+		`{"args":[{"string":"a"},{"int":"1"}],"prim":"Elt"},{"args":[{"string":"b"},{"prim":"Unit"}],"prim":"Elt"}` +
+		`]],"prim":"Pair"}`
+	if string(buf) != want {
+		t.Errorf("mismatch\n  want=%s\n  got= %s", want, buf)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		B    *Builder
+		Want string
+	}{
+		{Name: "short pair", B: NewBuilder().Pair().Int(1), Want: "needs exactly 2 arguments, got 1"},
+		{Name: "early end", B: NewBuilder().Pair().Int(1).End(), Want: "needs exactly 2 arguments, got 1"},
+		{Name: "open seq", B: NewBuilder().Seq().Int(1), Want: "unclosed"},
+		{Name: "extra root", B: NewBuilder().Int(1).Int(2), Want: "single root"},
+		{Name: "elt outside seq", B: NewBuilder().Pair().Elt(NewInt64(1), NewInt64(2)), Want: "outside of sequence"},
+		{Name: "stray end", B: NewBuilder().Int(1).End(), Want: "without open container"},
+		{Name: "empty", B: NewBuilder(), Want: "empty"},
+	} {
+		_, err := test.B.Build()
+		if err == nil || !strings.Contains(err.Error(), test.Want) {
+			t.Errorf("%s: expected error %q, got %v", test.Name, test.Want, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ComparePrims compares two values of comparable type typ like the Michelson
+// COMPARE instruction and returns -1, 0 or +1. Values may be given in
+// optimized (bytes, int) or readable (string) form, i.e. addresses, keys and
+// timestamps are compared by their binary representation as the node does.
+// Implicit addresses order before originated contracts.
+func ComparePrims(a, b Prim, typ Type) (int, error) {
+	pa, err := packPrim(typ.Prim, a)
+	if err != nil {
+		return 0, err
+	}
+	pb, err := packPrim(typ.Prim, b)
+	if err != nil {
+		return 0, err
+	}
+	return comparePrims(typ.Prim, pa, pb)
+}
+
+// comparePrims compares values in the optimized form produced by packPrim.
+func comparePrims(typ, a, b Prim) (int, error) {
+	switch typ.OpCode {
+	case T_INT, T_NAT, T_MUTEZ, T_TIMESTAMP:
+		if a.Type != PrimInt || b.Type != PrimInt || a.Int == nil || b.Int == nil {
+			return 0, compareMismatch(typ, a, b)
+		}
+		return a.Int.Cmp(b.Int), nil
+
+	case T_STRING:
+		if a.Type != PrimString || b.Type != PrimString {
+			return 0, compareMismatch(typ, a, b)
+		}
+		return strings.Compare(a.String, b.String), nil
+
+	case T_BYTES, T_ADDRESS, T_KEY_HASH, T_KEY, T_SIGNATURE, T_CHAIN_ID:
+		if a.Type != PrimBytes || b.Type != PrimBytes {
+			return 0, compareMismatch(typ, a, b)
+		}
+		return bytes.Compare(a.Bytes, b.Bytes), nil
+
+	case T_BOOL:
+		x, y := a.OpCode == D_TRUE, b.OpCode == D_TRUE
+		switch {
+		case x == y:
+			return 0, nil
+		case y:
+			return -1, nil
+		default:
+			return 1, nil
+		}
+
+	case T_UNIT:
+		return 0, nil
+
+	case T_OPTION:
+		switch {
+		case a.OpCode == D_NONE && b.OpCode == D_NONE:
+			return 0, nil
+		case a.OpCode == D_NONE:
+			return -1, nil
+		case b.OpCode == D_NONE:
+			return 1, nil
+		}
+		if len(a.Args) != 1 || len(b.Args) != 1 {
+			return 0, compareMismatch(typ, a, b)
+		}
+		return comparePrims(typ.Args[0], a.Args[0], b.Args[0])
+
+	case T_OR:
+		if len(a.Args) != 1 || len(b.Args) != 1 {
+			return 0, compareMismatch(typ, a, b)
+		}
+		switch {
+		case a.OpCode == b.OpCode:
+			t := typ.Args[0]
+			if a.OpCode == D_RIGHT {
+				t = typ.Args[1]
+			}
+			return comparePrims(t, a.Args[0], b.Args[0])
+		case a.OpCode == D_LEFT:
+			return -1, nil
+		default:
+			return 1, nil
+		}
+
+	case T_PAIR:
+		// packPrim has already converted values to binary pairs
+		typ = binaryPair(typ)
+		if len(typ.Args) != 2 || len(a.Args) != 2 || len(b.Args) != 2 {
+			return 0, compareMismatch(typ, a, b)
+		}
+		c, err := comparePrims(typ.Args[0], a.Args[0], b.Args[0])
+		if err != nil || c != 0 {
+			return c, err
+		}
+		return comparePrims(typ.Args[1], a.Args[1], b.Args[1])
+
+	default:
+		return 0, fmt.Errorf("micheline: type %s is not comparable", typ.OpCode)
+	}
+}
+
+func compareMismatch(typ, a, b Prim) error {
+	return fmt.Errorf("micheline: cannot compare %s and %s as %s", a.DumpLimit(64), b.DumpLimit(64), typ.OpCode)
+}
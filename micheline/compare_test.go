@@ -0,0 +1,164 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package micheline
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+type compareTest struct {
+	Name string
+	Type string
+	// values in ascending order as sorted by the node
+	Values []string
+}
+
+var compareInfo = []compareTest{
+	{
+		Name:   "int",
+		Type:   `{"prim":"int"}`,
+		Values: []string{`{"int":"-100"}`, `{"int":"-1"}`, `{"int":"0"}`, `{"int":"2"}`, `{"int":"100000000000000000000"}`},
+	},
+	{
+		Name:   "string",
+		Type:   `{"prim":"string"}`,
+		Values: []string{`{"string":""}`, `{"string":"A"}`, `{"string":"Z"}`, `{"string":"a"}`, `{"string":"aa"}`, `{"string":"b"}`},
+	},
+	{
+		Name:   "bytes",
+		Type:   `{"prim":"bytes"}`,
+		Values: []string{`{"bytes":""}`, `{"bytes":"00"}`, `{"bytes":"0001"}`, `{"bytes":"01"}`, `{"bytes":"ff"}`},
+	},
+	{
+		Name: "address",
+		Type: `{"prim":"address"}`,
+		Values: []string{
+			`{"string":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"}`,
+			`{"string":"tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs"}`,
+			`{"string":"KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"}`,
+			`{"string":"KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu%mint"}`,
+		},
+	},
+	{
+		Name: "key_hash",
+		Type: `{"prim":"key_hash"}`,
+		Values: []string{
+			`{"string":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"}`,
+			`{"string":"tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs"}`,
+			`{"string":"tz28TEDQtxMmoi4ji4A1iYK8hdLLuUAzCbhk"}`,
+		},
+	},
+	{
+		Name:   "timestamp",
+		Type:   `{"prim":"timestamp"}`,
+		Values: []string{`{"int":"-1"}`, `{"string":"1970-01-01T00:00:00Z"}`, `{"int":"1"}`, `{"string":"2021-01-01T00:00:00Z"}`},
+	},
+	{
+		Name:   "bool",
+		Type:   `{"prim":"bool"}`,
+		Values: []string{`{"prim":"False"}`, `{"prim":"True"}`},
+	},
+	{
+		Name: "pair",
+		Type: `{"prim":"pair","args":[{"prim":"nat"},{"prim":"string"},{"prim":"bool"}]}`,
+		Values: []string{
+			`{"prim":"Pair","args":[{"int":"1"},{"string":"b"},{"prim":"True"}]}`,
+			`{"prim":"Pair","args":[{"int":"2"},{"prim":"Pair","args":[{"string":"a"},{"prim":"False"}]}]}`,
+			`{"prim":"Pair","args":[{"int":"2"},{"string":"a"},{"prim":"True"}]}`,
+			`[{"int":"2"},{"string":"b"},{"prim":"False"}]`,
+		},
+	},
+	{
+		Name: "option",
+		Type: `{"prim":"option","args":[{"prim":"int"}]}`,
+		Values: []string{
+			`{"prim":"None"}`,
+			`{"prim":"Some","args":[{"int":"-5"}]}`,
+			`{"prim":"Some","args":[{"int":"5"}]}`,
+		},
+	},
+	{
+		Name: "or",
+		Type: `{"prim":"or","args":[{"prim":"string"},{"prim":"int"}]}`,
+		Values: []string{
+			`{"prim":"Left","args":[{"string":"a"}]}`,
+			`{"prim":"Left","args":[{"string":"b"}]}`,
+			`{"prim":"Right","args":[{"int":"-1"}]}`,
+		},
+	},
+}
+
+func TestComparePrims(t *testing.T) {
+	for _, test := range compareInfo {
+		typ := newTestType(t, test.Type)
+		vals := make([]Prim, len(test.Values))
+		for i, v := range test.Values {
+			if err := json.Unmarshal([]byte(v), &vals[i]); err != nil {
+				t.Fatalf("%s: %v", test.Name, err)
+			}
+		}
+		for i := range vals {
+			for j := range vals {
+				want := 0
+				switch {
+				case i < j:
+					want = -1
+				case i > j:
+					want = 1
+				}
+				c, err := ComparePrims(vals[i], vals[j], typ)
+				if err != nil {
+					t.Errorf("%s: %s vs %s: %v", test.Name, test.Values[i], test.Values[j], err)
+					continue
+				}
+				if c != want {
+					t.Errorf("%s: %s vs %s: want=%d got=%d", test.Name, test.Values[i], test.Values[j], want, c)
+				}
+			}
+		}
+
+		// sort a reversed copy
+		rev := make([]Prim, len(vals))
+		for i := range vals {
+			rev[len(vals)-1-i] = vals[i]
+		}
+		sort.SliceStable(rev, func(i, j int) bool {
+			c, _ := ComparePrims(rev[i], rev[j], typ)
+			return c < 0
+		})
+		for i := range vals {
+			if !rev[i].IsEqual(vals[i]) {
+				t.Errorf("%s: sort mismatch at %d", test.Name, i)
+			}
+		}
+	}
+}
+
+func TestComparePrimsMixedForms(t *testing.T) {
+	addr := tezos.MustParseAddress("KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu")
+	typ := newTestType(t, `{"prim":"address"}`)
+	c, err := ComparePrims(NewString(addr.String()), NewBytes(addr.Bytes22()), typ)
+	if err != nil || c != 0 {
+		t.Errorf("expected string and bytes address to be equal, got %d err=%v", c, err)
+	}
+	b, _ := hex.DecodeString("0000" + hex.EncodeToString(tezos.MustParseAddress("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb").Hash))
+	c, err = ComparePrims(NewBytes(b), NewString(addr.String()), typ)
+	if err != nil || c != -1 {
+		t.Errorf("expected implicit before originated, got %d err=%v", c, err)
+	}
+
+	// errors
+	if _, err := ComparePrims(NewCode(D_UNIT), NewCode(D_UNIT), newTestType(t, `{"prim":"list","args":[{"prim":"int"}]}`)); err == nil {
+		t.Errorf("expected error for non-comparable type")
+	}
+	if _, err := ComparePrims(NewInt64(1), NewString("1"), newTestType(t, `{"prim":"int"}`)); err == nil {
+		t.Errorf("expected error for mismatched values")
+	}
+}
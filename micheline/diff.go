@@ -5,6 +5,8 @@ package micheline
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 )
 
 type DiffAction byte
@@ -57,3 +59,116 @@ func ParseDiffAction(data string) (DiffAction, error) {
 		return DiffActionUpdate, fmt.Errorf("micheline: invalid big_map_diff action '%s'", string(data))
 	}
 }
+
+// ValueDiffKind describes how a leaf changed between two values.
+type ValueDiffKind byte
+
+const (
+	ValueDiffAdded ValueDiffKind = iota
+	ValueDiffRemoved
+	ValueDiffChanged
+)
+
+func (k ValueDiffKind) String() string {
+	switch k {
+	case ValueDiffAdded:
+		return "added"
+	case ValueDiffRemoved:
+		return "removed"
+	case ValueDiffChanged:
+		return "changed"
+	}
+	return ""
+}
+
+func (k ValueDiffKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// ValueDiff is a single change between two values. Path uses the same dotted
+// notation as the Value getters. Old is nil for added and New is nil for
+// removed paths.
+type ValueDiff struct {
+	Path string        `json:"path"`
+	Kind ValueDiffKind `json:"kind"`
+	Old  interface{}   `json:"old,omitempty"`
+	New  interface{}   `json:"new,omitempty"`
+}
+
+// Diff returns the changes from v to after, see DiffValues.
+func (v Value) Diff(after Value) ([]ValueDiff, error) {
+	return DiffValues(v, after)
+}
+
+// DiffValues compares the decoded trees of two values, usually contract
+// storage before and after an update, and returns all added, removed and
+// changed leaves ordered by path. Map and big_map entries are matched by
+// key, list elements by position. When a subtree changes its shape, e.g. an
+// option turns from None into Some, the subtree is reported as one change.
+func DiffValues(before, after Value) ([]ValueDiff, error) {
+	a, err := before.Map()
+	if err != nil {
+		return nil, err
+	}
+	b, err := after.Map()
+	if err != nil {
+		return nil, err
+	}
+	diff := make([]ValueDiff, 0)
+	diffValueTree(&diff, "", a, b)
+	return diff, nil
+}
+
+func diffValueTree(diff *[]ValueDiff, path string, a, b interface{}) {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(x)+len(y))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		for k := range y {
+			if _, ok := x[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v, inX := x[k]
+			w, inY := y[k]
+			child := joinPath(path, k)
+			switch {
+			case !inY:
+				*diff = append(*diff, ValueDiff{Path: child, Kind: ValueDiffRemoved, Old: v})
+			case !inX:
+				*diff = append(*diff, ValueDiff{Path: child, Kind: ValueDiffAdded, New: w})
+			default:
+				diffValueTree(diff, child, v, w)
+			}
+		}
+		return
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(x) || i < len(y); i++ {
+			child := joinPath(path, strconv.Itoa(i))
+			switch {
+			case i >= len(y):
+				*diff = append(*diff, ValueDiff{Path: child, Kind: ValueDiffRemoved, Old: x[i]})
+			case i >= len(x):
+				*diff = append(*diff, ValueDiff{Path: child, Kind: ValueDiffAdded, New: y[i]})
+			default:
+				diffValueTree(diff, child, x[i], y[i])
+			}
+		}
+		return
+	}
+	if !equalValueTree(a, b) {
+		*diff = append(*diff, ValueDiff{Path: path, Kind: ValueDiffChanged, Old: a, New: b})
+	}
+}
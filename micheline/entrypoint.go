@@ -4,8 +4,13 @@
 package micheline
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+
+	"golang.org/x/crypto/blake2b"
 )
 
 type Entrypoint struct {
@@ -50,6 +55,69 @@ func (e Entrypoints) FindId(id int) (Entrypoint, bool) {
 	return Entrypoint{}, false
 }
 
+// Sorted returns the entrypoints ordered by id, i.e. in the order they are
+// declared in the parameter type.
+func (e Entrypoints) Sorted() []Entrypoint {
+	list := make([]Entrypoint, 0, len(e))
+	for _, v := range e {
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Id < list[j].Id })
+	return list
+}
+
+// Match returns the entrypoint a full parameter value, i.e. a value sent to
+// the root or default entrypoint, is dispatched to. It follows the Left/Right
+// wrappers of value down the union branches until they reach a declared
+// entrypoint.
+func (e Entrypoints) Match(value Prim) (Entrypoint, bool) {
+	branch := ""
+	for {
+		for _, v := range e {
+			if v.Branch == branch {
+				return v, true
+			}
+		}
+		switch {
+		case value.OpCode == D_LEFT && len(value.Args) == 1:
+			branch += "/L"
+		case value.OpCode == D_RIGHT && len(value.Args) == 1:
+			branch += "/R"
+		default:
+			return Entrypoint{}, false
+		}
+		value = value.Args[0]
+	}
+}
+
+// Fingerprint returns a stable hash over the sorted list of entrypoint names,
+// their ids and branches and the hashes of their type definitions. It does not
+// depend on whether entrypoints were created with or without Prim and can be
+// used as cache key.
+func (e Entrypoints) Fingerprint() [32]byte {
+	names := make([]string, 0, len(e))
+	for n := range e {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	h, _ := blake2b.New256(nil)
+	for _, n := range names {
+		ep := e[n]
+		buf, _ := json.Marshal(ep.Typedef)
+		th := blake2b.Sum256(buf)
+		h.Write([]byte(n))
+		h.Write([]byte{0})
+		h.Write([]byte(strconv.Itoa(ep.Id)))
+		h.Write([]byte{0})
+		h.Write([]byte(ep.Branch))
+		h.Write([]byte{0})
+		h.Write(th[:])
+	}
+	var res [32]byte
+	copy(res[:], h.Sum(nil))
+	return res
+}
+
 func (t Type) Entrypoints(withPrim bool) (Entrypoints, error) {
 	e := make(Entrypoints)
 	if !t.IsValid() {
@@ -67,6 +135,47 @@ func (t Type) Entrypoints(withPrim bool) (Entrypoints, error) {
 	return e, nil
 }
 
+// EntrypointSet holds the entrypoints of a parameter type for reuse across
+// many calls to the same contract, see Parameters.MapEntrypointSet. The set
+// keeps its own copy of the type, so later changes to the type it was created
+// from do not affect it. Create a new set when the type changes.
+type EntrypointSet struct {
+	typ Type
+	eps Entrypoints
+}
+
+// NewEntrypointSet computes the entrypoints of parameter type typ.
+func NewEntrypointSet(typ Type) (*EntrypointSet, error) {
+	typ = typ.Clone()
+	eps, err := typ.Entrypoints(true)
+	if err != nil {
+		return nil, err
+	}
+	return &EntrypointSet{typ: typ, eps: eps}, nil
+}
+
+// Type returns the parameter type of the set.
+func (s *EntrypointSet) Type() Type {
+	return s.typ
+}
+
+// Entrypoints returns the entrypoints including their type prims. The map
+// is shared and must not be modified.
+func (s *EntrypointSet) Entrypoints() Entrypoints {
+	return s.eps
+}
+
+// List returns the entrypoints ordered by id.
+func (s *EntrypointSet) List() []Entrypoint {
+	return s.eps.Sorted()
+}
+
+// Matches reports whether the set was created from a type equal to typ.
+// It allows callers to detect stale sets after a type changed.
+func (s *EntrypointSet) Matches(typ Type) bool {
+	return s.typ.IsEqualWithAnno(typ)
+}
+
 // returns path to named entrypoint
 func (t Type) SearchEntrypointName(name string) string {
 	if !t.IsValid() {
@@ -165,3 +274,50 @@ func listEntrypoints(e Entrypoints, branch string, node Prim) error {
 	e[name] = ep
 	return nil
 }
+
+// EntrypointTemplates returns a call template for each entrypoint of the
+// parameter type t. Templates are values of the full parameter type wrapped
+// in the Left/Right nesting which selects the entrypoint, so once filled they
+// can be sent to the root entrypoint. Pairs are expanded and unit becomes
+// Unit, all other leaves are placeholders: a copy of the annotated type
+// prim at that position which tooling replaces with an actual value.
+func (t Type) EntrypointTemplates() map[string]Prim {
+	res := make(map[string]Prim)
+	eps, err := t.Entrypoints(true)
+	if err != nil {
+		return res
+	}
+	for name, ep := range eps {
+		if ep.Prim == nil {
+			continue
+		}
+		tpl := entrypointTemplate(*ep.Prim)
+		steps := strings.Split(strings.Trim(ep.Branch, "/"), "/")
+		for i := len(steps) - 1; i >= 0; i-- {
+			switch steps[i] {
+			case "L":
+				tpl = NewCode(D_LEFT, tpl)
+			case "R":
+				tpl = NewCode(D_RIGHT, tpl)
+			}
+		}
+		res[name] = tpl
+	}
+	return res
+}
+
+// entrypointTemplate returns the value skeleton of type typ.
+func entrypointTemplate(typ Prim) Prim {
+	switch typ.OpCode {
+	case T_PAIR:
+		args := make([]Prim, len(typ.Args))
+		for i, v := range typ.Args {
+			args[i] = entrypointTemplate(v)
+		}
+		return NewCode(D_PAIR, args...)
+	case T_UNIT:
+		return NewCode(D_UNIT)
+	default:
+		return typ.Clone()
+	}
+}
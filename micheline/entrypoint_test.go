@@ -115,3 +115,171 @@ func TestEntrypointRendering(t *testing.T) {
 		})
 	}
 }
+
+func TestEntrypointFingerprint(t *testing.T) {
+	fingerprint := func(spec string, withPrim bool) [32]byte {
+		t.Helper()
+		script := NewScript()
+		if err := script.Code.Param.UnmarshalJSON([]byte(spec)); err != nil {
+			t.Fatalf("unmarshal error: %v", err)
+		}
+		eps, err := script.Entrypoints(withPrim)
+		if err != nil {
+			t.Fatalf("entrypoint list error: %v", err)
+		}
+		return eps.Fingerprint()
+	}
+	seen := make(map[[32]byte]string)
+	for _, test := range entryInfo {
+		a, b := fingerprint(test.Spec, false), fingerprint(test.Spec, true)
+		if a != b {
+			t.Errorf("%s: fingerprint differs between decodes", test.Name)
+		}
+		if name, ok := seen[a]; ok {
+			t.Errorf("%s: fingerprint collides with %s", test.Name, name)
+		}
+		seen[a] = test.Name
+	}
+
+	// same shape, different argument type
+	a := fingerprint(`{"prim":"parameter","args":[{"prim":"option","args":[{"prim":"address"}]}]}`, false)
+	b := fingerprint(`{"prim":"parameter","args":[{"prim":"option","args":[{"prim":"key_hash"}]}]}`, false)
+	if a == b {
+		t.Errorf("expected different fingerprints for different types")
+	}
+
+	// same names and types, different branches
+	a = fingerprint(`{"prim":"parameter","args":[{"prim":"or","args":[{"prim":"or","args":[{"prim":"unit","annots":["%a"]},{"prim":"unit","annots":["%b"]}]},{"prim":"unit","annots":["%c"]}]}]}`, false)
+	b = fingerprint(`{"prim":"parameter","args":[{"prim":"or","args":[{"prim":"unit","annots":["%a"]},{"prim":"or","args":[{"prim":"unit","annots":["%b"]},{"prim":"unit","annots":["%c"]}]}]}]}`, false)
+	if a == b {
+		t.Errorf("expected different fingerprints for different branches")
+	}
+}
+
+func TestEntrypointTemplates(t *testing.T) {
+	p, err := ParseMichelson(`or
+		(or (pair %transfer (address :from) (pair (address :to) (nat :value)))
+			(pair %approve (address :spender) (nat :value)))
+		(or (unit %pause)
+			(or (list %burn nat) (pair %mint (address %to) (nat %amount) (option %memo string))))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ := NewType(p)
+	tpls := typ.EntrypointTemplates()
+	if len(tpls) != 5 {
+		t.Fatalf("expected 5 templates, got %d", len(tpls))
+	}
+	for name, want := range map[string]string{
+		"transfer": `Left (Left (Pair (address :from) (Pair (address :to) (nat :value))))`,
+		"pause":    `Right (Left Unit)`,
+		"mint":     `Right (Right (Right (Pair (address %to) (nat %amount) (option %memo string))))`,
+	} {
+		if got := tpls[name].Michelson(); got != want {
+			t.Errorf("%s: template mismatch\n  want=%s\n  got= %s", name, want, got)
+		}
+	}
+
+	// each template resolves back to its entrypoint
+	for name, tpl := range tpls {
+		ep, _, err := Parameters{Value: tpl}.MapEntrypoint(typ)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if ep.Call != name {
+			t.Errorf("%s: resolved to %s", name, ep.Call)
+		}
+	}
+
+	// a parameter without entrypoints yields an unwrapped template
+	single := NewType(NewCode(T_NAT))
+	if tpl := single.EntrypointTemplates()["default"]; tpl.OpCode != T_NAT {
+		t.Errorf("unexpected default template %s", tpl.Dump())
+	}
+}
+
+func TestEntrypointMatch(t *testing.T) {
+	// right comb of unions with a nested annotated union
+	p, err := ParseMichelson(`or (nat %a)
+		(or (string %b)
+			(or (or %choose (unit %yes) (unit %no))
+				(or (bytes %c) (pair %d nat nat))))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eps, err := NewType(p).Entrypoints(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for val, want := range map[string]string{
+		`Left 1`:                                   "a",
+		`Right (Left "x")`:                         "b",
+		`Right (Right (Left (Left Unit)))`:         "yes",
+		`Right (Right (Left (Right Unit)))`:        "no",
+		`Right (Right (Right (Left 0x00)))`:        "c",
+		`Right (Right (Right (Right (Pair 1 2))))`: "d",
+	} {
+		v, err := ParseMichelson(val)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ep, ok := eps.Match(v)
+		if !ok || ep.Call != want {
+			t.Errorf("%s: matched %q %t, want %q", val, ep.Call, ok, want)
+		}
+	}
+
+	// values which stop above an entrypoint do not match
+	v, _ := ParseMichelson(`Right (Right 1)`)
+	if ep, ok := eps.Match(v); ok {
+		t.Errorf("unexpected match %s", ep.Call)
+	}
+
+	// a single entrypoint matches any value
+	single, _ := NewType(NewCode(T_NAT)).Entrypoints(false)
+	if ep, ok := single.Match(NewInt64(1)); !ok || ep.Call != "default" {
+		t.Errorf("unexpected single match %q %t", ep.Call, ok)
+	}
+}
+
+func TestEntrypointSet(t *testing.T) {
+	p, err := ParseMichelson(`or (or (nat %a) (string %b)) (or (unit %c) (pair %d nat nat))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ := NewType(p)
+	set, err := NewEntrypointSet(typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// listed in declaration order with their types
+	list := set.List()
+	if len(list) != 4 {
+		t.Fatalf("expected 4 entrypoints, got %d", len(list))
+	}
+	for i, name := range []string{"a", "b", "c", "d"} {
+		if list[i].Id != i || list[i].Call != name || !list[i].Type().IsValid() {
+			t.Errorf("entry %d: unexpected %s id=%d", i, list[i].Call, list[i].Id)
+		}
+	}
+
+	// same result as mapping with the type
+	v, _ := ParseMichelson(`Right (Right (Pair 1 2))`)
+	params := Parameters{Value: v}
+	ep1, prim1, m1, err1 := params.MapEntrypointExt(typ)
+	ep2, prim2, m2, err2 := params.MapEntrypointSet(set)
+	if err1 != nil || err2 != nil || ep1.Call != ep2.Call || !prim1.IsEqual(prim2) || m1 != m2 {
+		t.Errorf("set mapping differs: %s/%s %v/%v", ep1.Call, ep2.Call, err1, err2)
+	}
+
+	// changes to the original type do not leak into the set
+	typ.Args[0].Args[0].Anno = []string{"%x"}
+	if set.Matches(typ) {
+		t.Errorf("expected stale set")
+	}
+	if _, ok := set.Entrypoints()["a"]; !ok {
+		t.Errorf("set changed with original type")
+	}
+}
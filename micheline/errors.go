@@ -0,0 +1,41 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"errors"
+)
+
+var (
+	// ErrTypeMismatch is returned when a value does not match its type.
+	ErrTypeMismatch = errors.New("micheline: type mismatch")
+
+	// ErrMaxDepth is returned when a value is nested deeper than the
+	// decoding limit, see Value.MaxDepth.
+	ErrMaxDepth = errors.New("micheline: max nesting level reached")
+
+	// ErrUnexpectedOpCode is returned when a value contains an opcode
+	// that is invalid for its type, like a map item other than Elt.
+	ErrUnexpectedOpCode = errors.New("micheline: unexpected opcode")
+)
+
+// DecodeError is returned when a value cannot be decoded into a tree by Map,
+// FlatPairs, WalkTyped and friends. It wraps one of ErrTypeMismatch,
+// ErrMaxDepth or ErrUnexpectedOpCode, so failures can be classified with
+// errors.Is, and carries the location of the failure.
+type DecodeError struct {
+	Err      error  // failure class
+	Path     string // path of the container holding the failed node, empty at the root
+	Expected OpCode // type code being decoded
+	Actual   OpCode // opcode of the offending value
+	msg      string
+}
+
+func (e *DecodeError) Error() string {
+	return e.msg
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
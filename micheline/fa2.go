@@ -0,0 +1,172 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"fmt"
+	"math/big"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// FA2Kind identifies the ledger layout of a TZIP-012 (FA2) token contract.
+type FA2Kind byte
+
+const (
+	FA2Unknown     FA2Kind = iota // no known ledger found
+	FA2SingleAsset                // address -> nat
+	FA2MultiAsset                 // (address, nat) -> nat
+	FA2NFT                        // nat -> address
+)
+
+func (k FA2Kind) String() string {
+	switch k {
+	case FA2SingleAsset:
+		return "single_asset"
+	case FA2MultiAsset:
+		return "multi_asset"
+	case FA2NFT:
+		return "nft"
+	default:
+		return "unknown"
+	}
+}
+
+// DetectFA2Kind inspects the key and value types of the ledger in a FA2
+// storage type. A map or big_map annotated %ledger is preferred, otherwise the
+// first big_map with a known ledger shape is used. When typ is invalid the
+// type of storage is used instead.
+func DetectFA2Kind(typ Type, storage Value) FA2Kind {
+	if !typ.IsValid() {
+		typ = storage.Type
+	}
+	if ledgers, ok := typ.FindLabels("ledger"); ok {
+		for _, l := range ledgers {
+			if k := fa2LedgerKind(l); k != FA2Unknown {
+				return k
+			}
+		}
+	}
+	maps, _ := typ.FindOpCodes(T_BIG_MAP)
+	for _, m := range maps {
+		if k := fa2LedgerKind(m); k != FA2Unknown {
+			return k
+		}
+	}
+	return FA2Unknown
+}
+
+// fa2LedgerKind returns the ledger kind of a map or big_map type.
+func fa2LedgerKind(typ Prim) FA2Kind {
+	if (typ.OpCode != T_BIG_MAP && typ.OpCode != T_MAP) || len(typ.Args) != 2 {
+		return FA2Unknown
+	}
+	key, val := typ.Args[0], typ.Args[1]
+	switch {
+	case key.OpCode == T_ADDRESS && val.OpCode == T_NAT:
+		return FA2SingleAsset
+	case key.OpCode == T_NAT && val.OpCode == T_ADDRESS:
+		return FA2NFT
+	case key.OpCode == T_PAIR && val.OpCode == T_NAT:
+		key = binaryPair(key)
+		if len(key.Args) != 2 {
+			return FA2Unknown
+		}
+		l, r := key.Args[0].OpCode, key.Args[1].OpCode
+		if (l == T_ADDRESS && r == T_NAT) || (l == T_NAT && r == T_ADDRESS) {
+			return FA2MultiAsset
+		}
+	}
+	return FA2Unknown
+}
+
+// FA2Balance is a single FA2 ledger entry.
+type FA2Balance struct {
+	Owner   tezos.Address
+	TokenId *big.Int
+	Balance *big.Int
+}
+
+// DecodeFA2Ledger decodes all entries of a FA2 ledger. The value may be the
+// ledger map itself, e.g. built from big_map keys and values, or a storage
+// value which contains a map annotated %ledger. Single asset ledgers report
+// token id 0 and NFT ledgers a balance of 1. Ledgers of an unknown layout and
+// big_map ids without contents are rejected with an error.
+func DecodeFA2Ledger(val Value) ([]FA2Balance, error) {
+	typ, v := val.Type.Prim, val.Value
+	if typ.OpCode != T_MAP && typ.OpCode != T_BIG_MAP {
+		var ok bool
+		typ, v, ok = findTypedPrim(typ, v, "ledger")
+		if !ok {
+			return nil, fmt.Errorf("micheline: no FA2 ledger found")
+		}
+	}
+	kind := fa2LedgerKind(typ)
+	if kind == FA2Unknown {
+		return nil, fmt.Errorf("micheline: non-standard FA2 ledger type %s", typ.DumpLimit(64))
+	}
+	if v.Type == PrimInt {
+		return nil, fmt.Errorf("micheline: FA2 ledger is big_map %s without contents", v.Int)
+	}
+	if v.Type != PrimSequence {
+		return nil, fmt.Errorf("micheline: invalid FA2 ledger value %s", v.DumpLimit(64))
+	}
+	res := make([]FA2Balance, 0, len(v.Args))
+	for _, elt := range v.Args {
+		if elt.OpCode != D_ELT || len(elt.Args) != 2 {
+			return nil, fmt.Errorf("micheline: invalid FA2 ledger entry %s", elt.DumpLimit(64))
+		}
+		b, err := decodeFA2Entry(kind, typ.Args[0], elt.Args[0], elt.Args[1])
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, b)
+	}
+	return res, nil
+}
+
+// decodeFA2Entry decodes a single ledger key and value of a known kind.
+func decodeFA2Entry(kind FA2Kind, keyType, key, val Prim) (FA2Balance, error) {
+	var (
+		b   FA2Balance
+		err error
+	)
+	switch kind {
+	case FA2SingleAsset:
+		b.TokenId = big.NewInt(0)
+		if b.Owner, err = decodeAddressPrim(key); err == nil {
+			b.Balance, err = decodeFA2Nat(val)
+		}
+	case FA2NFT:
+		b.Balance = big.NewInt(1)
+		if b.TokenId, err = decodeFA2Nat(key); err == nil {
+			b.Owner, err = decodeAddressPrim(val)
+		}
+	case FA2MultiAsset:
+		key = binaryPair(key)
+		if len(key.Args) != 2 {
+			return b, fmt.Errorf("micheline: invalid FA2 ledger key %s", key.DumpLimit(64))
+		}
+		owner, id := key.Args[0], key.Args[1]
+		if binaryPair(keyType).Args[0].OpCode == T_NAT {
+			owner, id = id, owner
+		}
+		if b.Owner, err = decodeAddressPrim(owner); err == nil {
+			if b.TokenId, err = decodeFA2Nat(id); err == nil {
+				b.Balance, err = decodeFA2Nat(val)
+			}
+		}
+	}
+	if err != nil {
+		return FA2Balance{}, fmt.Errorf("micheline: invalid FA2 ledger entry: %w", err)
+	}
+	return b, nil
+}
+
+func decodeFA2Nat(p Prim) (*big.Int, error) {
+	if p.Type != PrimInt || p.Int == nil || p.Int.Sign() < 0 {
+		return nil, fmt.Errorf("invalid nat %s", p.DumpLimit(64))
+	}
+	return new(big.Int).Set(p.Int), nil
+}
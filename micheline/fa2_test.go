@@ -0,0 +1,161 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package micheline
+
+import (
+	"testing"
+)
+
+func TestDetectFA2Kind(t *testing.T) {
+	for _, test := range []struct {
+		Name    string
+		Type    string
+		Storage string
+		Kind    FA2Kind
+	}{
+		{
+			Name: "single asset",
+			Type: `{"prim":"pair","args":[
+				{"prim":"big_map","annots":["%ledger"],"args":[{"prim":"address"},{"prim":"nat"}]},
+				{"prim":"nat","annots":["%total_supply"]}
+			]}`,
+			Storage: `{"prim":"Pair","args":[{"int":"17"},{"int":"1000"}]}`,
+			Kind:    FA2SingleAsset,
+		},
+		{
+			Name: "multi asset",
+			Type: `{"prim":"pair","args":[
+				{"prim":"address","annots":["%administrator"]},
+				{"prim":"big_map","annots":["%ledger"],"args":[
+					{"prim":"pair","args":[{"prim":"address"},{"prim":"nat"}]},{"prim":"nat"}
+				]},
+				{"prim":"big_map","annots":["%operators"],"args":[
+					{"prim":"pair","args":[{"prim":"address"},{"prim":"address"},{"prim":"nat"}]},{"prim":"unit"}
+				]}
+			]}`,
+			Storage: `{"prim":"Pair","args":[{"string":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"},{"int":"1"},{"int":"2"}]}`,
+			Kind:    FA2MultiAsset,
+		},
+		{
+			Name: "nft",
+			Type: `{"prim":"pair","args":[
+				{"prim":"big_map","annots":["%operators"],"args":[
+					{"prim":"pair","args":[{"prim":"address"},{"prim":"address"}]},{"prim":"unit"}
+				]},
+				{"prim":"big_map","annots":["%ledger"],"args":[{"prim":"nat"},{"prim":"address"}]}
+			]}`,
+			Storage: `{"prim":"Pair","args":[{"int":"5"},{"int":"6"}]}`,
+			Kind:    FA2NFT,
+		},
+		{
+			Name: "unannotated multi asset",
+			Type: `{"prim":"pair","args":[
+				{"prim":"big_map","args":[{"prim":"string"},{"prim":"bytes"}]},
+				{"prim":"big_map","args":[{"prim":"pair","args":[{"prim":"nat"},{"prim":"address"}]},{"prim":"nat"}]}
+			]}`,
+			Storage: `{"prim":"Pair","args":[{"int":"3"},{"int":"4"}]}`,
+			Kind:    FA2MultiAsset,
+		},
+		{
+			Name:    "in-storage map",
+			Type:    `{"prim":"map","annots":["%ledger"],"args":[{"prim":"address"},{"prim":"nat"}]}`,
+			Storage: `[{"prim":"Elt","args":[{"string":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"},{"int":"10"}]}]`,
+			Kind:    FA2SingleAsset,
+		},
+		{
+			Name:    "not a ledger",
+			Type:    `{"prim":"pair","args":[{"prim":"big_map","args":[{"prim":"string"},{"prim":"nat"}]},{"prim":"nat"}]}`,
+			Storage: `{"prim":"Pair","args":[{"int":"3"},{"int":"4"}]}`,
+			Kind:    FA2Unknown,
+		},
+	} {
+		val := newTestValue(t, test.Type, test.Storage)
+		if got := DetectFA2Kind(val.Type, *val); got != test.Kind {
+			t.Errorf("%s: want=%s got=%s", test.Name, test.Kind, got)
+		}
+		// type taken from storage value
+		if got := DetectFA2Kind(Type{}, *val); got != test.Kind {
+			t.Errorf("%s: storage type: want=%s got=%s", test.Name, test.Kind, got)
+		}
+	}
+}
+
+func TestDecodeFA2Ledger(t *testing.T) {
+	const (
+		alice = "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"
+		bob   = "tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs"
+	)
+	for _, test := range []struct {
+		Name  string
+		Type  string
+		Value string
+		Want  []string // owner:token:balance
+		Err   bool
+	}{
+		{
+			Name:  "single asset",
+			Type:  `{"prim":"big_map","args":[{"prim":"address"},{"prim":"nat"}]}`,
+			Value: `[{"prim":"Elt","args":[{"string":"` + alice + `"},{"int":"100"}]},{"prim":"Elt","args":[{"bytes":"000100e365162e6d78d258e6850e31d3820b19e82008"},{"int":"5"}]}]`,
+			Want:  []string{alice + ":0:100", bob + ":0:5"},
+		},
+		{
+			Name:  "multi asset",
+			Type:  `{"prim":"map","args":[{"prim":"pair","args":[{"prim":"address"},{"prim":"nat"}]},{"prim":"nat"}]}`,
+			Value: `[{"prim":"Elt","args":[{"prim":"Pair","args":[{"string":"` + alice + `"},{"int":"3"}]},{"int":"7"}]}]`,
+			Want:  []string{alice + ":3:7"},
+		},
+		{
+			Name:  "multi asset token first",
+			Type:  `{"prim":"map","args":[{"prim":"pair","args":[{"prim":"nat"},{"prim":"address"}]},{"prim":"nat"}]}`,
+			Value: `[{"prim":"Elt","args":[[{"int":"4"},{"string":"` + bob + `"}],{"int":"1"}]}]`,
+			Want:  []string{bob + ":4:1"},
+		},
+		{
+			Name:  "nft in storage",
+			Type:  `{"prim":"pair","args":[{"prim":"address","annots":["%admin"]},{"prim":"map","annots":["%ledger"],"args":[{"prim":"nat"},{"prim":"address"}]}]}`,
+			Value: `{"prim":"Pair","args":[{"string":"` + bob + `"},[{"prim":"Elt","args":[{"int":"12"},{"string":"` + alice + `"}]}]]}`,
+			Want:  []string{alice + ":12:1"},
+		},
+		{
+			Name:  "big_map id",
+			Type:  `{"prim":"pair","args":[{"prim":"big_map","annots":["%ledger"],"args":[{"prim":"address"},{"prim":"nat"}]},{"prim":"nat"}]}`,
+			Value: `{"prim":"Pair","args":[{"int":"17"},{"int":"1000"}]}`,
+			Err:   true,
+		},
+		{
+			Name:  "non-standard",
+			Type:  `{"prim":"map","args":[{"prim":"address"},{"prim":"pair","args":[{"prim":"nat"},{"prim":"map","args":[{"prim":"address"},{"prim":"nat"}]}]}]}`,
+			Value: `[]`,
+			Err:   true,
+		},
+		{
+			Name:  "negative balance",
+			Type:  `{"prim":"map","args":[{"prim":"address"},{"prim":"nat"}]}`,
+			Value: `[{"prim":"Elt","args":[{"string":"` + alice + `"},{"int":"-1"}]}]`,
+			Err:   true,
+		},
+	} {
+		res, err := DecodeFA2Ledger(*newTestValue(t, test.Type, test.Value))
+		if test.Err {
+			if err == nil {
+				t.Errorf("%s: expected error", test.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.Name, err)
+			continue
+		}
+		if len(res) != len(test.Want) {
+			t.Errorf("%s: expected %d entries, got %d", test.Name, len(test.Want), len(res))
+			continue
+		}
+		for i, b := range res {
+			if got := b.Owner.String() + ":" + b.TokenId.String() + ":" + b.Balance.String(); got != test.Want[i] {
+				t.Errorf("%s: entry %d want=%s got=%s", test.Name, i, test.Want[i], got)
+			}
+		}
+	}
+}
@@ -0,0 +1,384 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"encoding"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// MarshalPrim converts a Go value into a Prim tree that matches type typ. Struct
+// fields are matched against type annotations using the `michelson` struct tag
+// or, when no tag is present, a case-insensitive match on the field name. Tag
+// `michelson:"-"` skips a field. Unannotated pair combs are flattened into the
+// surrounding struct, alternatively pairs may be filled from slices or arrays
+// of matching length.
+//
+// Supported Go inputs are
+//
+//	pair             struct, map[string]T, slice or array of arguments
+//	option           nil pointer or interface (None), any other value (Some)
+//	list, set        slice or array
+//	map, big_map     Go map (elements are sorted by key)
+//	int, nat, mutez  signed and unsigned integers, *big.Int, tezos.Z, string
+//	timestamp        time.Time, integers (UNIX seconds), RFC3339 string
+//	string           string
+//	bytes            []byte, encoding.BinaryMarshaler
+//	bool             bool
+//	unit             any value
+//	address, contract, key_hash  tezos.Address or string
+//	key, signature, chain_id     tezos.Key, tezos.Signature, Stringer or string
+//
+// A Prim passed as value is used unchanged. Missing struct fields are an error
+// unless the field's type is an option which is then set to None.
+func MarshalPrim(typ Type, val interface{}) (Prim, error) {
+	return marshalPrim(typ.Prim, reflect.ValueOf(val), "")
+}
+
+var (
+	primType   = reflect.TypeOf(Prim{})
+	timeType   = reflect.TypeOf(time.Time{})
+	bigIntType = reflect.TypeOf(big.Int{})
+	zType      = reflect.TypeOf(tezos.Z{})
+	addrType   = reflect.TypeOf(tezos.Address{})
+)
+
+func marshalPrim(typ Prim, val reflect.Value, path string) (Prim, error) {
+	// unwrap interfaces and pointers except for option types which
+	// need them to detect None
+	if typ.OpCode != T_OPTION {
+		val = indirect(val)
+	}
+	if val.IsValid() && val.Type() == primType {
+		return val.Interface().(Prim), nil
+	}
+	if !val.IsValid() && typ.OpCode != T_OPTION && typ.OpCode != T_UNIT {
+		return Prim{}, fmt.Errorf("micheline: missing value for %s of type %s", pathName(path), typ.OpCode)
+	}
+
+	switch typ.OpCode {
+	case T_PAIR:
+		return marshalPair(typ, val, path)
+
+	case T_OPTION:
+		val = indirectNil(val)
+		if !val.IsValid() {
+			return NewCode(D_NONE), nil
+		}
+		p, err := marshalPrim(typ.Args[0], val, path)
+		if err != nil {
+			return Prim{}, err
+		}
+		return NewCode(D_SOME, p), nil
+
+	case T_LIST, T_SET:
+		if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+			return Prim{}, typeMismatch(typ, val, path)
+		}
+		seq := NewSeq()
+		for i := 0; i < val.Len(); i++ {
+			p, err := marshalPrim(typ.Args[0], val.Index(i), joinPath(path, fmt.Sprint(i)))
+			if err != nil {
+				return Prim{}, err
+			}
+			seq.Args = append(seq.Args, p)
+		}
+		if typ.OpCode == T_SET {
+			sortPrims(seq.Args, typ.Args[0], func(p Prim) Prim { return p })
+		}
+		return seq, nil
+
+	case T_MAP, T_BIG_MAP:
+		if val.Kind() != reflect.Map {
+			return Prim{}, typeMismatch(typ, val, path)
+		}
+		seq := NewSeq()
+		iter := val.MapRange()
+		for iter.Next() {
+			name := fmt.Sprint(iter.Key().Interface())
+			k, err := marshalPrim(typ.Args[0], iter.Key(), joinPath(path, name))
+			if err != nil {
+				return Prim{}, err
+			}
+			v, err := marshalPrim(typ.Args[1], iter.Value(), joinPath(path, name))
+			if err != nil {
+				return Prim{}, err
+			}
+			seq.Args = append(seq.Args, NewCode(D_ELT, k, v))
+		}
+		sortPrims(seq.Args, typ.Args[0], func(p Prim) Prim { return p.Args[0] })
+		return seq, nil
+
+	case T_UNIT:
+		return NewCode(D_UNIT), nil
+
+	case T_BOOL:
+		if val.Kind() != reflect.Bool {
+			return Prim{}, typeMismatch(typ, val, path)
+		}
+		if val.Bool() {
+			return NewCode(D_TRUE), nil
+		}
+		return NewCode(D_FALSE), nil
+
+	case T_INT, T_NAT, T_MUTEZ:
+		i, err := marshalInt(typ, val, path)
+		if err != nil {
+			return Prim{}, err
+		}
+		if typ.OpCode != T_INT && i.Sign() < 0 {
+			return Prim{}, fmt.Errorf("micheline: negative value %s for %s of type %s", i, pathName(path), typ.OpCode)
+		}
+		return NewBig(i), nil
+
+	case T_TIMESTAMP:
+		switch {
+		case val.Type() == timeType:
+			return NewInt64(val.Interface().(time.Time).Unix()), nil
+		case val.Kind() == reflect.String:
+			if _, ok := parseTimestamp(val.String()); !ok {
+				return Prim{}, fmt.Errorf("micheline: invalid timestamp %q for %s", val.String(), pathName(path))
+			}
+			return NewString(val.String()), nil
+		}
+		i, err := marshalInt(typ, val, path)
+		if err != nil {
+			return Prim{}, err
+		}
+		return NewBig(i), nil
+
+	case T_STRING:
+		if val.Kind() != reflect.String {
+			return Prim{}, typeMismatch(typ, val, path)
+		}
+		return NewString(val.String()), nil
+
+	case T_BYTES:
+		if val.Kind() == reflect.Slice && val.Type().Elem().Kind() == reflect.Uint8 {
+			return NewBytes(val.Bytes()), nil
+		}
+		if m, ok := val.Interface().(encoding.BinaryMarshaler); ok {
+			buf, err := m.MarshalBinary()
+			if err != nil {
+				return Prim{}, fmt.Errorf("micheline: %s: %v", pathName(path), err)
+			}
+			return NewBytes(buf), nil
+		}
+		return Prim{}, typeMismatch(typ, val, path)
+
+	case T_ADDRESS, T_CONTRACT, T_KEY_HASH:
+		var a tezos.Address
+		switch {
+		case val.Type() == addrType:
+			a = val.Interface().(tezos.Address)
+		case val.Kind() == reflect.String:
+			var err error
+			a, err = tezos.ParseAddress(val.String())
+			if err != nil {
+				return Prim{}, fmt.Errorf("micheline: %s: %v", pathName(path), err)
+			}
+		default:
+			return Prim{}, typeMismatch(typ, val, path)
+		}
+		if !a.IsValid() {
+			return Prim{}, fmt.Errorf("micheline: invalid address for %s", pathName(path))
+		}
+		if typ.OpCode == T_KEY_HASH {
+			if !a.IsImplicit() {
+				return Prim{}, fmt.Errorf("micheline: non-implicit address %s for %s of type key_hash", a, pathName(path))
+			}
+			return NewBytes(a.Bytes()), nil
+		}
+		return NewBytes(a.Bytes22()), nil
+
+	case T_KEY, T_SIGNATURE, T_CHAIN_ID:
+		if val.Kind() == reflect.String {
+			return NewString(val.String()), nil
+		}
+		if s, ok := val.Interface().(fmt.Stringer); ok {
+			return NewString(s.String()), nil
+		}
+		return Prim{}, typeMismatch(typ, val, path)
+
+	default:
+		return Prim{}, fmt.Errorf("micheline: marshaling type %s for %s is not supported", typ.OpCode, pathName(path))
+	}
+}
+
+// marshalPair builds a (possibly nested) Pair value. Pair types may be combs
+// with more than two arguments. Each annotated argument is looked up as a field
+// of val, unannotated pairs are filled from val itself.
+func marshalPair(typ Prim, val reflect.Value, path string) (Prim, error) {
+	pair := NewCode(D_PAIR, make([]Prim, 0, len(typ.Args))...)
+	pair.Type = PrimBinary
+	if len(typ.Args) > 2 {
+		pair.Type = PrimVariadicAnno
+	}
+
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		if val.Len() != len(typ.Args) {
+			return Prim{}, fmt.Errorf("micheline: pair %s needs %d arguments, got %d",
+				pathName(path), len(typ.Args), val.Len())
+		}
+		for i, t := range typ.Args {
+			p, err := marshalPrim(t, val.Index(i), joinPath(path, fmt.Sprint(i)))
+			if err != nil {
+				return Prim{}, err
+			}
+			pair.Args = append(pair.Args, p)
+		}
+		return pair, nil
+	case reflect.Struct, reflect.Map:
+	default:
+		return Prim{}, typeMismatch(typ, val, path)
+	}
+
+	for _, t := range typ.Args {
+		label := t.GetVarAnnoAny()
+		if label == "" {
+			if t.OpCode != T_PAIR {
+				return Prim{}, fmt.Errorf("micheline: unannotated %s argument in pair %s cannot be mapped from %s",
+					t.OpCode, pathName(path), val.Type())
+			}
+			p, err := marshalPair(t, val, path)
+			if err != nil {
+				return Prim{}, err
+			}
+			pair.Args = append(pair.Args, p)
+			continue
+		}
+		f, ok := lookupField(val, label)
+		if !ok {
+			if t.OpCode == T_OPTION {
+				pair.Args = append(pair.Args, NewCode(D_NONE))
+				continue
+			}
+			return Prim{}, fmt.Errorf("micheline: missing field %s in %s", joinPath(path, label), val.Type())
+		}
+		p, err := marshalPrim(t, f, joinPath(path, label))
+		if err != nil {
+			return Prim{}, err
+		}
+		pair.Args = append(pair.Args, p)
+	}
+	return pair, nil
+}
+
+func marshalInt(typ Prim, val reflect.Value, path string) (*big.Int, error) {
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(val.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(val.Uint()), nil
+	case reflect.String:
+		i, ok := new(big.Int).SetString(val.String(), 10)
+		if !ok {
+			return nil, fmt.Errorf("micheline: invalid %s value %q for %s", typ.OpCode, val.String(), pathName(path))
+		}
+		return i, nil
+	}
+	switch val.Type() {
+	case bigIntType:
+		i := val.Interface().(big.Int)
+		return new(big.Int).Set(&i), nil
+	case zType:
+		z := val.Interface().(tezos.Z)
+		return new(big.Int).Set(z.Big()), nil
+	}
+	return nil, typeMismatch(typ, val, path)
+}
+
+// lookupField finds a struct field or string map key matching label.
+func lookupField(val reflect.Value, label string) (reflect.Value, bool) {
+	if val.Kind() == reflect.Map {
+		if val.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, false
+		}
+		f := val.MapIndex(reflect.ValueOf(label).Convert(val.Type().Key()))
+		return f, f.IsValid()
+	}
+	typ := val.Type()
+	var fallback reflect.Value
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		tag := sf.Tag.Get("michelson")
+		if tag == "-" {
+			continue
+		}
+		if sf.Anonymous && tag == "" {
+			if fv := indirect(val.Field(i)); fv.Kind() == reflect.Struct {
+				if f, ok := lookupField(fv, label); ok {
+					return f, true
+				}
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue // unexported
+		}
+		if name := strings.Split(tag, ",")[0]; name != "" {
+			if name == label {
+				return val.Field(i), true
+			}
+			continue
+		}
+		if !fallback.IsValid() && strings.EqualFold(sf.Name, label) {
+			fallback = val.Field(i)
+		}
+	}
+	return fallback, fallback.IsValid()
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// indirectNil is like indirect but keeps the outermost non-nil value.
+func indirectNil(v reflect.Value) reflect.Value {
+	if v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface || v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.IsNil() {
+		return reflect.Value{}
+	}
+	return v
+}
+
+// sortPrims orders set and map elements by their comparable key as required
+// by the Michelson type checker.
+func sortPrims(args []Prim, typ Prim, key func(Prim) Prim) {
+	sort.SliceStable(args, func(i, j int) bool {
+		c, err := ComparePrims(key(args[i]), key(args[j]), Type{typ})
+		return err == nil && c < 0
+	})
+}
+
+func typeMismatch(typ Prim, val reflect.Value, path string) error {
+	return fmt.Errorf("micheline: cannot marshal %s into %s of type %s", val.Type(), pathName(path), typ.OpCode)
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + PATH_SEPARATOR + name
+}
+
+func pathName(path string) string {
+	if path == "" {
+		return "value"
+	}
+	return path
+}
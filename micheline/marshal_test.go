@@ -0,0 +1,121 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package micheline
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+type testTransferDest struct {
+	To      tezos.Address `michelson:"to_"`
+	TokenId int64         `michelson:"token_id"`
+	Amount  *big.Int      `michelson:"amount"`
+}
+
+type testTransfer struct {
+	From tezos.Address      `michelson:"from_"`
+	Txs  []testTransferDest `michelson:"txs"`
+}
+
+const testTransferType = `{"prim":"list","args":[{"prim":"pair","args":[
+	{"prim":"address","annots":["%from_"]},
+	{"prim":"list","annots":["%txs"],"args":[{"prim":"pair","args":[
+		{"prim":"address","annots":["%to_"]},
+		{"prim":"pair","args":[{"prim":"nat","annots":["%token_id"]},{"prim":"nat","annots":["%amount"]}]}
+	]}]}
+]}]}`
+
+func newTestType(t *testing.T, typ string) Type {
+	t.Helper()
+	var p Prim
+	if err := json.Unmarshal([]byte(typ), &p); err != nil {
+		t.Fatalf("invalid json type: %v", err)
+	}
+	return NewType(p)
+}
+
+func TestMarshalPrim(t *testing.T) {
+	from := tezos.MustParseAddress("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb")
+	to := tezos.MustParseAddress("KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu")
+	typ := newTestType(t, testTransferType)
+	prim, err := MarshalPrim(typ, []testTransfer{{
+		From: from,
+		Txs:  []testTransferDest{{To: to, TokenId: 0, Amount: big.NewInt(1000)}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, _ := json.Marshal(prim)
+	want := `[{"args":[{"bytes":"` + hex.EncodeToString(from.Bytes22()) + `"},` +
+		`[{"args":[{"bytes":"` + hex.EncodeToString(to.Bytes22()) + `"},` +
+		`{"args":[{"int":"0"},{"int":"1000"}],"prim":"Pair"}],"prim":"Pair"}]],"prim":"Pair"}]`
+	if string(buf) != want {
+		t.Errorf("mismatch\n  want=%s\n  got= %s", want, buf)
+	}
+
+	// decode back through Value
+	val := NewValue(typ, prim)
+	if a, ok := val.GetAddress("0.from_"); !ok || !a.Equal(from) {
+		t.Errorf("from_ mismatch got=%s", a)
+	}
+	if a, ok := val.GetAddress("0.txs.0.to_"); !ok || !a.Equal(to) {
+		t.Errorf("to_ mismatch got=%s", a)
+	}
+	if n, ok := val.GetBig("0.txs.0.amount"); !ok || n.Int64() != 1000 {
+		t.Errorf("amount mismatch got=%s", n)
+	}
+}
+
+func TestMarshalPrimOptionsAndMaps(t *testing.T) {
+	typ := newTestType(t, `{"prim":"pair","args":[
+		{"prim":"option","annots":["%owner"],"args":[{"prim":"string"}]},
+		{"prim":"pair","args":[
+			{"prim":"option","annots":["%limit"],"args":[{"prim":"mutez"}]},
+			{"prim":"map","annots":["%meta"],"args":[{"prim":"string"},{"prim":"bool"}]}
+		]}
+	]}`)
+	limit := int64(5)
+	prim, err := MarshalPrim(typ, map[string]interface{}{
+		"limit": &limit,
+		"meta":  map[string]bool{"b": false, "a": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, _ := json.Marshal(prim)
+	want := `{"args":[{"prim":"None"},{"args":[` +
+		`{"args":[{"int":"5"}],"prim":"Some"},` +
+		`[{"args":[{"string":"a"},{"prim":"True"}],"prim":"Elt"},{"args":[{"string":"b"},{"prim":"False"}],"prim":"Elt"}]],"prim":"Pair"}],"prim":"Pair"}`
+	if string(buf) != want {
+		t.Errorf("mismatch\n  want=%s\n  got= %s", want, buf)
+	}
+}
+
+func TestMarshalPrimErrors(t *testing.T) {
+	typ := newTestType(t, testTransferType)
+	from := tezos.MustParseAddress("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb")
+	for _, test := range []struct {
+		Val  interface{}
+		Want string
+	}{
+		{Val: []struct {
+			From string `michelson:"from_"`
+		}{{From: from.String()}}, Want: "missing field 0.txs"},
+		{Val: []testTransfer{{From: from, Txs: []testTransferDest{{To: from, Amount: big.NewInt(-1)}}}}, Want: "negative value"},
+		{Val: []map[string]interface{}{{"from_": 1, "txs": nil}}, Want: "cannot marshal int into 0.from_"},
+		{Val: "x", Want: "cannot marshal string"},
+	} {
+		_, err := MarshalPrim(typ, test.Val)
+		if err == nil || !strings.Contains(err.Error(), test.Want) {
+			t.Errorf("expected error %q, got %v", test.Want, err)
+		}
+	}
+}
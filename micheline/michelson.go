@@ -0,0 +1,157 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// MichelsonPrinter renders prim trees in Michelson concrete syntax as
+// accepted by octez-client. Comb pairs keep their n-ary form and
+// annotations follow the primitive name in their original order.
+type MichelsonPrinter struct {
+	// Indent is inserted once per nesting level before each element of a
+	// sequence that is broken into lines. Sequences are broken when they
+	// contain nested sequences. An empty Indent renders a single line.
+	Indent string
+}
+
+// DefaultMichelsonPrinter renders code with two space indentation.
+var DefaultMichelsonPrinter = MichelsonPrinter{Indent: "  "}
+
+// Michelson returns p in Michelson concrete syntax on a single line.
+func (p Prim) Michelson() string {
+	return MichelsonPrinter{}.Print(p)
+}
+
+// Michelson returns the contract program in Michelson concrete syntax with
+// one section per line, the form octez-client expects in script files.
+func (c Code) Michelson() string {
+	if c.BadCode != nil {
+		return DefaultMichelsonPrinter.Print(*c.BadCode)
+	}
+	var b strings.Builder
+	for i, p := range []Prim{c.Param, c.Storage, c.Code} {
+		if i > 0 {
+			b.WriteString(" ;\n")
+		}
+		DefaultMichelsonPrinter.write(&b, p, 0, false)
+	}
+	return b.String()
+}
+
+// Print returns p in Michelson concrete syntax.
+func (m MichelsonPrinter) Print(p Prim) string {
+	var b strings.Builder
+	m.write(&b, p, 0, false)
+	return b.String()
+}
+
+// write renders p at nesting level. Primitives in argument position which
+// have arguments or annotations need to be wrapped in parentheses.
+func (m MichelsonPrinter) write(b *strings.Builder, p Prim, level int, isArg bool) {
+	switch p.Type {
+	case PrimInt:
+		b.WriteString(p.Int.Text(10))
+	case PrimString:
+		writeMichelsonString(b, p.String)
+	case PrimBytes:
+		b.WriteString("0x")
+		b.WriteString(hex.EncodeToString(p.Bytes))
+	case PrimSequence:
+		m.writeSeq(b, p, level)
+	default:
+		wrap := isArg && (len(p.Args) > 0 || len(p.Anno) > 0)
+		if wrap {
+			b.WriteByte('(')
+		}
+		b.WriteString(p.OpCode.String())
+		for _, a := range p.Anno {
+			b.WriteByte(' ')
+			b.WriteString(a)
+		}
+		for _, v := range p.Args {
+			b.WriteByte(' ')
+			m.write(b, v, level, true)
+		}
+		if wrap {
+			b.WriteByte(')')
+		}
+	}
+}
+
+func (m MichelsonPrinter) writeSeq(b *strings.Builder, p Prim, level int) {
+	if len(p.Args) == 0 {
+		b.WriteString("{}")
+		return
+	}
+	if m.Indent == "" || !hasNestedSeq(p) {
+		b.WriteString("{ ")
+		for i, v := range p.Args {
+			if i > 0 {
+				b.WriteString(" ; ")
+			}
+			m.write(b, v, level, false)
+		}
+		b.WriteString(" }")
+		return
+	}
+	b.WriteString("{\n")
+	for i, v := range p.Args {
+		b.WriteString(strings.Repeat(m.Indent, level+1))
+		m.write(b, v, level+1, false)
+		if i < len(p.Args)-1 {
+			b.WriteString(" ;")
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString(strings.Repeat(m.Indent, level))
+	b.WriteByte('}')
+}
+
+// hasNestedSeq returns true when any element of sequence p contains
+// another sequence.
+func hasNestedSeq(p Prim) bool {
+	var contains func(Prim) bool
+	contains = func(p Prim) bool {
+		if p.Type == PrimSequence {
+			return true
+		}
+		for _, v := range p.Args {
+			if contains(v) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, v := range p.Args {
+		if contains(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeMichelsonString writes s as quoted Michelson string literal.
+func writeMichelsonString(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package micheline
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPrimMichelson(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Json string
+		Want string
+	}{
+		{
+			Name: "param type",
+			Json: `{"prim":"or","args":[{"prim":"nat","annots":["%mint"]},{"prim":"pair","annots":["%burn"],"args":[{"prim":"address"},{"prim":"nat"},{"prim":"bool"}]}]}`,
+			Want: `or (nat %mint) (pair %burn address nat bool)`,
+		},
+		{
+			Name: "comb data",
+			Json: `{"prim":"Pair","args":[{"int":"-1"},{"string":"a \"b\"\n"},{"bytes":"00ff"}]}`,
+			Want: `Pair -1 "a \"b\"\n" 0x00ff`,
+		},
+		{
+			Name: "nested data",
+			Json: `[{"prim":"Elt","args":[{"int":"1"},{"prim":"Some","args":[{"prim":"Unit"}]}]},{"prim":"Elt","args":[{"int":"2"},{"prim":"None"}]}]`,
+			Want: `{ Elt 1 (Some Unit) ; Elt 2 None }`,
+		},
+		{
+			Name: "code",
+			Json: `[{"prim":"UNPAIR"},{"prim":"IF_LEFT","args":[[{"prim":"ADD"}],[]]},{"prim":"PUSH","args":[{"prim":"nat"},{"int":"1"}]},{"prim":"SUB","annots":["@diff"]}]`,
+			Want: `{ UNPAIR ; IF_LEFT { ADD } {} ; PUSH nat 1 ; SUB @diff }`,
+		},
+		{
+			Name: "empty",
+			Json: `[]`,
+			Want: `{}`,
+		},
+	} {
+		var p Prim
+		if err := json.Unmarshal([]byte(test.Json), &p); err != nil {
+			t.Fatalf("%s: %v", test.Name, err)
+		}
+		if got := p.Michelson(); got != test.Want {
+			t.Errorf("%s: want=%s got=%s", test.Name, test.Want, got)
+		}
+	}
+}
+
+func TestCodeMichelson(t *testing.T) {
+	s := newTestScript(t, testScriptCode)
+	want := `parameter (or (nat %mint) (nat %burn)) ;
+storage (nat %total) ;
+code {
+  UNPAIR ;
+  IF_LEFT { ADD } { SWAP ; SUB @diff ; ABS } ;
+  NIL operation ;
+  PAIR
+}`
+	if got := s.Code.Michelson(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+	if got := (MichelsonPrinter{}).Print(s.Code.Code.Args[0]); got != `{ UNPAIR ; IF_LEFT { ADD } { SWAP ; SUB @diff ; ABS } ; NIL operation ; PAIR }` {
+		t.Errorf("unexpected single line output %s", got)
+	}
+}
@@ -172,6 +172,10 @@ const (
 	I_GET_AND_UPDATE        // 8C
 )
 
+// OpCodeInvalid is returned when no opcode is known, e.g. by ParseOpCode
+// for unknown primitives.
+const OpCodeInvalid OpCode = 255
+
 func (op OpCode) IsValid() bool {
 	return op <= I_GET_AND_UPDATE
 }
@@ -345,7 +349,7 @@ func (op OpCode) MarshalText() ([]byte, error) {
 func ParseOpCode(str string) (OpCode, error) {
 	op, ok := stringToOp[str]
 	if !ok {
-		return 255, fmt.Errorf("Unknown michelson primitive %s", str)
+		return OpCodeInvalid, fmt.Errorf("Unknown michelson primitive %s", str)
 	}
 	return op, nil
 }
@@ -0,0 +1,278 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"fmt"
+	"strings"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// PackedSize returns the exact length in bytes of the PACK serialization of
+// value p with type typ including the leading 0x05 byte. Like the PACK
+// instruction, strings in typed positions (address, key, signature, etc) are
+// converted to their optimized binary form and pair combs are counted as
+// nested binary pairs. Code inside lambdas is counted as is.
+func PackedSize(p Prim, typ Type) (int, error) {
+	pp, err := packPrim(typ.Prim, p)
+	if err != nil {
+		return 0, err
+	}
+	return 1 + pp.encodedSize(), nil
+}
+
+// PackPrim returns the PACK serialization of value p with type typ like the
+// Michelson PACK instruction, i.e. the optimized binary form prefixed with
+// 0x05. The BLAKE2b hash of the result matches the on-chain key hash used
+// by big_maps, see KeyHash.
+func PackPrim(typ Type, p Prim) ([]byte, error) {
+	pp, err := packPrim(typ.Prim, p)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := pp.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{0x5}, buf...), nil
+}
+
+// packPrim converts value p into the optimized form used by PACK.
+func packPrim(typ, p Prim) (Prim, error) {
+	switch typ.OpCode {
+	case T_PAIR:
+		typ, p = binaryPair(typ), binaryPair(p)
+		if len(typ.Args) != 2 || len(p.Args) != 2 || (p.OpCode != D_PAIR && p.Type != PrimSequence) {
+			return InvalidPrim, packMismatch(typ, p)
+		}
+		l, err := packPrim(typ.Args[0], p.Args[0])
+		if err != nil {
+			return InvalidPrim, err
+		}
+		r, err := packPrim(typ.Args[1], p.Args[1])
+		if err != nil {
+			return InvalidPrim, err
+		}
+		return NewCode(D_PAIR, l, r), nil
+
+	case T_OPTION:
+		switch p.OpCode {
+		case D_NONE:
+			return NewCode(D_NONE), nil
+		case D_SOME:
+			if len(p.Args) != 1 {
+				return InvalidPrim, packMismatch(typ, p)
+			}
+			v, err := packPrim(typ.Args[0], p.Args[0])
+			if err != nil {
+				return InvalidPrim, err
+			}
+			return NewCode(D_SOME, v), nil
+		}
+		return InvalidPrim, packMismatch(typ, p)
+
+	case T_OR:
+		switch p.OpCode {
+		case D_LEFT, D_RIGHT:
+			if len(p.Args) != 1 {
+				return InvalidPrim, packMismatch(typ, p)
+			}
+			t := typ.Args[0]
+			if p.OpCode == D_RIGHT {
+				t = typ.Args[1]
+			}
+			v, err := packPrim(t, p.Args[0])
+			if err != nil {
+				return InvalidPrim, err
+			}
+			return NewCode(p.OpCode, v), nil
+		}
+		return InvalidPrim, packMismatch(typ, p)
+
+	case T_LIST, T_SET:
+		if p.Type != PrimSequence {
+			return InvalidPrim, packMismatch(typ, p)
+		}
+		seq := NewSeq()
+		for _, v := range p.Args {
+			pv, err := packPrim(typ.Args[0], v)
+			if err != nil {
+				return InvalidPrim, err
+			}
+			seq.Args = append(seq.Args, pv)
+		}
+		return seq, nil
+
+	case T_MAP, T_BIG_MAP:
+		if p.Type == PrimInt && typ.OpCode == T_BIG_MAP {
+			return p, nil // bigmap id
+		}
+		if p.Type != PrimSequence {
+			return InvalidPrim, packMismatch(typ, p)
+		}
+		seq := NewSeq()
+		for _, v := range p.Args {
+			if v.OpCode != D_ELT || len(v.Args) != 2 {
+				return InvalidPrim, packMismatch(typ, v)
+			}
+			k, err := packPrim(typ.Args[0], v.Args[0])
+			if err != nil {
+				return InvalidPrim, err
+			}
+			vv, err := packPrim(typ.Args[1], v.Args[1])
+			if err != nil {
+				return InvalidPrim, err
+			}
+			seq.Args = append(seq.Args, NewCode(D_ELT, k, vv))
+		}
+		return seq, nil
+
+	case T_ADDRESS, T_CONTRACT:
+		if p.Type != PrimString {
+			return p, nil
+		}
+		addr, ep := p.String, ""
+		if i := strings.IndexByte(addr, '%'); i >= 0 {
+			addr, ep = addr[:i], addr[i+1:]
+		}
+		a, err := tezos.ParseAddress(addr)
+		if err != nil || !a.IsValid() {
+			return InvalidPrim, fmt.Errorf("micheline: pack: invalid address %q", p.String)
+		}
+		buf := a.Bytes22()
+		if ep != "" && ep != "default" {
+			buf = append(buf, []byte(ep)...)
+		}
+		return NewBytes(buf), nil
+
+	case T_KEY_HASH:
+		if p.Type != PrimString {
+			return p, nil
+		}
+		a, err := tezos.ParseAddress(p.String)
+		if err != nil || !a.IsImplicit() {
+			return InvalidPrim, fmt.Errorf("micheline: pack: invalid key hash %q", p.String)
+		}
+		return NewBytes(a.Bytes()), nil
+
+	case T_KEY:
+		if p.Type != PrimString {
+			return p, nil
+		}
+		k, err := tezos.ParseKey(p.String)
+		if err != nil {
+			return InvalidPrim, fmt.Errorf("micheline: pack: invalid key %q: %v", p.String, err)
+		}
+		return NewBytes(k.Bytes()), nil
+
+	case T_SIGNATURE:
+		if p.Type != PrimString {
+			return p, nil
+		}
+		s, err := tezos.ParseSignature(p.String)
+		if err != nil {
+			return InvalidPrim, fmt.Errorf("micheline: pack: invalid signature %q: %v", p.String, err)
+		}
+		return NewBytes(s.Data), nil
+
+	case T_CHAIN_ID:
+		if p.Type != PrimString {
+			return p, nil
+		}
+		h, err := tezos.ParseChainIdHash(p.String)
+		if err != nil {
+			return InvalidPrim, fmt.Errorf("micheline: pack: invalid chain id %q: %v", p.String, err)
+		}
+		return NewBytes(h.Hash.Hash), nil
+
+	case T_TIMESTAMP:
+		if p.Type != PrimString {
+			return p, nil
+		}
+		t, ok := parseTimestamp(p.String)
+		if !ok {
+			return InvalidPrim, fmt.Errorf("micheline: pack: invalid timestamp %q", p.String)
+		}
+		return NewInt64(t.Unix()), nil
+
+	default:
+		return p, nil
+	}
+}
+
+// binaryPair converts right combs with more than two arguments (pair types,
+// Pair values and sequence literals) into nested binary pairs.
+func binaryPair(p Prim) Prim {
+	if len(p.Args) <= 2 {
+		return p
+	}
+	rest := p
+	rest.Args = p.Args[1:]
+	r := binaryPair(rest)
+	if p.Type == PrimSequence {
+		return NewSeq(p.Args[0], r)
+	}
+	res := NewCode(p.OpCode, p.Args[0], r)
+	if p.Type == PrimVariadicAnno && len(p.Anno) > 0 {
+		res.Type = PrimBinaryAnno
+		res.Anno = p.Anno
+	}
+	return res
+}
+
+func packMismatch(typ, p Prim) error {
+	return fmt.Errorf("micheline: pack: value %s does not match type %s", p.DumpLimit(64), typ.OpCode)
+}
+
+// encodedSize returns the length of the binary encoding produced by
+// MarshalBinary without writing it.
+func (p Prim) encodedSize() int {
+	n := 1 // type tag
+	annoSize := func() int {
+		return 4 + len(strings.Join(p.Anno, " "))
+	}
+	switch p.Type {
+	case PrimInt:
+		if p.Int == nil {
+			return n + 1
+		}
+		bits := p.Int.BitLen()
+		if bits <= 6 {
+			return n + 1
+		}
+		return n + 1 + (bits-6+6)/7
+	case PrimString:
+		return n + 4 + len(p.String)
+	case PrimBytes:
+		return n + 4 + len(p.Bytes)
+	case PrimSequence:
+		n += 4
+		for _, v := range p.Args {
+			n += v.encodedSize()
+		}
+		return n
+	case PrimNullary:
+		return n + 1
+	case PrimNullaryAnno:
+		return n + 1 + annoSize()
+	case PrimUnary, PrimBinary, PrimUnaryAnno, PrimBinaryAnno:
+		n++
+		for _, v := range p.Args {
+			n += v.encodedSize()
+		}
+		if p.Type == PrimUnaryAnno || p.Type == PrimBinaryAnno {
+			n += annoSize()
+		}
+		return n
+	case PrimVariadicAnno:
+		n += 1 + 4
+		for _, v := range p.Args {
+			n += v.encodedSize()
+		}
+		return n + annoSize()
+	default:
+		return n
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package micheline
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// packBytes is the reference PACK implementation used to verify sizes
+func packBytes(t *testing.T, p Prim, typ Type) []byte {
+	t.Helper()
+	buf, err := PackPrim(typ, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestPackedSize(t *testing.T) {
+	addr := tezos.MustParseAddress("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb")
+	huge, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	for _, test := range []struct {
+		Name string
+		Type Type
+		Val  Prim
+		Hex  string // optional octez-client reference
+	}{
+		{
+			Name: "nat",
+			Type: NewType(NewPrim(T_NAT)),
+			Val:  NewInt64(1),
+			Hex:  "050001",
+		},
+		{
+			Name: "string",
+			Type: NewType(NewPrim(T_STRING)),
+			Val:  NewString("hello"),
+			Hex:  "05010000000568656c6c6f",
+		},
+		{
+			Name: "pair nat address",
+			Type: NewType(NewPairType(NewPrim(T_NAT), NewPrim(T_ADDRESS))),
+			Val:  NewPairValue(NewInt64(1), NewString(addr.String())),
+			Hex:  "05070700010a00000016" + hex.EncodeToString(addr.Bytes22()),
+		},
+		{
+			Name: "big int",
+			Type: NewType(NewPrim(T_INT)),
+			Val:  NewBig(huge),
+		},
+		{
+			Name: "comb",
+			Type: NewType(NewCode(T_PAIR, NewPrim(T_NAT), NewPrim(T_TIMESTAMP), NewPrim(T_KEY_HASH))),
+			Val:  NewSeq(NewInt64(64), NewString("2021-06-01T12:00:00Z"), NewString(addr.String())),
+		},
+		{
+			Name: "containers",
+			Type: NewType(NewPairType(
+				NewCode(T_MAP, NewPrim(T_STRING), NewCode(T_OPTION, NewPrim(T_CONTRACT))),
+				NewCode(T_OR, NewPrim(T_UNIT), NewCode(T_LIST, NewPrim(T_BYTES))),
+			)),
+			Val: NewPairValue(
+				NewSeq(
+					NewCode(D_ELT, NewString("a"), NewCode(D_NONE)),
+					NewCode(D_ELT, NewString("b"), NewCode(D_SOME, NewString(addr.String()+"%transfer"))),
+				),
+				NewCode(D_RIGHT, NewSeq(NewBytes([]byte{1, 2, 3}), NewBytes(nil))),
+			),
+		},
+	} {
+		n, err := PackedSize(test.Val, test.Type)
+		if err != nil {
+			t.Errorf("%s: %v", test.Name, err)
+			continue
+		}
+		buf := packBytes(t, test.Val, test.Type)
+		if n != len(buf) {
+			t.Errorf("%s: size mismatch want=%d got=%d", test.Name, len(buf), n)
+		}
+		if test.Hex != "" && hex.EncodeToString(buf) != test.Hex {
+			t.Errorf("%s: pack mismatch\n  want=%s\n  got= %x", test.Name, test.Hex, buf)
+		}
+	}
+
+	// type mismatch
+	if _, err := PackedSize(NewInt64(1), NewType(NewPairType(NewPrim(T_NAT), NewPrim(T_NAT)))); err == nil {
+		t.Errorf("expected mismatch error")
+	}
+}
+
+func TestValuePack(t *testing.T) {
+	// octez-client hash data 'Pair 1 "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"' of type 'pair nat address'
+	val := newTestValue(t,
+		`{"prim":"pair","args":[{"prim":"nat"},{"prim":"address"}]}`,
+		`{"prim":"Pair","args":[{"int":"1"},{"string":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"}]}`,
+	)
+	buf, err := val.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "05070700010a0000001600005c56bbc501ad676afc27ae3d660232287a15b5e2"
+	if got := hex.EncodeToString(buf); got != want {
+		t.Errorf("pack mismatch\n  want=%s\n  got= %s", want, got)
+	}
+	// unpacking restores the value
+	up, err := NewBytes(buf).Unpack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up.Args[1].String != "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb" || up.Args[0].Int.Int64() != 1 {
+		t.Errorf("unexpected unpacked value %s", up.Dump())
+	}
+	if h := KeyHash(buf); !h.IsValid() {
+		t.Errorf("invalid key hash %s", h)
+	}
+
+	if _, err := PackPrim(val.Type, NewString("x")); err == nil {
+		t.Errorf("expected error for mismatched value")
+	}
+}
@@ -17,6 +17,39 @@ type Parameters struct {
 	Value      Prim   `json:"value"`
 }
 
+// NewParameters returns call parameters for entrypoint name with argument
+// value. An empty name calls the default entrypoint.
+func NewParameters(name string, value Prim) (Parameters, error) {
+	if name != "" && !IsValidEntrypointName(name) {
+		return Parameters{}, fmt.Errorf("micheline: invalid entrypoint name %q", name)
+	}
+	return Parameters{Entrypoint: name, Value: value}, nil
+}
+
+// MaxEntrypointNameLength is the longest entrypoint name the protocol accepts.
+const MaxEntrypointNameLength = 31
+
+// IsValidEntrypointName reports whether s can be used as entrypoint name, i.e.
+// as field annotation without its % prefix. Names are at most 31 characters
+// of [a-zA-Z0-9_.%@] and must start with a letter, digit or underscore which
+// excludes the special annotations % and @. The protocol reserves no names:
+// default addresses the default entrypoint and the staking pseudo-entrypoints
+// like stake are plain names on smart contracts, so both are accepted.
+func IsValidEntrypointName(s string) bool {
+	if len(s) == 0 || len(s) > MaxEntrypointNameLength {
+		return false
+	}
+	if !isIdentChar(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isAnnoChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 func (p Parameters) MarshalJSON() ([]byte, error) {
 	if p.Entrypoint == "" || (p.Entrypoint == "default" && p.Value.OpCode == D_UNIT) {
 		return json.Marshal(p.Value)
@@ -25,24 +58,89 @@ func (p Parameters) MarshalJSON() ([]byte, error) {
 	return json.Marshal(alias(p))
 }
 
+// EntrypointMatch describes how MapEntrypointExt resolved a call. Flags may
+// be combined, a zero value means the entrypoint was found directly.
+type EntrypointMatch byte
+
+const (
+	EntrypointMatchExact     EntrypointMatch = 0
+	EntrypointMatchRebased   EntrypointMatch = 1 << (iota - 1) // branch resolved below an annotated prefix
+	EntrypointMatchFallback                                    // no branch matched, fell back to entrypoint 0
+	EntrypointMatchAmbiguous                                   // unwrapped value has unused Left/Right branches
+)
+
+func (m EntrypointMatch) IsExact() bool {
+	return m == EntrypointMatchExact
+}
+
+func (m EntrypointMatch) IsFallback() bool {
+	return m&EntrypointMatchFallback > 0
+}
+
+func (m EntrypointMatch) IsAmbiguous() bool {
+	return m&EntrypointMatchAmbiguous > 0
+}
+
+func (m EntrypointMatch) String() string {
+	if m == EntrypointMatchExact {
+		return "exact"
+	}
+	ss := make([]string, 0)
+	if m&EntrypointMatchRebased > 0 {
+		ss = append(ss, "rebased")
+	}
+	if m&EntrypointMatchFallback > 0 {
+		ss = append(ss, "fallback")
+	}
+	if m&EntrypointMatchAmbiguous > 0 {
+		ss = append(ss, "ambiguous")
+	}
+	return strings.Join(ss, ",")
+}
+
 func (p Parameters) MapEntrypoint(typ Type) (Entrypoint, Prim, error) {
-	var ep Entrypoint
-	var ok bool
-	var prim Prim
+	ep, prim, _, err := p.MapEntrypointExt(typ)
+	return ep, prim, err
+}
 
+// MapEntrypointExt works like MapEntrypoint and additionally reports how the
+// entrypoint was resolved. Callers can use this to detect calls which only
+// matched after falling back to the first entrypoint or which carry trailing
+// union branches the entrypoint type does not expect.
+func (p Parameters) MapEntrypointExt(typ Type) (Entrypoint, Prim, EntrypointMatch, error) {
 	// get list of script entrypoints
 	eps, _ := typ.Entrypoints(true)
+	return p.mapEntrypoint(typ, eps)
+}
+
+// MapEntrypointSet works like MapEntrypointExt, but uses the precomputed
+// entrypoints of set instead of walking the parameter type on each call.
+func (p Parameters) MapEntrypointSet(set *EntrypointSet) (Entrypoint, Prim, EntrypointMatch, error) {
+	return p.mapEntrypoint(set.typ, set.eps)
+}
+
+func (p Parameters) mapEntrypoint(typ Type, eps Entrypoints) (Entrypoint, Prim, EntrypointMatch, error) {
+	var ep Entrypoint
+	var ok bool
+	var prim Prim
+	var match EntrypointMatch
 
 	switch p.Entrypoint {
 	case "default":
 		// rebase branch by prepending the path to the named default entrypoint
 		prefix := typ.SearchEntrypointName("default")
+		if prefix != "" {
+			match |= EntrypointMatchRebased
+		}
 		// can be [LR]+ or empty when entrypoint is used
 		branch := p.Branch(prefix, eps)
 		ep, ok = eps.FindBranch(branch)
 		if !ok {
 			ep, _ = eps.FindId(0)
 			prim = p.Value
+			if branch != ep.Branch {
+				match |= EntrypointMatchFallback
+			}
 		} else {
 			prim = p.Unwrap(strings.TrimPrefix(ep.Branch, prefix))
 		}
@@ -53,6 +151,12 @@ func (p Parameters) MapEntrypoint(typ Type) (Entrypoint, Prim, error) {
 		ep, ok = eps.FindBranch(branch)
 		if !ok {
 			ep, _ = eps.FindId(0)
+			if branch != ep.Branch {
+				// the value does not follow the entrypoint branch
+				match |= EntrypointMatchFallback
+				prim = p.Value
+				break
+			}
 		}
 		prim = p.Unwrap(ep.Branch)
 
@@ -65,21 +169,41 @@ func (p Parameters) MapEntrypoint(typ Type) (Entrypoint, Prim, error) {
 			prefix := typ.SearchEntrypointName(p.Entrypoint)
 			if prefix == "" {
 				// meh
-				return ep, prim, fmt.Errorf("micheline: missing entrypoint '%s'", p.Entrypoint)
+				return ep, prim, match, fmt.Errorf("micheline: missing entrypoint '%s'", p.Entrypoint)
 			}
 			// otherwise rebase using the annotated branch as prefix
 			branch := p.Branch(prefix, eps)
 			ep, ok = eps.FindBranch(branch)
 			if !ok {
-				return ep, prim, fmt.Errorf("micheline: missing entrypoint '%s' + %s", p.Entrypoint, prefix)
+				return ep, prim, match, fmt.Errorf("micheline: missing entrypoint '%s' + %s", p.Entrypoint, prefix)
 			}
 			// unwrap the suffix branch only
 			prim = p.Unwrap(strings.TrimPrefix(ep.Branch, prefix))
+			match |= EntrypointMatchRebased
 		} else {
 			prim = p.Value
 		}
 	}
-	return ep, prim, nil
+
+	// a union value is only expected when the entrypoint takes a union
+	if (prim.OpCode == D_LEFT || prim.OpCode == D_RIGHT) && ep.Prim != nil && ep.Prim.OpCode != T_OR {
+		match |= EntrypointMatchAmbiguous
+	}
+	return ep, prim, match, nil
+}
+
+// GetEntrypointType resolves the entrypoint of the call like MapEntrypoint
+// and returns its argument type together with the unwrapped argument value,
+// ready to be used with NewValue.
+func (p Parameters) GetEntrypointType(typ Type) (Type, Prim, error) {
+	ep, prim, err := p.MapEntrypoint(typ)
+	if err != nil {
+		return Type{}, prim, err
+	}
+	if ep.Prim == nil {
+		return Type{}, prim, fmt.Errorf("micheline: missing type for entrypoint '%s'", ep.Call)
+	}
+	return ep.Type(), prim, nil
 }
 
 func (p Parameters) Branch(prefix string, eps Entrypoints) string {
@@ -98,6 +222,9 @@ done:
 		default:
 			break done
 		}
+		if len(node.Args) == 0 {
+			break done
+		}
 		node = node.Args[0]
 		if _, ok := eps.FindBranch(branch); ok {
 			break done
@@ -106,22 +233,83 @@ done:
 	return branch
 }
 
+// Unwrap follows branch, a path of L and R steps separated by slashes, into
+// nested Left/Right values and returns the innermost value. When the value
+// does not match the branch the original value is returned unchanged.
 func (p Parameters) Unwrap(branch string) Prim {
+	node, err := p.UnwrapChecked(branch)
+	if err != nil {
+		return p.Value
+	}
+	return node
+}
+
+// UnwrapChecked works like Unwrap but returns an error when branch contains
+// invalid steps or does not match the shape of the value.
+func (p Parameters) UnwrapChecked(branch string) (Prim, error) {
 	node := p.Value
 	branch = strings.TrimPrefix(branch, "/")
 	branch = strings.TrimSuffix(branch, "/")
 	for _, v := range strings.Split(branch, "/") {
-		if !node.IsValid() {
-			break
-		}
+		var code OpCode
 		switch v {
+		case "":
+			continue
 		case "L":
-			node = node.Args[0]
+			code = D_LEFT
 		case "R":
-			node = node.Args[0]
+			code = D_RIGHT
+		default:
+			return p.Value, fmt.Errorf("micheline: invalid branch step %q", v)
+		}
+		if (node.Type != PrimUnary && node.Type != PrimUnaryAnno) || node.OpCode != code || len(node.Args) != 1 {
+			return p.Value, fmt.Errorf("micheline: value %s does not match branch %s", node.DumpLimit(64), branch)
 		}
+		node = node.Args[0]
 	}
-	return node
+	return node, nil
+}
+
+// entrypointTags maps built-in entrypoint names to their binary tag. Names
+// without a tag are serialized in full after tag 255.
+var (
+	entrypointTags = map[string]byte{
+		"default":                 0,
+		"root":                    1,
+		"do":                      2,
+		"set_delegate":            3,
+		"remove_delegate":         4,
+		"deposit":                 5,
+		"stake":                   6,
+		"unstake":                 7,
+		"finalize_unstake":        8,
+		"set_delegate_parameters": 9,
+	}
+	entrypointNames = make(map[byte]string)
+)
+
+func init() {
+	for n, t := range entrypointTags {
+		entrypointNames[t] = n
+	}
+}
+
+// RegisterEntrypointTag adds a built-in entrypoint with a fixed binary tag
+// as introduced by a protocol upgrade. Registration is not safe for concurrent
+// use and should happen during program initialization.
+func RegisterEntrypointTag(name string, tag byte) error {
+	if tag == 255 || name == "" {
+		return fmt.Errorf("micheline: invalid entrypoint tag %d for %q", tag, name)
+	}
+	if n, ok := entrypointNames[tag]; ok && n != name {
+		return fmt.Errorf("micheline: entrypoint tag %d already used by %q", tag, n)
+	}
+	if t, ok := entrypointTags[name]; ok && t != tag {
+		return fmt.Errorf("micheline: entrypoint %q already registered with tag %d", name, t)
+	}
+	entrypointTags[name] = tag
+	entrypointNames[tag] = name
+	return nil
 }
 
 // stay compatible with v005 transaction serialization
@@ -134,18 +322,16 @@ func (p Parameters) MarshalBinary() ([]byte, error) {
 	// entrypoint format, compatible with v005
 	buf := bytes.NewBuffer([]byte{1})
 	n := 2
-	switch p.Entrypoint {
-	case "", "default":
-		buf.WriteByte(0)
-	case "root":
-		buf.WriteByte(1)
-	case "do":
-		buf.WriteByte(2)
-	case "set_delegate":
-		buf.WriteByte(3)
-	case "remove_delegate":
-		buf.WriteByte(4)
-	default:
+	name := p.Entrypoint
+	if name == "" {
+		name = "default"
+	}
+	if tag, ok := entrypointTags[name]; ok {
+		buf.WriteByte(tag)
+	} else {
+		if !IsValidEntrypointName(name) {
+			return nil, fmt.Errorf("micheline: invalid entrypoint name %q", name)
+		}
 		buf.WriteByte(255)
 		buf.WriteByte(byte(len(p.Entrypoint)))
 		buf.WriteString(p.Entrypoint)
@@ -174,10 +360,13 @@ func (p *Parameters) UnmarshalJSON(data []byte) error {
 		return json.Unmarshal(data, &p.Value)
 	} else {
 		// try entrypoint calling convention
-		type alias *Parameters
-		if err := json.Unmarshal(data, alias(p)); err != nil {
+		type alias Parameters
+		if err := json.Unmarshal(data, (*alias)(p)); err != nil {
 			return err
 		}
+		if p.Entrypoint != "" && !IsValidEntrypointName(p.Entrypoint) {
+			return fmt.Errorf("micheline: invalid entrypoint name %q", p.Entrypoint)
+		}
 		if p.Value.IsValid() {
 			return nil
 		}
@@ -198,23 +387,19 @@ func (p *Parameters) UnmarshalBinary(data []byte) error {
 	if len(tag) == 0 {
 		return io.ErrShortBuffer
 	}
-	switch tag[0] {
-	case 0:
-		p.Entrypoint = "default"
-	case 1:
-		p.Entrypoint = "root"
-	case 2:
-		p.Entrypoint = "do"
-	case 3:
-		p.Entrypoint = "set_delegate"
-	case 4:
-		p.Entrypoint = "remove_delegate"
-	default:
+	if tag[0] == 255 {
 		sz := buf.Next(1)
 		if len(sz) == 0 || buf.Len() < int(sz[0]) {
 			return io.ErrShortBuffer
 		}
 		p.Entrypoint = string(buf.Next(int(sz[0])))
+		if !IsValidEntrypointName(p.Entrypoint) {
+			return fmt.Errorf("micheline: invalid entrypoint name %q", p.Entrypoint)
+		}
+	} else if name, ok := entrypointNames[tag[0]]; ok {
+		p.Entrypoint = name
+	} else {
+		return fmt.Errorf("micheline: unknown entrypoint tag %d", tag[0])
 	}
 
 	// read serialized data
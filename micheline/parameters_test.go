@@ -0,0 +1,328 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package micheline
+
+import (
+	"encoding/json"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestMapEntrypointExt(t *testing.T) {
+	// or (or %default (nat %x) (string %y)) (or (unit %admin) (or %group (int %a) (bytes %b)))
+	typ := newTestType(t, `{"prim":"or","args":[
+		{"prim":"or","annots":["%default"],"args":[{"prim":"nat","annots":["%x"]},{"prim":"string","annots":["%y"]}]},
+		{"prim":"or","args":[
+			{"prim":"unit","annots":["%admin"]},
+			{"prim":"or","annots":["%group"],"args":[{"prim":"int","annots":["%a"]},{"prim":"bytes","annots":["%b"]}]}
+		]}
+	]}`)
+	single := newTestType(t, `{"prim":"nat"}`)
+
+	for _, test := range []struct {
+		Name  string
+		Type  Type
+		Call  string
+		Entry string
+		Value string
+		Match EntrypointMatch
+	}{
+		{
+			Name:  "default prefix",
+			Type:  typ,
+			Call:  `{"entrypoint":"default","value":{"prim":"Right","args":[{"string":"hi"}]}}`,
+			Entry: "y",
+			Value: `{"string":"hi"}`,
+			Match: EntrypointMatchRebased,
+		},
+		{
+			Name:  "naked root",
+			Type:  typ,
+			Call:  `{"entrypoint":"root","value":{"prim":"Left","args":[{"prim":"Left","args":[{"int":"1"}]}]}}`,
+			Entry: "x",
+			Value: `{"int":"1"}`,
+			Match: EntrypointMatchExact,
+		},
+		{
+			Name:  "naked root without union",
+			Type:  typ,
+			Call:  `{"entrypoint":"","value":{"int":"1"}}`,
+			Entry: "x",
+			Value: `{"int":"1"}`,
+			Match: EntrypointMatchFallback,
+		},
+		{
+			Name:  "named",
+			Type:  typ,
+			Call:  `{"entrypoint":"admin","value":{"prim":"Unit"}}`,
+			Entry: "admin",
+			Value: `{"prim":"Unit"}`,
+			Match: EntrypointMatchExact,
+		},
+		{
+			Name:  "named group",
+			Type:  typ,
+			Call:  `{"entrypoint":"group","value":{"prim":"Right","args":[{"bytes":"00"}]}}`,
+			Entry: "b",
+			Value: `{"bytes":"00"}`,
+			Match: EntrypointMatchRebased,
+		},
+		{
+			Name:  "trailing union",
+			Type:  typ,
+			Call:  `{"entrypoint":"admin","value":{"prim":"Left","args":[{"prim":"Unit"}]}}`,
+			Entry: "admin",
+			Value: `{"args":[{"prim":"Unit"}],"prim":"Left"}`,
+			Match: EntrypointMatchAmbiguous,
+		},
+		{
+			Name:  "single entrypoint",
+			Type:  single,
+			Call:  `{"entrypoint":"default","value":{"int":"5"}}`,
+			Entry: "default",
+			Value: `{"int":"5"}`,
+			Match: EntrypointMatchExact,
+		},
+	} {
+		var params Parameters
+		if err := json.Unmarshal([]byte(test.Call), &params); err != nil {
+			t.Fatalf("%s: %v", test.Name, err)
+		}
+		ep, prim, match, err := params.MapEntrypointExt(test.Type)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.Name, err)
+			continue
+		}
+		if ep.Call != test.Entry {
+			t.Errorf("%s: entrypoint mismatch want=%s got=%s", test.Name, test.Entry, ep.Call)
+		}
+		if buf, _ := json.Marshal(prim); string(buf) != test.Value {
+			t.Errorf("%s: value mismatch want=%s got=%s", test.Name, test.Value, buf)
+		}
+		if match != test.Match {
+			t.Errorf("%s: match mismatch want=%s got=%s", test.Name, test.Match, match)
+		}
+	}
+
+	var params Parameters
+	if err := json.Unmarshal([]byte(`{"entrypoint":"missing","value":{"prim":"Unit"}}`), &params); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := params.MapEntrypointExt(typ); err == nil {
+		t.Errorf("expected error for missing entrypoint")
+	}
+}
+
+func TestParametersUnwrap(t *testing.T) {
+	var params Parameters
+	if err := json.Unmarshal([]byte(`{"entrypoint":"default","value":{"prim":"Left","args":[{"prim":"Right","args":[{"int":"7"}]}]}}`), &params); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		Branch string
+		Want   string
+		Err    bool
+	}{
+		{Branch: "/L/R", Want: `{"int":"7"}`},
+		{Branch: "L/R/", Want: `{"int":"7"}`},
+		{Branch: "/L", Want: `{"args":[{"int":"7"}],"prim":"Right"}`},
+		{Branch: "", Want: `{"args":[{"args":[{"int":"7"}],"prim":"Right"}],"prim":"Left"}`},
+		{Branch: "/R", Err: true},
+		{Branch: "/L/L", Err: true},
+		{Branch: "/L/R/L", Err: true},
+		{Branch: "/X", Err: true},
+	} {
+		prim, err := params.UnwrapChecked(test.Branch)
+		if test.Err {
+			if err == nil {
+				t.Errorf("%q: expected error", test.Branch)
+			}
+			if !params.Unwrap(test.Branch).IsEqual(params.Value) {
+				t.Errorf("%q: expected original value on mismatch", test.Branch)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.Branch, err)
+			continue
+		}
+		if buf, _ := json.Marshal(prim); string(buf) != test.Want {
+			t.Errorf("%q: want=%s got=%s", test.Branch, test.Want, buf)
+		}
+	}
+}
+
+func TestParametersUnwrapRandom(t *testing.T) {
+	var params Parameters
+	if err := json.Unmarshal([]byte(`{"entrypoint":"default","value":{"prim":"Right","args":[{"prim":"Left","args":[{"prim":"Unit"}]}]}}`), &params); err != nil {
+		t.Fatal(err)
+	}
+	branches := []string{"", "/", "/R/L", "/R/L/L", "/L", "R//L", "/R/L/R/R", "LR", "/%"}
+	// random branches from a small alphabet, seeded for reproducibility
+	rnd := rand.New(rand.NewSource(1))
+	alphabet := []byte("LR/%x")
+	for i := 0; i < 1000; i++ {
+		buf := make([]byte, rnd.Intn(12))
+		for k := range buf {
+			buf[k] = alphabet[rnd.Intn(len(alphabet))]
+		}
+		branches = append(branches, string(buf))
+	}
+	for _, branch := range branches {
+		prim, err := params.UnwrapChecked(branch)
+		if err != nil && !prim.IsEqual(params.Value) {
+			t.Errorf("%q: expected original value on error", branch)
+		}
+		_ = params.Unwrap(branch)
+	}
+}
+
+func TestParametersEntrypointTags(t *testing.T) {
+	for _, test := range []struct {
+		Entry string
+		Tag   byte
+	}{
+		{Entry: "default", Tag: 0},
+		{Entry: "remove_delegate", Tag: 4},
+		{Entry: "deposit", Tag: 5},
+		{Entry: "finalize_unstake", Tag: 8},
+		{Entry: "transfer", Tag: 255},
+	} {
+		p := Parameters{Entrypoint: test.Entry, Value: NewPairValue(NewBytes([]byte{1}), NewBig(big.NewInt(10)))}
+		buf, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: %v", test.Entry, err)
+		}
+		if buf[0] != 1 || buf[1] != test.Tag {
+			t.Errorf("%s: tag mismatch want=%d got=%x", test.Entry, test.Tag, buf[:2])
+		}
+		var p2 Parameters
+		if err := p2.UnmarshalBinary(buf); err != nil {
+			t.Fatalf("%s: decode: %v", test.Entry, err)
+		}
+		if p2.Entrypoint != test.Entry || !p2.Value.IsEqual(p.Value) {
+			t.Errorf("%s: round-trip mismatch got=%s %s", test.Entry, p2.Entrypoint, p2.Value.Dump())
+		}
+	}
+
+	if err := RegisterEntrypointTag("stake", 6); err != nil {
+		t.Errorf("re-registering same tag: %v", err)
+	}
+	if err := RegisterEntrypointTag("other", 5); err == nil {
+		t.Errorf("expected error for duplicate tag")
+	}
+	var p Parameters
+	if err := p.UnmarshalBinary([]byte{1, 200, 0, 0, 0, 1, 0x03, 0x0b}); err == nil {
+		t.Errorf("expected error for unknown tag")
+	}
+}
+
+func TestIsValidEntrypointName(t *testing.T) {
+	for _, name := range []string{
+		// no reserved words, default and staking names are legal annotations
+		"default",
+		"stake",
+		"set_delegate_parameters",
+		"root",
+		"transfer",
+		"update_operators",
+		"_private",
+		"1st",
+		"a.b%c@d",
+		"abcdefghijklmnopqrstuvwxyz01234",
+	} {
+		if !IsValidEntrypointName(name) {
+			t.Errorf("%q: expected valid name", name)
+		}
+		if _, err := NewParameters(name, NewCode(D_UNIT)); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	for _, name := range []string{
+		"",
+		"%",
+		"@",
+		"%transfer",
+		".x",
+		"with space",
+		"semi;colon",
+		"dash-name",
+		"üml",
+		"abcdefghijklmnopqrstuvwxyz012345",
+	} {
+		if IsValidEntrypointName(name) {
+			t.Errorf("%q: expected invalid name", name)
+		}
+		if name == "" {
+			// empty names select the default entrypoint
+			continue
+		}
+		p := Parameters{Entrypoint: name, Value: NewCode(D_UNIT)}
+		if _, err := NewParameters(name, NewCode(D_UNIT)); err == nil {
+			t.Errorf("%q: expected constructor error", name)
+		}
+		if _, err := p.MarshalBinary(); err == nil {
+			t.Errorf("%q: expected encoding error", name)
+		}
+		buf, _ := json.Marshal(map[string]interface{}{"entrypoint": name, "value": NewCode(D_UNIT)})
+		if err := json.Unmarshal(buf, &p); err == nil {
+			t.Errorf("%q: expected decoding error", name)
+		}
+	}
+
+	// binary decoding rejects names a node would not produce
+	buf := []byte{1, 255, 3, 'a', ' ', 'b', 0, 0, 0, 2, 0x03, 0x0b}
+	var p Parameters
+	if err := p.UnmarshalBinary(buf); err == nil {
+		t.Errorf("expected error for invalid binary entrypoint name")
+	}
+}
+
+func TestParametersGetEntrypointType(t *testing.T) {
+	// FA2 parameter with transfer, balance_of and update_operators
+	p, err := ParseMichelson(`or
+		(or (pair %balance_of
+				(list %requests (pair (address %owner) (nat %token_id)))
+				(contract %callback (list (pair (pair %request (address %owner) (nat %token_id)) (nat %balance)))))
+			(list %transfer (pair (address %from_) (list %txs (pair (address %to_) (nat %token_id) (nat %amount))))))
+		(list %update_operators
+			(or (pair %add_operator (address %owner) (address %operator) (nat %token_id))
+				(pair %remove_operator (address %owner) (address %operator) (nat %token_id))))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ := NewType(p)
+	arg, err := ParseMichelson(`{ Pair "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"
+		{ Pair "tz1gjaF81ZRRvdzjobyfVNsAeSC6PScjfQwN" 0 100 } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, params := range []Parameters{
+		{Entrypoint: "transfer", Value: arg},
+		{Entrypoint: "default", Value: NewCode(D_LEFT, NewCode(D_RIGHT, arg))},
+	} {
+		argType, prim, err := params.GetEntrypointType(typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if argType.OpCode != T_LIST || argType.Label() != "transfer" {
+			t.Errorf("%s: unexpected type %s", params.Entrypoint, argType.Dump())
+		}
+		val := NewValue(argType, prim)
+		if from, ok := val.GetAddress("transfer.0.from_"); !ok || from.String() != "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx" {
+			t.Errorf("%s: unexpected sender %s", params.Entrypoint, from)
+		}
+		if amount, ok := val.GetBig("transfer.0.txs.0.amount"); !ok || amount.Int64() != 100 {
+			t.Errorf("%s: unexpected amount %v", params.Entrypoint, amount)
+		}
+	}
+
+	// unknown entrypoints fail
+	if _, _, err := (Parameters{Entrypoint: "mint", Value: arg}).GetEntrypointType(typ); err == nil {
+		t.Errorf("expected error for missing entrypoint")
+	}
+}
@@ -0,0 +1,411 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ParseMichelson parses Michelson concrete syntax into a prim tree. It accepts
+// types, data and code as written in contracts or printed by octez-client.
+// Several expressions separated by semicolons, like the sections of a script
+// file, are returned as sequence. Comments in # and /* */ style are skipped.
+// Errors report the line and column of the offending token.
+//
+//	typ, err := ParseMichelson("pair (address %owner) (nat %balance)")
+func ParseMichelson(src string) (Prim, error) {
+	p := &michelsonParser{lex: michelsonLexer{src: src, line: 1, col: 1}}
+	if err := p.next(); err != nil {
+		return InvalidPrim, err
+	}
+	list, semi, err := p.parseSeq(tokEOF)
+	if err != nil {
+		return InvalidPrim, err
+	}
+	switch {
+	case len(list) == 0:
+		return InvalidPrim, p.errorf("empty input")
+	case len(list) == 1 && !semi:
+		return list[0], nil
+	default:
+		return NewSeq(list...), nil
+	}
+}
+
+type michelsonToken byte
+
+const (
+	tokEOF michelsonToken = iota
+	tokIdent
+	tokAnno
+	tokInt
+	tokString
+	tokBytes
+	tokLBrace
+	tokRBrace
+	tokLParen
+	tokRParen
+	tokSemi
+)
+
+func (t michelsonToken) String() string {
+	switch t {
+	case tokEOF:
+		return "end of input"
+	case tokIdent:
+		return "primitive"
+	case tokAnno:
+		return "annotation"
+	case tokInt:
+		return "int"
+	case tokString:
+		return "string"
+	case tokBytes:
+		return "bytes"
+	case tokLBrace:
+		return "'{'"
+	case tokRBrace:
+		return "'}'"
+	case tokLParen:
+		return "'('"
+	case tokRParen:
+		return "')'"
+	case tokSemi:
+		return "';'"
+	default:
+		return "invalid token"
+	}
+}
+
+type michelsonLexer struct {
+	src  string
+	pos  int
+	line int
+	col  int
+}
+
+func (l *michelsonLexer) errorf(line, col int, format string, args ...interface{}) error {
+	return fmt.Errorf("micheline: line %d col %d: %s", line, col, fmt.Sprintf(format, args...))
+}
+
+func (l *michelsonLexer) advance(n int) {
+	for i := 0; i < n && l.pos < len(l.src); i++ {
+		if l.src[l.pos] == '\n' {
+			l.line++
+			l.col = 1
+		} else {
+			l.col++
+		}
+		l.pos++
+	}
+}
+
+// skip moves past whitespace and comments.
+func (l *michelsonLexer) skip() error {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			l.advance(1)
+		case c == '#':
+			n := strings.IndexByte(l.src[l.pos:], '\n')
+			if n < 0 {
+				n = len(l.src) - l.pos
+			}
+			l.advance(n)
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			line, col := l.line, l.col
+			n := strings.Index(l.src[l.pos+2:], "*/")
+			if n < 0 {
+				return l.errorf(line, col, "unterminated comment")
+			}
+			l.advance(n + 4)
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// scan returns the next token and its text.
+func (l *michelsonLexer) scan() (michelsonToken, string, error) {
+	if err := l.skip(); err != nil {
+		return tokEOF, "", err
+	}
+	if l.pos >= len(l.src) {
+		return tokEOF, "", nil
+	}
+	start, c := l.pos, l.src[l.pos]
+	switch {
+	case c == '{':
+		l.advance(1)
+		return tokLBrace, "{", nil
+	case c == '}':
+		l.advance(1)
+		return tokRBrace, "}", nil
+	case c == '(':
+		l.advance(1)
+		return tokLParen, "(", nil
+	case c == ')':
+		l.advance(1)
+		return tokRParen, ")", nil
+	case c == ';':
+		l.advance(1)
+		return tokSemi, ";", nil
+	case c == '"':
+		return l.scanString()
+	case c == '%' || c == '@' || c == ':':
+		l.advance(1)
+		for l.pos < len(l.src) && isAnnoChar(l.src[l.pos]) {
+			l.advance(1)
+		}
+		return tokAnno, l.src[start:l.pos], nil
+	case strings.HasPrefix(l.src[l.pos:], "0x"):
+		l.advance(2)
+		for l.pos < len(l.src) && isHexChar(l.src[l.pos]) {
+			l.advance(1)
+		}
+		return tokBytes, l.src[start:l.pos], nil
+	case c == '-' || isDigit(c):
+		l.advance(1)
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.advance(1)
+		}
+		return tokInt, l.src[start:l.pos], nil
+	case isIdentChar(c):
+		for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+			l.advance(1)
+		}
+		return tokIdent, l.src[start:l.pos], nil
+	default:
+		return tokEOF, "", l.errorf(l.line, l.col, "unexpected character %q", c)
+	}
+}
+
+func (l *michelsonLexer) scanString() (michelsonToken, string, error) {
+	line, col := l.line, l.col
+	l.advance(1)
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch c {
+		case '"':
+			l.advance(1)
+			return tokString, b.String(), nil
+		case '\n':
+			return tokEOF, "", l.errorf(l.line, l.col, "newline in string")
+		case '\\':
+			if l.pos+1 >= len(l.src) {
+				return tokEOF, "", l.errorf(line, col, "unterminated string")
+			}
+			switch e := l.src[l.pos+1]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'b':
+				b.WriteByte('\b')
+			case '\\', '"':
+				b.WriteByte(e)
+			default:
+				return tokEOF, "", l.errorf(l.line, l.col, "invalid escape sequence \\%c", e)
+			}
+			l.advance(2)
+		default:
+			b.WriteByte(c)
+			l.advance(1)
+		}
+	}
+	return tokEOF, "", l.errorf(line, col, "unterminated string")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexChar(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isIdentChar(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+func isAnnoChar(c byte) bool {
+	return isIdentChar(c) || c == '.' || c == '%' || c == '@'
+}
+
+type michelsonParser struct {
+	lex  michelsonLexer
+	tok  michelsonToken
+	text string
+	line int
+	col  int
+}
+
+func (p *michelsonParser) next() error {
+	if err := p.lex.skip(); err != nil {
+		return err
+	}
+	p.line, p.col = p.lex.line, p.lex.col
+	tok, text, err := p.lex.scan()
+	if err != nil {
+		return err
+	}
+	p.tok, p.text = tok, text
+	return nil
+}
+
+func (p *michelsonParser) errorf(format string, args ...interface{}) error {
+	return p.lex.errorf(p.line, p.col, format, args...)
+}
+
+func (p *michelsonParser) unexpected() error {
+	if p.tok == tokEOF {
+		return p.errorf("unexpected end of input")
+	}
+	return p.errorf("unexpected %s %q", p.tok, p.text)
+}
+
+// parseSeq parses semicolon separated expressions until end is reached and
+// reports whether any separator was found.
+func (p *michelsonParser) parseSeq(end michelsonToken) ([]Prim, bool, error) {
+	list := make([]Prim, 0)
+	semi := false
+	for p.tok != end {
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, semi, err
+		}
+		list = append(list, expr)
+		if p.tok != tokSemi {
+			break
+		}
+		semi = true
+		if err := p.next(); err != nil {
+			return nil, semi, err
+		}
+	}
+	if p.tok != end {
+		return nil, semi, p.unexpected()
+	}
+	return list, semi, nil
+}
+
+// parseExpr parses a primitive application with annotations and arguments
+// or a single literal.
+func (p *michelsonParser) parseExpr() (Prim, error) {
+	if p.tok != tokIdent {
+		return p.parseArg()
+	}
+	prim, err := p.parsePrim()
+	if err != nil {
+		return InvalidPrim, err
+	}
+	for p.tok == tokAnno {
+		prim.Anno = append(prim.Anno, p.text)
+		if err := p.next(); err != nil {
+			return InvalidPrim, err
+		}
+	}
+	for {
+		switch p.tok {
+		case tokIdent, tokInt, tokString, tokBytes, tokLBrace, tokLParen:
+			arg, err := p.parseArg()
+			if err != nil {
+				return InvalidPrim, err
+			}
+			prim.Args = append(prim.Args, arg)
+		default:
+			prim.Type = michelsonPrimType(len(prim.Args), len(prim.Anno) > 0)
+			return prim, nil
+		}
+	}
+}
+
+// parsePrim parses the primitive name at the current token.
+func (p *michelsonParser) parsePrim() (Prim, error) {
+	oc, err := ParseOpCode(p.text)
+	if err != nil {
+		return InvalidPrim, p.errorf("unknown primitive %q", p.text)
+	}
+	if err := p.next(); err != nil {
+		return InvalidPrim, err
+	}
+	return Prim{Type: PrimNullary, OpCode: oc}, nil
+}
+
+// parseArg parses a literal, sequence, parenthesized expression or a
+// primitive without arguments and annotations.
+func (p *michelsonParser) parseArg() (Prim, error) {
+	var prim Prim
+	switch p.tok {
+	case tokIdent:
+		return p.parsePrim()
+	case tokInt:
+		i, ok := new(big.Int).SetString(p.text, 10)
+		if !ok {
+			return InvalidPrim, p.errorf("invalid int %q", p.text)
+		}
+		prim = NewBig(i)
+	case tokString:
+		prim = NewString(p.text)
+	case tokBytes:
+		b, err := hex.DecodeString(p.text[2:])
+		if err != nil {
+			return InvalidPrim, p.errorf("invalid bytes %q", p.text)
+		}
+		prim = NewBytes(b)
+	case tokLBrace:
+		if err := p.next(); err != nil {
+			return InvalidPrim, err
+		}
+		list, _, err := p.parseSeq(tokRBrace)
+		if err != nil {
+			return InvalidPrim, err
+		}
+		prim = NewSeq(list...)
+	case tokLParen:
+		if err := p.next(); err != nil {
+			return InvalidPrim, err
+		}
+		expr, err := p.parseExpr()
+		if err != nil {
+			return InvalidPrim, err
+		}
+		if p.tok != tokRParen {
+			return InvalidPrim, p.unexpected()
+		}
+		prim = expr
+	default:
+		return InvalidPrim, p.unexpected()
+	}
+	if err := p.next(); err != nil {
+		return InvalidPrim, err
+	}
+	return prim, nil
+}
+
+func michelsonPrimType(n int, anno bool) PrimType {
+	switch {
+	case n == 0 && anno:
+		return PrimNullaryAnno
+	case n == 0:
+		return PrimNullary
+	case n == 1 && anno:
+		return PrimUnaryAnno
+	case n == 1:
+		return PrimUnary
+	case n == 2 && anno:
+		return PrimBinaryAnno
+	case n == 2:
+		return PrimBinary
+	default:
+		return PrimVariadicAnno
+	}
+}
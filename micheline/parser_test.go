@@ -0,0 +1,92 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package micheline
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseMichelson(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Src  string
+		Json string
+	}{
+		{
+			Name: "type",
+			Src:  "pair (address %owner) (nat %balance)",
+			Json: `{"args":[{"annots":["%owner"],"prim":"address"},{"annots":["%balance"],"prim":"nat"}],"prim":"pair"}`,
+		},
+		{
+			Name: "comb type",
+			Src:  "(pair :t nat (option string) bool)",
+			Json: `{"annots":[":t"],"args":[{"prim":"nat"},{"args":[{"prim":"string"}],"prim":"option"},{"prim":"bool"}],"prim":"pair"}`,
+		},
+		{
+			Name: "data",
+			Src:  `{ Elt -1 (Pair "a\"\n" 0x00ff) ; Elt 2 (Pair "" 0x) }`,
+			Json: `[{"args":[{"int":"-1"},{"args":[{"string":"a\"\n"},{"bytes":"00ff"}],"prim":"Pair"}],"prim":"Elt"},{"args":[{"int":"2"},{"args":[{"string":""},{"bytes":""}],"prim":"Pair"}],"prim":"Elt"}]`,
+		},
+		{
+			Name: "code",
+			Src: `# a comment
+				{ UNPAIR ; /* inline */ IF_LEFT { ADD } {} ;
+				  PUSH nat 1 ; SUB @diff ; }`,
+			Json: `[{"prim":"UNPAIR"},{"args":[[{"prim":"ADD"}],[]],"prim":"IF_LEFT"},{"args":[{"prim":"nat"},{"int":"1"}],"prim":"PUSH"},{"annots":["@diff"],"prim":"SUB"}]`,
+		},
+		{
+			Name: "script sections",
+			Src:  "parameter unit ; storage unit ; code { CDR ; NIL operation ; PAIR } ;",
+			Json: `[{"args":[{"prim":"unit"}],"prim":"parameter"},{"args":[{"prim":"unit"}],"prim":"storage"},{"args":[[{"prim":"CDR"},{"args":[{"prim":"operation"}],"prim":"NIL"},{"prim":"PAIR"}]],"prim":"code"}]`,
+		},
+	} {
+		p, err := ParseMichelson(test.Src)
+		if err != nil {
+			t.Errorf("%s: %v", test.Name, err)
+			continue
+		}
+		if buf, _ := json.Marshal(p); string(buf) != test.Json {
+			t.Errorf("%s: want=%s got=%s", test.Name, test.Json, buf)
+		}
+		var want Prim
+		if err := json.Unmarshal([]byte(test.Json), &want); err != nil {
+			t.Fatal(err)
+		}
+		if !p.IsEqualWithAnno(want) {
+			t.Errorf("%s: prim mismatch with JSON decoded tree", test.Name)
+		}
+		// printer output parses to the same tree
+		if p2, err := ParseMichelson(p.Michelson()); err != nil || !p2.IsEqualWithAnno(p) {
+			t.Errorf("%s: round-trip failed for %s: %v", test.Name, p.Michelson(), err)
+		}
+	}
+}
+
+func TestParseMichelsonErrors(t *testing.T) {
+	for _, test := range []struct {
+		Src string
+		Err string
+	}{
+		{Src: "", Err: "line 1 col 1: empty input"},
+		{Src: "pair nat\n  foo", Err: "line 2 col 3: unknown primitive"},
+		{Src: "{ UNIT ; UNIT", Err: "line 1 col 14: unexpected end of input"},
+		{Src: "pair nat nat )", Err: "line 1 col 14: unexpected ')'"},
+		{Src: `"abc`, Err: "line 1 col 1: unterminated string"},
+		{Src: `"a\q"`, Err: "line 1 col 3: invalid escape"},
+		{Src: "PUSH nat 0x0", Err: "line 1 col 10: invalid bytes"},
+		{Src: "UNIT $", Err: "line 1 col 6: unexpected character"},
+	} {
+		_, err := ParseMichelson(test.Src)
+		if err == nil {
+			t.Errorf("%q: expected error", test.Src)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.Err) {
+			t.Errorf("%q: want error %q, got %q", test.Src, test.Err, err)
+		}
+	}
+}
@@ -543,6 +543,56 @@ func (p Prim) LooksLikeLambda() bool {
 	}
 }
 
+// Checks if a Prim contains bytes that look like a binary encoded address
+// or key hash. Both the 21 byte (key hash) and 22 byte (address) forms
+// are detected.
+func (p Prim) LooksLikeAddress() bool {
+	if p.Type != PrimBytes {
+		return false
+	}
+	b := p.Bytes
+	switch len(b) {
+	case 21:
+		return tezos.ParseAddressTag(b[0]).IsValid()
+	case 22:
+		switch b[0] {
+		case 0:
+			// implicit account with key type tag
+			return tezos.ParseAddressTag(b[1]).IsValid()
+		case 1, 2, 3:
+			// originated contract or rollup with trailing padding
+			return b[21] == 0
+		}
+	}
+	return false
+}
+
+// Checks if a Prim contains bytes that look like a binary encoded public key,
+// i.e. a known key type tag followed by key data of matching length.
+func (p Prim) LooksLikeKey() bool {
+	if p.Type != PrimBytes || len(p.Bytes) < 33 {
+		return false
+	}
+	b := p.Bytes
+	typ := tezos.ParseKeyTag(b[0])
+	if !typ.IsValid() || len(b) != typ.Len()+1 {
+		return false
+	}
+	switch typ {
+	case tezos.KeyTypeSecp256k1, tezos.KeyTypeP256:
+		// compressed curve points start with 0x02 or 0x03
+		return b[1] == 0x02 || b[1] == 0x03
+	}
+	return true
+}
+
+// Checks if a Prim contains bytes that look like a binary encoded signature.
+// Michelson uses untagged 64 byte signatures. Printable text of the same
+// length is not considered a signature.
+func (p Prim) LooksLikeSignature() bool {
+	return p.Type == PrimBytes && len(p.Bytes) == 64 && !isASCIIBytes(p.Bytes)
+}
+
 // Converts a pair tree into a flat sequence. While Michelson
 // optimized comb pairs are only used for right-side combs, this
 // function applies to all pairs. It makes use of the type definition
@@ -601,7 +651,7 @@ func (p Prim) FoldPair() Prim {
 // generated with PACK (starting with 0x05), an address or ascii/utf string.
 func (p Prim) IsPacked() bool {
 	return p.Type == PrimBytes &&
-		(isPackedBytes(p.Bytes) || tezos.IsAddressBytes(p.Bytes) || isASCIIBytes(p.Bytes))
+		(isPackedBytes(p.Bytes) || p.LooksLikeAddress() || isASCIIBytes(p.Bytes))
 }
 
 // Unpacks all primitive contents that looks like packed and returns a new primitive
@@ -619,15 +669,20 @@ func (p Prim) Unpack() (pp Prim, err error) {
 	pp = Prim{WasPacked: true}
 	switch true {
 	case isPackedBytes(p.Bytes):
-		if err := pp.UnmarshalBinary(p.Bytes[1:]); err != nil {
+		buf := bytes.NewBuffer(p.Bytes[1:])
+		if err := pp.DecodeBuffer(buf); err != nil {
 			return p, err
 		}
+		// bytes that only start like packed data are left alone
+		if buf.Len() > 0 {
+			return p, fmt.Errorf("prim is not packed: %d trailing bytes", buf.Len())
+		}
 		if pp.IsPackedAny() {
 			if up, err := pp.UnpackAll(); err == nil {
 				pp = up
 			}
 		}
-	case tezos.IsAddressBytes(p.Bytes):
+	case p.LooksLikeAddress():
 		a := tezos.Address{}
 		if err := a.UnmarshalBinary(p.Bytes); err != nil {
 			return p, err
@@ -665,13 +720,13 @@ func (p Prim) UnpackAll() (Prim, error) {
 	pp := p
 	pp.Args = make([]Prim, len(p.Args))
 	for i, v := range p.Args {
+		pp.Args[i] = v
 		if v.IsPackedAny() {
+			// keep children which fail to unpack unchanged
 			if up, err := v.UnpackAll(); err == nil {
 				pp.Args[i] = up
 			}
-			continue
 		}
-		pp.Args[i] = v
 	}
 	return pp, nil
 }
@@ -695,10 +750,15 @@ func (p Prim) Value(as OpCode) interface{} {
 	case PrimString:
 		switch as {
 		case T_TIMESTAMP:
-			if t, err := time.Parse(time.RFC3339, p.String); err == nil {
+			if t, ok := parseTimestamp(p.String); ok {
 				return t
 			}
 			return p.String
+		case T_CHAIN_ID:
+			if c, err := tezos.ParseChainId(p.String); err == nil {
+				return c
+			}
+			return p.String
 		default:
 			return p.String
 		}
@@ -724,8 +784,8 @@ func (p Prim) Value(as OpCode) interface{} {
 			}
 
 		case T_CHAIN_ID:
-			if len(p.Bytes) == tezos.HashTypeChainId.Len() {
-				return tezos.NewChainIdHash(p.Bytes).String()
+			if c, err := tezos.DecodeChainId(p.Bytes); err == nil {
+				return c
 			}
 
 		default:
@@ -887,6 +947,12 @@ func (p Prim) MarshalJSON() ([]byte, error) {
 	return json.Marshal(m)
 }
 
+// Size returns the length in bytes of the binary encoding produced by
+// MarshalBinary and EncodeBuffer without encoding the prim.
+func (p Prim) Size() int {
+	return p.encodedSize()
+}
+
 func (p Prim) MarshalBinary() ([]byte, error) {
 	buf := bytes.NewBuffer(nil)
 	if err := p.EncodeBuffer(buf); err != nil {
@@ -0,0 +1,111 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package micheline
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+type sniffTest struct {
+	Name string
+	Hex  string
+	Addr bool
+	Key  bool
+	Sig  bool
+}
+
+var sniffInfo = []sniffTest{
+	// genuine
+	sniffTest{Name: "tz1_address", Hex: "000002298c03ed7d454a101eb7022bc95f7e5f41ac78", Addr: true},
+	sniffTest{Name: "kt1_address", Hex: "01a8b26fa6d9e6b1b3d675d25320d3d37b1eb8d93800", Addr: true},
+	sniffTest{Name: "tz2_key_hash", Hex: "01b6c5e7fa2a0b8d2c9ba400c51778c21b8a8bc54e", Addr: true},
+	sniffTest{Name: "edpk", Hex: "00" + "d670f72efd9475b62275fae773eb5f5eb1fea4f2a0880e6d21983273bf95a0af", Key: true},
+	sniffTest{Name: "sppk", Hex: "01" + "03b524d0184276466f0e0a9a2faa43a1dd184bc2b104f8d38cc7a4c0c84a0dc32a", Key: true},
+	sniffTest{Name: "sig", Hex: "a04991b4e1a53ef47e1bae4bb6b7c8af4fd918fd5fb2f7ebd05ea5415e9ddc0da26d994c5b4f6b0a8b9e5b8e8c0256e13f6a03885b9f8cd0f0e02f7596f84008", Sig: true},
+	// decoys
+	sniffTest{Name: "empty", Hex: ""},
+	sniffTest{Name: "short", Hex: "0000"},
+	sniffTest{Name: "kt1_no_padding", Hex: "01a8b26fa6d9e6b1b3d675d25320d3d37b1eb8d93801"},
+	sniffTest{Name: "bad_addr_tag", Hex: "000902298c03ed7d454a101eb7022bc95f7e5f41ac78"},
+	sniffTest{Name: "bad_key_len", Hex: "00" + "d670f72efd9475b62275fae773eb5f5eb1fea4f2a0880e6d21983273bf95a0afff"},
+	sniffTest{Name: "sppk_bad_point", Hex: "01" + "05b524d0184276466f0e0a9a2faa43a1dd184bc2b104f8d38cc7a4c0c84a0dc32a"},
+	sniffTest{Name: "ascii_64", Hex: hex.EncodeToString(bytes.Repeat([]byte("tzgo"), 16))},
+	sniffTest{Name: "packed_int", Hex: "0500a005"},
+}
+
+func TestLooksLike(t *testing.T) {
+	for _, test := range sniffInfo {
+		buf, err := hex.DecodeString(test.Hex)
+		if err != nil {
+			t.Fatalf("%s: %v", test.Name, err)
+		}
+		p := NewBytes(buf)
+		if got := p.LooksLikeAddress(); got != test.Addr {
+			t.Errorf("%s: LooksLikeAddress want=%t got=%t", test.Name, test.Addr, got)
+		}
+		if got := p.LooksLikeKey(); got != test.Key {
+			t.Errorf("%s: LooksLikeKey want=%t got=%t", test.Name, test.Key, got)
+		}
+		if got := p.LooksLikeSignature(); got != test.Sig {
+			t.Errorf("%s: LooksLikeSignature want=%t got=%t", test.Name, test.Sig, got)
+		}
+		if test.Addr {
+			var a tezos.Address
+			if err := a.UnmarshalBinary(buf); err != nil {
+				t.Errorf("%s: genuine address does not decode: %v", test.Name, err)
+			}
+		}
+		if test.Key {
+			var k tezos.Key
+			if err := k.UnmarshalBinary(buf); err != nil {
+				t.Errorf("%s: genuine key does not decode: %v", test.Name, err)
+			}
+		}
+	}
+
+	// non-bytes never match
+	s := NewString("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb")
+	if s.LooksLikeAddress() || s.LooksLikeKey() || s.LooksLikeSignature() {
+		t.Errorf("string prim must not look like binary data")
+	}
+}
+
+func TestPrimSize(t *testing.T) {
+	b, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	for _, p := range []Prim{
+		NewInt64(0),
+		NewInt64(63),
+		NewInt64(64),
+		NewInt64(-8192),
+		NewBig(b),
+		NewString(""),
+		NewString("tzgo"),
+		NewBytes([]byte{1, 2, 3}),
+		NewSeq(),
+		NewSeq(NewInt64(1), NewString("a")),
+		NewCode(D_UNIT),
+		NewCodeAnno(T_UNIT, "%u"),
+		NewCode(D_SOME, NewInt64(1)),
+		NewCodeAnno(T_OPTION, "%o", NewPrim(T_NAT)),
+		NewPairValue(NewInt64(1), NewBytes([]byte{0xff})),
+		NewPairType(NewPrim(T_NAT, "%a"), NewPrim(T_STRING, "%b"), "%p"),
+		NewCode(D_PAIR, NewInt64(1), NewInt64(2), NewInt64(3)),
+		NewCodeAnno(T_PAIR, "%c", NewPrim(T_NAT), NewPrim(T_NAT), NewPrim(T_NAT)),
+		NewCode(T_LAMBDA, NewPrim(T_UNIT), NewPrim(T_UNIT), NewSeq(NewCode(I_DROP), NewCode(I_UNIT))),
+	} {
+		buf, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: %v", p.Dump(), err)
+		}
+		if n := p.Size(); n != len(buf) {
+			t.Errorf("%s: size %d, encoded %d", p.Dump(), n, len(buf))
+		}
+	}
+}
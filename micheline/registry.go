@@ -0,0 +1,116 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package micheline
+
+import (
+	"fmt"
+	"sync"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// ScriptTypes holds the storage and parameter types of a contract.
+type ScriptTypes struct {
+	Storage Type
+	Param   Type
+}
+
+// TypeRegistry maps contract addresses and code hashes to their script types
+// so that indexers can decode storage and call parameters of many contracts
+// without passing types around. Contracts originated from the same code share
+// a registration when linked by code hash. A TypeRegistry is safe for
+// concurrent use.
+type TypeRegistry struct {
+	mu     sync.RWMutex
+	byAddr map[string]*ScriptTypes
+	byCode map[[32]byte]*ScriptTypes
+}
+
+// NewTypeRegistry returns an empty type registry.
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		byAddr: make(map[string]*ScriptTypes),
+		byCode: make(map[[32]byte]*ScriptTypes),
+	}
+}
+
+// Register adds or replaces the types of contract addr.
+func (r *TypeRegistry) Register(addr tezos.Address, storage, param Type) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byAddr[addr.String()] = &ScriptTypes{Storage: storage, Param: param}
+}
+
+// RegisterCode adds or replaces the types of all contracts with code hash
+// codeHash as returned by Script.CanonicalCodeHash.
+func (r *TypeRegistry) RegisterCode(codeHash [32]byte, storage, param Type) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byCode[codeHash] = &ScriptTypes{Storage: storage, Param: param}
+}
+
+// RegisterScript registers the types of script s for contract addr and for
+// its code hash.
+func (r *TypeRegistry) RegisterScript(addr tezos.Address, s *Script) {
+	t := &ScriptTypes{Storage: s.StorageType(), Param: s.ParamType()}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byAddr[addr.String()] = t
+	r.byCode[s.CanonicalCodeHash()] = t
+}
+
+// Link registers contract addr with the types previously registered for
+// codeHash.
+func (r *TypeRegistry) Link(addr tezos.Address, codeHash [32]byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.byCode[codeHash]
+	if !ok {
+		return fmt.Errorf("micheline: no types registered for code hash %x", codeHash)
+	}
+	r.byAddr[addr.String()] = t
+	return nil
+}
+
+// Lookup returns the types registered for contract addr.
+func (r *TypeRegistry) Lookup(addr tezos.Address) (ScriptTypes, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.byAddr[addr.String()]
+	if !ok {
+		return ScriptTypes{}, false
+	}
+	return *t, true
+}
+
+// Remove deletes the registration of contract addr.
+func (r *TypeRegistry) Remove(addr tezos.Address) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.byAddr, addr.String())
+}
+
+// Decode returns storage as a value of the storage type registered for
+// contract addr.
+func (r *TypeRegistry) Decode(addr tezos.Address, storage Prim) (Value, error) {
+	t, ok := r.Lookup(addr)
+	if !ok || !t.Storage.IsValid() {
+		return Value{}, fmt.Errorf("micheline: no storage type registered for %s", addr)
+	}
+	return NewValue(t.Storage, storage), nil
+}
+
+// DecodeParams maps call parameters of contract addr to their entrypoint and
+// returns the entrypoint argument as value.
+func (r *TypeRegistry) DecodeParams(addr tezos.Address, params Parameters) (Entrypoint, Value, error) {
+	t, ok := r.Lookup(addr)
+	if !ok || !t.Param.IsValid() {
+		return Entrypoint{}, Value{}, fmt.Errorf("micheline: no parameter type registered for %s", addr)
+	}
+	ep, prim, err := params.MapEntrypoint(t.Param)
+	if err != nil {
+		return ep, Value{}, err
+	}
+	return ep, NewValue(ep.Type(), prim), nil
+}
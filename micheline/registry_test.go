@@ -0,0 +1,68 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package micheline
+
+import (
+	"encoding/json"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func TestTypeRegistry(t *testing.T) {
+	addr := tezos.MustParseAddress("KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu")
+	clone := tezos.MustParseAddress("tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs")
+	script := NewScript()
+	script.Code.Storage = NewCode(K_STORAGE, newTestType(t, `{"prim":"pair","args":[{"prim":"address","annots":["%admin"]},{"prim":"nat","annots":["%total"]}]}`).Prim)
+	script.Code.Param = NewCode(K_PARAMETER, newTestType(t, `{"prim":"or","args":[{"prim":"nat","annots":["%mint"]},{"prim":"unit","annots":["%pause"]}]}`).Prim)
+
+	reg := NewTypeRegistry()
+	if _, err := reg.Decode(addr, NewInt64(1)); err == nil {
+		t.Errorf("expected error for unregistered contract")
+	}
+	reg.RegisterScript(addr, script)
+
+	var storage Prim
+	if err := json.Unmarshal([]byte(`{"prim":"Pair","args":[{"string":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"},{"int":"42"}]}`), &storage); err != nil {
+		t.Fatal(err)
+	}
+	val, err := reg.Decode(addr, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := val.GetInt64("total"); !ok || n != 42 {
+		t.Errorf("total mismatch got=%d ok=%t", n, ok)
+	}
+	if a, ok := val.GetAddress("admin"); !ok || a.String() != "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb" {
+		t.Errorf("admin mismatch got=%s ok=%t", a, ok)
+	}
+
+	// contracts with the same code share types
+	if err := reg.Link(clone, script.CanonicalCodeHash()); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Link(clone, [32]byte{1, 2, 3, 4}); err == nil {
+		t.Errorf("expected error for unknown code hash")
+	}
+	var params Parameters
+	if err := json.Unmarshal([]byte(`{"entrypoint":"mint","value":{"int":"5"}}`), &params); err != nil {
+		t.Fatal(err)
+	}
+	ep, arg, err := reg.DecodeParams(clone, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep.Call != "mint" || arg.Value.Int.Int64() != 5 {
+		t.Errorf("unexpected params %s %s", ep.Call, arg.Value.Dump())
+	}
+
+	reg.Remove(clone)
+	if _, ok := reg.Lookup(clone); ok {
+		t.Errorf("expected removed contract")
+	}
+	if _, ok := reg.Lookup(addr); !ok {
+		t.Errorf("expected registered contract")
+	}
+}
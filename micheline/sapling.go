@@ -4,13 +4,14 @@
 package micheline
 
 import (
-// "bytes"
-// "encoding/binary"
-// "encoding/hex"
-// "encoding/json"
-// "fmt"
-// "math/big"
-// "blockwatch.cc/tzgo/tezos"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	saplingInputSize        = 352 // cv, nf, rk, proof, signature
+	saplingOutputFixedSize  = 416 // cm, proof, cv, epk, nonces, payload_out (w/o payload_enc)
+	saplingPayloadEmptySize = 71  // payload_enc size for an empty memo
 )
 
 type SaplingDiffElem struct {
@@ -42,3 +43,87 @@ func (c Ciphertext) MarshalJSON() ([]byte, error) {
 func (c *Ciphertext) UnmarshalJSON(data []byte) error {
 	return nil
 }
+
+// SaplingTransaction is the structural view of a sapling_transaction value.
+// Spend and output descriptions are counted, not decoded.
+type SaplingTransaction struct {
+	Inputs     int    // number of spend descriptions
+	Outputs    int    // number of output descriptions
+	MemoSize   int    // memo size of the first output, -1 without outputs
+	BindingSig []byte // 64 byte binding signature
+	Balance    int64  // value balance in mutez
+	Root       []byte // commitment tree root
+	BoundData  []byte // data bound to the transaction, empty before Jakarta
+}
+
+// DecodeSaplingTransaction decodes the binary encoding of a sapling
+// transaction.
+func DecodeSaplingTransaction(buf []byte) (SaplingTransaction, error) {
+	tx := SaplingTransaction{MemoSize: -1}
+	readLen := func() (int, error) {
+		if len(buf) < 4 {
+			return 0, fmt.Errorf("micheline: short sapling transaction")
+		}
+		l := int(binary.BigEndian.Uint32(buf))
+		buf = buf[4:]
+		if l > len(buf) {
+			return 0, fmt.Errorf("micheline: short sapling transaction")
+		}
+		return l, nil
+	}
+
+	// inputs have a fixed size
+	l, err := readLen()
+	if err != nil {
+		return tx, err
+	}
+	if l%saplingInputSize != 0 {
+		return tx, fmt.Errorf("micheline: invalid sapling inputs size %d", l)
+	}
+	tx.Inputs = l / saplingInputSize
+	buf = buf[l:]
+
+	// outputs differ in size by their encrypted payload
+	l, err = readLen()
+	if err != nil {
+		return tx, err
+	}
+	outputs := buf[:l]
+	buf = buf[l:]
+	for len(outputs) > 0 {
+		if len(outputs) < saplingOutputFixedSize+4 {
+			return tx, fmt.Errorf("micheline: short sapling output")
+		}
+		n := int(binary.BigEndian.Uint32(outputs[32+192+64:]))
+		if n < saplingPayloadEmptySize || len(outputs) < saplingOutputFixedSize+4+n {
+			return tx, fmt.Errorf("micheline: invalid sapling output payload size %d", n)
+		}
+		if tx.MemoSize < 0 {
+			tx.MemoSize = n - saplingPayloadEmptySize
+		}
+		tx.Outputs++
+		outputs = outputs[saplingOutputFixedSize+4+n:]
+	}
+
+	if len(buf) < 64+8+32 {
+		return tx, fmt.Errorf("micheline: short sapling transaction")
+	}
+	tx.BindingSig = buf[:64]
+	tx.Balance = int64(binary.BigEndian.Uint64(buf[64:]))
+	tx.Root = buf[72:104]
+	buf = buf[104:]
+
+	// bound data is optional
+	if len(buf) > 0 {
+		l, err = readLen()
+		if err != nil {
+			return tx, err
+		}
+		tx.BoundData = buf[:l]
+		buf = buf[l:]
+	}
+	if len(buf) > 0 {
+		return tx, fmt.Errorf("micheline: %d trailing bytes after sapling transaction", len(buf))
+	}
+	return tx, nil
+}
@@ -11,6 +11,8 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+
+	"golang.org/x/crypto/blake2b"
 )
 
 type Script struct {
@@ -75,12 +77,106 @@ func (s *Script) StorageHash() []byte {
 
 // Returns the first 4 bytes of the SHA256 hash from a binary encoded code section
 // of a contract.
+//
+// Deprecated: CodeHash hashes the code as encoded on chain and is sensitive to
+// comb notation. Use CanonicalCodeHash to group contracts with identical code.
 func (s *Script) CodeHash() []byte {
 	buf, _ := s.Code.Code.MarshalBinary()
 	h := sha256.Sum256(buf)
 	return h[:4]
 }
 
+// CanonicalCodeHash returns the BLAKE2b-256 hash over the canonical binary
+// encoding of the code section of a contract. Storage is ignored so that all
+// contracts originated from the same code share a hash. It equals ScriptHash
+// with the CodeOnly option.
+func (s *Script) CanonicalCodeHash() [32]byte {
+	var res [32]byte
+	copy(res[:], s.ScriptHash(ScriptHashOptions{CodeOnly: true}))
+	return res
+}
+
+// ScriptHashOptions controls which parts of a script are hashed by ScriptHash.
+type ScriptHashOptions struct {
+	CodeOnly         bool // hash the code section only, skip parameter and storage types
+	StripAnnotations bool // remove all type, field and variable annotations
+}
+
+// ScriptHash returns the BLAKE2b-256 digest of the binary encoded parameter,
+// storage and code sections of a contract. Storage contents are not included
+// so that all contracts originated from the same template share a hash. Before
+// hashing, pair combs are converted to nested binary pairs and nodes with
+// empty annotation lists use their plain encoding. With annotations stripped,
+// scripts which differ only in field names hash equal, which is useful to
+// detect families of token contracts.
+func (s *Script) ScriptHash(opts ScriptHashOptions) []byte {
+	sections := []Prim{s.Code.Param, s.Code.Storage, s.Code.Code}
+	if opts.CodeOnly {
+		sections = sections[2:]
+	}
+	h, _ := blake2b.New256(nil)
+	for _, p := range sections {
+		p = canonicalPrim(p)
+		if opts.StripAnnotations {
+			p = stripAnnotations(p)
+		}
+		buf, _ := p.MarshalBinary()
+		h.Write(buf)
+	}
+	return h.Sum(nil)
+}
+
+// canonicalPrim returns a copy of p where type and value pair combs are
+// nested binary pairs and nodes without annotations use their plain type.
+func canonicalPrim(p Prim) Prim {
+	if (p.OpCode == T_PAIR || p.OpCode == D_PAIR) && p.Type != PrimSequence {
+		p = binaryPair(p)
+		if p.Type == PrimVariadicAnno && len(p.Args) == 2 {
+			p.Type = PrimBinaryAnno
+		}
+	}
+	if len(p.Anno) == 0 {
+		switch p.Type {
+		case PrimNullaryAnno:
+			p.Type = PrimNullary
+		case PrimUnaryAnno:
+			p.Type = PrimUnary
+		case PrimBinaryAnno:
+			p.Type = PrimBinary
+		}
+		p.Anno = nil
+	}
+	if p.Args != nil {
+		args := make([]Prim, len(p.Args))
+		for i, v := range p.Args {
+			args[i] = canonicalPrim(v)
+		}
+		p.Args = args
+	}
+	return p
+}
+
+// stripAnnotations returns a copy of p without annotations on any node.
+func stripAnnotations(p Prim) Prim {
+	switch p.Type {
+	case PrimNullaryAnno:
+		p.Type = PrimNullary
+	case PrimUnaryAnno:
+		p.Type = PrimUnary
+	case PrimBinaryAnno:
+		p.Type = PrimBinary
+	}
+	p.Anno = nil
+	if p.Args != nil {
+		args := make([]Prim, len(p.Args))
+		for i, v := range p.Args {
+			args[i] = stripAnnotations(v)
+		}
+		p.Args = args
+	}
+	return p
+}
+
 // Returns a list of bigmaps referenced by a contracts current storage. Note that
 // in rare cases when storage type uses a T_OR branch above its bigmap type definitions
 // and the relevant branch is inactive/hidden the storage value lacks bigmap
@@ -0,0 +1,116 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package micheline
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testScriptCode = `[
+	{"prim":"parameter","args":[{"prim":"or","args":[{"prim":"nat","annots":["%mint"]},{"prim":"nat","annots":["%burn"]}]}]},
+	{"prim":"storage","args":[{"prim":"nat","annots":["%total"]}]},
+	{"prim":"code","args":[[{"prim":"UNPAIR"},{"prim":"IF_LEFT","args":[[{"prim":"ADD"}],[{"prim":"SWAP"},{"prim":"SUB","annots":["@diff"]},{"prim":"ABS"}]]},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]]}
+]`
+
+func newTestScript(t *testing.T, code string) *Script {
+	t.Helper()
+	s := NewScript()
+	if err := json.Unmarshal([]byte(code), &s.Code); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestScriptHash(t *testing.T) {
+	a := newTestScript(t, testScriptCode)
+	b := newTestScript(t, strings.NewReplacer("%mint", "%issue", "%total", "%supply", "@diff", "@d").Replace(testScriptCode))
+	c := newTestScript(t, strings.Replace(testScriptCode, `{"prim":"nat","annots":["%total"]}`, `{"prim":"int"}`, 1))
+
+	full := ScriptHashOptions{}
+	stripped := ScriptHashOptions{StripAnnotations: true}
+	code := ScriptHashOptions{CodeOnly: true, StripAnnotations: true}
+
+	if h := a.ScriptHash(full); len(h) != 32 || !bytes.Equal(h, a.ScriptHash(full)) {
+		t.Errorf("expected stable 32 byte hash, got %x", h)
+	}
+	if bytes.Equal(a.ScriptHash(full), b.ScriptHash(full)) {
+		t.Errorf("expected different hashes for different annotations")
+	}
+	if !bytes.Equal(a.ScriptHash(stripped), b.ScriptHash(stripped)) {
+		t.Errorf("expected equal hashes without annotations")
+	}
+	if bytes.Equal(a.ScriptHash(stripped), c.ScriptHash(stripped)) {
+		t.Errorf("expected different hashes for different storage types")
+	}
+	if !bytes.Equal(a.ScriptHash(code), c.ScriptHash(code)) {
+		t.Errorf("expected equal code-only hashes")
+	}
+	// comb and nested pair notation are the same type
+	comb := newTestScript(t, strings.Replace(testScriptCode, `{"prim":"nat","annots":["%total"]}`,
+		`{"prim":"pair","args":[{"prim":"nat"},{"prim":"nat"},{"prim":"nat"}]}`, 1))
+	nested := newTestScript(t, strings.Replace(testScriptCode, `{"prim":"nat","annots":["%total"]}`,
+		`{"prim":"pair","args":[{"prim":"nat"},{"prim":"pair","args":[{"prim":"nat"},{"prim":"nat"}]}]}`, 1))
+	if !bytes.Equal(comb.ScriptHash(full), nested.ScriptHash(full)) {
+		t.Errorf("expected equal hashes for comb and nested pairs")
+	}
+	// stripping must not alter the script
+	if !a.Code.Param.Args[0].Args[0].HasAnno() {
+		t.Errorf("script annotations were modified")
+	}
+}
+
+func TestScriptCodeHash(t *testing.T) {
+	comb := `{"prim":"pair","args":[{"prim":"nat"},{"prim":"nat"},{"prim":"nat"}]}`
+	nested := `{"prim":"pair","args":[{"prim":"nat"},{"prim":"pair","args":[{"prim":"nat"},{"prim":"nat"}]}]}`
+	push := `{"prim":"PUSH","args":[%s,{"prim":"Pair","args":[{"int":"1"},{"int":"2"},{"int":"3"}]}]},{"prim":"DROP"},{"prim":"NIL"`
+	base := strings.Replace(testScriptCode, `{"prim":"NIL"`, fmt.Sprintf(push, comb), 1)
+
+	// two originations of the same code with different initial storage
+	a := newTestScript(t, base)
+	a.Storage = NewInt64(1)
+	b := newTestScript(t, base)
+	b.Storage = NewInt64(2)
+	if a.CanonicalCodeHash() != b.CanonicalCodeHash() {
+		t.Errorf("expected equal code hashes for equal code")
+	}
+
+	// comb and nested pair notation are the same type
+	c := newTestScript(t, strings.Replace(testScriptCode, `{"prim":"NIL"`, fmt.Sprintf(push, nested), 1))
+	if a.CanonicalCodeHash() != c.CanonicalCodeHash() {
+		t.Errorf("expected equal code hashes for comb and nested pairs")
+	}
+
+	// an explicit empty annotation list is the same as none
+	d := newTestScript(t, strings.Replace(base, `{"prim":"UNPAIR"}`, `{"prim":"UNPAIR","annots":[]}`, 1))
+	if a.CanonicalCodeHash() != d.CanonicalCodeHash() {
+		t.Errorf("expected equal code hashes for empty annotations")
+	}
+
+	// storage and parameter types are not part of the code hash
+	e := newTestScript(t, strings.Replace(base, `{"prim":"nat","annots":["%total"]}`, `{"prim":"int"}`, 1))
+	if a.CanonicalCodeHash() != e.CanonicalCodeHash() {
+		t.Errorf("expected equal code hashes for different storage types")
+	}
+
+	// different code
+	f := newTestScript(t, strings.Replace(base, `{"prim":"ADD"}`, `{"prim":"MUL"}`, 1))
+	if a.CanonicalCodeHash() == f.CanonicalCodeHash() {
+		t.Errorf("expected different code hashes for different code")
+	}
+	if h := a.CanonicalCodeHash(); !bytes.Equal(h[:], a.ScriptHash(ScriptHashOptions{CodeOnly: true})) {
+		t.Errorf("expected code hash to equal code-only script hash")
+	}
+
+	// the legacy hash is a 4 byte SHA256 prefix over the code as encoded
+	buf, _ := a.Code.Code.MarshalBinary()
+	if h := sha256.Sum256(buf); !bytes.Equal(a.CodeHash(), h[:4]) {
+		t.Errorf("legacy code hash mismatch")
+	}
+}
@@ -3,6 +3,64 @@
 
 package micheline
 
+import (
+	"fmt"
+	"math/big"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// Ticket is a decoded ticket value.
+type Ticket struct {
+	Ticketer tezos.Address
+	Type     Prim // content type
+	Content  Prim
+	Amount   *big.Int
+}
+
+// DecodeTicket decodes a ticket value of content type typ. Values are
+// accepted in nested pair, comb pair and sequence form.
+func DecodeTicket(typ Prim, val Prim) (Ticket, error) {
+	var args []Prim
+	switch {
+	case val.OpCode == D_PAIR || val.Type == PrimSequence:
+		args = flattenComb(val)
+	default:
+		return Ticket{}, fmt.Errorf("micheline: invalid ticket value %s", val.DumpLimit(64))
+	}
+	if len(args) < 3 {
+		return Ticket{}, fmt.Errorf("micheline: invalid ticket value %s", val.DumpLimit(64))
+	}
+	t := Ticket{Type: typ.Clone()}
+	tk, amount := args[0], args[len(args)-1]
+	a, err := decodeAddressPrim(tk)
+	if err != nil {
+		return Ticket{}, fmt.Errorf("micheline: invalid ticketer: %w", err)
+	}
+	t.Ticketer = a
+	if amount.Type != PrimInt || amount.Int == nil {
+		return Ticket{}, fmt.Errorf("micheline: invalid ticket amount %s", amount.DumpLimit(64))
+	}
+	t.Amount = new(big.Int).Set(amount.Int)
+	if content := args[1 : len(args)-1]; len(content) == 1 {
+		t.Content = content[0].Clone()
+	} else {
+		// a pair content was flattened together with the ticket fields
+		t.Content = NewSeq(content...).FoldPair()
+	}
+	return t, nil
+}
+
+// flattenComb returns the elements of a right-comb of pairs.
+func flattenComb(p Prim) []Prim {
+	if (p.OpCode != D_PAIR && p.Type != PrimSequence) || len(p.Args) == 0 {
+		return []Prim{p}
+	}
+	res := make([]Prim, 0, len(p.Args)+1)
+	res = append(res, p.Args[:len(p.Args)-1]...)
+	return append(res, flattenComb(p.Args[len(p.Args)-1])...)
+}
+
 // Wraps ticket value type into type structure that is compatible
 // with ticket values. This is necessary because T_TICKET uses an
 // implicit structure (extra fields amount, ticketer) in addition
@@ -73,6 +73,113 @@ func (t Type) IsEqualWithAnno(t2 Type) bool {
 	return IsEqualPrim(t.Prim, t2.Prim, true)
 }
 
+// IsComparable returns true when t is a comparable type which can be used as
+// set element, map key or COMPARE argument. Simple types are comparable
+// except operation, contract, lambda, ticket, BLS12-381 and sapling types.
+// Pairs (including n-ary combs), options and unions are comparable when all
+// their arguments are.
+func (t Type) IsComparable() bool {
+	return isComparable(t.Prim)
+}
+
+func isComparable(p Prim) bool {
+	switch p.OpCode {
+	case T_UNIT, T_NEVER, T_BOOL, T_INT, T_NAT, T_STRING, T_BYTES, T_MUTEZ,
+		T_TIMESTAMP, T_ADDRESS, T_KEY, T_KEY_HASH, T_SIGNATURE, T_CHAIN_ID:
+		return p.Type != PrimSequence && len(p.Args) == 0
+	case T_OPTION:
+		return len(p.Args) == 1 && isComparable(p.Args[0])
+	case T_OR:
+		return len(p.Args) == 2 && isComparable(p.Args[0]) && isComparable(p.Args[1])
+	case T_PAIR:
+		if len(p.Args) < 2 {
+			return false
+		}
+		for _, v := range p.Args {
+			if !isComparable(v) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// Validate checks that t is a well-formed Michelson type. It rejects unknown
+// primitives, wrong argument counts, non-comparable set elements, map and
+// big_map keys and ticket contents, as well as big_map values containing
+// big_maps, operations or sapling states.
+func (t Type) Validate() error {
+	return validateType(t.Prim, false)
+}
+
+func validateType(p Prim, inBigmap bool) error {
+	if p.Type == PrimSequence || !p.OpCode.IsTypeCode() {
+		return fmt.Errorf("micheline: invalid type %s", p.DumpLimit(64))
+	}
+	n := len(p.Args)
+	switch p.OpCode {
+	case T_OPTION, T_LIST, T_SET, T_CONTRACT, T_TICKET, T_SAPLING_STATE, T_SAPLING_TRANSACTION:
+		if n != 1 {
+			return fmt.Errorf("micheline: type %s requires 1 argument, got %d", p.OpCode, n)
+		}
+	case T_OR, T_MAP, T_BIG_MAP, T_LAMBDA:
+		if n != 2 {
+			return fmt.Errorf("micheline: type %s requires 2 arguments, got %d", p.OpCode, n)
+		}
+	case T_PAIR:
+		if n < 2 {
+			return fmt.Errorf("micheline: type %s requires at least 2 arguments, got %d", p.OpCode, n)
+		}
+	default:
+		if n != 0 {
+			return fmt.Errorf("micheline: type %s takes no arguments, got %d", p.OpCode, n)
+		}
+	}
+	switch p.OpCode {
+	case T_SET, T_MAP, T_BIG_MAP, T_TICKET:
+		if !isComparable(p.Args[0]) {
+			return fmt.Errorf("micheline: type %s requires comparable key, got %s", p.OpCode, p.Args[0].DumpLimit(64))
+		}
+	case T_SAPLING_STATE, T_SAPLING_TRANSACTION:
+		if p.Args[0].Type != PrimInt {
+			return fmt.Errorf("micheline: type %s requires memo size, got %s", p.OpCode, p.Args[0].DumpLimit(64))
+		}
+		if p.OpCode == T_SAPLING_STATE && inBigmap {
+			return fmt.Errorf("micheline: big_map value must not contain %s", p.OpCode)
+		}
+		return nil
+	case T_OPERATION:
+		if inBigmap {
+			return fmt.Errorf("micheline: big_map value must not contain %s", p.OpCode)
+		}
+	case T_LAMBDA:
+		// lambda arguments are checked as independent types
+		for _, v := range p.Args {
+			if err := validateType(v, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if p.OpCode == T_BIG_MAP {
+		if inBigmap {
+			return fmt.Errorf("micheline: big_map value must not contain %s", p.OpCode)
+		}
+		if err := validateType(p.Args[0], false); err != nil {
+			return err
+		}
+		return validateType(p.Args[1], true)
+	}
+	for _, v := range p.Args {
+		if err := validateType(v, inBigmap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (t Type) Left() Type {
 	if len(t.Args) > 0 {
 		return Type{t.Args[0]}
@@ -204,3 +204,71 @@ func TestTypeRendering(t *testing.T) {
 		})
 	}
 }
+
+func TestTypeIsComparable(t *testing.T) {
+	for _, test := range []struct {
+		Src  string
+		Want bool
+	}{
+		{"nat", true},
+		{"timestamp", true},
+		{"chain_id", true},
+		{"pair nat string", true},
+		{"pair nat string (option address) (or bool bytes)", true},
+		{"pair nat (pair string (pair key_hash mutez))", true},
+		{"pair nat string (list int)", false},
+		{"pair nat (pair string (set int))", false},
+		{"option (big_map nat nat)", false},
+		{"or unit operation", false},
+		{"contract unit", false},
+		{"lambda unit unit", false},
+		{"ticket nat", false},
+		{"bls12_381_fr", false},
+	} {
+		typ, err := ParseMichelson(test.Src)
+		if err != nil {
+			t.Fatalf("%s: %v", test.Src, err)
+		}
+		if got := NewType(typ).IsComparable(); got != test.Want {
+			t.Errorf("%s: want=%t got=%t", test.Src, test.Want, got)
+		}
+	}
+}
+
+func TestTypeValidate(t *testing.T) {
+	for _, test := range []struct {
+		Src string
+		Err bool
+	}{
+		{Src: "pair (big_map %ledger address nat) (map (pair address nat) (list operation))"},
+		{Src: "big_map (pair nat string bool) (lambda (big_map nat nat) unit)"},
+		{Src: "set (pair nat string bool)"},
+		{Src: "sapling_state 8"},
+		{Src: "ticket (pair nat bytes)"},
+		{Src: "big_map nat (big_map nat nat)", Err: true},
+		{Src: "big_map nat (pair nat (option (big_map nat nat)))", Err: true},
+		{Src: "big_map nat (list operation)", Err: true},
+		{Src: "big_map nat (sapling_state 8)", Err: true},
+		{Src: "map (list nat) nat", Err: true},
+		{Src: "set (pair nat (set nat))", Err: true},
+		{Src: "ticket (contract unit)", Err: true},
+		{Src: "pair nat", Err: true},
+		{Src: "option nat nat", Err: true},
+		{Src: "nat int", Err: true},
+		{Src: "sapling_state nat", Err: true},
+		{Src: "{ nat }", Err: true},
+		{Src: "Pair 1 2", Err: true},
+	} {
+		typ, err := ParseMichelson(test.Src)
+		if err != nil {
+			t.Fatalf("%s: %v", test.Src, err)
+		}
+		err = NewType(typ).Validate()
+		if test.Err && err == nil {
+			t.Errorf("%s: expected error", test.Src)
+		}
+		if !test.Err && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.Src, err)
+		}
+	}
+}
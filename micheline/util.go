@@ -4,13 +4,96 @@
 package micheline
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
+
+	"blockwatch.cc/tzgo/tezos"
 )
 
 const PATH_SEPARATOR = "."
 
+// timestamp layouts seen in node RPC output and contract storage; RFC3339
+// parsing also accepts an optional fractional second
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+}
+
+// parseTimestamp parses a Michelson string timestamp in any of the
+// variants produced by Tezos nodes and returns the time in UTC.
+func parseTimestamp(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// decodeAddressPrim decodes an address value in string or optimized bytes form.
+func decodeAddressPrim(p Prim) (tezos.Address, error) {
+	switch p.Type {
+	case PrimString:
+		return tezos.ParseAddress(p.String)
+	case PrimBytes:
+		var a tezos.Address
+		err := a.UnmarshalBinary(p.Bytes)
+		return a, err
+	default:
+		return tezos.Address{}, fmt.Errorf("invalid address %s", p.DumpLimit(64))
+	}
+}
+
+// decodeBytesAny decodes bytes that hold an address in its canonical 22 byte
+// binary form. With keys set, tagged public keys and 64 byte signatures are
+// decoded too. Only exact lengths are accepted so that encodeBytesAny
+// restores the original bytes.
+func decodeBytesAny(b []byte, keys bool) (interface{}, bool) {
+	switch l := len(b); {
+	case l == 22:
+		var a tezos.Address
+		if b[0] > 0 && b[21] != 0 {
+			return nil, false
+		}
+		if err := a.UnmarshalBinary(b); err != nil {
+			return nil, false
+		}
+		return a, true
+	case !keys:
+		return nil, false
+	case l == 64:
+		return tezos.NewSignature(tezos.SignatureTypeGeneric, append([]byte(nil), b...)), true
+	case l > 32:
+		typ := tezos.ParseKeyTag(b[0])
+		if !typ.IsValid() || typ.Len() != l-1 {
+			return nil, false
+		}
+		return tezos.NewKey(typ, append([]byte(nil), b[1:]...)), true
+	}
+	return nil, false
+}
+
+// encodeBytesAny returns the binary form of a value produced by decodeBytesAny.
+func encodeBytesAny(v interface{}) ([]byte, bool) {
+	switch t := v.(type) {
+	case tezos.Address:
+		return t.Bytes22(), true
+	case tezos.Key:
+		return t.Bytes(), true
+	case tezos.Signature:
+		return t.Data, true
+	}
+	return nil, false
+}
+
 func isASCII(s string) bool {
 	for i := 0; i < len(s); i++ {
 		if s[i] < 32 || s[i] > unicode.MaxASCII {
@@ -39,6 +122,9 @@ func min(x, y int) int {
 	return y
 }
 
+// getPath resolves a dotted path like "balances.0.amount" against a decoded
+// value tree. Segments select map keys or, when the current node is a list,
+// zero-based element indices.
 func getPath(val interface{}, path string) (interface{}, bool) {
 	if val == nil {
 		return nil, false
@@ -48,7 +134,7 @@ func getPath(val interface{}, path string) (interface{}, bool) {
 	}
 	frag := strings.Split(path, PATH_SEPARATOR)
 	next := val
-	for i, v := range frag {
+	for _, v := range frag {
 		switch t := next.(type) {
 		case map[string]interface{}:
 			var ok bool
@@ -58,12 +144,13 @@ func getPath(val interface{}, path string) (interface{}, bool) {
 			}
 		case []interface{}:
 			idx, err := strconv.Atoi(v)
-			if err != nil || idx < 0 || len(t) < idx {
+			if err != nil || idx < 0 || idx >= len(t) {
 				return nil, false
 			}
 			next = t[idx]
 		default:
-			return next, i == len(frag)-1
+			// cannot descend into scalars or None values
+			return nil, false
 		}
 	}
 	return next, true
@@ -96,3 +183,69 @@ func walkValueMap(name string, val interface{}, fn ValueWalkerFunc) error {
 	}
 	return nil
 }
+
+// equalValueTree compares two decoded value trees. Numbers are compared by
+// value, so a *big.Int equals its decimal string form, times by instant and
+// bytes and addresses by content.
+func equalValueTree(a, b interface{}) bool {
+	if n, ok := a.(json.Number); ok {
+		a = n.String()
+	}
+	if n, ok := b.(json.Number); ok {
+		b = n.String()
+	}
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !equalValueTree(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equalValueTree(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case *big.Int:
+		y, ok := toBig(b)
+		return ok && x.Cmp(y) == 0
+	case time.Time:
+		y, ok := b.(time.Time)
+		return ok && x.Equal(y)
+	case []byte:
+		y, ok := b.([]byte)
+		return ok && bytes.Equal(x, y)
+	case tezos.Address:
+		y, ok := b.(tezos.Address)
+		return ok && x.Equal(y)
+	case string:
+		if _, ok := b.(*big.Int); ok {
+			return equalValueTree(b, a)
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toBig(v interface{}) (*big.Int, bool) {
+	switch t := v.(type) {
+	case *big.Int:
+		return t, t != nil
+	case string:
+		return new(big.Int).SetString(t, 10)
+	case json.Number:
+		return new(big.Int).SetString(t.String(), 10)
+	}
+	return nil, false
+}
@@ -4,11 +4,14 @@
 package micheline
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"blockwatch.cc/tzgo/tezos"
@@ -19,13 +22,91 @@ const (
 	RENDER_TYPE_PRIM  = 0      // silently output primitive tree instead if human-readable
 	RENDER_TYPE_FAIL  = 1      // return error if human-readable formatting fails
 	RENDER_TYPE_PANIC = 2      // panic with error if human-readable formatting fails
+	OPTION_SOME_LABEL = "Some" // wraps the inner value of nested options
 )
 
+// RENDER_NUMERIC_AS_JSONNUMBER can be combined with a RENDER_TYPE mode to map
+// int, nat and mutez leaves to json.Number instead of decimal strings.
+// MarshalJSON then emits them as JSON numbers without losing precision,
+// which suits consumers that decode with json.Decoder.UseNumber. Generic
+// JSON tools that read numbers as float64 silently round values above
+// 2^53 though, so the default string form is the safer choice for output
+// with unknown consumers.
+const RENDER_NUMERIC_AS_JSONNUMBER = 1 << 4
+
+// RENDER_BYTES modes can be combined with a RENDER_TYPE mode to select how
+// bytes leaves are emitted. Contracts often store addresses, keys and
+// signatures in plain bytes fields. By default such leaves are rendered as
+// hex. RENDER_BYTES_TYPED emits a tezos.Address when the bytes have its
+// exact binary form and hex otherwise. RENDER_BYTES_BOTH emits a BytesLeaf
+// with the hex string and the decoded value if any. GetBytes returns the raw
+// bytes in all modes.
+const (
+	RENDER_BYTES_HEX   = 0 << 5
+	RENDER_BYTES_TYPED = 1 << 5
+	RENDER_BYTES_BOTH  = 2 << 5
+	RENDER_BYTES_MASK  = 3 << 5
+)
+
+// RENDER_BYTES_KEYS extends RENDER_BYTES_TYPED and RENDER_BYTES_BOTH to also
+// decode tagged public keys and 64 byte signatures. Their binary forms carry
+// no checksum, so any bytes of matching length such as hashes may be
+// misread. Only use it for contracts known to store keys or signatures.
+const RENDER_BYTES_KEYS = 1 << 9
+
+// RENDER_UNION modes can be combined with a RENDER_TYPE mode to select how
+// anonymous union branches are labeled. By default each anonymous or emits
+// its branch as @or_0 or @or_1, so nested unions become nested objects.
+// RENDER_UNION_INDEX flattens nested anonymous unions and labels the branch
+// with its position among all branches as @or_<n>. RENDER_UNION_ENTRYPOINT
+// works like RENDER_UNION_INDEX, but labels branches of the root union with
+// the entrypoint name reported by Type.Entrypoints, which suits values of a
+// parameter type.
+const (
+	RENDER_UNION_OR         = 0 << 7
+	RENDER_UNION_INDEX      = 1 << 7
+	RENDER_UNION_ENTRYPOINT = 2 << 7
+	RENDER_UNION_MASK       = 3 << 7
+)
+
+// unionLabelPrefix is the common prefix of CONST_UNION_LEFT/RIGHT.
+const unionLabelPrefix = "@or_"
+
+// renderTypeMask selects the RENDER_TYPE mode from a Render value.
+const renderTypeMask = 0xf
+
+// BytesLeaf is the value of a bytes leaf rendered with RENDER_BYTES_BOTH.
+// Value is nil when the bytes do not hold a value decoded by the render mode.
+type BytesLeaf struct {
+	Hex   string      `json:"hex"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// DefaultMaxDepth is the nesting level at which decoding a value is aborted
+// unless Value.MaxDepth sets a different limit. It protects against
+// runaway recursion on malformed or adversarial data while leaving room
+// for long comb pairs and nested options in real contracts.
+const DefaultMaxDepth = 99
+
+// Value is a Michelson value together with its type. Map caches the decoded
+// tree. The cache is rebuilt when Render changes, but not when Type or Value
+// are modified in place, call Invalidate after such changes.
+//
+// Building the cache writes to the value, so a Value must not be decoded from
+// multiple goroutines at once. Once the cache is built, Map, the Get accessors
+// and MarshalJSON only read. Use Snapshot to obtain a decoded copy which can
+// be shared between goroutines as long as nobody modifies it.
 type Value struct {
-	Type   Type
-	Value  Prim
-	Render int
-	mapped interface{}
+	Type         Type
+	Value        Prim
+	Render       int
+	MaxDepth     int // nesting limit when decoding, DefaultMaxDepth when zero
+	mapped       map[string]interface{}
+	mappedRender int   // Render mode mapped was built with
+	packed       *Prim // original value before unpacking
+
+	timeLayout string         // timestamp format in Map output, see SetTimeFormat
+	timeLoc    *time.Location // timestamp location in Map output
 }
 
 func NewValue(typ Type, val Prim) Value {
@@ -42,6 +123,7 @@ func NewValuePtr(typ Type, val Prim) *Value {
 }
 
 func (v *Value) Decode(buf []byte) error {
+	v.mapped = nil
 	return v.Value.UnmarshalBinary(buf)
 }
 
@@ -62,9 +144,13 @@ func (v Value) Unpack() (Value, error) {
 		return v, err
 	}
 	vv := Value{
-		Type:   v.Type.Clone(),
-		Value:  up,
-		Render: v.Render,
+		Type:       v.Type.Clone(),
+		Value:      up,
+		Render:     v.Render,
+		MaxDepth:   v.MaxDepth,
+		packed:     v.original(),
+		timeLayout: v.timeLayout,
+		timeLoc:    v.timeLoc,
 	}
 	return vv, nil
 }
@@ -78,42 +164,143 @@ func (v Value) UnpackAll() (Value, error) {
 		return v, err
 	}
 	vv := Value{
-		Type:   v.Type.Clone(),
-		Value:  up,
-		Render: v.Render,
+		Type:       v.Type.Clone(),
+		Value:      up,
+		Render:     v.Render,
+		MaxDepth:   v.MaxDepth,
+		packed:     v.original(),
+		timeLayout: v.timeLayout,
+		timeLoc:    v.timeLoc,
 	}
 	return vv, nil
 }
 
+// UnpackWithType decodes a value holding PACK serialized data using hint as
+// type instead of detecting the type from the data. Annotations of hint are
+// kept and leaves are decoded as hint prescribes, so packed bytes are not
+// mistaken for strings. It fails when the data does not match hint or is
+// followed by trailing bytes.
+func (v Value) UnpackWithType(hint Type) (Value, error) {
+	if v.Value.Type != PrimBytes || !isPackedBytes(v.Value.Bytes) {
+		return v, fmt.Errorf("micheline: value is not packed")
+	}
+	var up Prim
+	buf := bytes.NewBuffer(v.Value.Bytes[1:])
+	if err := up.DecodeBuffer(buf); err != nil {
+		return v, err
+	}
+	if buf.Len() > 0 {
+		return v, fmt.Errorf("micheline: value is not packed: %d trailing bytes", buf.Len())
+	}
+	vv := Value{
+		Type:       hint.Clone(),
+		Value:      up,
+		Render:     v.Render,
+		MaxDepth:   v.MaxDepth,
+		packed:     v.original(),
+		timeLayout: v.timeLayout,
+		timeLoc:    v.timeLoc,
+	}
+	// validate shape
+	if _, err := vv.Map(); err != nil {
+		return v, err
+	}
+	return vv, nil
+}
+
+// Pack returns the PACK serialization of the value, see PackPrim.
+func (v Value) Pack() ([]byte, error) {
+	return PackPrim(v.Type, v.Value)
+}
+
+// original returns the value as it was before any call to Unpack or UnpackAll.
+func (v Value) original() *Prim {
+	if v.packed != nil {
+		return v.packed
+	}
+	p := v.Value
+	return &p
+}
+
 func (e *Value) FixType() {
 	labels := e.Type.Anno
 	e.Type = e.Value.BuildType()
 	e.Type.WasPacked = true
 	e.Type.Anno = labels
+	e.mapped = nil
 }
 
-func (e *Value) Map() (interface{}, error) {
-	if e.mapped != nil {
-		return e.mapped, nil
+// Invalidate drops the cached result of Map. It must be called after Type
+// or Value were changed directly, otherwise Map keeps returning the tree of
+// the previous contents.
+func (e *Value) Invalidate() {
+	e.mapped = nil
+}
+
+// SetTimeFormat makes Map and MarshalJSON render timestamps as strings
+// formatted with layout in location loc. A nil location selects UTC. An empty
+// layout restores the default which renders timestamps as RFC3339 in UTC.
+func (e *Value) SetTimeFormat(layout string, loc *time.Location) {
+	if loc == nil {
+		loc = time.UTC
 	}
-	m := make(map[string]interface{})
-	if err := walkTree(m, EMPTY_LABEL, e.Type, NewStack(e.Value), 0); err != nil {
-		return nil, err
+	e.timeLayout, e.timeLoc = layout, loc
+	e.mapped = nil
+}
+
+func (e *Value) Map() (interface{}, error) {
+	if e.mapped == nil || e.mappedRender != e.Render {
+		m := make(map[string]interface{})
+		w := treeWalker{
+			maxDepth:   e.MaxDepth,
+			timeLayout: e.timeLayout,
+			timeLoc:    e.timeLoc,
+			numbers:    e.Render&RENDER_NUMERIC_AS_JSONNUMBER > 0,
+			bytes:      e.Render & RENDER_BYTES_MASK,
+			keys:       e.Render&RENDER_BYTES_KEYS > 0,
+			unions:     e.Render & RENDER_UNION_MASK,
+		}
+		if err := w.walkTree(m, EMPTY_LABEL, e.Type, NewStack(e.Value), 0); err != nil {
+			return nil, err
+		}
+		e.mapped, e.mappedRender = m, e.Render
 	}
-	e.mapped = m
 
 	// lift scalar values
-	if len(m) == 1 {
-		for n, v := range m {
-			if n == "0" {
-				e.mapped = v
-			}
+	if len(e.mapped) == 1 {
+		if v, ok := e.mapped["0"]; ok {
+			return v, nil
 		}
 	}
-
 	return e.mapped, nil
 }
 
+// Snapshot decodes the value and returns a copy with the decoded tree cached.
+// The copy is safe for concurrent readers as long as neither its fields nor
+// its Render mode are changed. Decoded maps and slices are shared with e and
+// must not be modified.
+func (e *Value) Snapshot() (Value, error) {
+	if _, err := e.Map(); err != nil {
+		return Value{}, err
+	}
+	return *e, nil
+}
+
+// Equal reports whether both values decode into the same tree. Unlike
+// comparing JSON output this is independent of map ordering and of the
+// representation of numbers, times and bytes.
+func (e Value) Equal(v Value) bool {
+	a, err := e.Map()
+	if err != nil {
+		return false
+	}
+	b, err := v.Map()
+	if err != nil {
+		return false
+	}
+	return equalValueTree(a, b)
+}
+
 func (e Value) MarshalJSON() ([]byte, error) {
 	m, err := e.Map()
 	if err != nil {
@@ -134,7 +321,7 @@ func (e Value) MarshalJSON() ([]byte, error) {
 		// FIXME: this is a good place to plug in an error reporting facility
 		buf, _ := json.Marshal(resp)
 
-		switch e.Render {
+		switch e.Render & renderTypeMask {
 		default:
 			log.Errorf("RENDER: %s", string(buf))
 			// render the plain prim tree
@@ -149,10 +336,176 @@ func (e Value) MarshalJSON() ([]byte, error) {
 	return json.Marshal(m)
 }
 
-func walkTree(m map[string]interface{}, label string, typ Type, stack *Stack, lvl int) error {
+// treeWalker converts a value tree into nested Go maps and slices
+type treeWalker struct {
+	typed    bool                 // wrap scalar leaves with their type code
+	emit     ValueTypedWalkerFunc // stream container elements instead of storing them
+	path     string               // path of the map currently filled when streaming
+	maxDepth int                  // abort when nesting exceeds this level, see depthLimit
+
+	timeLayout string         // format timestamp leaves as string when set
+	timeLoc    *time.Location // location for formatted timestamps
+	numbers    bool           // map integer leaves to json.Number
+	bytes      int            // RENDER_BYTES mode for bytes leaves
+	keys       bool           // also decode keys and signatures from bytes
+	unions     int            // RENDER_UNION mode for anonymous union branches
+}
+
+// streamedNode marks a container whose elements were already emitted while
+// streaming. It keeps the slot so that anonymous labels stay the same.
+type streamedNode struct{}
+
+// typedLeaf is a scalar leaf value produced by typed tree walks
+type typedLeaf struct {
+	typ OpCode
+	val interface{}
+}
+
+func (w *treeWalker) leaf(typ OpCode, val interface{}) interface{} {
+	if w.typed {
+		return typedLeaf{typ, val}
+	}
+	if tm, ok := val.(time.Time); ok && w.timeLayout != "" {
+		return tm.In(w.timeLoc).Format(w.timeLayout)
+	}
+	if s, ok := val.(string); ok && w.numbers {
+		switch typ {
+		case T_INT, T_NAT, T_MUTEZ:
+			return json.Number(s)
+		}
+	}
+	return val
+}
+
+// scalar decodes a scalar value of type typ, applying the bytes render mode.
+func (w *treeWalker) scalar(typ OpCode, p Prim) interface{} {
+	if typ != T_BYTES || p.Type != PrimBytes || w.bytes == RENDER_BYTES_HEX {
+		return p.Value(typ)
+	}
+	v, ok := decodeBytesAny(p.Bytes, w.keys)
+	switch w.bytes {
+	case RENDER_BYTES_TYPED:
+		if ok {
+			return v
+		}
+	case RENDER_BYTES_BOTH:
+		return BytesLeaf{Hex: hex.EncodeToString(p.Bytes), Value: v}
+	}
+	return p.Value(typ)
+}
+
+// nodePath returns the path of a node stored under label in the current map.
+// An anonymous root is lifted like in Map().
+func (w *treeWalker) nodePath(label string, lvl int) string {
+	if lvl == 0 && label == "0" {
+		return ""
+	}
+	return joinPath(w.path, label)
+}
+
+// walkAt walks a child into map m which is stored at path.
+func (w *treeWalker) walkAt(path string, m map[string]interface{}, label string, typ Type, stack *Stack, lvl int) error {
+	parent := w.path
+	w.path = path
+	err := w.walkTree(m, label, typ, stack, lvl)
+	w.path = parent
+	return err
+}
+
+// walkUnion decodes an anonymous union into m. Nested anonymous unions are
+// followed down to the selected branch which is labeled by its index or
+// entrypoint name depending on the RENDER_UNION mode.
+func (w *treeWalker) walkUnion(m map[string]interface{}, typ Type, val Prim, lvl int) error {
+	root := typ
+	t, branch, n := typ.Prim, "", 0
+	for t.OpCode == T_OR && (branch == "" || !t.HasAnno()) {
+		if len(t.Args) != 2 || len(val.Args) != 1 {
+			return w.fail(ErrTypeMismatch, t.OpCode, val.OpCode,
+				"micheline: invalid T_OR value %s", val.DumpLimit(64))
+		}
+		switch val.OpCode {
+		case D_LEFT:
+			t, branch = t.Args[0], branch+"/L"
+		case D_RIGHT:
+			n += countUnionBranches(t.Args[0])
+			t, branch = t.Args[1], branch+"/R"
+		default:
+			return w.fail(ErrUnexpectedOpCode, t.OpCode, val.OpCode,
+				"micheline: unexpected T_OR branch with value opcode %s", val.OpCode)
+		}
+		val = val.Args[0]
+	}
+	name := unionLabelPrefix + strconv.Itoa(n)
+	if w.unions == RENDER_UNION_ENTRYPOINT && lvl == 0 {
+		if eps, err := root.Entrypoints(false); err == nil {
+			if ep, ok := eps.FindBranch(branch); ok {
+				name = ep.Call
+			}
+		}
+	}
+	mm := make(map[string]interface{})
+	if err := w.walkTree(mm, EMPTY_LABEL, Type{t}, NewStack(val), lvl+1); err != nil {
+		return err
+	}
+	// lift named content
+	if len(mm) == 1 {
+		for k, v := range mm {
+			if k == "0" {
+				k = name
+			}
+			m[k] = v
+		}
+	} else {
+		m[name] = mm
+	}
+	return nil
+}
+
+// countUnionBranches returns the number of branches of a union type
+// when nested anonymous unions are flattened.
+func countUnionBranches(t Prim) int {
+	if t.OpCode != T_OR || t.HasAnno() || len(t.Args) != 2 {
+		return 1
+	}
+	return countUnionBranches(t.Args[0]) + countUnionBranches(t.Args[1])
+}
+
+// stream emits all leaves of a decoded sub-tree.
+func (w *treeWalker) stream(fn ValueTypedWalkerFunc, path string, val interface{}) error {
+	pairs := make([]FlatPair, 0)
+	flattenTree(path, val, &pairs)
+	for _, p := range pairs {
+		if err := fn(p.Path, p.Type, p.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fail returns a decode error of class err at the current path.
+func (w *treeWalker) fail(err error, expected, actual OpCode, format string, args ...interface{}) error {
+	return &DecodeError{
+		Err:      err,
+		Path:     w.path,
+		Expected: expected,
+		Actual:   actual,
+		msg:      fmt.Sprintf(format, args...),
+	}
+}
+
+// depthLimit returns the maximum nesting level, DefaultMaxDepth unless set.
+func (w *treeWalker) depthLimit() int {
+	if w.maxDepth > 0 {
+		return w.maxDepth
+	}
+	return DefaultMaxDepth
+}
+
+func (w *treeWalker) walkTree(m map[string]interface{}, label string, typ Type, stack *Stack, lvl int) error {
 	// abort infinite type recursions
-	if lvl > 99 {
-		return fmt.Errorf("micheline: max nesting level reached")
+	if lvl > w.depthLimit() {
+		return w.fail(ErrMaxDepth, typ.OpCode, stack.Peek().OpCode,
+			"micheline: max nesting level %d reached at level %d label %q", w.depthLimit(), lvl, label)
 	}
 
 	// take next value from stack
@@ -196,7 +549,7 @@ func walkTree(m map[string]interface{}, label string, typ Type, stack *Stack, lv
 	// make sure value + type we're going to process actually match up
 	// accept any kind of pairs/seq which will be unfolded again below
 	if !typ.IsPair() && !val.IsSequence() && !val.matchOpCode(typ.OpCode) {
-		return fmt.Errorf("micheline: type mismatch: type[%s]=%s value[%s/%d]=%s",
+		return w.fail(ErrTypeMismatch, typ.OpCode, val.OpCode, "micheline: type mismatch: type[%s]=%s value[%s/%d]=%s",
 			typ.OpCode, typ.DumpLimit(512), val.Type, val.OpCode, val.DumpLimit(512))
 	}
 
@@ -213,29 +566,49 @@ func walkTree(m map[string]interface{}, label string, typ Type, stack *Stack, lv
 			label = strconv.Itoa(len(m))
 		}
 	}
+	path := w.nodePath(label, lvl)
 
 	// attach sub-records and array elements based on type code
 	switch typ.OpCode {
 	case T_SET:
 		// set <comparable type>
+		// when streaming, elements are decoded one at a time and emitted
+		emit := w.emit
+		w.emit = nil
+		defer func() { w.emit = emit }()
 		arr := make([]interface{}, 0, len(val.Args))
-		for _, v := range val.Args {
+		for i, v := range val.Args {
+			var elem interface{}
 			if v.IsScalar() && !v.IsSequence() {
 				// array of scalar types
-				arr = append(arr, v.Value(typ.Args[0].OpCode))
+				elem = w.leaf(typ.Args[0].OpCode, w.scalar(typ.Args[0].OpCode, v))
 			} else {
 				// array of complex types
 				mm := make(map[string]interface{})
-				if err := walkTree(mm, EMPTY_LABEL, Type{typ.Args[0]}, NewStack(v), lvl+1); err != nil {
+				if err := w.walkTree(mm, EMPTY_LABEL, Type{typ.Args[0]}, NewStack(v), lvl+1); err != nil {
+					return err
+				}
+				elem = mm
+			}
+			if emit != nil {
+				if err := w.stream(emit, joinPath(path, strconv.Itoa(i)), elem); err != nil {
 					return err
 				}
-				arr = append(arr, mm)
+				continue
 			}
+			arr = append(arr, elem)
+		}
+		if emit == nil {
+			m[label] = arr
+		} else {
+			m[label] = streamedNode{}
 		}
-		m[label] = arr
 
 	case T_LIST:
 		// list <type>
+		emit := w.emit
+		w.emit = nil
+		defer func() { w.emit = emit }()
 		arr := make([]interface{}, 0, len(val.Args))
 		for i, v := range val.Args {
 			// lists may contain different types, i.e. when unpack+detect is used
@@ -245,31 +618,34 @@ func walkTree(m map[string]interface{}, label string, typ Type, stack *Stack, lv
 			}
 			// unpack into map
 			mm := make(map[string]interface{})
-			if err := walkTree(mm, EMPTY_LABEL, Type{valType}, NewStack(v), lvl+1); err != nil {
+			if err := w.walkTree(mm, EMPTY_LABEL, Type{valType}, NewStack(v), lvl+1); err != nil {
 				return err
 			}
 			// lift scalar nested list and simple element
-			unwrapped := false
+			var elem interface{} = mm
 			if len(mm) == 1 {
 				if mval, ok := mm["0"]; ok {
-					if marr, ok := mval.([]interface{}); ok {
-						arr = append(arr, marr)
-					} else {
-						arr = append(arr, mval)
-					}
-					unwrapped = true
+					elem = mval
 				}
 			}
-			if !unwrapped {
-				arr = append(arr, mm)
+			if emit != nil {
+				if err := w.stream(emit, joinPath(path, strconv.Itoa(i)), elem); err != nil {
+					return err
+				}
+				continue
 			}
+			arr = append(arr, elem)
+		}
+		if emit == nil {
+			m[label] = arr
+		} else {
+			m[label] = streamedNode{}
 		}
-		m[label] = arr
 
 	case T_LAMBDA:
 		// LAMBDA <type> <type> { <instruction> ... }
 		// fmt.Printf("L%0d: OUTPUT typ=%s %s\n\n", lvl, typ.OpCode, val.Dump())
-		m[label] = val
+		m[label] = w.leaf(T_LAMBDA, val)
 
 	case T_MAP, T_BIG_MAP:
 		// map <comparable type> <type>
@@ -281,11 +657,11 @@ func walkTree(m map[string]interface{}, label string, typ Type, stack *Stack, lv
 			switch val.Type {
 			case PrimInt:
 				// Babylon bigmaps contain a reference here
-				m[label] = val.Value(T_INT)
+				m[label] = w.leaf(T_BIG_MAP, val.Value(T_INT))
 			case PrimSequence:
 				// pre-babylon there's only an empty sequence
 				// FIXME: we could insert the bigmap id, but this is unknown at ths point
-				m[label] = nil
+				m[label] = w.leaf(T_BIG_MAP, nil)
 			}
 			return nil
 		}
@@ -312,16 +688,21 @@ func walkTree(m map[string]interface{}, label string, typ Type, stack *Stack, lv
 			}
 
 			mm := make(map[string]interface{})
-			if err := walkTree(mm, key.String(), valType, NewStack(val.Args[1]), lvl+1); err != nil {
+			if err := w.walkAt(path, mm, key.String(), valType, NewStack(val.Args[1]), lvl+1); err != nil {
 				return err
 			}
+			if w.emit != nil {
+				m[label] = streamedNode{}
+				return w.stream(w.emit, path, mm)
+			}
 			m[label] = mm
 
 		case PrimSequence: // sequence of ELTs
 			mm := make(map[string]interface{})
 			for _, v := range val.Args {
 				if v.OpCode != D_ELT {
-					return fmt.Errorf("micheline: unexpected type %s [%s] for %s Elt item", v.Type, v.OpCode, typ.OpCode)
+					return w.fail(ErrUnexpectedOpCode, typ.OpCode, v.OpCode,
+						"micheline: unexpected type %s [%s] for %s Elt item", v.Type, v.OpCode, typ.OpCode)
 				}
 
 				keyType := Type{typ.Args[0]}
@@ -342,15 +723,31 @@ func walkTree(m map[string]interface{}, label string, typ Type, stack *Stack, lv
 					return err
 				}
 
-				if err := walkTree(mm, key.String(), valType, NewStack(v.Args[1]), lvl+1); err != nil {
+				// when streaming, emit one entry at a time
+				if w.emit != nil {
+					em := make(map[string]interface{})
+					if err := w.walkAt(path, em, key.String(), valType, NewStack(v.Args[1]), lvl+1); err != nil {
+						return err
+					}
+					if err := w.stream(w.emit, path, em); err != nil {
+						return err
+					}
+					continue
+				}
+
+				if err := w.walkTree(mm, key.String(), valType, NewStack(v.Args[1]), lvl+1); err != nil {
 					return err
 				}
 			}
-			m[label] = mm
+			if w.emit == nil {
+				m[label] = mm
+			} else {
+				m[label] = streamedNode{}
+			}
 
 		default:
 			buf, _ := json.Marshal(val)
-			return fmt.Errorf("%*s> micheline: unexpected type %s [%s] for %s Elt sequence: %s",
+			return w.fail(ErrUnexpectedOpCode, typ.OpCode, val.OpCode, "%*s> micheline: unexpected type %s [%s] for %s Elt sequence: %s",
 				lvl, "", val.Type, val.OpCode, typ.OpCode, buf)
 		}
 
@@ -382,9 +779,13 @@ func walkTree(m map[string]interface{}, label string, typ Type, stack *Stack, lv
 			// fmt.Printf("L%0d: %s stack[%d]:\n%s\n\n", lvl, label, stack.Len(), stack.DumpIdent(4))
 		}
 
+		pairPath := w.path
+		if haveTypeLabel || haveKeyLabel {
+			pairPath = path
+		}
 		for _, t := range typ.Args {
 			// fmt.Printf("L%0d: %s/%s[%d/%d] CHILD=%s\n", lvl, label, t.GetVarAnnoAny(), i, len(typ.Args), stack.Peek().Dump())
-			if err := walkTree(mm, EMPTY_LABEL, Type{t}, stack, lvl+1); err != nil {
+			if err := w.walkAt(pairPath, mm, EMPTY_LABEL, Type{t}, stack, lvl+1); err != nil {
 				return err
 			}
 		}
@@ -398,34 +799,56 @@ func walkTree(m map[string]interface{}, label string, typ Type, stack *Stack, lv
 		switch val.OpCode {
 		case D_NONE:
 			// add empty option values as null
-			m[label] = nil
+			m[label] = w.leaf(T_OPTION, nil)
 		case D_SOME:
+			// nested options keep an explicit Some level so that Some None
+			// does not collapse into None
+			if typ.Args[0].OpCode == T_OPTION {
+				mm := make(map[string]interface{})
+				if err := w.walkAt(path, mm, OPTION_SOME_LABEL, Type{typ.Args[0]}, NewStack(val.Args[0]), lvl+1); err != nil {
+					return err
+				}
+				m[label] = mm
+				break
+			}
 			// with annots (name) use it for scalar or complex render
 			// when next level annot equals this option annot, skip this annot
 			if val.IsScalar() || label == typ.Args[0].GetVarAnnoAny() {
-				if err := walkTree(m, label, Type{typ.Args[0]}, NewStack(val.Args[0]), lvl+1); err != nil {
+				if err := w.walkTree(m, label, Type{typ.Args[0]}, NewStack(val.Args[0]), lvl+1); err != nil {
 					return err
 				}
 			} else {
 				mm := make(map[string]interface{})
-				if err := walkTree(mm, EMPTY_LABEL, Type{typ.Args[0]}, NewStack(val.Args[0]), lvl+1); err != nil {
+				if err := w.walkAt(path, mm, EMPTY_LABEL, Type{typ.Args[0]}, NewStack(val.Args[0]), lvl+1); err != nil {
 					return err
 				}
 				m[label] = mm
 			}
 		default:
-			return fmt.Errorf("micheline: unexpected T_OPTION code %s [%s]: %s", val.OpCode, val.OpCode, val.Dump())
+			return w.fail(ErrUnexpectedOpCode, typ.OpCode, val.OpCode,
+				"micheline: unexpected T_OPTION code %s [%s]: %s", val.OpCode, val.OpCode, val.Dump())
 		}
 
 	case T_OR:
 		// or <type> <type>
-		// use map to capture nested names
+		// use map to capture nested names; nested keys are renamed and lifted
+		// below, so union content is never streamed
+		emit := w.emit
+		w.emit = nil
+		defer func() { w.emit = emit }()
 		mm := make(map[string]interface{})
+		if w.unions != RENDER_UNION_OR && !(haveTypeLabel || haveKeyLabel) {
+			if err := w.walkUnion(mm, typ, val, lvl); err != nil {
+				return err
+			}
+			m[label] = mm
+			break
+		}
 		switch val.OpCode {
 		case D_LEFT:
 			if !(haveTypeLabel || haveKeyLabel) {
 				mmm := make(map[string]interface{})
-				if err := walkTree(mmm, EMPTY_LABEL, Type{typ.Args[0]}, NewStack(val.Args[0]), lvl+1); err != nil {
+				if err := w.walkTree(mmm, EMPTY_LABEL, Type{typ.Args[0]}, NewStack(val.Args[0]), lvl+1); err != nil {
 					return err
 				}
 				// lift named content
@@ -442,14 +865,14 @@ func walkTree(m map[string]interface{}, label string, typ Type, stack *Stack, lv
 					mm["@or_0"] = mmm
 				}
 			} else {
-				if err := walkTree(mm, EMPTY_LABEL, Type{typ.Args[0]}, NewStack(val.Args[0]), lvl+1); err != nil {
+				if err := w.walkTree(mm, EMPTY_LABEL, Type{typ.Args[0]}, NewStack(val.Args[0]), lvl+1); err != nil {
 					return err
 				}
 			}
 		case D_RIGHT:
 			if !(haveTypeLabel || haveKeyLabel) {
 				mmm := make(map[string]interface{})
-				if err := walkTree(mmm, EMPTY_LABEL, Type{typ.Args[1]}, NewStack(val.Args[0]), lvl+1); err != nil {
+				if err := w.walkTree(mmm, EMPTY_LABEL, Type{typ.Args[1]}, NewStack(val.Args[0]), lvl+1); err != nil {
 					return err
 				}
 				// lift named content
@@ -466,13 +889,14 @@ func walkTree(m map[string]interface{}, label string, typ Type, stack *Stack, lv
 					mm["@or_1"] = mmm
 				}
 			} else {
-				if err := walkTree(mm, EMPTY_LABEL, Type{typ.Args[1]}, NewStack(val.Args[0]), lvl+1); err != nil {
+				if err := w.walkTree(mm, EMPTY_LABEL, Type{typ.Args[1]}, NewStack(val.Args[0]), lvl+1); err != nil {
 					return err
 				}
 			}
 
 		default:
-			return fmt.Errorf("micheline: unexpected T_OR branch with value opcode %s", val.OpCode)
+			return w.fail(ErrUnexpectedOpCode, typ.OpCode, val.OpCode,
+				"micheline: unexpected T_OR branch with value opcode %s", val.OpCode)
 		}
 
 		// lift anon content
@@ -485,20 +909,51 @@ func walkTree(m map[string]interface{}, label string, typ Type, stack *Stack, lv
 	case T_TICKET:
 		// always Pair( ticketer:address, Pair( original_type, int ))
 		stack.Push(val)
-		if err := walkTree(m, label, TicketType(typ.Args[0]), stack, lvl+1); err != nil {
+		if err := w.walkTree(m, label, TicketType(typ.Args[0]), stack, lvl+1); err != nil {
 			return err
 		}
 
 	case T_SAPLING_STATE:
 		mm := make(map[string]interface{})
-		if err := walkTree(mm, "memo_size", Type{NewPrim(T_INT)}, NewStack(typ.Args[0]), lvl+1); err != nil {
+		if err := w.walkAt(path, mm, "memo_size", Type{NewPrim(T_INT)}, NewStack(typ.Args[0]), lvl+1); err != nil {
+			return err
+		}
+		if err := w.walkAt(path, mm, "content", val.BuildType(), NewStack(val), lvl+1); err != nil {
 			return err
 		}
-		if err := walkTree(mm, "content", val.BuildType(), NewStack(val), lvl+1); err != nil {
+		m[label] = mm
+
+	case T_SAPLING_TRANSACTION:
+		// structural view only, undecodable bytes are kept as raw hex
+		mm := make(map[string]interface{})
+		if err := w.walkAt(path, mm, "memo_size", Type{NewPrim(T_INT)}, NewStack(typ.Args[0]), lvl+1); err != nil {
 			return err
 		}
+		tx, err := DecodeSaplingTransaction(val.Bytes)
+		if val.Type != PrimBytes || err != nil {
+			mm["raw"] = w.leaf(T_BYTES, val.Value(T_BYTES))
+		} else {
+			mm["inputs"] = w.leaf(T_NAT, strconv.Itoa(tx.Inputs))
+			mm["outputs"] = w.leaf(T_NAT, strconv.Itoa(tx.Outputs))
+			mm["binding_sig"] = w.leaf(T_BYTES, hex.EncodeToString(tx.BindingSig))
+			mm["balance"] = w.leaf(T_INT, strconv.FormatInt(tx.Balance, 10))
+			mm["root"] = w.leaf(T_BYTES, hex.EncodeToString(tx.Root))
+			mm["bound_data"] = w.leaf(T_BYTES, hex.EncodeToString(tx.BoundData))
+		}
 		m[label] = mm
 
+	case T_BLS12_381_G1, T_BLS12_381_G2, T_BLS12_381_FR:
+		// Fr scalars written as int literal keep their decimal form
+		if val.Type != PrimBytes {
+			m[label] = w.leaf(typ.OpCode, val.Value(typ.OpCode))
+			break
+		}
+		pt, err := DecodeBlsPoint(typ.OpCode, val.Bytes)
+		if err != nil {
+			return w.fail(ErrTypeMismatch, typ.OpCode, val.OpCode, "%s", err)
+		}
+		m[label] = w.leaf(typ.OpCode, pt)
+
 	default:
 		// int
 		// nat
@@ -528,10 +983,10 @@ func walkTree(m map[string]interface{}, label string, typ Type, stack *Stack, lv
 		}
 
 		if val.IsScalar() {
-			m[label] = val.Value(typ.OpCode)
+			m[label] = w.leaf(typ.OpCode, w.scalar(typ.OpCode, val))
 		} else {
 			mm := make(map[string]interface{})
-			if err := walkTree(mm, EMPTY_LABEL, typ, NewStack(val), lvl+1); err != nil {
+			if err := w.walkAt(path, mm, EMPTY_LABEL, typ, NewStack(val), lvl+1); err != nil {
 				return err
 			}
 			m[label] = mm
@@ -624,13 +1079,37 @@ func (v *Value) GetValue(label string) (interface{}, bool) {
 	return nil, false
 }
 
+// OptionState is the result of an option lookup with GetOption.
+type OptionState byte
+
+const (
+	OptionMissing OptionState = iota // label not found
+	OptionNone                       // option is None
+	OptionSome                       // option is Some
+)
+
+// GetOption looks up an option value at label and reports whether it is None,
+// Some or missing. For nested options like `option (option nat)` the outer
+// value of Some is a map with a single OPTION_SOME_LABEL key, use label + ".Some"
+// to look up the inner option.
+func (v *Value) GetOption(label string) (interface{}, OptionState) {
+	vv, ok := v.GetValue(label)
+	if !ok {
+		return nil, OptionMissing
+	}
+	if vv == nil {
+		return nil, OptionNone
+	}
+	return vv, OptionSome
+}
+
 func (v *Value) GetString(label string) (string, bool) {
 	if m, err := v.Map(); err == nil {
 		if vv, ok := getPath(m, label); ok {
 			if s, ok := vv.(string); ok {
 				return s, true
 			} else {
-				return fmt.Sprint(s), true
+				return fmt.Sprint(vv), true
 			}
 		}
 	}
@@ -644,11 +1123,23 @@ func (v *Value) GetBytes(label string) ([]byte, bool) {
 			if vv == nil {
 				return nil, ok
 			}
-			if s, ok := vv.(string); ok {
-				h, err := hex.DecodeString(s)
+			switch t := vv.(type) {
+			case string:
+				h, err := hex.DecodeString(t)
+				if err == nil {
+					return h, true
+				}
+			case BytesLeaf:
+				h, err := hex.DecodeString(t.Hex)
 				if err == nil {
 					return h, true
 				}
+			case BlsPoint:
+				return append([]byte(nil), t.Bytes...), true
+			default:
+				if v.Render&RENDER_BYTES_MASK == RENDER_BYTES_TYPED {
+					return encodeBytesAny(t)
+				}
 			}
 		}
 	}
@@ -670,6 +1161,11 @@ func (v *Value) GetInt64(label string) (int64, bool) {
 				if err == nil {
 					return i, true
 				}
+			case json.Number:
+				i, err := t.Int64()
+				if err == nil {
+					return i, true
+				}
 			}
 		}
 	}
@@ -688,6 +1184,8 @@ func (v *Value) GetBig(label string) (*big.Int, bool) {
 				return t, true
 			case string:
 				return big.NewInt(0).SetString(t, 10)
+			case json.Number:
+				return big.NewInt(0).SetString(t.String(), 10)
 			}
 		}
 	}
@@ -725,33 +1223,49 @@ func (v *Value) GetTime(label string) (time.Time, bool) {
 			case time.Time:
 				return t, true
 			case string:
-				if b, err := time.Parse(t, time.RFC3339); err == nil {
+				if b, ok := parseTimestamp(t); ok {
 					return b, true
 				}
+				if v.timeLayout != "" {
+					if b, err := time.ParseInLocation(v.timeLayout, t, v.timeLoc); err == nil {
+						return b.UTC(), true
+					}
+				}
 			}
 		}
 	}
 	return time.Time{}, false
 }
 
+// GetAddress returns the address at label. It returns false when label is
+// missing, does not contain an address or is an `option address` set to None.
+// Use GetAddressOption to tell these cases apart.
 func (v *Value) GetAddress(label string) (tezos.Address, bool) {
+	a, state := v.GetAddressOption(label)
+	return a, state == OptionSome
+}
+
+// GetAddressOption looks up an address at label and reports whether it is
+// present (OptionSome), an `option address` set to None (OptionNone) or
+// missing. Labels which do not contain an address are reported as missing.
+func (v *Value) GetAddressOption(label string) (tezos.Address, OptionState) {
 	if m, err := v.Map(); err == nil {
 		if vv, ok := getPath(m, label); ok {
 			// Adddress, string or nil
 			if vv == nil {
-				return tezos.InvalidAddress, ok
+				return tezos.InvalidAddress, OptionNone
 			}
 			switch t := vv.(type) {
 			case tezos.Address:
-				return t, true
+				return t, OptionSome
 			case string:
-				if b, err := tezos.ParseAddress(t); err == nil {
-					return b, true
+				if b, err := tezos.ParseAddress(t); err == nil && b.IsValid() {
+					return b, OptionSome
 				}
 			}
 		}
 	}
-	return tezos.InvalidAddress, false
+	return tezos.InvalidAddress, OptionMissing
 }
 
 func (v *Value) GetKey(label string) (tezos.Key, bool) {
@@ -794,6 +1308,298 @@ func (v *Value) GetSignature(label string) (tezos.Signature, bool) {
 	return tezos.InvalidSignature, false
 }
 
+// GetChainId returns the chain id at label.
+func (v *Value) GetChainId(label string) (tezos.ChainId, bool) {
+	if m, err := v.Map(); err == nil {
+		if vv, ok := getPath(m, label); ok {
+			// ChainId or string
+			switch t := vv.(type) {
+			case tezos.ChainId:
+				return t, true
+			case string:
+				if c, err := tezos.ParseChainId(t); err == nil {
+					return c, true
+				}
+			}
+		}
+	}
+	return tezos.ChainId{}, false
+}
+
+// GetSlice returns the list or set at label as a slice of decoded elements.
+func (v *Value) GetSlice(label string) ([]interface{}, bool) {
+	if m, err := v.Map(); err == nil {
+		if vv, ok := getPath(m, label); ok {
+			if vv == nil {
+				return nil, ok
+			}
+			if s, ok := vv.([]interface{}); ok {
+				return s, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// GetBigSlice returns the list or set of int, nat or mutez at label. It fails
+// when any element cannot be converted.
+func (v *Value) GetBigSlice(label string) ([]*big.Int, bool) {
+	s, ok := v.GetSlice(label)
+	if !ok {
+		return nil, false
+	}
+	res := make([]*big.Int, 0, len(s))
+	for _, vv := range s {
+		switch t := vv.(type) {
+		case *big.Int:
+			res = append(res, t)
+		case string, json.Number:
+			b, ok := toBig(t)
+			if !ok {
+				return nil, false
+			}
+			res = append(res, b)
+		default:
+			return nil, false
+		}
+	}
+	return res, true
+}
+
+// GetAddressSlice returns the list or set of addresses at label. It fails
+// when any element cannot be converted.
+func (v *Value) GetAddressSlice(label string) ([]tezos.Address, bool) {
+	s, ok := v.GetSlice(label)
+	if !ok {
+		return nil, false
+	}
+	res := make([]tezos.Address, 0, len(s))
+	for _, vv := range s {
+		switch t := vv.(type) {
+		case tezos.Address:
+			res = append(res, t)
+		case string:
+			a, err := tezos.ParseAddress(t)
+			if err != nil {
+				return nil, false
+			}
+			res = append(res, a)
+		default:
+			return nil, false
+		}
+	}
+	return res, true
+}
+
+// GetSliceValues returns the elements of the list or set at label as typed
+// Values so callers can recurse into complex element types. Unlike GetSlice
+// the label is matched against type annotations only, the last path segment
+// is used and numeric indices are not supported.
+func (v *Value) GetSliceValues(label string) ([]Value, bool) {
+	if label != "" {
+		frag := strings.Split(label, PATH_SEPARATOR)
+		label = frag[len(frag)-1]
+	}
+	typ, val, ok := findTypedPrim(v.Type.Prim, v.Value, label)
+	if !ok || (typ.OpCode != T_LIST && typ.OpCode != T_SET) || len(typ.Args) != 1 {
+		return nil, false
+	}
+	if val.Type != PrimSequence {
+		return nil, false
+	}
+	res := make([]Value, len(val.Args))
+	for i, elem := range val.Args {
+		res[i] = NewValue(Type{typ.Args[0]}, elem)
+	}
+	return res, true
+}
+
+// GetPackedBytes returns the raw bytes of a packed `bytes` field at label
+// including the 0x05 prefix, e.g. to verify or re-sign a payload. Contrary to
+// GetBytes it returns the original bytes even after the value has been
+// unpacked with Unpack or UnpackAll. Like GetSliceValues the label is matched
+// against type annotations using the last path segment only.
+func (v *Value) GetPackedBytes(label string) ([]byte, bool) {
+	if label != "" {
+		frag := strings.Split(label, PATH_SEPARATOR)
+		label = frag[len(frag)-1]
+	}
+	typ, val, ok := findTypedPrim(v.Type.Prim, *v.original(), label)
+	if !ok || typ.OpCode != T_BYTES || val.Type != PrimBytes || !isPackedBytes(val.Bytes) {
+		return nil, false
+	}
+	buf := make([]byte, len(val.Bytes))
+	copy(buf, val.Bytes)
+	return buf, true
+}
+
+// GetTickets decodes the ticket, list of tickets or ticket-valued map at label.
+// Map values are returned in key order. Like GetSliceValues the label is
+// matched against type annotations using the last path segment only.
+func (v *Value) GetTickets(label string) ([]Ticket, bool) {
+	if label != "" {
+		frag := strings.Split(label, PATH_SEPARATOR)
+		label = frag[len(frag)-1]
+	}
+	typ, val, ok := findTypedPrim(v.Type.Prim, v.Value, label)
+	if !ok {
+		return nil, false
+	}
+	var elems []Prim
+	switch typ.OpCode {
+	case T_TICKET:
+		elems = []Prim{val}
+	case T_LIST:
+		if len(typ.Args) != 1 || val.Type != PrimSequence {
+			return nil, false
+		}
+		typ, elems = typ.Args[0], val.Args
+	case T_MAP:
+		if len(typ.Args) != 2 || val.Type != PrimSequence {
+			return nil, false
+		}
+		typ = typ.Args[1]
+		for _, elt := range val.Args {
+			if !elt.IsElt() || len(elt.Args) != 2 {
+				return nil, false
+			}
+			elems = append(elems, elt.Args[1])
+		}
+	default:
+		return nil, false
+	}
+	if typ.OpCode != T_TICKET || len(typ.Args) != 1 {
+		return nil, false
+	}
+	res := make([]Ticket, len(elems))
+	for i, elem := range elems {
+		t, err := DecodeTicket(typ.Args[0], elem)
+		if err != nil {
+			return nil, false
+		}
+		res[i] = t
+	}
+	return res, true
+}
+
+// findTypedPrim walks type and value in parallel through pairs, options and
+// unions and returns the first type annotated with label together with its
+// value. An empty label selects the root.
+func findTypedPrim(typ, val Prim, label string) (Prim, Prim, bool) {
+	if label == "" || typ.MatchesAnno(label) {
+		return typ, val, true
+	}
+	switch typ.OpCode {
+	case T_PAIR:
+		typ, val = binaryPair(typ), binaryPair(val)
+		if len(typ.Args) != 2 || len(val.Args) != 2 {
+			return InvalidPrim, InvalidPrim, false
+		}
+		for i := range typ.Args {
+			if t, v, ok := findTypedPrim(typ.Args[i], val.Args[i], label); ok {
+				return t, v, true
+			}
+		}
+	case T_OPTION:
+		if val.OpCode == D_SOME && len(typ.Args) == 1 && len(val.Args) == 1 {
+			return findTypedPrim(typ.Args[0], val.Args[0], label)
+		}
+	case T_OR:
+		if len(typ.Args) == 2 && len(val.Args) == 1 {
+			switch val.OpCode {
+			case D_LEFT:
+				return findTypedPrim(typ.Args[0], val.Args[0], label)
+			case D_RIGHT:
+				return findTypedPrim(typ.Args[1], val.Args[0], label)
+			}
+		}
+	}
+	return InvalidPrim, InvalidPrim, false
+}
+
+// GetMap returns the decoded record, map or big_map at label. It fails when
+// label points at a scalar or a list.
+func (v *Value) GetMap(label string) (map[string]interface{}, bool) {
+	if m, err := v.Map(); err == nil {
+		if vv, ok := getPath(m, label); ok {
+			if mm, ok := vv.(map[string]interface{}); ok {
+				return mm, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// GetMapKeys returns the sorted keys of the record, map or big_map at label.
+// Map keys are in their canonical string form.
+func (v *Value) GetMapKeys(label string) ([]string, bool) {
+	mm, ok := v.GetMap(label)
+	if !ok {
+		return nil, false
+	}
+	keys := make([]string, 0, len(mm))
+	for k := range mm {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, true
+}
+
+// MapLookup returns the value stored under key in the map or big_map found at
+// label. The key is converted into the same canonical string form Map() uses
+// for keys, so callers can pass typed Prims like address bytes or ints.
+func (v *Value) MapLookup(label string, key Prim) (interface{}, bool) {
+	typ, ok := v.findMapType(label)
+	if !ok {
+		return nil, false
+	}
+	k, err := NewKey(Type{typ.Args[0]}, key)
+	if err != nil {
+		return nil, false
+	}
+	vv, ok := v.GetValue(label)
+	if !ok {
+		return nil, false
+	}
+	mm, ok := vv.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	val, ok := mm[k.String()]
+	return val, ok
+}
+
+// findMapType resolves the map or big_map type at label by its trailing
+// annotation. Without a label only a root map or a single map type in the
+// type tree is accepted.
+func (v *Value) findMapType(label string) (Prim, bool) {
+	isMap := func(p Prim) bool {
+		return (p.OpCode == T_MAP || p.OpCode == T_BIG_MAP) && len(p.Args) == 2
+	}
+	if isMap(v.Type.Prim) && (label == "" || v.Type.Prim.MatchesAnno(label)) {
+		return v.Type.Prim, true
+	}
+	var candidates []Prim
+	if label != "" {
+		frag := strings.Split(label, PATH_SEPARATOR)
+		found, _ := v.Type.FindLabels(frag[len(frag)-1])
+		candidates = found
+	} else {
+		maps, _ := v.Type.FindOpCodes(T_MAP)
+		bigmaps, _ := v.Type.FindOpCodes(T_BIG_MAP)
+		candidates = append(maps, bigmaps...)
+	}
+	var typ Prim
+	var n int
+	for _, p := range candidates {
+		if isMap(p) {
+			typ = p
+			n++
+		}
+	}
+	return typ, n == 1
+}
+
 func (v *Value) Unmarshal(val interface{}) error {
 	if m, err := v.Map(); err == nil {
 		buf, _ := json.Marshal(m)
@@ -812,3 +1618,82 @@ func (v *Value) Walk(label string, fn ValueWalkerFunc) error {
 	}
 	return walkValueMap(label, val, fn)
 }
+
+// ValueTypedWalkerFunc is called by WalkTyped for every leaf with its dotted
+// path as accepted by GetValue and its Michelson type code.
+type ValueTypedWalkerFunc func(path string, typ OpCode, value interface{}) error
+
+// WalkTyped calls fn for every leaf of the decoded value like FlatPairs, but
+// lists, sets, maps and bigmaps are decoded and emitted one element at a time
+// instead of materializing the entire tree first. Elements of containers are
+// emitted in value order, all other leaves follow sorted by path. Walking
+// stops at and returns the first error returned by fn.
+func (e *Value) WalkTyped(fn ValueTypedWalkerFunc) error {
+	m := make(map[string]interface{})
+	w := treeWalker{typed: true, emit: fn, maxDepth: e.MaxDepth}
+	if err := w.walkTree(m, EMPTY_LABEL, e.Type, NewStack(e.Value), 0); err != nil {
+		return err
+	}
+	var root interface{} = m
+	if v, ok := m["0"]; ok && len(m) == 1 {
+		root = v
+	}
+	return w.stream(fn, "", root)
+}
+
+// FlatPair is a single leaf of a flattened value tree. Path is the dotted
+// path as accepted by GetValue, Type is the leaf's Michelson type code or
+// OpCodeInvalid when the type is unknown.
+type FlatPair struct {
+	Path  string
+	Type  OpCode
+	Value interface{}
+}
+
+// FlatPairs returns all leaves of the decoded value as a flat list sorted by
+// path. Map keys are visited in sorted order and list elements by index.
+func (e *Value) FlatPairs() ([]FlatPair, error) {
+	m := make(map[string]interface{})
+	w := treeWalker{typed: true, maxDepth: e.MaxDepth}
+	if err := w.walkTree(m, EMPTY_LABEL, e.Type, NewStack(e.Value), 0); err != nil {
+		return nil, err
+	}
+	var root interface{} = m
+
+	// lift scalar values like Map() does
+	if v, ok := m["0"]; ok && len(m) == 1 {
+		root = v
+	}
+
+	pairs := make([]FlatPair, 0)
+	flattenTree("", root, &pairs)
+	return pairs, nil
+}
+
+func flattenTree(path string, val interface{}, pairs *[]FlatPair) {
+	prefix := path
+	if len(prefix) > 0 {
+		prefix += PATH_SEPARATOR
+	}
+	switch t := val.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			flattenTree(prefix+k, t[k], pairs)
+		}
+	case []interface{}:
+		for i, v := range t {
+			flattenTree(prefix+strconv.Itoa(i), v, pairs)
+		}
+	case streamedNode:
+		// already emitted
+	case typedLeaf:
+		*pairs = append(*pairs, FlatPair{Path: path, Type: t.typ, Value: t.val})
+	default:
+		*pairs = append(*pairs, FlatPair{Path: path, Type: OpCodeInvalid, Value: t})
+	}
+}
@@ -5,19 +5,26 @@
 package micheline
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/fs"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"blockwatch.cc/tzgo/tezos"
 	"github.com/pmezard/go-difflib/difflib"
 )
 
@@ -388,3 +395,1187 @@ func TestParamsValues(t *testing.T) {
 		}
 	}
 }
+
+func newTestValue(t *testing.T, typ, val string) *Value {
+	t.Helper()
+	var tp, vp Prim
+	if err := json.Unmarshal([]byte(typ), &tp); err != nil {
+		t.Fatalf("invalid json type: %v", err)
+	}
+	if err := json.Unmarshal([]byte(val), &vp); err != nil {
+		t.Fatalf("invalid json value: %v", err)
+	}
+	return NewValuePtr(NewType(tp), vp)
+}
+
+func TestValueFlatPairs(t *testing.T) {
+	val := newTestValue(t,
+		`{"prim":"pair","args":[
+			{"prim":"string","annots":["%name"]},
+			{"prim":"pair","args":[
+				{"prim":"list","annots":["%items"],"args":[
+					{"prim":"pair","args":[{"prim":"nat","annots":["%id"]},{"prim":"mutez","annots":["%amount"]}]}
+				]},
+				{"prim":"option","annots":["%owner"],"args":[{"prim":"address"}]}
+			]}
+		]}`,
+		`{"prim":"Pair","args":[
+			{"string":"shop"},
+			{"prim":"Pair","args":[
+				[
+					{"prim":"Pair","args":[{"int":"1"},{"int":"100"}]},
+					{"prim":"Pair","args":[{"int":"2"},{"int":"250"}]}
+				],
+				{"prim":"None"}
+			]}
+		]}`,
+	)
+	pairs, err := val.FlatPairs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		Path  string
+		Type  OpCode
+		Value string
+	}{
+		{"items.0.amount", T_MUTEZ, "100"},
+		{"items.0.id", T_NAT, "1"},
+		{"items.1.amount", T_MUTEZ, "250"},
+		{"items.1.id", T_NAT, "2"},
+		{"name", T_STRING, "shop"},
+		{"owner", T_OPTION, "<nil>"},
+	}
+	if len(pairs) != len(want) {
+		t.Fatalf("expected %d pairs, got %d: %v", len(want), len(pairs), pairs)
+	}
+	for i, w := range want {
+		p := pairs[i]
+		if p.Path != w.Path || p.Type != w.Type || fmt.Sprint(p.Value) != w.Value {
+			t.Errorf("pair %d: want %s/%s/%s got %s/%s/%v", i, w.Path, w.Type, w.Value, p.Path, p.Type, p.Value)
+		}
+		if p.Value != nil {
+			if v, ok := val.GetValue(p.Path); !ok || fmt.Sprint(v) != w.Value {
+				t.Errorf("pair %d: path %s does not resolve with GetValue", i, p.Path)
+			}
+		}
+	}
+
+	// leaves without type are reported as OpCodeInvalid
+	var untyped []FlatPair
+	flattenTree("", map[string]interface{}{"x": "1"}, &untyped)
+	if len(untyped) != 1 || untyped[0].Type != OpCodeInvalid {
+		t.Errorf("unexpected untyped pairs %v", untyped)
+	}
+	if op, err := ParseOpCode("NOT_AN_OPCODE"); err == nil || op != OpCodeInvalid {
+		t.Errorf("expected OpCodeInvalid for unknown primitive, got %s", op)
+	}
+}
+
+func TestValueGetTime(t *testing.T) {
+	// storage excerpt of an auction contract with timestamps in int and
+	// in several string forms produced by Tezos nodes
+	val := newTestValue(t,
+		`{"prim":"pair","args":[
+			{"prim":"timestamp","annots":["%start_time"]},
+			{"prim":"pair","args":[
+				{"prim":"timestamp","annots":["%end_time"]},
+				{"prim":"pair","args":[
+					{"prim":"timestamp","annots":["%vesting_time"]},
+					{"prim":"timestamp","annots":["%claim_time"]}
+				]}
+			]}
+		]}`,
+		`{"prim":"Pair","args":[
+			{"int":"1622548800"},
+			{"prim":"Pair","args":[
+				{"string":"2021-06-01T12:00:00Z"},
+				{"prim":"Pair","args":[
+					{"string":"2021-06-01T12:00:00.000Z"},
+					{"string":"2021-06-01T12:00:00"}
+				]}
+			]}
+		]}`,
+	)
+	want := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, label := range []string{"start_time", "end_time", "vesting_time", "claim_time"} {
+		tm, ok := val.GetTime(label)
+		if !ok {
+			t.Errorf("%s: lookup failed", label)
+			continue
+		}
+		if !tm.Equal(want) {
+			t.Errorf("%s: mismatch want=%s got=%s", label, want, tm)
+		}
+	}
+	if _, ok := val.GetTime("missing"); ok {
+		t.Errorf("expected missing label to fail")
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, s := range []string{
+		"2021-06-01T12:00:00Z",
+		"2021-06-01T12:00:00.000Z",
+		"2021-06-01T14:00:00+02:00",
+		"2021-06-01T12:00:00",
+	} {
+		tm, ok := parseTimestamp(s)
+		if !ok {
+			t.Errorf("%q: parse failed", s)
+			continue
+		}
+		if !tm.Equal(want) {
+			t.Errorf("%q: mismatch want=%s got=%s", s, want, tm)
+		}
+	}
+	if _, ok := parseTimestamp("1622548800"); ok {
+		t.Errorf("expected bare number to fail")
+	}
+}
+
+func TestValueMapLookup(t *testing.T) {
+	addr := tezos.MustParseAddress("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb")
+	val := newTestValue(t,
+		`{"prim":"pair","args":[
+			{"prim":"map","annots":["%balances"],"args":[{"prim":"address"},{"prim":"nat"}]},
+			{"prim":"map","annots":["%names"],"args":[{"prim":"int"},{"prim":"string"}]}
+		]}`,
+		`{"prim":"Pair","args":[
+			[{"prim":"Elt","args":[{"bytes":"`+hex.EncodeToString(addr.Bytes22())+`"},{"int":"42"}]}],
+			[{"prim":"Elt","args":[{"int":"-1"},{"string":"minus one"}]},{"prim":"Elt","args":[{"int":"7"},{"string":"seven"}]}]
+		]}`,
+	)
+
+	// address key as bytes and as string
+	for _, key := range []Prim{NewBytes(addr.Bytes22()), NewString(addr.String())} {
+		v, ok := val.MapLookup("balances", key)
+		if !ok {
+			t.Errorf("address key %s: lookup failed", key.Dump())
+			continue
+		}
+		if v != "42" {
+			t.Errorf("address key: mismatch got=%v", v)
+		}
+	}
+
+	// int key
+	v, ok := val.MapLookup("names", NewInt64(7))
+	if !ok || v != "seven" {
+		t.Errorf("int key: mismatch got=%v ok=%t", v, ok)
+	}
+	if _, ok := val.MapLookup("names", NewInt64(8)); ok {
+		t.Errorf("int key: expected missing key to fail")
+	}
+	if _, ok := val.MapLookup("unknown", NewInt64(7)); ok {
+		t.Errorf("expected unknown label to fail")
+	}
+}
+
+func TestValueNestedOption(t *testing.T) {
+	opt := `{"prim":"option","annots":["%%%s"],"args":[{"prim":"option","args":[{"prim":"nat"}]}]}`
+	val := newTestValue(t,
+		`{"prim":"pair","args":[`+
+			fmt.Sprintf(opt, "a")+`,{"prim":"pair","args":[`+
+			fmt.Sprintf(opt, "b")+`,{"prim":"pair","args":[`+
+			fmt.Sprintf(opt, "c")+`,`+fmt.Sprintf(opt, "d")+`]}]}]}`,
+		`{"prim":"Pair","args":[
+			{"prim":"None"},
+			{"prim":"Pair","args":[
+				{"prim":"Some","args":[{"prim":"None"}]},
+				{"prim":"Pair","args":[
+					{"prim":"Some","args":[{"prim":"Some","args":[{"int":"0"}]}]},
+					{"prim":"Some","args":[{"prim":"Some","args":[{"int":"42"}]}]}
+				]}
+			]}
+		]}`,
+	)
+	for _, test := range []struct {
+		Label string
+		Outer OptionState
+		Inner OptionState
+		Value interface{}
+	}{
+		{Label: "a", Outer: OptionNone, Inner: OptionMissing},
+		{Label: "b", Outer: OptionSome, Inner: OptionNone},
+		{Label: "c", Outer: OptionSome, Inner: OptionSome, Value: "0"},
+		{Label: "d", Outer: OptionSome, Inner: OptionSome, Value: "42"},
+	} {
+		_, outer := val.GetOption(test.Label)
+		if outer != test.Outer {
+			t.Errorf("%s: outer state want=%d got=%d", test.Label, test.Outer, outer)
+		}
+		inner, state := val.GetOption(test.Label + "." + OPTION_SOME_LABEL)
+		if state != test.Inner {
+			t.Errorf("%s: inner state want=%d got=%d", test.Label, test.Inner, state)
+		}
+		if inner != test.Value {
+			t.Errorf("%s: inner value want=%v got=%v", test.Label, test.Value, inner)
+		}
+	}
+	if _, state := val.GetOption("e"); state != OptionMissing {
+		t.Errorf("expected missing label, got state %d", state)
+	}
+
+	// JSON output keeps None and Some None apart
+	buf, err := json.Marshal(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"a":null,"b":{"Some":null},"c":{"Some":"0"},"d":{"Some":"42"}}`
+	if string(buf) != want {
+		t.Errorf("json mismatch\n  want=%s\n  got= %s", want, buf)
+	}
+}
+
+func TestValueGetSlice(t *testing.T) {
+	a1 := tezos.MustParseAddress("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb")
+	a2 := tezos.MustParseAddress("KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu")
+	val := newTestValue(t,
+		`{"prim":"pair","args":[
+			{"prim":"list","annots":["%whitelist"],"args":[{"prim":"address"}]},
+			{"prim":"set","annots":["%ids"],"args":[{"prim":"nat"}]},
+			{"prim":"list","annots":["%entries"],"args":[
+				{"prim":"pair","args":[{"prim":"string","annots":["%name"]},{"prim":"nat","annots":["%count"]}]}
+			]}
+		]}`,
+		`{"prim":"Pair","args":[
+			[{"bytes":"`+hex.EncodeToString(a1.Bytes22())+`"},{"string":"`+a2.String()+`"}],
+			[{"int":"1"},{"int":"12345678901234567890"}],
+			[{"prim":"Pair","args":[{"string":"a"},{"int":"3"}]}]
+		]}`,
+	)
+
+	s, ok := val.GetSlice("whitelist")
+	if !ok || len(s) != 2 {
+		t.Fatalf("whitelist: unexpected result %v ok=%t", s, ok)
+	}
+	addrs, ok := val.GetAddressSlice("whitelist")
+	if !ok || len(addrs) != 2 || !addrs[0].Equal(a1) || !addrs[1].Equal(a2) {
+		t.Errorf("whitelist: address mismatch got=%v ok=%t", addrs, ok)
+	}
+	ids, ok := val.GetBigSlice("ids")
+	if !ok || len(ids) != 2 || ids[0].Int64() != 1 || ids[1].String() != "12345678901234567890" {
+		t.Errorf("ids: mismatch got=%v ok=%t", ids, ok)
+	}
+	if _, ok := val.GetBigSlice("whitelist"); ok {
+		t.Errorf("expected address list to fail as big slice")
+	}
+	if _, ok := val.GetSlice("unknown"); ok {
+		t.Errorf("expected unknown label to fail")
+	}
+
+	// element values
+	elems, ok := val.GetSliceValues("entries")
+	if !ok || len(elems) != 1 {
+		t.Fatalf("entries: unexpected result %v ok=%t", elems, ok)
+	}
+	if name, ok := elems[0].GetString("name"); !ok || name != "a" {
+		t.Errorf("entries: name mismatch got=%s ok=%t", name, ok)
+	}
+	if n, ok := elems[0].GetInt64("count"); !ok || n != 3 {
+		t.Errorf("entries: count mismatch got=%d ok=%t", n, ok)
+	}
+	if _, ok := val.GetSliceValues("ids"); !ok {
+		t.Errorf("ids: expected set values")
+	}
+	if _, ok := val.GetSliceValues("unknown"); ok {
+		t.Errorf("expected unknown label to fail")
+	}
+}
+
+func TestValueGetMap(t *testing.T) {
+	val := newTestValue(t,
+		`{"prim":"pair","args":[
+			{"prim":"pair","annots":["%config"],"args":[
+				{"prim":"map","annots":["%limits"],"args":[{"prim":"string"},{"prim":"nat"}]},
+				{"prim":"bool","annots":["%paused"]}
+			]},
+			{"prim":"nat","annots":["%total"]}
+		]}`,
+		`{"prim":"Pair","args":[
+			{"prim":"Pair","args":[
+				[{"prim":"Elt","args":[{"string":"b"},{"int":"2"}]},{"prim":"Elt","args":[{"string":"a"},{"int":"1"}]}],
+				{"prim":"False"}
+			]},
+			{"int":"3"}
+		]}`,
+	)
+	cfg, ok := val.GetMap("config")
+	if !ok {
+		t.Fatalf("config: lookup failed")
+	}
+	if _, ok := cfg["paused"]; !ok {
+		t.Errorf("config: missing paused field in %v", cfg)
+	}
+	keys, ok := val.GetMapKeys("config.limits")
+	if !ok || len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("limits: keys mismatch got=%v ok=%t", keys, ok)
+	}
+	if _, ok := val.GetMap("total"); ok {
+		t.Errorf("expected scalar to fail")
+	}
+	if _, ok := val.GetMapKeys("unknown"); ok {
+		t.Errorf("expected unknown label to fail")
+	}
+}
+
+func TestValueGetPackedBytes(t *testing.T) {
+	// payload is PACK (Pair "a" 1)
+	const payload = "050707010000000161" + "0001"
+	val := newTestValue(t,
+		`{"prim":"pair","args":[
+			{"prim":"bytes","annots":["%payload"]},
+			{"prim":"bytes","annots":["%raw"]}
+		]}`,
+		`{"prim":"Pair","args":[{"bytes":"`+payload+`"},{"bytes":"cafe"}]}`,
+	)
+	buf, ok := val.GetPackedBytes("payload")
+	if !ok || hex.EncodeToString(buf) != payload {
+		t.Errorf("payload: mismatch got=%x ok=%t", buf, ok)
+	}
+	if _, ok := val.GetPackedBytes("raw"); ok {
+		t.Errorf("raw: expected non-packed bytes to fail")
+	}
+
+	// after unpacking GetBytes no longer sees the original bytes
+	up, err := val.UnpackAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := up.GetBytes("payload"); ok && hex.EncodeToString(b) == payload {
+		t.Errorf("payload: expected GetBytes to return the unpacked form")
+	}
+	buf, ok = up.GetPackedBytes("payload")
+	if !ok || hex.EncodeToString(buf) != payload {
+		t.Errorf("unpacked payload: mismatch got=%x ok=%t", buf, ok)
+	}
+	// returned bytes must not alias the value
+	buf[0] = 0
+	if again, _ := up.GetPackedBytes("payload"); again[0] != 0x05 {
+		t.Errorf("payload: result aliases value bytes")
+	}
+}
+
+func TestValueGetTickets(t *testing.T) {
+	val := newTestValue(t,
+		`{"prim":"pair","args":[
+			{"prim":"list","annots":["%tickets"],"args":[{"prim":"ticket","args":[{"prim":"string"}]}]},
+			{"prim":"map","annots":["%wallet"],"args":[{"prim":"nat"},{"prim":"ticket","args":[{"prim":"pair","args":[{"prim":"nat"},{"prim":"bytes"}]}]}]}
+		]}`,
+		`{"prim":"Pair","args":[
+			[
+				{"prim":"Pair","args":[{"string":"KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"},{"prim":"Pair","args":[{"string":"gold"},{"int":"10"}]}]},
+				{"prim":"Pair","args":[{"bytes":"00005c56bbc501ad676afc27ae3d660232287a15b5e2"},{"string":"silver"},{"int":"25"}]}
+			],
+			[
+				{"prim":"Elt","args":[{"int":"1"},{"prim":"Pair","args":[{"string":"KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"},{"prim":"Pair","args":[{"int":"7"},{"bytes":"ff"}]},{"int":"3"}]}]}
+			]
+		]}`,
+	)
+	tickets, ok := val.GetTickets("tickets")
+	if !ok || len(tickets) != 2 {
+		t.Fatalf("tickets: lookup failed ok=%t n=%d", ok, len(tickets))
+	}
+	for i, want := range []struct {
+		Ticketer string
+		Content  string
+		Amount   int64
+	}{
+		{"KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu", "gold", 10},
+		{"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb", "silver", 25},
+	} {
+		tk := tickets[i]
+		if tk.Ticketer.String() != want.Ticketer || tk.Content.String != want.Content || tk.Amount.Int64() != want.Amount {
+			t.Errorf("ticket %d: unexpected %s %s %s", i, tk.Ticketer, tk.Content.Dump(), tk.Amount)
+		}
+		if tk.Type.OpCode != T_STRING {
+			t.Errorf("ticket %d: unexpected content type %s", i, tk.Type.OpCode)
+		}
+	}
+	if tickets[0].Ticketer.Equal(tickets[1].Ticketer) {
+		t.Errorf("expected differing ticketers")
+	}
+
+	wallet, ok := val.GetTickets("wallet")
+	if !ok || len(wallet) != 1 {
+		t.Fatalf("wallet: lookup failed ok=%t n=%d", ok, len(wallet))
+	}
+	if want := NewPairValue(NewBig(big.NewInt(7)), NewBytes([]byte{0xff})); !wallet[0].Content.IsEqual(want) || wallet[0].Amount.Int64() != 3 {
+		t.Errorf("wallet: unexpected ticket %s %s", wallet[0].Content.Dump(), wallet[0].Amount)
+	}
+	if _, ok := val.GetTickets("missing"); ok {
+		t.Errorf("expected missing label to fail")
+	}
+}
+
+func TestValueGetPathIndex(t *testing.T) {
+	val := newTestValue(t,
+		`{"prim":"pair","args":[
+			{"prim":"list","annots":["%balances"],"args":[
+				{"prim":"pair","args":[{"prim":"address","annots":["%owner"]},{"prim":"nat","annots":["%amount"]}]}
+			]},
+			{"prim":"list","annots":["%ids"],"args":[{"prim":"nat"}]}
+		]}`,
+		`{"prim":"Pair","args":[
+			[
+				{"prim":"Pair","args":[{"string":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"},{"int":"10"}]},
+				{"prim":"Pair","args":[{"string":"KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"},{"int":"20"}]}
+			],
+			[{"int":"5"},{"int":"6"}]
+		]}`,
+	)
+	if n, ok := val.GetInt64("balances.1.amount"); !ok || n != 20 {
+		t.Errorf("balances.1.amount: mismatch got=%d ok=%t", n, ok)
+	}
+	if a, ok := val.GetAddress("balances.0.owner"); !ok || a.String() != "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb" {
+		t.Errorf("balances.0.owner: mismatch got=%s ok=%t", a, ok)
+	}
+	if n, ok := val.GetInt64("ids.0"); !ok || n != 5 {
+		t.Errorf("ids.0: mismatch got=%d ok=%t", n, ok)
+	}
+	for _, path := range []string{"balances.2.amount", "ids.2", "ids.-1", "ids.x", "balances.0.missing", "ids.0.value"} {
+		if v, ok := val.GetValue(path); ok {
+			t.Errorf("%s: expected lookup to fail, got %v", path, v)
+		}
+	}
+}
+
+func TestValueEqual(t *testing.T) {
+	addr := tezos.MustParseAddress("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb")
+	typ := `{"prim":"pair","args":[
+		{"prim":"map","annots":["%ledger"],"args":[{"prim":"address"},{"prim":"nat"}]},
+		{"prim":"timestamp","annots":["%updated"]},
+		{"prim":"list","annots":["%ids"],"args":[{"prim":"int"}]}
+	]}`
+	a := newTestValue(t, typ, `{"prim":"Pair","args":[
+		[{"prim":"Elt","args":[{"string":"`+addr.String()+`"},{"int":"1"}]},{"prim":"Elt","args":[{"string":"KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"},{"int":"2"}]}],
+		{"string":"2021-01-01T00:00:00Z"},
+		[{"int":"1"},{"int":"2"}]
+	]}`)
+	// same storage in optimized form, nested pairs and different element order
+	b := newTestValue(t, typ, `{"prim":"Pair","args":[
+		[{"prim":"Elt","args":[{"string":"KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"},{"int":"2"}]},{"prim":"Elt","args":[{"bytes":"`+hex.EncodeToString(addr.Bytes22())+`"},{"int":"1"}]}],
+		{"prim":"Pair","args":[{"int":"1609459200"},[{"int":"1"},{"int":"2"}]]}
+	]}`)
+	c := newTestValue(t, typ, `{"prim":"Pair","args":[
+		[{"prim":"Elt","args":[{"string":"`+addr.String()+`"},{"int":"1"}]}],
+		{"string":"2021-01-01T00:00:00Z"},
+		[{"int":"2"},{"int":"1"}]
+	]}`)
+	if !a.Equal(*b) || !b.Equal(*a) {
+		t.Errorf("expected equal values")
+	}
+	if a.Equal(*c) {
+		t.Errorf("expected different values")
+	}
+
+	// leaf representations
+	for _, test := range []struct {
+		A, B interface{}
+		Want bool
+	}{
+		{A: big.NewInt(42), B: "42", Want: true},
+		{A: "42", B: big.NewInt(42), Want: true},
+		{A: big.NewInt(42), B: big.NewInt(43), Want: false},
+		{A: time.Unix(0, 0).UTC(), B: time.Unix(0, 0).In(time.FixedZone("X", 3600)), Want: true},
+		{A: []byte{1, 2}, B: []byte{1, 2}, Want: true},
+		{A: []byte{1, 2}, B: []byte{1}, Want: false},
+		{A: addr, B: tezos.MustParseAddress(addr.String()), Want: true},
+		{A: nil, B: nil, Want: true},
+		{A: nil, B: "0", Want: false},
+	} {
+		if got := equalValueTree(test.A, test.B); got != test.Want {
+			t.Errorf("%v == %v: want=%t got=%t", test.A, test.B, test.Want, got)
+		}
+	}
+}
+
+func TestValueWalkTyped(t *testing.T) {
+	val := newTestValue(t,
+		`{"prim":"pair","args":[
+			{"prim":"big_map","annots":["%ledger"],"args":[{"prim":"address"},{"prim":"pair","args":[{"prim":"nat","annots":["%balance"]},{"prim":"set","annots":["%ops"],"args":[{"prim":"nat"}]}]}]},
+			{"prim":"list","annots":["%admins"],"args":[{"prim":"address"}]},
+			{"prim":"or","annots":["%state"],"args":[{"prim":"unit","annots":["%paused"]},{"prim":"map","annots":["%active"],"args":[{"prim":"string"},{"prim":"int"}]}]},
+			{"prim":"string","annots":["%name"]}
+		]}`,
+		`{"prim":"Pair","args":[
+			[
+				{"prim":"Elt","args":[{"string":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"},{"prim":"Pair","args":[{"int":"10"},[{"int":"1"},{"int":"2"}]]}]},
+				{"prim":"Elt","args":[{"string":"KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"},{"prim":"Pair","args":[{"int":"20"},[]]}]}
+			],
+			[{"string":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"}],
+			{"prim":"Right","args":[[{"prim":"Elt","args":[{"string":"x"},{"int":"-1"}]}]]},
+			{"string":"token"}
+		]}`,
+	)
+	checkWalkTyped(t, val)
+
+	// user errors stop the walk
+	stop := fmt.Errorf("stop")
+	var n int
+	err := val.WalkTyped(func(path string, typ OpCode, value interface{}) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("expected walk to stop after first leaf, got err=%v calls=%d", err, n)
+	}
+
+	// same leaves as FlatPairs for all test data
+	for _, category := range []string{"storage", "bigmap"} {
+		scanTestFiles(t, category)
+		var next int
+		for {
+			var tests []testcase
+			next, err = loadNextTestFile(category, next, &tests)
+			if err != nil {
+				break
+			}
+			for _, test := range tests {
+				v := newTestValue(t, string(test.Type), string(test.Value))
+				if _, err := v.FlatPairs(); err != nil {
+					continue
+				}
+				checkWalkTyped(t, v)
+			}
+		}
+	}
+}
+
+func checkWalkTyped(t *testing.T, val *Value) {
+	t.Helper()
+	want, err := val.FlatPairs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]FlatPair, 0)
+	err = val.WalkTyped(func(path string, typ OpCode, value interface{}) error {
+		got = append(got, FlatPair{Path: path, Type: typ, Value: value})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// FlatPairs orders list elements by index, compare by plain path order
+	for _, pairs := range [][]FlatPair{want, got} {
+		sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Path < pairs[j].Path })
+	}
+	if len(got) != len(want) {
+		t.Fatalf("leaf count mismatch want=%d got=%d\n  want=%v\n  got= %v", len(want), len(got), want, got)
+	}
+	for i := range want {
+		if got[i].Path != want[i].Path || got[i].Type != want[i].Type || fmt.Sprint(got[i].Value) != fmt.Sprint(want[i].Value) {
+			t.Errorf("leaf %d mismatch want=%v got=%v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestValueTimeFormat(t *testing.T) {
+	val := newTestValue(t,
+		`{"prim":"pair","args":[{"prim":"timestamp","annots":["%start"]},{"prim":"timestamp","annots":["%end"]}]}`,
+		`{"prim":"Pair","args":[{"int":"1609459200"},{"string":"2021-06-30T12:30:00Z"}]}`,
+	)
+	buf, err := json.Marshal(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"end":"2021-06-30T12:30:00Z","start":"2021-01-01T00:00:00Z"}`; string(buf) != want {
+		t.Errorf("default mismatch\n  want=%s\n  got= %s", want, buf)
+	}
+
+	loc := time.FixedZone("CEST", 2*3600)
+	val.SetTimeFormat("2006-01-02 15:04 MST", loc)
+	buf, err = json.Marshal(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"end":"2021-06-30 14:30 CEST","start":"2021-01-01 02:00 CEST"}`; string(buf) != want {
+		t.Errorf("format mismatch\n  want=%s\n  got= %s", want, buf)
+	}
+	if tm, ok := val.GetTime("end"); !ok || !tm.Equal(time.Date(2021, 6, 30, 12, 30, 0, 0, time.UTC)) {
+		t.Errorf("GetTime mismatch got=%s ok=%t", tm, ok)
+	}
+
+	// reset to default
+	val.SetTimeFormat("", nil)
+	if tm, ok := val.GetValue("start"); !ok {
+		t.Errorf("missing start")
+	} else if _, ok := tm.(time.Time); !ok {
+		t.Errorf("expected time.Time with default format, got %T", tm)
+	}
+}
+
+func TestValueRenderJSONNumber(t *testing.T) {
+	typ := `{"prim":"pair","args":[{"prim":"nat","annots":["%supply"]},{"prim":"pair","args":[{"prim":"mutez","annots":["%fee"]},{"prim":"list","annots":["%deltas"],"args":[{"prim":"int"}]}]}]}`
+	data := `{"prim":"Pair","args":[{"int":"123456789012345678901234567890"},{"prim":"Pair","args":[{"int":"1500"},[{"int":"-9007199254740993"},{"int":"7"}]]}]}`
+
+	// default renders strings
+	val := newTestValue(t, typ, data)
+	buf, err := json.Marshal(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"deltas":["-9007199254740993","7"],"fee":"1500","supply":"123456789012345678901234567890"}`; string(buf) != want {
+		t.Errorf("string mode mismatch\n  want=%s\n  got= %s", want, buf)
+	}
+
+	val = newTestValue(t, typ, data)
+	val.Render = RENDER_TYPE_FAIL | RENDER_NUMERIC_AS_JSONNUMBER
+	buf, err = json.Marshal(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"deltas":[-9007199254740993,7],"fee":1500,"supply":123456789012345678901234567890}`; string(buf) != want {
+		t.Errorf("number mode mismatch\n  want=%s\n  got= %s", want, buf)
+	}
+
+	// a number aware decoder keeps all digits
+	var m map[string]interface{}
+	dec := json.NewDecoder(strings.NewReader(string(buf)))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := m["supply"].(json.Number); !ok || n.String() != "123456789012345678901234567890" {
+		t.Errorf("supply lost precision: %v", m["supply"])
+	}
+	if d := m["deltas"].([]interface{}); d[0].(json.Number).String() != "-9007199254740993" {
+		t.Errorf("delta lost precision: %v", d[0])
+	}
+
+	// getters and comparison accept both forms
+	if fee, ok := val.GetInt64("fee"); !ok || fee != 1500 {
+		t.Errorf("GetInt64 mismatch got=%d ok=%t", fee, ok)
+	}
+	if b, ok := val.GetBig("supply"); !ok || b.String() != "123456789012345678901234567890" {
+		t.Errorf("GetBig mismatch got=%s ok=%t", b, ok)
+	}
+	if s, ok := val.GetString("fee"); !ok || s != "1500" {
+		t.Errorf("GetString mismatch got=%q ok=%t", s, ok)
+	}
+	if l, ok := val.GetBigSlice("deltas"); !ok || len(l) != 2 || l[1].Int64() != 7 {
+		t.Errorf("GetBigSlice mismatch got=%v ok=%t", l, ok)
+	}
+	if !val.Equal(*newTestValue(t, typ, data)) {
+		t.Errorf("expected values to be equal across render modes")
+	}
+}
+
+func TestValueGetAddressOption(t *testing.T) {
+	val := newTestValue(t,
+		`{"prim":"pair","args":[
+			{"prim":"option","annots":["%some"],"args":[{"prim":"address"}]},
+			{"prim":"option","annots":["%none"],"args":[{"prim":"address"}]},
+			{"prim":"nat","annots":["%count"]}
+		]}`,
+		`{"prim":"Pair","args":[
+			{"prim":"Some","args":[{"string":"KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"}]},
+			{"prim":"None"},
+			{"int":"1"}
+		]}`,
+	)
+	for _, test := range []struct {
+		Label string
+		Addr  string
+		State OptionState
+	}{
+		{Label: "some", Addr: "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu", State: OptionSome},
+		{Label: "none", State: OptionNone},
+		{Label: "count", State: OptionMissing},
+		{Label: "absent", State: OptionMissing},
+	} {
+		a, state := val.GetAddressOption(test.Label)
+		if state != test.State {
+			t.Errorf("%s: state want=%d got=%d", test.Label, test.State, state)
+		}
+		b, ok := val.GetAddress(test.Label)
+		if ok != (test.State == OptionSome) {
+			t.Errorf("%s: GetAddress ok=%t", test.Label, ok)
+		}
+		if test.Addr == "" {
+			if a.IsValid() || b.IsValid() {
+				t.Errorf("%s: expected invalid address, got %s %s", test.Label, a, b)
+			}
+			continue
+		}
+		if a.String() != test.Addr || b.String() != test.Addr {
+			t.Errorf("%s: want=%s got=%s %s", test.Label, test.Addr, a, b)
+		}
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	typ := `{"prim":"pair","args":[
+		{"prim":"big_map","annots":["%ledger"],"args":[{"prim":"address"},{"prim":"nat"}]},
+		{"prim":"option","annots":["%admin"],"args":[{"prim":"address"}]},
+		{"prim":"list","annots":["%ids"],"args":[{"prim":"int"}]},
+		{"prim":"nat","annots":["%total"]}
+	]}`
+	before := newTestValue(t, typ, `{"prim":"Pair","args":[
+		[{"prim":"Elt","args":[{"string":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"},{"int":"1"}]},{"prim":"Elt","args":[{"string":"tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs"},{"int":"2"}]}],
+		{"prim":"None"},
+		[{"int":"1"},{"int":"2"},{"int":"3"}],
+		{"int":"3"}
+	]}`)
+	after := newTestValue(t, typ, `{"prim":"Pair","args":[
+		[{"prim":"Elt","args":[{"string":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"},{"int":"5"}]},{"prim":"Elt","args":[{"string":"KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"},{"int":"1"}]}],
+		{"prim":"Some","args":[{"string":"KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"}]},
+		[{"int":"1"},{"int":"4"}],
+		{"int":"3"}
+	]}`)
+	diff, err := DiffValues(*before, *after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		Path string
+		Kind ValueDiffKind
+		Old  string
+		New  string
+	}{
+		{"admin", ValueDiffChanged, "<nil>", "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"},
+		{"ids.1", ValueDiffChanged, "2", "4"},
+		{"ids.2", ValueDiffRemoved, "3", "<nil>"},
+		{"ledger.KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu", ValueDiffAdded, "<nil>", "1"},
+		{"ledger.tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb", ValueDiffChanged, "1", "5"},
+		{"ledger.tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs", ValueDiffRemoved, "2", "<nil>"},
+	}
+	if len(diff) != len(want) {
+		t.Fatalf("want %d changes, got %d: %v", len(want), len(diff), diff)
+	}
+	for i, w := range want {
+		d := diff[i]
+		if d.Path != w.Path || d.Kind != w.Kind || fmt.Sprint(d.Old) != w.Old || fmt.Sprint(d.New) != w.New {
+			t.Errorf("%d: want=%v got=%s %s %v %v", i, w, d.Path, d.Kind, d.Old, d.New)
+		}
+		// paths resolve with the getters
+		if d.Kind != ValueDiffRemoved {
+			if v, ok := after.GetValue(d.Path); !ok || !equalValueTree(v, d.New) {
+				t.Errorf("%s: path does not resolve in new value", d.Path)
+			}
+		}
+	}
+
+	if diff, err := before.Diff(*before); err != nil || len(diff) != 0 {
+		t.Errorf("expected no changes, got %v err=%v", diff, err)
+	}
+}
+
+func TestValueMapCache(t *testing.T) {
+	val := newTestValue(t, `{"prim":"nat"}`, `{"int":"1"}`)
+	if m, _ := val.Map(); m != "1" {
+		t.Fatalf("unexpected map %v", m)
+	}
+
+	// direct changes are picked up after Invalidate
+	val.Value = NewInt64(2)
+	if m, _ := val.Map(); m != "1" {
+		t.Errorf("expected cached result, got %v", m)
+	}
+	val.Invalidate()
+	if m, _ := val.Map(); m != "2" {
+		t.Errorf("expected fresh result after Invalidate, got %v", m)
+	}
+
+	// changing the render mode rebuilds the cache
+	val.Render |= RENDER_NUMERIC_AS_JSONNUMBER
+	if m, _ := val.Map(); m != json.Number("2") {
+		t.Errorf("expected json.Number after render change, got %T %v", m, m)
+	}
+
+	// Decode replaces the value and drops the cache
+	buf, _ := NewInt64(3).MarshalBinary()
+	if err := val.Decode(buf); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := val.Map(); m != json.Number("3") {
+		t.Errorf("expected fresh result after Decode, got %v", m)
+	}
+}
+
+func TestValueMaxDepth(t *testing.T) {
+	nested := func(n int) Value {
+		typ, val := NewCode(T_NAT), NewInt64(1)
+		for i := 0; i < n; i++ {
+			typ = NewCode(T_OPTION, typ)
+			val = NewCode(D_SOME, val)
+		}
+		return NewValue(NewType(typ), val)
+	}
+
+	// the default limit rejects a 100 deep option chain
+	val := nested(100)
+	if _, err := val.Map(); err == nil || !strings.Contains(err.Error(), "max nesting level 99 reached at level 100") {
+		t.Errorf("expected max depth error, got %v", err)
+	}
+
+	// a higher limit accepts it
+	val.MaxDepth = 128
+	if _, err := val.Map(); err != nil {
+		t.Errorf("expected success with raised limit, got %v", err)
+	}
+
+	// a lower limit rejects shallow values
+	val = nested(20)
+	val.MaxDepth = 10
+	if _, err := val.FlatPairs(); err == nil {
+		t.Errorf("expected error with lowered limit")
+	}
+	val.MaxDepth = 0
+	if _, err := val.FlatPairs(); err != nil {
+		t.Errorf("unexpected error with default limit: %v", err)
+	}
+}
+
+func TestValueDecodeError(t *testing.T) {
+	// mismatched scalar
+	val := newTestValue(t, `{"prim":"pair","args":[{"prim":"nat","annots":["%a"]},{"prim":"string","annots":["%b"]}]}`,
+		`{"prim":"Pair","args":[{"int":"1"},{"int":"2"}]}`)
+	_, err := val.Map()
+	var derr *DecodeError
+	if !errors.As(err, &derr) {
+		t.Fatalf("expected DecodeError, got %T %v", err, err)
+	}
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected ErrTypeMismatch, got %v", derr.Err)
+	}
+	if derr.Expected != T_STRING || derr.Path != "" {
+		t.Errorf("unexpected location path=%q expected=%s", derr.Path, derr.Expected)
+	}
+	if !strings.Contains(err.Error(), "type mismatch") {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+
+	// non-Elt map item
+	val = newTestValue(t, `{"prim":"map","args":[{"prim":"nat"},{"prim":"nat"}]}`, `[{"int":"1"}]`)
+	if _, err := val.Map(); !errors.Is(err, ErrUnexpectedOpCode) {
+		t.Errorf("expected ErrUnexpectedOpCode, got %v", err)
+	}
+
+	// nesting limit
+	val = newTestValue(t, `{"prim":"option","args":[{"prim":"option","args":[{"prim":"nat"}]}]}`,
+		`{"prim":"Some","args":[{"prim":"Some","args":[{"int":"1"}]}]}`)
+	val.MaxDepth = 1
+	if _, err := val.Map(); !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("expected ErrMaxDepth, got %v", err)
+	}
+}
+
+func TestValueRenderBytes(t *testing.T) {
+	addr := tezos.MustParseAddress("tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx")
+	raw := hex.EncodeToString(addr.Bytes22())
+	typ := `{"prim":"pair","args":[{"prim":"bytes","annots":["%a"]},{"prim":"bytes","annots":["%b"]}]}`
+	val := newTestValue(t, typ, `{"prim":"Pair","args":[{"bytes":"`+raw+`"},{"bytes":"cafe"}]}`)
+
+	// default is hex
+	buf, err := json.Marshal(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := `{"a":"` + raw + `","b":"cafe"}`; string(buf) != exp {
+		t.Errorf("hex: got %s, want %s", buf, exp)
+	}
+
+	// typed decodes the address only
+	val.Render = RENDER_BYTES_TYPED
+	buf, _ = json.Marshal(val)
+	if exp := `{"a":"` + addr.String() + `","b":"cafe"}`; string(buf) != exp {
+		t.Errorf("typed: got %s, want %s", buf, exp)
+	}
+	if b, ok := val.GetBytes("a"); !ok || hex.EncodeToString(b) != raw {
+		t.Errorf("typed: GetBytes returned %x %t", b, ok)
+	}
+
+	// both emits hex and decoded value
+	val.Render = RENDER_BYTES_BOTH | RENDER_TYPE_FAIL
+	buf, err = json.Marshal(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := `{"a":{"hex":"` + raw + `","value":"` + addr.String() + `"},"b":{"hex":"cafe"}}`; string(buf) != exp {
+		t.Errorf("both: got %s, want %s", buf, exp)
+	}
+	if b, ok := val.GetBytes("b"); !ok || hex.EncodeToString(b) != "cafe" {
+		t.Errorf("both: GetBytes returned %x %t", b, ok)
+	}
+
+	// keys and signatures are only decoded on request, a 64 byte hash
+	// would otherwise be shown as signature
+	key := tezos.MustParseKey("edpkuZ7ERiU5B8knLqQsVMH86j9RLMUyHyL665oCXDkPQxF7HGqSeJ")
+	rawKey, rawSig := hex.EncodeToString(key.Bytes()), strings.Repeat("ab", 64)
+	val = newTestValue(t, typ, `{"prim":"Pair","args":[{"bytes":"`+rawKey+`"},{"bytes":"`+rawSig+`"}]}`)
+	val.Render = RENDER_BYTES_TYPED
+	buf, _ = json.Marshal(val)
+	if exp := `{"a":"` + rawKey + `","b":"` + rawSig + `"}`; string(buf) != exp {
+		t.Errorf("typed: got %s, want %s", buf, exp)
+	}
+	val.Render = RENDER_BYTES_TYPED | RENDER_BYTES_KEYS
+	buf, _ = json.Marshal(val)
+	sig := tezos.NewSignature(tezos.SignatureTypeGeneric, bytes.Repeat([]byte{0xab}, 64))
+	if exp := `{"a":"` + key.String() + `","b":"` + sig.String() + `"}`; string(buf) != exp {
+		t.Errorf("keys: got %s, want %s", buf, exp)
+	}
+	if b, ok := val.GetBytes("a"); !ok || hex.EncodeToString(b) != rawKey {
+		t.Errorf("keys: GetBytes returned %x %t", b, ok)
+	}
+}
+
+func TestValueSaplingTransaction(t *testing.T) {
+	// one output with an 8 byte memo, no inputs
+	output := make([]byte, 32+192+64)
+	output = append(output, 0, 0, 0, 71+8)
+	output = append(output, make([]byte, 71+8+24+80+24)...)
+	buf := []byte{0, 0, 0, 0}
+	buf = append(buf, 0, 0, byte(len(output)>>8), byte(len(output)))
+	buf = append(buf, output...)
+	buf = append(buf, make([]byte, 64)...)
+	buf = append(buf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xf6) // -10
+	buf = append(buf, make([]byte, 32)...)
+	buf = append(buf, 0, 0, 0, 1, 0xab)
+
+	tx, err := DecodeSaplingTransaction(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Inputs != 0 || tx.Outputs != 1 || tx.MemoSize != 8 || tx.Balance != -10 || hex.EncodeToString(tx.BoundData) != "ab" {
+		t.Errorf("unexpected transaction %+v", tx)
+	}
+
+	typ := `{"prim":"sapling_transaction","args":[{"int":"8"}]}`
+	val := newTestValue(t, typ, `{"bytes":"`+hex.EncodeToString(buf)+`"}`)
+	m, err := val.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mm, ok := m.(map[string]interface{})
+	if !ok || mm["memo_size"] != "8" || mm["outputs"] != "1" || mm["balance"] != "-10" {
+		t.Errorf("unexpected map %v", m)
+	}
+
+	// malformed bytes are kept as raw hex
+	val = newTestValue(t, typ, `{"bytes":"cafe"}`)
+	if m, err := val.Map(); err != nil {
+		t.Error(err)
+	} else if mm, _ := m.(map[string]interface{}); mm["raw"] != "cafe" {
+		t.Errorf("unexpected map %v", m)
+	}
+}
+
+func TestValueBlsPoints(t *testing.T) {
+	// G1 generator in uncompressed form
+	g1 := "17f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb" +
+		"08b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e1"
+	// Fr one in little endian
+	fr := "01" + strings.Repeat("00", 31)
+	typ := `{"prim":"pair","args":[{"prim":"bls12_381_g1","annots":["%g1"]},{"prim":"pair","args":[{"prim":"bls12_381_fr","annots":["%fr"]},{"prim":"bls12_381_fr","annots":["%n"]}]}]}`
+	val := newTestValue(t, typ, `{"prim":"Pair","args":[{"bytes":"`+g1+`"},{"bytes":"`+fr+`"},{"int":"7"}]}`)
+	m, err := val.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mm := m.(map[string]interface{})
+	if p, ok := mm["g1"].(BlsPoint); !ok || p.Type != T_BLS12_381_G1 || p.String() != g1 {
+		t.Errorf("unexpected g1 %#v", mm["g1"])
+	}
+	if p, ok := mm["fr"].(BlsPoint); !ok || p.Type != T_BLS12_381_FR {
+		t.Errorf("unexpected fr %#v", mm["fr"])
+	}
+	if mm["n"] != "7" {
+		t.Errorf("unexpected int fr %#v", mm["n"])
+	}
+	if b, ok := val.GetBytes("g1"); !ok || hex.EncodeToString(b) != g1 {
+		t.Errorf("GetBytes returned %x %t", b, ok)
+	}
+	buf, _ := json.Marshal(val)
+	if exp := `{"fr":"` + fr + `","g1":"` + g1 + `","n":"7"}`; string(buf) != exp {
+		t.Errorf("got %s, want %s", buf, exp)
+	}
+
+	// G2 points are 192 bytes
+	val = newTestValue(t, `{"prim":"bls12_381_g2"}`, `{"bytes":"`+strings.Repeat("ab", 192)+`"}`)
+	if _, err := val.Map(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// wrong lengths fail
+	for _, typ := range []string{"bls12_381_g1", "bls12_381_g2", "bls12_381_fr"} {
+		val = newTestValue(t, `{"prim":"`+typ+`"}`, `{"bytes":"`+g1[:62]+`"}`)
+		if _, err := val.Map(); !errors.Is(err, ErrTypeMismatch) {
+			t.Errorf("%s: expected length error, got %v", typ, err)
+		}
+	}
+}
+
+func TestValueRenderUnionLabels(t *testing.T) {
+	typ := `{"prim":"or","args":[{"prim":"nat"},{"prim":"or","args":[{"prim":"string"},{"prim":"or","args":[{"prim":"bytes"},{"prim":"unit","annots":["%stop"]}]}]}]}`
+	val := newTestValue(t, typ, `{"prim":"Right","args":[{"prim":"Right","args":[{"prim":"Left","args":[{"bytes":"cafe"}]}]}]}`)
+
+	for _, v := range []struct {
+		mode int
+		want string
+	}{
+		{RENDER_UNION_OR, `{"@or_1":{"@or_1":{"@or_0":"cafe"}}}`},
+		{RENDER_UNION_INDEX, `{"@or_2":"cafe"}`},
+		{RENDER_UNION_ENTRYPOINT, `{"@entrypoint_2":"cafe"}`},
+	} {
+		val.Render = v.mode
+		buf, err := json.Marshal(val)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != v.want {
+			t.Errorf("mode %d: got %s, want %s", v.mode, buf, v.want)
+		}
+	}
+
+	// named branches keep their name, the first anonymous one is default
+	val = newTestValue(t, typ, `{"prim":"Right","args":[{"prim":"Right","args":[{"prim":"Right","args":[{"prim":"Unit"}]}]}]}`)
+	val.Render = RENDER_UNION_ENTRYPOINT
+	if buf, _ := json.Marshal(val); string(buf) != `{"stop":null}` {
+		t.Errorf("named branch: got %s", buf)
+	}
+	val = newTestValue(t, typ, `{"prim":"Left","args":[{"int":"1"}]}`)
+	val.Render = RENDER_UNION_ENTRYPOINT
+	if buf, _ := json.Marshal(val); string(buf) != `{"default":"1"}` {
+		t.Errorf("first branch: got %s", buf)
+	}
+}
+
+func TestValueUnpackWithType(t *testing.T) {
+	// PACK (Pair "ab" 0x6364) where the bytes look like an ASCII string
+	hint := newTestType(t, `{"prim":"pair","args":[{"prim":"string","annots":["%name"]},{"prim":"bytes","annots":["%data"]}]}`)
+	packed, err := PackPrim(hint, NewPairValue(NewString("ab"), NewBytes([]byte("cd"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	val := NewValue(NewType(NewCode(T_BYTES)), NewBytes(packed))
+
+	up, err := val.UnpackWithType(hint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := up.GetString("name"); !ok || s != "ab" {
+		t.Errorf("unexpected name %q %t", s, ok)
+	}
+	if b, ok := up.GetBytes("data"); !ok || string(b) != "cd" {
+		t.Errorf("unexpected data %x %t", b, ok)
+	}
+	if buf, err := up.Pack(); err != nil || !reflect.DeepEqual(buf, packed) {
+		t.Errorf("repack mismatch %x %v", buf, err)
+	}
+
+	// a hint with a different shape fails
+	if _, err := val.UnpackWithType(newTestType(t, `{"prim":"list","args":[{"prim":"nat"}]}`)); err == nil {
+		t.Errorf("expected shape mismatch error")
+	}
+
+	// packed data is required
+	if _, err := NewValue(hint, NewBytes([]byte{1, 2})).UnpackWithType(hint); err == nil {
+		t.Errorf("expected error for non-packed value")
+	}
+
+	// trailing bytes are rejected
+	trailing := NewValue(NewType(NewCode(T_BYTES)), NewBytes(append(packed, 0)))
+	if _, err := trailing.UnpackWithType(hint); err == nil {
+		t.Errorf("expected error for trailing bytes")
+	}
+}
+
+func TestValueUnpackAllNested(t *testing.T) {
+	rec := newTestType(t, `{"prim":"pair","args":[{"prim":"nat"},{"prim":"string"}]}`)
+	packed := func(n int64, s string) string {
+		buf, err := PackPrim(rec, NewPairValue(NewInt64(n), NewString(s)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hex.EncodeToString(buf)
+	}
+	// packed records inside a list inside a pair; the last item only looks
+	// packed and carries a trailing byte
+	typ := `{"prim":"pair","args":[{"prim":"nat","annots":["%id"]},{"prim":"list","annots":["%items"],"args":[
+		{"prim":"pair","args":[{"prim":"nat","annots":["%n"]},{"prim":"bytes","annots":["%data"]}]}]}]}`
+	val := newTestValue(t, typ, `{"prim":"Pair","args":[{"int":"1"},[
+		{"prim":"Pair","args":[{"int":"0"},{"bytes":"`+packed(7, "a")+`"}]},
+		{"prim":"Pair","args":[{"int":"1"},{"bytes":"`+packed(8, "b")+`"}]},
+		{"prim":"Pair","args":[{"int":"2"},{"bytes":"050001ff"}]}]]}`)
+
+	if !val.IsPackedAny() {
+		t.Fatal("expected nested packed data to be detected")
+	}
+	up, err := val.UnpackAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := up.Value.Args[1].Args
+	for i, want := range []string{`Pair 7 "a"`, `Pair 8 "b"`} {
+		if got := items[i].Args[1].Michelson(); got != want {
+			t.Errorf("item %d: got %s, want %s", i, got, want)
+		}
+	}
+	if last := items[2].Args[1]; last.Type != PrimBytes || hex.EncodeToString(last.Bytes) != "050001ff" {
+		t.Errorf("expected non-packed bytes to be unchanged, got %s", last.Dump())
+	}
+	if id, ok := up.GetInt64("id"); !ok || id != 1 {
+		t.Errorf("unexpected id %d %t", id, ok)
+	}
+}
+
+func TestValueGetChainId(t *testing.T) {
+	typ := `{"prim":"pair","args":[{"prim":"chain_id","annots":["%a"]},{"prim":"chain_id","annots":["%b"]}]}`
+	val := newTestValue(t, typ, `{"prim":"Pair","args":[{"bytes":"7a06a770"},{"string":"NetXdQprcVkpaWU"}]}`)
+	for _, label := range []string{"a", "b"} {
+		c, ok := val.GetChainId(label)
+		if !ok || c.String() != "NetXdQprcVkpaWU" {
+			t.Errorf("%s: unexpected chain id %s %t", label, c, ok)
+		}
+	}
+	if v, _ := val.GetValue("a"); !reflect.DeepEqual(v, tezos.MustParseChainIdHash("NetXdQprcVkpaWU")) {
+		t.Errorf("expected typed chain id, got %T", v)
+	}
+	buf, _ := json.Marshal(val)
+	if exp := `{"a":"NetXdQprcVkpaWU","b":"NetXdQprcVkpaWU"}`; string(buf) != exp {
+		t.Errorf("got %s, want %s", buf, exp)
+	}
+}
+
+func TestValueSnapshotConcurrent(t *testing.T) {
+	typ := `{"prim":"pair","args":[{"prim":"nat","annots":["%n"]},{"prim":"map","annots":["%m"],"args":[{"prim":"string"},{"prim":"unit"}]}]}`
+	val := newTestValue(t, typ, `{"prim":"Pair","args":[{"int":"42"},[{"prim":"Elt","args":[{"string":"a"},{"prim":"Unit"}]}]]}`)
+	unit := NewValue(NewType(NewCode(T_UNIT)), NewCode(D_UNIT))
+	snaps := make([]Value, 0, 2)
+	for _, v := range []*Value{val, &unit} {
+		snap, err := v.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		snaps = append(snaps, snap)
+	}
+	want, _ := json.Marshal(snaps[0])
+
+	// run with -race to detect writes during concurrent reads
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s := &snaps[0]
+				if n, ok := s.GetInt64("n"); !ok || n != 42 {
+					t.Errorf("unexpected n %d", n)
+				}
+				if _, err := s.Map(); err != nil {
+					t.Error(err)
+				}
+				if buf, _ := json.Marshal(s); string(buf) != string(want) {
+					t.Errorf("unexpected json %s", buf)
+				}
+				if m, err := snaps[1].Map(); err != nil || m != nil {
+					t.Errorf("unexpected unit %v %v", m, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
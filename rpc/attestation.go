@@ -0,0 +1,40 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// AttestationOp represents an attestation operation (endorsement in protocols
+// before v018)
+type AttestationOp struct {
+	GenericOp
+	Slot        int                    `json:"slot"`
+	Level       int64                  `json:"level"`
+	Round       int                    `json:"round"`
+	PayloadHash string                 `json:"block_payload_hash"`
+	Metadata    *AttestationOpMetadata `json:"metadata"`
+}
+
+func (a AttestationOp) GetLevel() int64 {
+	return a.Level
+}
+
+// AttestationOpMetadata represents attestation and preattestation metadata
+type AttestationOpMetadata struct {
+	BalanceUpdates BalanceUpdates `json:"balance_updates"`
+	Delegate       tezos.Address  `json:"delegate"`
+	ConsensusPower int            `json:"consensus_power"`
+}
+
+func (m AttestationOpMetadata) Address() tezos.Address {
+	return m.Delegate
+}
+
+// PreattestationOp represents a preattestation operation (preendorsement
+// in protocols before v018)
+type PreattestationOp struct {
+	AttestationOp
+}
@@ -7,9 +7,11 @@ package rpc
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"blockwatch.cc/tzgo/tezos"
+	"golang.org/x/crypto/blake2b"
 )
 
 // Block holds information about a Tezos block
@@ -98,6 +100,94 @@ func (b Block) IsProtocolUpgrade() bool {
 	return !b.Metadata.Protocol.Equal(b.Metadata.NextProtocol)
 }
 
+// OperationListListHash computes the operations hash of the block header from
+// the hashes of all operations in the block. Each validation pass is reduced
+// to an operation list hash and the list of these hashes to the final hash.
+func (b Block) OperationListListHash() tezos.Hash {
+	lists := make([][]byte, len(b.Operations))
+	for i, pass := range b.Operations {
+		hashes := make([][]byte, len(pass))
+		for j, op := range pass {
+			hashes[j] = op.Hash.Hash.Hash
+		}
+		lists[i] = merkleRoot(hashes)
+	}
+	return tezos.NewHash(tezos.HashTypeOperationListList, merkleRoot(lists))
+}
+
+// VerifyOperationListHash recomputes the operations hash from the hashes of
+// the fetched operations and compares it against the block header. It fails
+// when operations were added, removed, reordered or altered in transit.
+func (b Block) VerifyOperationListHash() error {
+	if have, want := b.OperationListListHash().String(), b.Header.OperationsHash; have != want {
+		return fmt.Errorf("rpc: block %s operations hash mismatch: header=%s computed=%s", b.Hash, want, have)
+	}
+	return nil
+}
+
+// EachOperation calls fn for every operation content in the block in
+// validation pass order and stops at the first error fn returns.
+func (b Block) EachOperation(fn func(op Operation) error) error {
+	for _, pass := range b.Operations {
+		for _, oh := range pass {
+			for _, op := range oh.Contents {
+				if err := fn(op); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Addresses returns the set of all accounts touched by operations in the
+// block, see OperationAddresses.
+func (b Block) Addresses() *tezos.AddressSet {
+	set := tezos.NewAddressSet()
+	b.EachOperation(func(op Operation) error {
+		addOperationAddresses(set, op)
+		return nil
+	})
+	return set
+}
+
+// merkleRoot computes the root of a Tezos BLAKE2b Merkle tree over leaves.
+// Leaves are hashed before pairing and odd levels are padded by repeating
+// their last node.
+func merkleRoot(leaves [][]byte) []byte {
+	n := len(leaves)
+	if n == 0 {
+		return blake2bSum()
+	}
+	nodes := make([][]byte, n+1)
+	for i, v := range leaves {
+		nodes[i] = blake2bSum(v)
+	}
+	if n == 1 {
+		return nodes[0]
+	}
+	nodes[n] = nodes[n-1]
+	for {
+		m := (n + 1) / 2
+		for i := 0; i < m; i++ {
+			nodes[i] = blake2bSum(nodes[2*i], nodes[2*i+1])
+		}
+		if m == 1 {
+			return nodes[0]
+		}
+		nodes[m] = nodes[m-1]
+		n = m
+	}
+}
+
+func blake2bSum(parts ...[]byte) []byte {
+	h, _ := blake2b.New256(nil)
+	for _, v := range parts {
+		h.Write(v)
+	}
+	return h.Sum(nil)
+}
+
 // InvalidBlock represents invalid block hash along with the errors that led to it being declared invalid
 type InvalidBlock struct {
 	Block tezos.BlockHash `json:"block"`
@@ -214,6 +304,95 @@ func (c *Client) GetBlockHeight(ctx context.Context, height int64) (*Block, erro
 	return &block, nil
 }
 
+// GetBlockRange fetches all blocks from height from to height to (inclusive)
+// using at most concurrency parallel requests and returns them in height order.
+// The first failed request cancels all outstanding requests and its error is
+// returned.
+func (c *Client) GetBlockRange(ctx context.Context, from, to int64, concurrency int) ([]*Block, error) {
+	if to < from {
+		return nil, fmt.Errorf("rpc: invalid block range %d..%d", from, to)
+	}
+	n := int(to - from + 1)
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if concurrency > n {
+		concurrency = n
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		blocks   = make([]*Block, n)
+		heights  = make(chan int64)
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for h := range heights {
+				b, err := c.GetBlockHeight(ctx, h)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("rpc: block %d: %w", h, err)
+						cancel()
+					})
+					return
+				}
+				blocks[h-from] = b
+			}
+		}()
+	}
+
+feed:
+	for h := from; h <= to; h++ {
+		select {
+		case <-ctx.Done():
+			break feed
+		case heights <- h:
+		}
+	}
+	close(heights)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// ScanAddress fetches blocks from height from to height to (inclusive) and
+// calls fn in block order for each operation touching addr as source,
+// destination, delegate, originated contract or in an internal operation.
+// Scanning stops at the first error fn returns, which is passed through.
+func (c *Client) ScanAddress(ctx context.Context, addr tezos.Address, from, to int64, fn func(op Operation) error) error {
+	if to < from {
+		return fmt.Errorf("rpc: invalid block range %d..%d", from, to)
+	}
+	for h := from; h <= to; h++ {
+		b, err := c.GetBlockHeight(ctx, h)
+		if err != nil {
+			return fmt.Errorf("rpc: block %d: %w", h, err)
+		}
+		err = b.EachOperation(func(op Operation) error {
+			if !OperationAddresses(op).Contains(addr) {
+				return nil
+			}
+			return fn(op)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetTips returns hashes of the current chain tip blocks, first in the array is the
 // current main chain.
 // https://tezos.gitlab.io/mainnet/api/rpc.html#chains-chain-id-blocks
@@ -0,0 +1,181 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"blockwatch.cc/tzgo/tezos"
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestGetBlockRange(t *testing.T) {
+	var active, peak int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		height, err := strconv.ParseInt(path.Base(r.URL.Path), 10, 64)
+		if err != nil {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		switch {
+		case height == 13:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case height > 13:
+			// stall until the request is cancelled
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		time.Sleep(time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"header":{"level":%d}}`, height)
+	})
+	ctx := context.Background()
+
+	blocks, err := c.GetBlockRange(ctx, 1, 12, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 12 {
+		t.Fatalf("expected 12 blocks, got %d", len(blocks))
+	}
+	for i, b := range blocks {
+		if b.Header.Level != int64(i+1) {
+			t.Errorf("block %d: out of order level %d", i, b.Header.Level)
+		}
+	}
+	if p := atomic.LoadInt32(&peak); p > 4 {
+		t.Errorf("concurrency limit exceeded: %d", p)
+	}
+
+	// the first error cancels outstanding requests
+	start := time.Now()
+	if _, err := c.GetBlockRange(ctx, 10, 20, 4); err == nil {
+		t.Errorf("expected error")
+	} else if ErrorStatus(errors.Unwrap(err)) != http.StatusNotFound {
+		t.Errorf("unexpected error %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("outstanding requests were not cancelled")
+	}
+
+	if _, err := c.GetBlockRange(ctx, 5, 4, 1); err == nil {
+		t.Errorf("expected error for invalid range")
+	}
+}
+
+func TestVerifyOperationListHash(t *testing.T) {
+	// mainnet blocks without operations in all four validation passes
+	b := Block{
+		Header:     BlockHeader{OperationsHash: "LLoa7bxRTKaQN2bLYoitYB6bU2DvLnBAqrVjZcvJ364cTcX2PZYKU"},
+		Operations: [][]*OperationHeader{{}, {}, {}, {}},
+	}
+	if err := b.VerifyOperationListHash(); err != nil {
+		t.Errorf("empty block: %v", err)
+	}
+
+	// a single manager operation, tree assembled by hand
+	op := tezos.MustParseOpHash("ooXrSxKx6DkzbXFfFJmKDVPPdbFuvX1gu6i9hC999aroYrnsWFw")
+	empty := blake2b.Sum256(nil)
+	list := blake2b.Sum256(op.Hash.Hash)
+	leaf := func(b []byte) []byte { h := blake2b.Sum256(b); return h[:] }
+	pair := func(a, b []byte) []byte { h := blake2b.Sum256(append(append([]byte{}, a...), b...)); return h[:] }
+	root := pair(
+		pair(leaf(empty[:]), leaf(empty[:])),
+		pair(leaf(empty[:]), leaf(list[:])),
+	)
+	b.Operations[3] = []*OperationHeader{{Hash: op}}
+	b.Header.OperationsHash = tezos.NewHash(tezos.HashTypeOperationListList, root).String()
+	if err := b.VerifyOperationListHash(); err != nil {
+		t.Errorf("single op: %v", err)
+	}
+
+	// moved, altered and removed operations are detected
+	b.Operations[2], b.Operations[3] = b.Operations[3], b.Operations[2]
+	if err := b.VerifyOperationListHash(); err == nil {
+		t.Errorf("expected error for reordered passes")
+	}
+	b.Operations[2], b.Operations[3] = b.Operations[3], b.Operations[2]
+	other := tezos.MustParseOpHash("ooXrSxKx6DkzbXFfFJmKDVPPdbFuvX1gu6i9hC999aroYrnsWFw")
+	other.Hash.Hash = append([]byte{}, other.Hash.Hash...)
+	other.Hash.Hash[0] ^= 1
+	b.Operations[3] = []*OperationHeader{{Hash: other}}
+	if err := b.VerifyOperationListHash(); err == nil {
+		t.Errorf("expected error for altered operation")
+	}
+	b.Operations[3] = nil
+	if err := b.VerifyOperationListHash(); err == nil {
+		t.Errorf("expected error for removed operation")
+	}
+}
+
+func TestScanAddress(t *testing.T) {
+	const (
+		other = "tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs"
+		tx    = `{"kind":"transaction","source":"%s","destination":"%s","amount":"%d","fee":"0","counter":"%d","gas_limit":"0","storage_limit":"0"%s}`
+	)
+	internal := `,"metadata":{"operation_result":{"status":"applied"},"internal_operation_results":[{"kind":"transaction","source":"` +
+		testContract + `","nonce":0,"destination":"` + testDelegate + `","amount":"5"}]}`
+	blocks := map[string][]string{
+		"1": {fmt.Sprintf(tx, testDelegate, other, 1, 1, "")},
+		"2": {fmt.Sprintf(tx, other, other, 2, 2, ""), fmt.Sprintf(tx, other, testContract, 3, 3, internal)},
+		"3": {`{"kind":"delegation","source":"` + other + `","fee":"0","counter":"4","gas_limit":"0","storage_limit":"0","delegate":"` + testDelegate + `"}`},
+		"4": {fmt.Sprintf(tx, other, testContract, 5, 5, "")},
+	}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		height := path.Base(r.URL.Path)
+		contents := blocks[height]
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"header":{"level":%s},"operations":[[],[],[],[{"contents":[%s]}]]}`, height, strings.Join(contents, ","))
+	})
+	addr := tezos.MustParseAddress(testDelegate)
+
+	var counters []int64
+	err := c.ScanAddress(context.Background(), addr, 1, 4, func(op Operation) error {
+		switch o := op.(type) {
+		case *TransactionOp:
+			counters = append(counters, o.Counter)
+		case *DelegationOp:
+			counters = append(counters, o.Counter)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(counters) != "[1 3 4]" {
+		t.Errorf("unexpected matches %v", counters)
+	}
+
+	// callback errors stop the scan
+	stop := errors.New("stop")
+	var n int
+	err = c.ScanAddress(context.Background(), addr, 1, 4, func(op Operation) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("expected scan to stop after first match, got %v after %d calls", err, n)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// NewOperation returns an unsigned operation group on top of branch. Use
+// Forge to obtain the bytes to sign.
+//
+//	tx := NewTransaction(src, dst, 1000000).WithFee(1420).WithCounter(11).WithLimits(10600, 300)
+//	buf, err := NewOperation(branch, tx).Forge()
+func NewOperation(branch tezos.BlockHash, ops ...Operation) *OperationHeader {
+	return &OperationHeader{
+		Branch:   branch,
+		Contents: Operations(ops),
+	}
+}
+
+// NewTransaction returns a transaction that sends amount mutez from src to
+// dst. Fee, counter and limits are zero until set.
+func NewTransaction(src, dst tezos.Address, amount int64) *TransactionOp {
+	return &TransactionOp{
+		GenericOp:   GenericOp{Kind: tezos.OpTypeTransaction},
+		Source:      src,
+		Destination: dst,
+		Amount:      amount,
+	}
+}
+
+// WithFee sets the fee in mutez.
+func (o *TransactionOp) WithFee(fee int64) *TransactionOp {
+	o.Fee = fee
+	return o
+}
+
+// WithCounter sets the source counter, usually one above the counter
+// returned by Client.GetContractCounter.
+func (o *TransactionOp) WithCounter(counter int64) *TransactionOp {
+	o.Counter = counter
+	return o
+}
+
+// WithLimits sets the gas and storage limits.
+func (o *TransactionOp) WithLimits(gasLimit, storageLimit int64) *TransactionOp {
+	o.GasLimit = gasLimit
+	o.StorageLimit = storageLimit
+	return o
+}
+
+// WithParameters sets the entrypoint and argument of a contract call.
+func (o *TransactionOp) WithParameters(params micheline.Parameters) *TransactionOp {
+	o.Parameters = &params
+	return o
+}
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -15,6 +16,9 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 const (
@@ -34,10 +38,63 @@ type Client struct {
 	UserAgent string
 	// The chain the client will query.
 	ChainID string
+	// optional behaviour like retries
+	opts ClientOptions
+	// in-flight GET requests when coalescing is enabled
+	flight singleflight.Group
+	// extra headers sent with every request
+	header http.Header
+}
+
+// ClientOptions configures optional client behaviour. The zero value disables
+// retries.
+type ClientOptions struct {
+	// MaxRetries is the number of times a failed GET request is retried.
+	MaxRetries int
+	// RetryDelay is the wait time before the first retry. It doubles with
+	// every further attempt up to MaxRetryDelay. Defaults to 100ms.
+	RetryDelay time.Duration
+	// MaxRetryDelay limits the exponential backoff, zero means no limit.
+	MaxRetryDelay time.Duration
+	// RetryOn decides whether a failed request is retried based on the HTTP
+	// status code (zero when no response was received) and the error.
+	// Defaults to DefaultRetryOn.
+	RetryOn func(status int, err error) bool
+	// Coalesce makes concurrent GET requests for the same URL share a single
+	// round-trip. All callers receive the response or error of the shared
+	// request. It runs independent of the callers' contexts, a caller whose
+	// context ends returns early while the others keep waiting. Use a timeout
+	// on the HTTP client to bound shared requests.
+	Coalesce bool
+}
+
+// DefaultRetryOn retries transport errors and 5xx server errors, but never
+// client errors or requests cancelled by their context.
+func DefaultRetryOn(status int, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return status == 0 || status >= 500
 }
 
-// NewClient returns a new Tezos RPC client.
-func NewClient(baseURL string, httpClient *http.Client) (*Client, error) {
+// backoff returns the wait time before retry attempt n (starting at 0).
+func (o ClientOptions) backoff(n int) time.Duration {
+	d := o.RetryDelay
+	if d <= 0 {
+		d = 100 * time.Millisecond
+	}
+	for i := 0; i < n && (o.MaxRetryDelay <= 0 || d < o.MaxRetryDelay); i++ {
+		d *= 2
+	}
+	if o.MaxRetryDelay > 0 && d > o.MaxRetryDelay {
+		d = o.MaxRetryDelay
+	}
+	return d
+}
+
+// NewClient returns a new Tezos RPC client. An optional ClientOptions value
+// configures retries.
+func NewClient(baseURL string, httpClient *http.Client, opts ...ClientOptions) (*Client, error) {
 	if httpClient == nil {
 		httpClient = http.DefaultClient
 	}
@@ -49,15 +106,92 @@ func NewClient(baseURL string, httpClient *http.Client) (*Client, error) {
 		return nil, err
 	}
 	c := &Client{client: httpClient, BaseURL: u, UserAgent: userAgent, ChainID: MAIN_NET}
+	if len(opts) > 0 {
+		c.opts = opts[0]
+	}
+	if c.opts.RetryOn == nil {
+		c.opts.RetryOn = DefaultRetryOn
+	}
 	return c, nil
 }
 
+// SetHTTPClient replaces the HTTP client used for requests, e.g. to configure
+// proxies, TLS client certificates or timeouts. A nil client selects
+// http.DefaultClient. Like SetHeader it should be called before the client
+// is used.
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	c.client = httpClient
+}
+
+// SetHeader sets a header which is sent with every request including monitor
+// streams, e.g. an API key for a hosted node. It replaces any value set for
+// key before, an empty value removes the header.
+func (c *Client) SetHeader(key, value string) {
+	if c.header == nil {
+		c.header = make(http.Header)
+	}
+	if value == "" {
+		c.header.Del(key)
+		return
+	}
+	c.header.Set(key, value)
+}
+
+// sensitiveHeaders are never logged in clear text.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// redactHeader returns a copy of h where the values of all headers set with
+// SetHeader and of well-known credential headers are replaced.
+func (c *Client) redactHeader(h http.Header) http.Header {
+	res := h.Clone()
+	for k := range c.header {
+		if _, ok := res[k]; ok {
+			res[k] = []string{"[redacted]"}
+		}
+	}
+	for _, k := range sensitiveHeaders {
+		if _, ok := res[k]; ok {
+			res[k] = []string{"[redacted]"}
+		}
+	}
+	return res
+}
+
 func (c *Client) Get(ctx context.Context, urlpath string, result interface{}) error {
-	req, err := c.NewRequest(ctx, http.MethodGet, urlpath, nil)
+	if !c.opts.Coalesce || result == nil {
+		req, err := c.NewRequest(ctx, http.MethodGet, urlpath, nil)
+		if err != nil {
+			return err
+		}
+		return c.Do(req, result)
+	}
+	rel, err := url.Parse(urlpath)
 	if err != nil {
 		return err
 	}
-	return c.Do(req, result)
+	// the shared request is not bound to any caller's context, callers whose
+	// context ends stop waiting without affecting the running call
+	ch := c.flight.DoChan(c.BaseURL.ResolveReference(rel).String(), func() (interface{}, error) {
+		req, err := c.NewRequest(context.Background(), http.MethodGet, urlpath, nil)
+		if err != nil {
+			return nil, err
+		}
+		var raw json.RawMessage
+		err = c.Do(req, &raw)
+		return raw, err
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return res.Err
+		}
+		return json.Unmarshal(res.Val.(json.RawMessage), result)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (c *Client) GetAsync(ctx context.Context, urlpath string, mon Monitor) error {
@@ -76,6 +210,14 @@ func (c *Client) Put(ctx context.Context, urlpath string, body, result interface
 	return c.Do(req, result)
 }
 
+func (c *Client) Post(ctx context.Context, urlpath string, body, result interface{}) error {
+	req, err := c.NewRequest(ctx, http.MethodPost, urlpath, body)
+	if err != nil {
+		return err
+	}
+	return c.Do(req, result)
+}
+
 // NewRequest creates a Tezos RPC request.
 func (c *Client) NewRequest(ctx context.Context, method, urlStr string, body interface{}) (*http.Request, error) {
 	rel, err := url.Parse(urlStr)
@@ -102,9 +244,16 @@ func (c *Client) NewRequest(ctx context.Context, method, urlStr string, body int
 	req.Header.Add("Content-Type", mediaType)
 	req.Header.Add("Accept", mediaType)
 	req.Header.Add("User-Agent", c.UserAgent)
+	for k, v := range c.header {
+		req.Header[k] = append([]string(nil), v...)
+	}
 
 	log.Debug(newLogClosure(func() string {
+		// dump with redacted credentials, DumpRequest restores the body on req
+		h := req.Header
+		req.Header = c.redactHeader(h)
 		d, _ := httputil.DumpRequest(req, true)
+		req.Header = h
 		return string(d)
 	}))
 
@@ -151,10 +300,35 @@ func (c *Client) handleResponseMonitor(ctx context.Context, resp *http.Response,
 }
 
 // Do retrieves values from the API and marshals them into the provided interface.
-func (c *Client) Do(req *http.Request, v interface{}) (err error) {
+// GET requests that fail are retried according to the client's ClientOptions
+// until the request context is done.
+func (c *Client) Do(req *http.Request, v interface{}) error {
+	for n := 0; ; n++ {
+		status, err := c.do(req, v)
+		if err == nil {
+			return nil
+		}
+		if req.Method != http.MethodGet || n >= c.opts.MaxRetries || !c.opts.RetryOn(status, err) {
+			return err
+		}
+		d := c.opts.backoff(n)
+		log.Debugf("rpc: %s %s failed (%v), retry %d/%d in %s", req.Method, req.URL.Path, err, n+1, c.opts.MaxRetries, d)
+		t := time.NewTimer(d)
+		select {
+		case <-req.Context().Done():
+			t.Stop()
+			return req.Context().Err()
+		case <-t.C:
+		}
+	}
+}
+
+// do executes a single request and returns the HTTP status code or zero when
+// no response was received.
+func (c *Client) do(req *http.Request, v interface{}) (status int, err error) {
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	defer func() {
@@ -163,8 +337,9 @@ func (c *Client) Do(req *http.Request, v interface{}) (err error) {
 		}
 	}()
 
+	status = resp.StatusCode
 	if resp.StatusCode == http.StatusNoContent {
-		return nil
+		return status, nil
 	}
 
 	log.Trace(newLogClosure(func() string {
@@ -175,12 +350,12 @@ func (c *Client) Do(req *http.Request, v interface{}) (err error) {
 	statusClass := resp.StatusCode / 100
 	if statusClass == 2 {
 		if v == nil {
-			return nil
+			return status, nil
 		}
-		return c.handleResponse(req.Context(), resp, v)
+		return status, c.handleResponse(req.Context(), resp, v)
 	}
 
-	return handleError(resp)
+	return status, handleError(resp)
 }
 
 // DoAsync retrieves values from the API and sends responses using the provided monitor.
@@ -0,0 +1,316 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client connected to a mock server using handler.
+// The server is closed when the test ends.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c, err := NewClient(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	return c
+}
+
+// serveJSON returns a handler that checks the request path and query
+// and replies with body.
+func serveJSON(t *testing.T, path, query, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			t.Errorf("unexpected path want=%s got=%s", path, r.URL.Path)
+		}
+		if r.URL.RawQuery != query {
+			t.Errorf("unexpected query want=%s got=%s", query, r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/flaky":
+			if n < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`42`))
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+	c, err := NewClient(srv.URL, nil, ClientOptions{MaxRetries: 3, RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// succeeds after two 5xx responses
+	var v int
+	if err := c.Get(ctx, "flaky", &v); err != nil || v != 42 {
+		t.Errorf("flaky: unexpected result %d err=%v", v, err)
+	}
+	if n := atomic.SwapInt32(&hits, 0); n != 3 {
+		t.Errorf("flaky: expected 3 requests, got %d", n)
+	}
+
+	// 4xx is not retried
+	if err := c.Get(ctx, "missing", &v); ErrorStatus(err) != http.StatusNotFound {
+		t.Errorf("missing: unexpected error %v", err)
+	}
+	if n := atomic.SwapInt32(&hits, 0); n != 1 {
+		t.Errorf("missing: expected 1 request, got %d", n)
+	}
+
+	// gives up after max retries
+	if err := c.Get(ctx, "down", &v); ErrorStatus(err) != http.StatusBadGateway {
+		t.Errorf("down: unexpected error %v", err)
+	}
+	if n := atomic.SwapInt32(&hits, 0); n != 4 {
+		t.Errorf("down: expected 4 requests, got %d", n)
+	}
+
+	// honors context cancellation while waiting
+	c, _ = NewClient(srv.URL, nil, ClientOptions{MaxRetries: 10, RetryDelay: time.Hour})
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := c.Get(ctx, "down", &v); err != context.DeadlineExceeded {
+		t.Errorf("cancel: expected deadline error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("cancel: retry did not stop on context cancellation")
+	}
+}
+
+func TestClientRetryBackoff(t *testing.T) {
+	o := ClientOptions{RetryDelay: 10 * time.Millisecond, MaxRetryDelay: 50 * time.Millisecond}
+	for i, want := range []time.Duration{10, 20, 40, 50, 50} {
+		if got := o.backoff(i); got != want*time.Millisecond {
+			t.Errorf("attempt %d: want=%s got=%s", i, want*time.Millisecond, got)
+		}
+	}
+}
+
+func TestClientCoalesce(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"level":42}`))
+	})
+	c.opts.Coalesce = true
+
+	const n = 50
+	var (
+		wg     sync.WaitGroup
+		errs   = make(chan error, n)
+		levels = make([]int64, n)
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var res struct {
+				Level int64 `json:"level"`
+			}
+			errs <- c.Get(context.Background(), "chains/main/blocks/head/header", &res)
+			levels[i] = res.Level
+		}(i)
+	}
+	// let all callers join the in-flight request before it completes
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if h := atomic.LoadInt32(&hits); h != 1 {
+		t.Errorf("expected a single backend hit, got %d", h)
+	}
+	for i, l := range levels {
+		if l != 42 {
+			t.Errorf("caller %d: unexpected result %d", i, l)
+		}
+	}
+
+	// later requests are not served from a completed call
+	if err := c.Get(context.Background(), "chains/main/blocks/head/header", &struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if h := atomic.LoadInt32(&hits); h != 2 {
+		t.Errorf("expected a new backend hit, got %d", h)
+	}
+}
+
+func TestClientCoalesceWaiterCancel(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"level":42}`))
+	})
+	c.opts.Coalesce = true
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Get(context.Background(), "chains/main/blocks/head/header", &struct{}{})
+	}()
+	<-started
+
+	// a waiter whose context ends returns without affecting the running call
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.Get(ctx, "chains/main/blocks/head/header", &struct{}{}); err != context.Canceled {
+		t.Errorf("expected canceled waiter, got %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error for running call: %v", err)
+	}
+}
+
+func TestClientCoalesceFirstCancel(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	started := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			close(started)
+		}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"level":42}`))
+	})
+	c.opts.Coalesce = true
+
+	// the first caller starts the shared request and gives up
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		first <- c.Get(ctx, "chains/main/blocks/head/header", &struct{}{})
+	}()
+	<-started
+
+	second := make(chan error, 1)
+	var res struct {
+		Level int64 `json:"level"`
+	}
+	go func() {
+		second <- c.Get(context.Background(), "chains/main/blocks/head/header", &res)
+	}()
+	// let the second caller join before the first one leaves
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-first; err != context.Canceled {
+		t.Errorf("expected canceled first caller, got %v", err)
+	}
+	close(release)
+	if err := <-second; err != nil || res.Level != 42 {
+		t.Errorf("second caller: unexpected result %d err=%v", res.Level, err)
+	}
+	if h := atomic.LoadInt32(&hits); h != 1 {
+		t.Errorf("expected a single backend hit, got %d", h)
+	}
+}
+
+func TestClientHeaders(t *testing.T) {
+	var seen int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Api-Key"); got != "secret" {
+			t.Errorf("%s: unexpected api key %q", r.URL.Path, got)
+		}
+		if got := r.Header.Get("X-Removed"); got != "" {
+			t.Errorf("%s: unexpected removed header %q", r.URL.Path, got)
+		}
+		atomic.AddInt32(&seen, 1)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/monitor/bootstrapped":
+			w.Write([]byte(`{"block":"BLockGenesisGenesisGenesisGenesisGenesisf79b5d1CoW2","timestamp":"2018-06-30T16:07:32Z"}`))
+		default:
+			w.Write([]byte(`{"level":42}`))
+		}
+	})
+	c.SetHeader("X-Api-Key", "secret")
+	c.SetHeader("X-Removed", "x")
+	c.SetHeader("X-Removed", "")
+
+	// custom clients keep the headers
+	c.SetHTTPClient(&http.Client{Timeout: time.Second})
+	if err := c.Get(context.Background(), "chains/main/blocks/head/header", &struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// monitor streams send them too
+	mon := NewBootstrapMonitor()
+	defer mon.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c.MonitorBootstrapped(ctx, mon); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mon.Recv(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&seen); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestClientRedactHeader(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	c.SetHeader("X-Api-Key", "secret")
+	req, err := c.NewRequest(context.Background(), http.MethodPost, "injection/operation", "00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer token")
+	h := c.redactHeader(req.Header)
+	for _, k := range []string{"X-Api-Key", "Authorization"} {
+		if got := h.Get(k); got != "[redacted]" {
+			t.Errorf("%s: expected redacted value, got %q", k, got)
+		}
+	}
+	if got := h.Get("User-Agent"); got != c.UserAgent {
+		t.Errorf("unexpected user agent %q", got)
+	}
+	// the request itself is not modified
+	if got := req.Header.Get("X-Api-Key"); got != "secret" {
+		t.Errorf("request header was modified: %q", got)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != "\"00\"\n" {
+		t.Errorf("request body was consumed: %q", body)
+	}
+}
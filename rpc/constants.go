@@ -67,6 +67,50 @@ type Constants struct {
 	LiquidityBakingSubsidy            int64 `json:"liquidity_baking_subsidy,string"`
 	LiquidityBakingSunsetLevel        int64 `json:"liquidity_baking_sunset_level"`
 	MinimalBlockDelay                 int   `json:"minimal_block_delay,string"`
+
+	// New in v12 (Tenderbake)
+	BlocksPerStakeSnapshot                           int64         `json:"blocks_per_stake_snapshot"`
+	CyclesPerVotingPeriod                            int64         `json:"cycles_per_voting_period"`
+	MaxOperationsTTL                                 int64         `json:"max_operations_time_to_live"`
+	DelayIncrementPerRound                           int           `json:"delay_increment_per_round,string"`
+	ConsensusCommitteeSize                           int           `json:"consensus_committee_size"`
+	ConsensusThreshold                               int           `json:"consensus_threshold"`
+	MinimalParticipationRatio                        ConstantRatio `json:"minimal_participation_ratio"`
+	MaxSlashingPeriod                                int64         `json:"max_slashing_period"`
+	FrozenDepositsPercentage                         int           `json:"frozen_deposits_percentage"`
+	DoubleBakingPunishment                           int64         `json:"double_baking_punishment,string"`
+	RatioOfFrozenDepositsSlashedPerDoubleEndorsement ConstantRatio `json:"ratio_of_frozen_deposits_slashed_per_double_endorsement"`
+	BakingRewardFixedPortion                         int64         `json:"baking_reward_fixed_portion,string"`
+	BakingRewardBonusPerSlot                         int64         `json:"baking_reward_bonus_per_slot,string"`
+	EndorsingRewardPerSlot                           int64         `json:"endorsing_reward_per_slot,string"`
+
+	// New in v18
+	MinimalStake       int64 `json:"minimal_stake,string"`
+	MinimalFrozenStake int64 `json:"minimal_frozen_stake,string"`
+
+	// New in v19, replaces preserved_cycles
+	ConsensusRightsDelay              int64 `json:"consensus_rights_delay"`
+	BlocksPreservationCycles          int64 `json:"blocks_preservation_cycles"`
+	DelegateParametersActivationDelay int64 `json:"delegate_parameters_activation_delay"`
+}
+
+// ConstantRatio is a fractional protocol constant.
+type ConstantRatio struct {
+	Numerator   int64 `json:"numerator"`
+	Denominator int64 `json:"denominator"`
+}
+
+// GetBlockDelay returns the minimal time between blocks in the first round.
+func (c Constants) GetBlockDelay() time.Duration {
+	if c.MinimalBlockDelay > 0 {
+		return time.Duration(c.MinimalBlockDelay) * time.Second
+	}
+	if len(c.TimeBetweenBlocks) > 0 {
+		if val, err := strconv.ParseInt(c.TimeBetweenBlocks[0], 10, 64); err == nil {
+			return time.Duration(val) * time.Second
+		}
+	}
+	return 0
 }
 
 func (c Constants) HaveV6Rewards() bool {
@@ -177,6 +221,9 @@ func (c Constants) MapToChainParams() *tezos.Params {
 	p.BlocksPerCycle = c.BlocksPerCycle
 	p.BlocksPerCommitment = c.BlocksPerCommitment
 	p.BlocksPerRollSnapshot = c.BlocksPerRollSnapshot
+	if p.BlocksPerRollSnapshot == 0 {
+		p.BlocksPerRollSnapshot = c.BlocksPerStakeSnapshot
+	}
 	p.BlocksPerVotingPeriod = c.BlocksPerVotingPeriod
 	p.EndorsersPerBlock = c.EndorsersPerBlock
 	p.HardGasLimitPerOperation = c.HardGasLimitPerOperation
@@ -211,6 +258,9 @@ func (c Constants) MapToChainParams() *tezos.Params {
 	p.LiquidityBakingSubsidy = c.LiquidityBakingSubsidy
 	p.LiquidityBakingSunsetLevel = c.LiquidityBakingSunsetLevel
 	p.MinimalBlockDelay = time.Duration(c.MinimalBlockDelay) * time.Second
+	if c.MaxOperationsTTL > 0 {
+		p.MaxOperationsTTL = c.MaxOperationsTTL
+	}
 
 	for i, v := range c.TimeBetweenBlocks {
 		if i > 1 {
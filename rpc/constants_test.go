@@ -0,0 +1,77 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func TestGetConstants(t *testing.T) {
+	// trimmed Paris constants without any pre-Tenderbake fields
+	body := `{
+		"consensus_rights_delay": 2,
+		"blocks_preservation_cycles": 1,
+		"delegate_parameters_activation_delay": 5,
+		"blocks_per_cycle": 16384,
+		"blocks_per_commitment": 128,
+		"nonce_revelation_threshold": 512,
+		"cycles_per_voting_period": 5,
+		"hard_gas_limit_per_operation": "1040000",
+		"hard_gas_limit_per_block": "2600000",
+		"proof_of_work_threshold": "-1",
+		"minimal_stake": "6000000000",
+		"minimal_frozen_stake": "600000000",
+		"cost_per_byte": "250",
+		"hard_storage_limit_per_operation": "60000",
+		"quorum_min": 2000,
+		"quorum_max": 7000,
+		"min_proposal_quorum": 500,
+		"liquidity_baking_subsidy": "5000000",
+		"max_operations_time_to_live": 240,
+		"minimal_block_delay": "10",
+		"delay_increment_per_round": "5",
+		"consensus_committee_size": 7000,
+		"consensus_threshold": 4667,
+		"minimal_participation_ratio": {"numerator": 2, "denominator": 3},
+		"max_slashing_period": 2,
+		"origination_size": 257
+	}`
+	c := newTestClient(t, serveJSON(t, "/chains/main/blocks/"+testBlock+"/context/constants", "", body))
+	con, err := c.GetConstants(context.Background(), tezos.MustParseBlockHash(testBlock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if con.BlocksPerCycle != 16384 || con.ConsensusRightsDelay != 2 || con.MinimalStake != 6000000000 {
+		t.Errorf("unexpected constants %#v", con)
+	}
+	if con.MinimalParticipationRatio != (ConstantRatio{2, 3}) {
+		t.Errorf("ratio mismatch got=%v", con.MinimalParticipationRatio)
+	}
+	if got := con.GetBlockDelay(); got != 10*time.Second {
+		t.Errorf("block delay mismatch got=%s", got)
+	}
+	// removed fields are zero
+	if con.PreservedCycles != 0 || con.EndorsersPerBlock != 0 || con.BlocksPerRollSnapshot != 0 {
+		t.Errorf("expected zero values for missing fields")
+	}
+	p := con.MapToChainParams()
+	if p.MaxOperationsTTL != 240 || p.HardGasLimitPerBlock != 2600000 || p.MinimalBlockDelay != 10*time.Second {
+		t.Errorf("params mismatch %#v", p)
+	}
+
+	// legacy constants fall back to time_between_blocks
+	var old Constants
+	if err := json.Unmarshal([]byte(`{"blocks_per_cycle":4096,"time_between_blocks":["60","40"],"block_reward":"16000000"}`), &old); err != nil {
+		t.Fatal(err)
+	}
+	if old.GetBlockDelay() != time.Minute || old.GetBlockReward() != 16000000 || old.MaxOperationsTTL != 0 {
+		t.Errorf("unexpected legacy constants %#v", old)
+	}
+}
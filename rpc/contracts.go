@@ -5,7 +5,9 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"strconv"
 
 	"blockwatch.cc/tzgo/micheline"
@@ -61,6 +63,18 @@ func (c *Client) GetContractBalanceHeight(ctx context.Context, addr tezos.Addres
 	return strconv.ParseInt(bal, 10, 64)
 }
 
+// GetContractCounter returns the last used counter of an implicit account at block.
+// https://tezos.gitlab.io/tezos/api/rpc.html#get-block-id-context-contracts-contract-id-counter
+func (c *Client) GetContractCounter(ctx context.Context, addr tezos.Address, blockID tezos.BlockHash) (int64, error) {
+	u := fmt.Sprintf("chains/%s/blocks/%s/context/contracts/%s/counter", c.ChainID, blockID, addr)
+	var counter string
+	err := c.Get(ctx, u, &counter)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(counter, 10, 64)
+}
+
 // GetContractScript returns the originated contract script
 func (c *Client) GetContractScript(ctx context.Context, addr tezos.Address) (*micheline.Script, error) {
 	u := fmt.Sprintf("chains/%s/blocks/head/context/contracts/%s/script", c.ChainID, addr)
@@ -72,6 +86,33 @@ func (c *Client) GetContractScript(ctx context.Context, addr tezos.Address) (*mi
 	return s, nil
 }
 
+// GetContractScriptRaw returns the originated contract script at block as
+// unmodified JSON as delivered by the node.
+// https://tezos.gitlab.io/tezos/api/rpc.html#get-block-id-context-contracts-contract-id-script
+func (c *Client) GetContractScriptRaw(ctx context.Context, addr tezos.Address, blockID tezos.BlockHash) (json.RawMessage, error) {
+	u := fmt.Sprintf("chains/%s/blocks/%s/context/contracts/%s/script", c.ChainID, blockID, addr)
+	var raw json.RawMessage
+	if err := c.Get(ctx, u, &raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// GetContractCodeHash returns the canonical code hash of a contract at block as
+// computed by micheline.Script.CanonicalCodeHash. Contracts with equal code
+// produce the same hash regardless of their current storage.
+func (c *Client) GetContractCodeHash(ctx context.Context, addr tezos.Address, blockID tezos.BlockHash) ([32]byte, error) {
+	raw, err := c.GetContractScriptRaw(ctx, addr, blockID)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	s := micheline.NewScript()
+	if err := json.Unmarshal(raw, s); err != nil {
+		return [32]byte{}, fmt.Errorf("rpc: decoding script: %w", err)
+	}
+	return s.CanonicalCodeHash(), nil
+}
+
 // GetContractStorage returns the most recent version of the contract's storage
 func (c *Client) GetContractStorage(ctx context.Context, addr tezos.Address) (micheline.Prim, error) {
 	u := fmt.Sprintf("chains/%s/blocks/head/context/contracts/%s/storage", c.ChainID, addr)
@@ -94,6 +135,27 @@ func (c *Client) GetContractStorageHeight(ctx context.Context, addr tezos.Addres
 	return prim, nil
 }
 
+// StorageDiff returns the leaf-level changes of a contract's storage between
+// fromLevel and toLevel. Both storage states are decoded with the storage
+// type of the contract script at toLevel.
+func (c *Client) StorageDiff(ctx context.Context, addr tezos.Address, fromLevel, toLevel int64) ([]micheline.ValueDiff, error) {
+	u := fmt.Sprintf("chains/%s/blocks/%d/context/contracts/%s/script", c.ChainID, toLevel, addr)
+	s := micheline.NewScript()
+	if err := c.Get(ctx, u, s); err != nil {
+		return nil, err
+	}
+	before, err := c.GetContractStorageHeight(ctx, addr, fromLevel)
+	if err != nil {
+		return nil, err
+	}
+	after, err := c.GetContractStorageHeight(ctx, addr, toLevel)
+	if err != nil {
+		return nil, err
+	}
+	typ := s.StorageType()
+	return micheline.DiffValues(micheline.NewValue(typ, before), micheline.NewValue(typ, after))
+}
+
 // GetContractEntrypoints returns the contract's entrypoints
 func (c *Client) GetContractEntrypoints(ctx context.Context, addr tezos.Address) (map[string]micheline.Prim, error) {
 	u := fmt.Sprintf("chains/%s/blocks/head/context/contracts/%s/storage", c.ChainID, addr)
@@ -119,6 +181,71 @@ func (c *Client) GetBigmapKeys(ctx context.Context, id int64) ([]tezos.ExprHash,
 	return hashes, nil
 }
 
+// GetBigmapKeysPaged returns up to limit active keys in the bigmap id at block
+// starting at offset using the node's offset/length query arguments. It
+// returns io.EOF when no keys exist at or beyond offset.
+func (c *Client) GetBigmapKeysPaged(ctx context.Context, id int64, blockID tezos.BlockHash, offset, limit int) ([]tezos.ExprHash, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, fmt.Errorf("rpc: invalid bigmap key range offset=%d limit=%d", offset, limit)
+	}
+	u := fmt.Sprintf("chains/%s/blocks/%s/context/raw/json/big_maps/index/%d/contents?offset=%d&length=%d", c.ChainID, blockID, id, offset, limit)
+	hashes := make([]tezos.ExprHash, 0, limit)
+	err := c.Get(ctx, u, &hashes)
+	if err != nil {
+		return nil, err
+	}
+	if len(hashes) == 0 {
+		return nil, io.EOF
+	}
+	return hashes, nil
+}
+
+// BigmapKeyIterator fetches the keys of a bigmap in chunks of a fixed size.
+type BigmapKeyIterator struct {
+	Block tezos.BlockHash // all pages are read at this block
+	pages *PageIterator
+}
+
+// NewBigmapKeyIterator returns an iterator over keys in bigmap id which
+// fetches at most limit keys per call. The current head is resolved once so
+// that all pages are read from the same block even when new blocks arrive
+// during iteration.
+func (c *Client) NewBigmapKeyIterator(ctx context.Context, id int64, limit int) (*BigmapKeyIterator, error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("rpc: invalid bigmap key limit %d", limit)
+	}
+	var block tezos.BlockHash
+	u := fmt.Sprintf("chains/%s/blocks/head/hash", c.ChainID)
+	if err := c.Get(ctx, u, &block); err != nil {
+		return nil, err
+	}
+	fetch := func(ctx context.Context, offset, limit int) ([]interface{}, error) {
+		hashes, err := c.GetBigmapKeysPaged(ctx, id, block, offset, limit)
+		if err != nil {
+			return nil, err
+		}
+		page := make([]interface{}, len(hashes))
+		for i, v := range hashes {
+			page[i] = v
+		}
+		return page, nil
+	}
+	return &BigmapKeyIterator{Block: block, pages: NewPageIterator(fetch, limit)}, nil
+}
+
+// Next returns the next chunk of keys or io.EOF when all keys have been read.
+func (it *BigmapKeyIterator) Next(ctx context.Context) ([]tezos.ExprHash, error) {
+	page, err := it.pages.NextPage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]tezos.ExprHash, len(page))
+	for i, v := range page {
+		hashes[i] = v.(tezos.ExprHash)
+	}
+	return hashes, nil
+}
+
 // GetBigmapValue returns current active value at key hash from bigmap id
 func (c *Client) GetBigmapValue(ctx context.Context, id int64, hash tezos.ExprHash) (micheline.Prim, error) {
 	u := fmt.Sprintf("chains/%s/blocks/head/context/raw/json/big_maps/index/%d/contents/%s", c.ChainID, id, hash)
@@ -0,0 +1,201 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+const testContract = "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"
+
+func TestGetContractScriptRaw(t *testing.T) {
+	// two manager.tz scripts which only differ in storage
+	s1, err := micheline.MakeManagerScript(bytes.Repeat([]byte{1}, 21))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := micheline.MakeManagerScript(bytes.Repeat([]byte{2}, 21))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// BLAKE2b-256 of the binary encoded manager.tz code section
+	want := "0971214b3a989aa6c61e640d719974df6f7dbf0b00b5b53329bae10696f5084c"
+
+	for i, s := range []*micheline.Script{s1, s2} {
+		body, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := newTestClient(t, serveJSON(t,
+			"/chains/main/blocks/"+testBlock+"/context/contracts/"+testContract+"/script",
+			"",
+			string(body),
+		))
+		addr := tezos.MustParseAddress(testContract)
+		block := tezos.MustParseBlockHash(testBlock)
+		raw, err := c.GetContractScriptRaw(context.Background(), addr, block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(raw, body) {
+			t.Errorf("script %d: raw script was modified:\n  want=%s\n  got=%s", i, body, raw)
+		}
+		h, err := c.GetContractCodeHash(context.Background(), addr, block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(h[:]) != want {
+			t.Errorf("script %d: code hash mismatch want=%s got=%x", i, want, h)
+		}
+	}
+}
+
+func TestBigmapKeyIterator(t *testing.T) {
+	keys := []string{
+		"exprtWuZmD5SpVP9yd342kfDqCHVjzMzvCXwng3onimzMLDTCnhqdE",
+		"exprtWxyyYcc47bxg4KRxcw9J7pZ6Y1DHJKvp8usU8esS3whjYHHSf",
+		"exprtX3tpkJTPJFjXJGcTboaQQQT6cP6yuUJAMNjY9EkLgL1WBJR5y",
+		"exprtX4tNg2Ws3PXkkDCpe22j3v9tvGfrERrjugzYSQ3dcWLrUCicG",
+		"exprtWuZmD5SpVP9yd342kfDqCHVjzMzvCXwng3onimzMLDTCnhqdE",
+	}
+	var calls int
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/chains/main/blocks/head/hash" {
+			// head moves on after the first request
+			if calls == 0 {
+				w.Write([]byte(`"` + testBlock + `"`))
+			} else {
+				w.Write([]byte(`"BLockGenesisGenesisGenesisGenesisGenesisf79b5d1CoW2"`))
+			}
+			return
+		}
+		calls++
+		if r.URL.Path != "/chains/main/blocks/"+testBlock+"/context/raw/json/big_maps/index/42/contents" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		length, _ := strconv.Atoi(r.URL.Query().Get("length"))
+		page := []string{}
+		for i := offset; i < len(keys) && i < offset+length; i++ {
+			page = append(page, keys[i])
+		}
+		json.NewEncoder(w).Encode(page)
+	})
+
+	ctx := context.Background()
+	if _, err := c.NewBigmapKeyIterator(ctx, 42, -1); err == nil {
+		t.Errorf("expected error for negative limit")
+	}
+	it, err := c.NewBigmapKeyIterator(ctx, 42, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it.Block.String() != testBlock {
+		t.Errorf("iterator block mismatch want=%s got=%s", testBlock, it.Block)
+	}
+	var got []tezos.ExprHash
+	for {
+		page, err := it.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) > 2 {
+			t.Fatalf("page exceeds limit: %d", len(page))
+		}
+		got = append(got, page...)
+	}
+	if len(got) != len(keys) {
+		t.Fatalf("expected %d keys, got %d", len(keys), len(got))
+	}
+	for i := range keys {
+		if got[i].String() != keys[i] {
+			t.Errorf("key %d: want=%s got=%s", i, keys[i], got[i])
+		}
+	}
+	// the short last page ends iteration without another request
+	if calls != 3 {
+		t.Errorf("expected 3 requests, got %d", calls)
+	}
+	if _, err := it.Next(ctx); err != io.EOF {
+		t.Errorf("expected io.EOF after exhaustion, got %v", err)
+	}
+
+	// offset past the end
+	block := tezos.MustParseBlockHash(testBlock)
+	if _, err := c.GetBigmapKeysPaged(ctx, 42, block, 10, 2); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+	// invalid ranges fail before any request
+	calls = 0
+	for _, v := range [][2]int{{0, 0}, {0, -1}, {-1, 2}} {
+		if _, err := c.GetBigmapKeysPaged(ctx, 42, block, v[0], v[1]); err == nil || err == io.EOF {
+			t.Errorf("offset=%d limit=%d: expected range error, got %v", v[0], v[1], err)
+		}
+	}
+	if calls != 0 {
+		t.Errorf("expected no requests for invalid ranges, got %d", calls)
+	}
+}
+
+func TestStorageDiff(t *testing.T) {
+	script := `{"code":[
+		{"prim":"parameter","args":[{"prim":"unit"}]},
+		{"prim":"storage","args":[{"prim":"pair","args":[
+			{"prim":"map","annots":["%ledger"],"args":[{"prim":"address"},{"prim":"nat"}]},
+			{"prim":"nat","annots":["%total"]}
+		]}]},
+		{"prim":"code","args":[[{"prim":"CDR"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]]}
+	],"storage":{"prim":"Pair","args":[[],{"int":"0"}]}}`
+	storage := map[string]string{
+		"100": `{"prim":"Pair","args":[[{"prim":"Elt","args":[{"string":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"},{"int":"10"}]}],{"int":"10"}]}`,
+		"200": `{"prim":"Pair","args":[[{"prim":"Elt","args":[{"string":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"},{"int":"4"}]},{"prim":"Elt","args":[{"string":"tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs"},{"int":"6"}]}],{"int":"10"}]}`,
+	}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		for level, body := range storage {
+			switch r.URL.Path {
+			case "/chains/main/blocks/" + level + "/context/contracts/" + testContract + "/storage":
+				w.Write([]byte(body))
+				return
+			case "/chains/main/blocks/" + level + "/context/contracts/" + testContract + "/script":
+				if level != "200" {
+					t.Errorf("script should be loaded at the target level, got %s", level)
+				}
+				w.Write([]byte(script))
+				return
+			}
+		}
+		t.Errorf("unexpected path %s", r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	diff, err := c.StorageDiff(context.Background(), tezos.MustParseAddress(testContract), 100, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff) != 2 {
+		t.Fatalf("expected 2 changes, got %v", diff)
+	}
+	if d := diff[0]; d.Path != "ledger.tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb" || d.Kind != micheline.ValueDiffChanged {
+		t.Errorf("unexpected first change %#v", d)
+	}
+	if d := diff[1]; d.Path != "ledger.tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs" || d.Kind != micheline.ValueDiffAdded {
+		t.Errorf("unexpected second change %#v", d)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// DalPublishCommitmentOp represents the publication of a data availability
+// layer slot commitment. Before v019 this operation was named
+// dal_publish_slot_header.
+type DalPublishCommitmentOp struct {
+	GenericOp
+	Source       tezos.Address  `json:"source"`
+	Fee          int64          `json:"fee,string"`
+	Counter      int64          `json:"counter,string"`
+	GasLimit     int64          `json:"gas_limit,string"`
+	StorageLimit int64          `json:"storage_limit,string"`
+	SlotHeader   DalSlotHeader  `json:"slot_header"`
+	Metadata     *DalOpMetadata `json:"metadata"`
+}
+
+// DalSlotHeader identifies a published DAL slot. Operations carry the slot
+// index while operation results use index and add the published level.
+type DalSlotHeader struct {
+	SlotIndex       int    `json:"slot_index"`
+	Index           int    `json:"index"`
+	Level           int64  `json:"level"`
+	Commitment      string `json:"commitment"`
+	CommitmentProof string `json:"commitment_proof,omitempty"`
+}
+
+// GetIndex returns the slot index regardless of where it was encoded.
+func (h DalSlotHeader) GetIndex() int {
+	if h.SlotIndex != 0 {
+		return h.SlotIndex
+	}
+	return h.Index
+}
+
+// DalOpMetadata represents DAL operation metadata
+type DalOpMetadata struct {
+	BalanceUpdates BalanceUpdates `json:"balance_updates"` // fee-related
+	Result         DalResult      `json:"operation_result"`
+}
+
+// DalResult represents the result of a DAL commitment publication
+type DalResult struct {
+	ConsumedMilliGas int64            `json:"consumed_milligas,string"`
+	Status           tezos.OpStatus   `json:"status"`
+	Errors           []OperationError `json:"errors,omitempty"`
+	SlotHeader       DalSlotHeader    `json:"slot_header"`
+}
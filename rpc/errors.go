@@ -7,6 +7,9 @@ package rpc
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+
+	"blockwatch.cc/tzgo/micheline"
 )
 
 const (
@@ -180,3 +183,77 @@ var (
 	_ Error    = Errors{}
 	_ RPCError = &rpcError{}
 )
+
+// errorMessages maps protocol independent error ids to readable explanations.
+var errorMessages = map[string]string{
+	"michelson_v1.script_rejected":           "contract rejected the call",
+	"michelson_v1.runtime_error":             "contract execution failed",
+	"michelson_v1.script_overflow":           "arithmetic overflow during contract execution",
+	"michelson_v1.bad_contract_parameter":    "invalid contract call parameters",
+	"michelson_v1.ill_typed_data":            "value does not match the expected type",
+	"michelson_v1.ill_typed_contract":        "contract code is ill-typed",
+	"gas_exhausted.operation":                "operation ran out of gas, increase the gas limit",
+	"gas_exhausted.block":                    "block gas limit reached, retry in a later block",
+	"gas_limit_too_high":                     "gas limit exceeds the protocol maximum",
+	"storage_exhausted.operation":            "operation ran out of storage, increase the storage limit",
+	"storage_limit_too_high":                 "storage limit exceeds the protocol maximum",
+	"contract.balance_too_low":               "balance too low",
+	"tez.subtraction_underflow":              "balance too low",
+	"contract.counter_in_the_past":           "counter already used, the operation was likely injected before",
+	"contract.counter_in_the_future":         "counter too high, a previous operation is still pending",
+	"contract.non_existing_contract":         "contract does not exist",
+	"contract.empty_transaction":             "transaction of zero tez to an implicit account",
+	"implicit.empty_implicit_contract":       "account is empty",
+	"contract.manager.unregistered_delegate": "delegate is not registered as baker",
+	"delegate.unchanged":                     "delegate is already set",
+	"operation.invalid_signature":            "invalid operation signature",
+	"prefilter.fees_too_low":                 "fee too low for the mempool",
+}
+
+// DescribeError returns a readable explanation for an operation error. The
+// rejected value of a failed contract call and balance details are included
+// when present, unknown error ids are returned unchanged.
+func DescribeError(e OperationError) string {
+	id := trimProtoPrefix(e.ID)
+	msg, ok := errorMessages[id]
+	if !ok {
+		return e.ID
+	}
+	switch {
+	case e.With != nil:
+		msg += " with " + describePrim(*e.With)
+	case id == "contract.balance_too_low" && e.Contract != nil:
+		msg = fmt.Sprintf("%s: %s has %d mutez, needs %d", msg, e.Contract, e.Balance, e.Amount)
+	case e.Contract != nil:
+		msg += ": " + e.Contract.String()
+	}
+	return msg
+}
+
+// trimProtoPrefix strips the protocol part of error ids like
+// proto.017-PtNairob.michelson_v1.script_rejected.
+func trimProtoPrefix(id string) string {
+	if !strings.HasPrefix(id, "proto.") {
+		return id
+	}
+	id = strings.TrimPrefix(id, "proto.")
+	if i := strings.IndexByte(id, '.'); i >= 0 {
+		return id[i+1:]
+	}
+	return id
+}
+
+func describePrim(p micheline.Prim) string {
+	switch p.Type {
+	case micheline.PrimString:
+		return fmt.Sprintf("%q", p.String)
+	case micheline.PrimInt:
+		if p.Int != nil {
+			return p.Int.Text(10)
+		}
+		return "0"
+	default:
+		buf, _ := json.Marshal(p)
+		return string(buf)
+	}
+}
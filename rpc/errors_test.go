@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package rpc
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDescribeError(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		JSON string
+		Want string
+	}{
+		{
+			Name: "script_rejected",
+			JSON: `{"kind":"temporary","id":"proto.010-PtGRANAD.michelson_v1.script_rejected","location":517,"with":{"string":"FA2_INSUFFICIENT_BALANCE"}}`,
+			Want: `contract rejected the call with "FA2_INSUFFICIENT_BALANCE"`,
+		},
+		{
+			Name: "script_rejected pair",
+			JSON: `{"kind":"temporary","id":"proto.010-PtGRANAD.michelson_v1.script_rejected","location":12,"with":{"prim":"Pair","args":[{"int":"11"},{"string":"x"}]}}`,
+			Want: `contract rejected the call with {"args":[{"int":"11"},{"string":"x"}],"prim":"Pair"}`,
+		},
+		{
+			Name: "gas_exhausted",
+			JSON: `{"kind":"temporary","id":"proto.009-PsFLoren.gas_exhausted.operation"}`,
+			Want: "operation ran out of gas, increase the gas limit",
+		},
+		{
+			Name: "balance_too_low",
+			JSON: `{"kind":"temporary","id":"proto.009-PsFLoren.contract.balance_too_low","contract":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb","balance":"100","amount":"2000"}`,
+			Want: "balance too low: tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb has 100 mutez, needs 2000",
+		},
+		{
+			Name: "unknown",
+			JSON: `{"kind":"permanent","id":"proto.009-PsFLoren.some.new_error"}`,
+			Want: "proto.009-PsFLoren.some.new_error",
+		},
+	} {
+		var e OperationError
+		if err := json.Unmarshal([]byte(test.JSON), &e); err != nil {
+			t.Fatalf("%s: %v", test.Name, err)
+		}
+		if got := DescribeError(e); got != test.Want {
+			t.Errorf("%s:\n  want=%s\n  got= %s", test.Name, test.Want, got)
+		}
+	}
+}
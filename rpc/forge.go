@@ -0,0 +1,219 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// forgeParams selects the post-Babylon (v005+) operation tags which are the
+// only binary format supported by Forge.
+var forgeParams = &tezos.Params{OperationTagsVersion: 1}
+
+// forger is implemented by operation contents that can be re-serialized
+// into their binary form.
+type forger interface {
+	EncodeBuffer(buf *bytes.Buffer) error
+}
+
+// Forge returns the binary encoding of the operation group as signed by its
+// sender, i.e. the branch followed by all contents without signature. Only
+// manager operations (reveal, transaction, origination, delegation) are
+// supported.
+func (h OperationHeader) Forge() ([]byte, error) {
+	if len(h.Branch.Hash.Hash) != 32 {
+		return nil, fmt.Errorf("rpc: forge: invalid branch %q", h.Branch)
+	}
+	buf := bytes.NewBuffer(nil)
+	buf.Write(h.Branch.Hash.Hash)
+	for _, op := range h.Contents {
+		f, ok := op.(forger)
+		if !ok {
+			return nil, fmt.Errorf("rpc: forge: unsupported operation kind %s", op.OpKind())
+		}
+		if err := f.EncodeBuffer(buf); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// ForgeOperation asks the node at block blockID to forge the contents of op
+// on top of op.Branch. The result equals op.Forge and can be used to check
+// local forging before signing.
+// https://tezos.gitlab.io/active/rpc.html#post-block-id-helpers-forge-operations
+func (c *Client) ForgeOperation(ctx context.Context, blockID tezos.BlockHash, op *OperationHeader) ([]byte, error) {
+	var req struct {
+		Branch   tezos.BlockHash   `json:"branch"`
+		Contents []json.RawMessage `json:"contents"`
+	}
+	req.Branch = op.Branch
+	for _, v := range op.Contents {
+		buf, err := simulationContents(v)
+		if err != nil {
+			return nil, err
+		}
+		req.Contents = append(req.Contents, buf)
+	}
+	var res string
+	u := fmt.Sprintf("chains/%s/blocks/%s/helpers/forge/operations", c.ChainID, blockID)
+	if err := c.Post(ctx, u, &req, &res); err != nil {
+		return nil, err
+	}
+	buf, err := hex.DecodeString(res)
+	if err != nil {
+		return nil, fmt.Errorf("rpc: forge: invalid node response: %w", err)
+	}
+	return buf, nil
+}
+
+// Forge returns the binary encoding of a single transaction.
+func (o *TransactionOp) Forge() ([]byte, error) {
+	return forgeOp(o)
+}
+
+// EncodeBuffer writes the binary encoding of the transaction to buf.
+func (o *TransactionOp) EncodeBuffer(buf *bytes.Buffer) error {
+	if err := forgeManager(buf, o.Kind, o.Source, o.Fee, o.Counter, o.GasLimit, o.StorageLimit); err != nil {
+		return err
+	}
+	writeZarithN(buf, o.Amount)
+	if !o.Destination.IsValid() {
+		return fmt.Errorf("rpc: forge: invalid destination %q", o.Destination)
+	}
+	buf.Write(o.Destination.Bytes22())
+	if o.Parameters == nil {
+		buf.WriteByte(0)
+		return nil
+	}
+	params, err := o.Parameters.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if len(params) == 1 {
+		// unit without entrypoint is the same as no parameters
+		buf.WriteByte(0)
+		return nil
+	}
+	buf.WriteByte(0xff)
+	buf.Write(params[1:])
+	return nil
+}
+
+// Forge returns the binary encoding of a single reveal.
+func (o *RevelationOp) Forge() ([]byte, error) {
+	return forgeOp(o)
+}
+
+// EncodeBuffer writes the binary encoding of the reveal to buf.
+func (o *RevelationOp) EncodeBuffer(buf *bytes.Buffer) error {
+	if err := forgeManager(buf, o.Kind, o.Source, o.Fee, o.Counter, o.GasLimit, o.StorageLimit); err != nil {
+		return err
+	}
+	key, err := o.PublicKey.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	buf.Write(key)
+	return nil
+}
+
+// Forge returns the binary encoding of a single delegation.
+func (o *DelegationOp) Forge() ([]byte, error) {
+	return forgeOp(o)
+}
+
+// EncodeBuffer writes the binary encoding of the delegation to buf.
+func (o *DelegationOp) EncodeBuffer(buf *bytes.Buffer) error {
+	if err := forgeManager(buf, o.Kind, o.Source, o.Fee, o.Counter, o.GasLimit, o.StorageLimit); err != nil {
+		return err
+	}
+	writeOptionalAddress(buf, o.Delegate)
+	return nil
+}
+
+// Forge returns the binary encoding of a single origination.
+func (o *OriginationOp) Forge() ([]byte, error) {
+	return forgeOp(o)
+}
+
+// EncodeBuffer writes the binary encoding of the origination to buf.
+func (o *OriginationOp) EncodeBuffer(buf *bytes.Buffer) error {
+	if err := forgeManager(buf, o.Kind, o.Source, o.Fee, o.Counter, o.GasLimit, o.StorageLimit); err != nil {
+		return err
+	}
+	writeZarithN(buf, o.Balance)
+	if d, ok := o.DelegateAddress(); ok {
+		writeOptionalAddress(buf, d)
+	} else {
+		buf.WriteByte(0)
+	}
+	if o.Script == nil {
+		return fmt.Errorf("rpc: forge: missing origination script")
+	}
+	code, err := o.Script.Code.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	buf.Write(code)
+	storage, err := o.Script.Storage.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	binary.Write(buf, binary.BigEndian, uint32(len(storage)))
+	buf.Write(storage)
+	return nil
+}
+
+func forgeOp(f forger) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := f.EncodeBuffer(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// forgeManager writes the tag and common manager operation fields.
+func forgeManager(buf *bytes.Buffer, kind tezos.OpType, source tezos.Address, fee, counter, gasLimit, storageLimit int64) error {
+	tag := kind.Tag(forgeParams)
+	if tag == 255 {
+		return fmt.Errorf("rpc: forge: unsupported operation kind %s", kind)
+	}
+	if !source.IsImplicit() {
+		return fmt.Errorf("rpc: forge: invalid source %q", source)
+	}
+	buf.WriteByte(tag)
+	buf.Write(source.Bytes())
+	for _, v := range []int64{fee, counter, gasLimit, storageLimit} {
+		writeZarithN(buf, v)
+	}
+	return nil
+}
+
+func writeOptionalAddress(buf *bytes.Buffer, a tezos.Address) {
+	if !a.IsValid() {
+		buf.WriteByte(0)
+		return
+	}
+	buf.WriteByte(0xff)
+	buf.Write(a.Bytes())
+}
+
+// writeZarithN writes a non-negative number in unsigned zarith encoding, 7 bits
+// per byte in little endian order.
+func writeZarithN(buf *bytes.Buffer, v int64) {
+	u := uint64(v)
+	for u >= 0x80 {
+		buf.WriteByte(byte(u) | 0x80)
+		u >>= 7
+	}
+	buf.WriteByte(byte(u))
+}
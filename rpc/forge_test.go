@@ -0,0 +1,200 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestForgeOperation(t *testing.T) {
+	priv := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+	pub := priv.Public().(ed25519.PublicKey)
+	key := tezos.NewKey(tezos.KeyTypeEd25519, pub)
+	src := key.Address()
+	dst := tezos.MustParseAddress("KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu")
+	branch := tezos.MustParseBlockHash("BKjcyGqv8uF9jjHCHFNgZs8wFCe8vrDPmCZU1nbS9wM7ebcPQJT")
+
+	// expected binary encoding, assembled field by field
+	want := hex.EncodeToString(branch.Hash.Hash) +
+		// reveal: tag, source, fee 1420, counter 10, gas 10600, storage 0, key
+		"6b" + "00" + hex.EncodeToString(src.Hash) + "8c0b" + "0a" + "e852" + "00" +
+		"00" + hex.EncodeToString(pub) +
+		// transaction: tag, source, fee 1420, counter 11, gas 10600, storage 300,
+		// amount 1 tez, destination, named entrypoint with int 1
+		"6c" + "00" + hex.EncodeToString(src.Hash) + "8c0b" + "0b" + "e852" + "ac02" +
+		"c0843d" + hex.EncodeToString(dst.Bytes22()) +
+		"ff" + "ff08" + hex.EncodeToString([]byte("transfer")) + "00000002" + "0001"
+	wantBytes, _ := hex.DecodeString(want)
+
+	// sign the expected bytes like a wallet would
+	digest := blake2b.Sum256(append([]byte{3}, wantBytes...))
+	sig := tezos.NewSignature(tezos.SignatureTypeEd25519, ed25519.Sign(priv, digest[:]))
+
+	// operation as returned from RPC
+	buf := []byte(`{
+		"branch": "` + branch.String() + `",
+		"contents": [{
+			"kind": "reveal",
+			"source": "` + src.String() + `",
+			"fee": "1420",
+			"counter": "10",
+			"gas_limit": "10600",
+			"storage_limit": "0",
+			"public_key": "` + key.String() + `"
+		},{
+			"kind": "transaction",
+			"source": "` + src.String() + `",
+			"fee": "1420",
+			"counter": "11",
+			"gas_limit": "10600",
+			"storage_limit": "300",
+			"amount": "1000000",
+			"destination": "` + dst.String() + `",
+			"parameters": {"entrypoint": "transfer", "value": {"int": "1"}}
+		}],
+		"signature": "` + sig.String() + `"
+	}`)
+	var op OperationHeader
+	if err := json.Unmarshal(buf, &op); err != nil {
+		t.Fatalf("unmarshal operation: %v", err)
+	}
+	forged, err := op.Forge()
+	if err != nil {
+		t.Fatalf("forge: %v", err)
+	}
+	if got := hex.EncodeToString(forged); got != want {
+		t.Fatalf("forge mismatch\n  want=%s\n  got= %s", want, got)
+	}
+
+	// verify the included signature against forged bytes
+	s, err := tezos.ParseSignature(op.Signature)
+	if err != nil {
+		t.Fatalf("parse signature: %v", err)
+	}
+	digest = blake2b.Sum256(append([]byte{3}, forged...))
+	if !ed25519.Verify(pub, digest[:], s.Data) {
+		t.Errorf("signature verification failed")
+	}
+
+	// single operation
+	tx, err := op.Contents[1].(*TransactionOp).Forge()
+	if err != nil {
+		t.Fatalf("forge tx: %v", err)
+	}
+	if !bytes.HasSuffix(wantBytes, tx) || tx[0] != 108 {
+		t.Errorf("tx forge mismatch got=%x", tx)
+	}
+
+	// unsupported contents
+	op.Contents = append(op.Contents, &GenericOp{Kind: tezos.OpTypeEndorsement})
+	if _, err := op.Forge(); err == nil {
+		t.Errorf("expected error for unsupported operation")
+	}
+}
+
+func TestForgeDelegation(t *testing.T) {
+	src := tezos.MustParseAddress("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb")
+	op := &DelegationOp{
+		GenericOp:    GenericOp{Kind: tezos.OpTypeDelegation},
+		Source:       src,
+		Fee:          1257,
+		Counter:      1,
+		GasLimit:     1000,
+		StorageLimit: 0,
+	}
+	// withdraw delegation
+	buf, err := op.Forge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "6e" + hex.EncodeToString(src.Bytes()) + "e909" + "01" + "e807" + "00" + "00"
+	if got := hex.EncodeToString(buf); got != want {
+		t.Errorf("mismatch\n  want=%s\n  got= %s", want, got)
+	}
+	// set delegate
+	op.Delegate = src
+	buf, err = op.Forge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = want[:len(want)-2] + "ff" + hex.EncodeToString(src.Bytes())
+	if got := hex.EncodeToString(buf); got != want {
+		t.Errorf("mismatch\n  want=%s\n  got= %s", want, got)
+	}
+}
+
+func TestForgeTransactionBuilder(t *testing.T) {
+	// mainnet genesis block as branch
+	const branch = "BLockGenesisGenesisGenesisGenesisGenesisf79b5d1CoW2"
+	// expected binary encoding, assembled field by field from the protocol
+	// encoding rather than from Forge output
+	const forged = "8fcf233671b6a04fcf679d2a381c2544ea6c1ea29ba6157776ed8424c7ccd00b" + // branch
+		"6c" + // transaction tag
+		"00" + "5c56bbc501ad676afc27ae3d660232287a15b5e2" + // source tz1
+		"8c0b" + // fee 1420
+		"0b" + // counter 11
+		"e852" + // gas limit 10600
+		"ac02" + // storage limit 300
+		"c0843d" + // amount 1000000
+		"01" + "0474bbd2d5084f3c0cbe182d38323b4804cf01b5" + "00" + // destination KT1 with padding
+		"ff" + // parameters present
+		"ff" + "08" + "7472616e73666572" + // named entrypoint "transfer"
+		"00000002" + "0001" // parameter value 1
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/chains/main/blocks/"+branch+"/helpers/forge/operations" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var req struct {
+			Branch   string                   `json:"branch"`
+			Contents []map[string]interface{} `json:"contents"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if len(req.Contents) != 1 || req.Contents[0]["amount"] != "1000000" || req.Contents[0]["kind"] != "transaction" {
+			t.Errorf("unexpected contents %v", req.Contents)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`"` + forged + `"`))
+	})
+
+	params, err := micheline.NewParameters("transfer", micheline.NewBig(big.NewInt(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := NewTransaction(
+		tezos.MustParseAddress("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"),
+		tezos.MustParseAddress("KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"),
+		1000000,
+	).WithFee(1420).WithCounter(11).WithLimits(10600, 300).WithParameters(params)
+	op := NewOperation(tezos.MustParseBlockHash(branch), tx)
+
+	local, err := op.Forge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := c.ForgeOperation(context.Background(), op.Branch, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(local); got != forged {
+		t.Errorf("local forge mismatch\n  want=%s\n  got= %s", forged, got)
+	}
+	if !bytes.Equal(local, remote) {
+		t.Errorf("local and node forge differ\n  node= %x\n  local=%x", remote, local)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// InjectOperation injects a signed operation, i.e. forged bytes followed by
+// the signature, into the node and returns the operation hash. The node
+// only prevalidates the operation, use WaitConfirmation to learn when it
+// was included.
+// https://tezos.gitlab.io/active/rpc.html#post-injection-operation
+func (c *Client) InjectOperation(ctx context.Context, signed []byte) (tezos.OpHash, error) {
+	var hash tezos.OpHash
+	u := fmt.Sprintf("injection/operation?chain=%s", c.ChainID)
+	if err := c.Post(ctx, u, hex.EncodeToString(signed), &hash); err != nil {
+		return tezos.OpHash{}, err
+	}
+	return hash, nil
+}
+
+// WaitConfirmation watches new chain heads until the operation with hash
+// oh was included and n more blocks were baked on top of the including
+// block, then returns the level of the including block. Blocks which were
+// skipped by the monitor or replaced by a reorganization are scanned as
+// well. A reorganization which drops the including block restarts the wait.
+// Operations included before the call are not found, so call it right after
+// injection. Use ctx to set a timeout.
+func (c *Client) WaitConfirmation(ctx context.Context, oh tezos.OpHash, n int64) (int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	mon := NewBlockHeaderMonitor()
+	defer mon.Close()
+	if err := c.MonitorBlockHeader(ctx, mon); err != nil {
+		return 0, err
+	}
+
+	var (
+		block   tezos.BlockHash
+		level   int64
+		start   int64
+		scanned = make(map[string]bool)
+	)
+	for {
+		head, err := mon.Recv(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if start == 0 {
+			start = head.Level
+		}
+		for {
+			if !block.IsValid() {
+				block, level, err = c.findOperation(ctx, head, start, oh, scanned)
+				if err != nil {
+					return 0, err
+				}
+				if !block.IsValid() {
+					break
+				}
+			}
+			if head.Level < level+n {
+				break
+			}
+			// make sure the including block is still on the main chain
+			have := head.Hash
+			if head.Level > level {
+				u := fmt.Sprintf("chains/%s/blocks/%s~%d/hash", c.ChainID, head.Hash, head.Level-level)
+				if err := c.Get(ctx, u, &have); err != nil {
+					return 0, err
+				}
+			}
+			if have.Equal(block) {
+				return level, nil
+			}
+			// dropped by a reorganization, search the new chain
+			block = tezos.BlockHash{}
+		}
+	}
+}
+
+// findOperation scans head and its ancestors down to level start for
+// operation oh. The walk stops at the first block scanned before, so it
+// covers levels the monitor skipped as well as blocks of a new branch after
+// a reorganization. It returns the including block and its level or an
+// invalid hash when the operation was not found.
+func (c *Client) findOperation(ctx context.Context, head *BlockHeaderLogEntry, start int64, oh tezos.OpHash, scanned map[string]bool) (tezos.BlockHash, int64, error) {
+	hash := head.Hash
+	for l := head.Level; l >= start; l-- {
+		switch {
+		case l == head.Level:
+		case l == head.Level-1 && head.Predecessor.IsValid():
+			hash = head.Predecessor
+		default:
+			u := fmt.Sprintf("chains/%s/blocks/%s~%d/hash", c.ChainID, head.Hash, head.Level-l)
+			if err := c.Get(ctx, u, &hash); err != nil {
+				return tezos.BlockHash{}, 0, err
+			}
+		}
+		if scanned[hash.String()] {
+			break
+		}
+		scanned[hash.String()] = true
+		ok, err := c.containsOperation(ctx, hash, oh)
+		if err != nil {
+			return tezos.BlockHash{}, 0, err
+		}
+		if ok {
+			return hash, l, nil
+		}
+	}
+	return tezos.BlockHash{}, 0, nil
+}
+
+// containsOperation reports whether block blockID includes operation oh.
+func (c *Client) containsOperation(ctx context.Context, blockID tezos.BlockHash, oh tezos.OpHash) (bool, error) {
+	var hashes [][]tezos.OpHash
+	u := fmt.Sprintf("chains/%s/blocks/%s/operation_hashes", c.ChainID, blockID)
+	if err := c.Get(ctx, u, &hashes); err != nil {
+		return false, err
+	}
+	for _, pass := range hashes {
+		for _, h := range pass {
+			if h.Equal(oh) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
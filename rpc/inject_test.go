@@ -0,0 +1,122 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func TestInjectOperation(t *testing.T) {
+	signed := []byte{0xde, 0xad, 0xbe, 0xef}
+	oh := tezos.ComputeOpHash(signed)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/injection/operation" || r.URL.RawQuery != "chain=main" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		var body string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body != "deadbeef" {
+			t.Errorf("unexpected body %q: %v", body, err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, "%q", oh)
+	})
+	have, err := c.InjectOperation(context.Background(), signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !have.Equal(oh) {
+		t.Errorf("hash mismatch want=%s got=%s", oh, have)
+	}
+}
+
+func TestWaitConfirmation(t *testing.T) {
+	oh := tezos.ComputeOpHash([]byte{1})
+	block := func(n byte) tezos.BlockHash {
+		return tezos.NewBlockHash(bytes.Repeat([]byte{n}, 32))
+	}
+	// block 2 includes the operation but is replaced by a reorg to the branch
+	// of blocks 12-15, the operation is included again in block 14 at level
+	// 13 which the monitor skips
+	type node struct {
+		Level  int64
+		Parent tezos.BlockHash
+		Op     bool
+	}
+	chain := map[string]node{
+		block(1).String():  {Level: 10},
+		block(2).String():  {Level: 11, Parent: block(1), Op: true},
+		block(12).String(): {Level: 11, Parent: block(1)},
+		block(13).String(): {Level: 12, Parent: block(12)},
+		block(14).String(): {Level: 13, Parent: block(13), Op: true},
+		block(15).String(): {Level: 14, Parent: block(14)},
+	}
+	heads := []tezos.BlockHash{block(1), block(2), block(13), block(15)}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch p := r.URL.Path; {
+		case p == "/monitor/heads/main":
+			for _, h := range heads {
+				n := chain[h.String()]
+				if n.Parent.IsValid() {
+					fmt.Fprintf(w, `{"hash":"%s","level":%d,"predecessor":"%s"}`+"\n", h, n.Level, n.Parent)
+				} else {
+					fmt.Fprintf(w, `{"hash":"%s","level":%d}`+"\n", h, n.Level)
+				}
+			}
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		case strings.HasSuffix(p, "/operation_hashes"):
+			id := strings.TrimSuffix(strings.TrimPrefix(p, "/chains/main/blocks/"), "/operation_hashes")
+			if chain[id].Op {
+				fmt.Fprintf(w, `[[],[],[],["%s"]]`, oh)
+			} else {
+				fmt.Fprint(w, `[[],[],[],[]]`)
+			}
+		case strings.HasSuffix(p, "/hash"):
+			id := strings.TrimSuffix(strings.TrimPrefix(p, "/chains/main/blocks/"), "/hash")
+			var k int
+			if i := strings.IndexByte(id, '~'); i >= 0 {
+				k, _ = strconv.Atoi(id[i+1:])
+				id = id[:i]
+			}
+			h := tezos.MustParseBlockHash(id)
+			for ; k > 0; k-- {
+				h = chain[h.String()].Parent
+			}
+			if !h.IsValid() {
+				t.Errorf("unexpected ancestor request %s", p)
+			}
+			fmt.Fprintf(w, "%q", h)
+		default:
+			t.Errorf("unexpected path %s", p)
+		}
+	})
+
+	level, err := c.WaitConfirmation(context.Background(), oh, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level != 13 {
+		t.Errorf("expected inclusion at level 13, got %d", level)
+	}
+
+	// the context bounds the wait
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.WaitConfirmation(ctx, tezos.ComputeOpHash(nil), 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
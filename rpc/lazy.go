@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
 )
 
 type LazyDiffKind string
@@ -114,6 +115,91 @@ loop:
 type LazyBigMapDiff struct {
 	GenericDiff
 	Diff micheline.BigmapDiffElem `json:"diff"`
+	Lazy LazyBigMapElem           `json:"-"` // full diff including all key updates
+}
+
+func (d *LazyBigMapDiff) UnmarshalJSON(data []byte) error {
+	var val struct {
+		GenericDiff
+		Diff json.RawMessage `json:"diff"`
+	}
+	if err := json.Unmarshal(data, &val); err != nil {
+		return err
+	}
+	d.GenericDiff = val.GenericDiff
+	if val.Diff == nil {
+		return nil
+	}
+	if err := json.Unmarshal(val.Diff, &d.Diff); err != nil {
+		return err
+	}
+	return json.Unmarshal(val.Diff, &d.Lazy)
+}
+
+// LazyBigMapElem is the v008+ big_map diff with a batch of key updates
+type LazyBigMapElem struct {
+	Action    micheline.DiffAction `json:"action"`
+	Updates   []LazyBigMapUpdate   `json:"updates,omitempty"`    // update, alloc, copy
+	KeyType   *micheline.Prim      `json:"key_type,omitempty"`   // alloc
+	ValueType *micheline.Prim      `json:"value_type,omitempty"` // alloc
+	SourceId  int64                `json:"source,string"`        // copy
+}
+
+// LazyBigMapUpdate is a single key update, removed keys have no value
+type LazyBigMapUpdate struct {
+	KeyHash tezos.ExprHash `json:"key_hash"`
+	Key     micheline.Prim `json:"key"`
+	Value   micheline.Prim `json:"value"`
+}
+
+// BigmapDiff converts a lazy big_map diff into the legacy big_map_diff format
+// with one element per alloc/copy/remove action and key update.
+func (d LazyBigMapDiff) BigmapDiff() micheline.BigmapDiff {
+	res := make(micheline.BigmapDiff, 0, len(d.Lazy.Updates)+1)
+	switch d.Lazy.Action {
+	case micheline.DiffActionAlloc:
+		elem := micheline.BigmapDiffElem{
+			Action: micheline.DiffActionAlloc,
+			Id:     d.DiffId,
+		}
+		if d.Lazy.KeyType != nil {
+			elem.KeyType = *d.Lazy.KeyType
+		}
+		if d.Lazy.ValueType != nil {
+			elem.ValueType = *d.Lazy.ValueType
+		}
+		res = append(res, elem)
+	case micheline.DiffActionCopy:
+		res = append(res, micheline.BigmapDiffElem{
+			Action:   micheline.DiffActionCopy,
+			Id:       d.DiffId,
+			SourceId: d.Lazy.SourceId,
+			DestId:   d.DiffId,
+		})
+	case micheline.DiffActionRemove:
+		res = append(res, micheline.BigmapDiffElem{
+			Action: micheline.DiffActionRemove,
+			Id:     d.DiffId,
+			Key: micheline.Prim{
+				Type:   micheline.PrimNullary,
+				OpCode: micheline.I_EMPTY_BIG_MAP,
+			},
+		})
+	}
+	for _, v := range d.Lazy.Updates {
+		elem := micheline.BigmapDiffElem{
+			Action:  micheline.DiffActionUpdate,
+			Id:      d.DiffId,
+			KeyHash: v.KeyHash,
+			Key:     v.Key,
+			Value:   v.Value,
+		}
+		if !v.Value.IsValid() {
+			elem.Action = micheline.DiffActionRemove
+		}
+		res = append(res, elem)
+	}
+	return res
 }
 
 type LazySaplingDiff struct {
@@ -6,8 +6,15 @@ package rpc
 
 import (
 	"context"
+	"errors"
+	"io"
 )
 
+// ErrMonitorRestarted is returned by MempoolMonitor.Recv when the node closed
+// the stream, which happens whenever a new head is applied. Callers should
+// resume monitoring with a new monitor.
+var ErrMonitorRestarted = errors.New("monitor stream ended by node")
+
 // MempoolOperations represents mempool operations
 type MempoolOperations struct {
 	Applied       []*OperationHeader             `json:"applied"`
@@ -47,3 +54,91 @@ type OperationHeaderWithErrorAlt OperationHeaderWithError
 func (o *OperationHeaderWithErrorAlt) UnmarshalJSON(data []byte) error {
 	return unmarshalNamedJSONArray(data, &o.Hash, (*OperationHeaderWithError)(o))
 }
+
+// MempoolMonitor streams batches of operations the node has validated. Applied
+// operations have no error, refused and delayed operations carry the reason.
+type MempoolMonitor struct {
+	result chan []*OperationHeaderWithError
+	closed chan struct{}
+	err    error
+}
+
+// make sure MempoolMonitor implements Monitor interface
+var _ Monitor = (*MempoolMonitor)(nil)
+
+func NewMempoolMonitor() *MempoolMonitor {
+	return &MempoolMonitor{
+		result: make(chan []*OperationHeaderWithError),
+		closed: make(chan struct{}),
+	}
+}
+
+func (m *MempoolMonitor) New() interface{} {
+	return &[]*OperationHeaderWithError{}
+}
+
+func (m *MempoolMonitor) Send(ctx context.Context, val interface{}) {
+	select {
+	case <-m.closed:
+		return
+	default:
+	}
+	select {
+	case <-ctx.Done():
+	case <-m.closed:
+	case m.result <- *val.(*[]*OperationHeaderWithError):
+	}
+}
+
+func (m *MempoolMonitor) Recv(ctx context.Context) ([]*OperationHeaderWithError, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-m.closed:
+		return nil, m.closeErr()
+	case res, ok := <-m.result:
+		if !ok {
+			return nil, m.closeErr()
+		}
+		return res, nil
+	}
+}
+
+// closeErr translates the reason the stream ended into a Recv error.
+func (m *MempoolMonitor) closeErr() error {
+	switch m.err {
+	case nil:
+		return ErrMonitorClosed
+	case io.EOF:
+		return ErrMonitorRestarted
+	default:
+		return m.err
+	}
+}
+
+func (m *MempoolMonitor) Err(err error) {
+	m.err = err
+	m.Close()
+}
+
+func (m *MempoolMonitor) Close() {
+	select {
+	case <-m.closed:
+		return
+	default:
+	}
+	close(m.closed)
+	close(m.result)
+}
+
+func (m *MempoolMonitor) Closed() <-chan struct{} {
+	return m.closed
+}
+
+// MonitorMempoolOperations streams applied, refused, branch refused and branch
+// delayed operations from the mempool until the next head is applied.
+// https://tezos.gitlab.io/active/rpc.html#get-chains-chain-id-mempool-monitor-operations
+func (c *Client) MonitorMempoolOperations(ctx context.Context, monitor *MempoolMonitor) error {
+	u := "chains/" + c.ChainID + "/mempool/monitor_operations?applied=true&refused=true&branch_refused=true&branch_delayed=true"
+	return c.GetAsync(ctx, u, monitor)
+}
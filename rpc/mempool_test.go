@@ -0,0 +1,77 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func TestMempoolMonitor(t *testing.T) {
+	h1 := tezos.NewOpHash(bytes.Repeat([]byte{1}, 32))
+	h2 := tezos.NewOpHash(bytes.Repeat([]byte{2}, 32))
+	applied := `[{"hash":"` + h1.String() + `","protocol":"PsBABY5HQTSkA4297zNHfsZNKtxULfL18y95qb3m53QJiXGmrbU","branch":"` + testBlock + `",
+		"contents":[{"kind":"reveal","source":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb","fee":"1420","counter":"10","gas_limit":"10600","storage_limit":"0",
+		"public_key":"edpkuBknW28nW72KG6RoHtYW7p12T6GKc7nAbwYX5m8Wd9sDVC9yav"}],"signature":"sigTmwQMh2GRtXZRzGp5pX3FnT6cokt9asZxwpa2Sa8e5JkhCHWz5cYGKAnGodmAaL1vDJBP8WEJn1z1pyVziM9YiWhRTc5Y"}]`
+	refused := `[{"hash":"` + h2.String() + `","protocol":"PsBABY5HQTSkA4297zNHfsZNKtxULfL18y95qb3m53QJiXGmrbU","branch":"` + testBlock + `",
+		"contents":[{"kind":"reveal","source":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb","fee":"0","counter":"11","gas_limit":"10600","storage_limit":"0",
+		"public_key":"edpkuBknW28nW72KG6RoHtYW7p12T6GKc7nAbwYX5m8Wd9sDVC9yav"}],"signature":"sigTmwQMh2GRtXZRzGp5pX3FnT6cokt9asZxwpa2Sa8e5JkhCHWz5cYGKAnGodmAaL1vDJBP8WEJn1z1pyVziM9YiWhRTc5Y",
+		"error":[{"kind":"temporary","id":"proto.alpha.prefilter.fees_too_low"}]}]`
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chains/main/mempool/monitor_operations" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("refused") != "true" || r.URL.Query().Get("branch_delayed") != "true" {
+			t.Errorf("missing filter arguments %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		for _, chunk := range []string{applied, refused} {
+			w.Write([]byte(chunk))
+			w.(http.Flusher).Flush()
+		}
+		// end of stream like the node does on a new head
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	mon := NewMempoolMonitor()
+	if err := c.MonitorMempoolOperations(ctx, mon); err != nil {
+		t.Fatal(err)
+	}
+	ops, err := mon.Recv(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 || !ops[0].Hash.Equal(h1) || len(ops[0].Error) != 0 {
+		t.Errorf("unexpected applied batch %#v", ops)
+	}
+	if _, ok := ops[0].Contents[0].(*RevelationOp); !ok {
+		t.Errorf("expected *RevelationOp, got %T", ops[0].Contents[0])
+	}
+	ops, err = mon.Recv(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 || !ops[0].Hash.Equal(h2) || len(ops[0].Error) != 1 {
+		t.Errorf("unexpected refused batch %#v", ops)
+	}
+	if _, err := mon.Recv(ctx); !errors.Is(err, ErrMonitorRestarted) {
+		t.Errorf("expected ErrMonitorRestarted, got %v", err)
+	}
+
+	// closing by the caller is not recoverable
+	mon = NewMempoolMonitor()
+	mon.Close()
+	if _, err := mon.Recv(ctx); err != ErrMonitorClosed {
+		t.Errorf("expected ErrMonitorClosed, got %v", err)
+	}
+}
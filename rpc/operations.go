@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"blockwatch.cc/tzgo/micheline"
 	"blockwatch.cc/tzgo/tezos"
 )
 
@@ -28,9 +29,11 @@ type Operation interface {
 
 type OperationError struct {
 	GenericError
-	Contract *tezos.Address `json:"contract,omitempty"`
-	Amount   int64          `json:"amount,string,omitempty"`
-	Balance  int64          `json:"balance,string,omitempty"`
+	Contract *tezos.Address  `json:"contract,omitempty"`
+	Amount   int64           `json:"amount,string,omitempty"`
+	Balance  int64           `json:"balance,string,omitempty"`
+	Location int64           `json:"location,omitempty"` // script_rejected
+	With     *micheline.Prim `json:"with,omitempty"`     // script_rejected
 }
 
 // GenericOp is a most generic type
@@ -64,10 +67,15 @@ opLoop:
 		if r == nil {
 			continue
 		}
-		var tmp GenericOp
-		if err := json.Unmarshal(r, &tmp); err != nil {
+		// decode the kind as string first so unknown kinds from newer
+		// protocols don't fail the entire list
+		var kind struct {
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal(r, &kind); err != nil {
 			return fmt.Errorf("rpc: generic operation: %w", err)
 		}
+		tmp := GenericOp{Kind: tezos.ParseOpType(kind.Kind)}
 
 		switch tmp.Kind {
 		// anonymous operations
@@ -88,9 +96,33 @@ opLoop:
 			(*e)[i] = &DelegationOp{}
 		case tezos.OpTypeReveal:
 			(*e)[i] = &RevelationOp{}
+		// smart rollup operations
+		case tezos.OpTypeSmartRollupOriginate:
+			(*e)[i] = &SmartRollupOriginateOp{}
+		case tezos.OpTypeSmartRollupAddMessages:
+			(*e)[i] = &SmartRollupAddMessagesOp{}
+		case tezos.OpTypeSmartRollupCement:
+			(*e)[i] = &SmartRollupCementOp{}
+		case tezos.OpTypeSmartRollupPublish:
+			(*e)[i] = &SmartRollupPublishOp{}
+		case tezos.OpTypeSmartRollupRefute:
+			(*e)[i] = &SmartRollupRefuteOp{}
+		case tezos.OpTypeSmartRollupTimeout:
+			(*e)[i] = &SmartRollupTimeoutOp{}
+		case tezos.OpTypeSmartRollupExecuteOutboxMessage:
+			(*e)[i] = &SmartRollupExecuteOutboxMessageOp{}
+		case tezos.OpTypeSmartRollupRecoverBond:
+			(*e)[i] = &SmartRollupRecoverBondOp{}
+		// data availability layer operations
+		case tezos.OpTypeDalPublishCommitment:
+			(*e)[i] = &DalPublishCommitmentOp{}
 		// consensus operations
 		case tezos.OpTypeEndorsement:
 			(*e)[i] = &EndorsementOp{}
+		case tezos.OpTypeAttestation:
+			(*e)[i] = &AttestationOp{}
+		case tezos.OpTypePreendorsement, tezos.OpTypePreattestation:
+			(*e)[i] = &PreattestationOp{}
 		// amendment operations
 		case tezos.OpTypeProposals:
 			(*e)[i] = &ProposalsOp{}
@@ -98,7 +130,7 @@ opLoop:
 			(*e)[i] = &BallotOp{}
 
 		default:
-			log.Warnf("unsupported op '%s'", tmp.Kind)
+			log.Warnf("unsupported op '%s'", kind.Kind)
 			(*e)[i] = &tmp
 			continue opLoop
 		}
@@ -110,3 +142,61 @@ opLoop:
 
 	return nil
 }
+
+// OperationAddresses returns the accounts touched by a manager operation:
+// its source, destination or delegate, originated contracts and the
+// sources, destinations and delegates of its internal operations.
+func OperationAddresses(op Operation) *tezos.AddressSet {
+	set := tezos.NewAddressSet()
+	addOperationAddresses(set, op)
+	return set
+}
+
+func addOperationAddresses(set *tezos.AddressSet, op Operation) {
+	add := func(a tezos.Address) {
+		if a.IsValid() {
+			set.AddUnique(a)
+		}
+	}
+	var internal []*InternalResult
+	switch o := op.(type) {
+	case *TransactionOp:
+		add(o.Source)
+		add(o.Destination)
+		if o.Metadata != nil {
+			if o.Metadata.Result != nil {
+				for _, v := range o.Metadata.Result.OriginatedContracts {
+					add(v)
+				}
+			}
+			internal = o.Metadata.InternalResults
+		}
+	case *OriginationOp:
+		add(o.Source)
+		if d, ok := o.DelegateAddress(); ok {
+			add(d)
+		}
+		if c, ok := o.OriginatedContract(); ok {
+			add(c)
+		}
+	case *DelegationOp:
+		add(o.Source)
+		add(o.Delegate)
+	case *RevelationOp:
+		add(o.Source)
+	}
+	for _, v := range internal {
+		add(v.Source)
+		if v.Destination != nil {
+			add(*v.Destination)
+		}
+		if v.Delegate != nil {
+			add(*v.Delegate)
+		}
+		if v.Result != nil {
+			for _, c := range v.Result.OriginatedContracts {
+				add(c)
+			}
+		}
+	}
+}
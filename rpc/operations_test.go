@@ -0,0 +1,488 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package rpc
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func decodeOps(t *testing.T, data string) Operations {
+	t.Helper()
+	var ops Operations
+	if err := json.Unmarshal([]byte(data), &ops); err != nil {
+		t.Fatalf("decoding operations: %v", err)
+	}
+	return ops
+}
+
+func TestDecodeAttestation(t *testing.T) {
+	// consensus operations from a v018+ block
+	ops := decodeOps(t, `[
+		{
+			"kind": "attestation",
+			"slot": 12,
+			"level": 4719567,
+			"round": 0,
+			"block_payload_hash": "vh2zTa8nn3YVJ5GnVbJK7mWRTGpDiPTmrUEzDbQBjUuzzhF8SnW4",
+			"metadata": {
+				"balance_updates": [],
+				"delegate": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+				"consensus_power": 312
+			}
+		},
+		{
+			"kind": "preattestation",
+			"slot": 3,
+			"level": 4719568,
+			"round": 1,
+			"block_payload_hash": "vh2zTa8nn3YVJ5GnVbJK7mWRTGpDiPTmrUEzDbQBjUuzzhF8SnW4",
+			"metadata": {
+				"balance_updates": [],
+				"delegate": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+				"consensus_power": 17
+			}
+		}
+	]`)
+	if len(ops) != 2 {
+		t.Fatalf("expected 2 ops, got %d", len(ops))
+	}
+	a, ok := ops[0].(*AttestationOp)
+	if !ok {
+		t.Fatalf("expected *AttestationOp, got %T", ops[0])
+	}
+	if a.OpKind() != tezos.OpTypeAttestation {
+		t.Errorf("kind mismatch got=%s", a.OpKind())
+	}
+	if a.Slot != 12 || a.GetLevel() != 4719567 || a.Round != 0 {
+		t.Errorf("unexpected attestation %#v", a)
+	}
+	if a.Metadata == nil || a.Metadata.ConsensusPower != 312 || !a.Metadata.Address().IsValid() {
+		t.Errorf("unexpected attestation metadata %#v", a.Metadata)
+	}
+	p, ok := ops[1].(*PreattestationOp)
+	if !ok {
+		t.Fatalf("expected *PreattestationOp, got %T", ops[1])
+	}
+	if p.OpKind() != tezos.OpTypePreattestation || p.Round != 1 || p.Metadata.ConsensusPower != 17 {
+		t.Errorf("unexpected preattestation %#v", p)
+	}
+}
+
+func TestDecodeEndorsement(t *testing.T) {
+	// endorsement from a v009 block
+	ops := decodeOps(t, `[
+		{
+			"kind": "endorsement_with_slot",
+			"endorsement": {
+				"branch": "BKjcyGqv8uF9jjHCHFNgZs8wFCe8vrDPmCZU1nbS9wM7ebcPQJT",
+				"operations": {"kind": "endorsement", "level": 1466367}
+			},
+			"slot": 7,
+			"metadata": {
+				"balance_updates": [],
+				"delegate": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+				"slots": [7, 11, 25]
+			}
+		},
+		{
+			"kind": "endorsement",
+			"level": 1212000,
+			"metadata": {
+				"balance_updates": [],
+				"delegate": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+				"slots": [1]
+			}
+		}
+	]`)
+	for i, op := range ops {
+		e, ok := op.(*EndorsementOp)
+		if !ok {
+			t.Fatalf("op %d: expected *EndorsementOp, got %T", i, op)
+		}
+		if e.OpKind() != tezos.OpTypeEndorsement {
+			t.Errorf("op %d: kind mismatch got=%s", i, e.OpKind())
+		}
+	}
+	if l := ops[0].(*EndorsementOp).GetLevel(); l != 1466367 {
+		t.Errorf("v009 level mismatch got=%d", l)
+	}
+	if l := ops[1].(*EndorsementOp).GetLevel(); l != 1212000 {
+		t.Errorf("level mismatch got=%d", l)
+	}
+}
+
+func TestOpTypeNamingEras(t *testing.T) {
+	for _, v := range []struct {
+		Name string
+		Type tezos.OpType
+	}{
+		{"endorsement", tezos.OpTypeEndorsement},
+		{"endorsement_with_slot", tezos.OpTypeEndorsement},
+		{"preendorsement", tezos.OpTypePreendorsement},
+		{"attestation", tezos.OpTypeAttestation},
+		{"preattestation", tezos.OpTypePreattestation},
+	} {
+		if got := tezos.ParseOpType(v.Name); got != v.Type {
+			t.Errorf("%s: parse mismatch got=%s", v.Name, got)
+		}
+		if v.Type.ListId() != 0 {
+			t.Errorf("%s: expected consensus list", v.Name)
+		}
+	}
+}
+
+func TestDecodeSmartRollupOps(t *testing.T) {
+	ops := decodeOps(t, `[
+		{
+			"kind": "smart_rollup_originate",
+			"source": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+			"fee": "1587",
+			"counter": "51",
+			"gas_limit": "2849",
+			"storage_limit": "6572",
+			"pvm_kind": "wasm_2_0_0",
+			"kernel": "0061736d0100000001",
+			"parameters_ty": {"prim": "bytes"},
+			"metadata": {
+				"balance_updates": [],
+				"operation_result": {
+					"status": "applied",
+					"balance_updates": [],
+					"address": "sr1Ghq66tYK9y3r8CC1Tf8i8m5nxh8nTvZEf",
+					"genesis_commitment_hash": "src13wCGc2nMVfN7rD1rgeG3g1q7oXYX2m5MJY5ZRooVhLt7JwKXwX",
+					"consumed_milligas": "2748269",
+					"size": "6552"
+				}
+			}
+		},
+		{
+			"kind": "smart_rollup_add_messages",
+			"source": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+			"fee": "397",
+			"counter": "52",
+			"gas_limit": "1103",
+			"storage_limit": "0",
+			"message": ["0102", "cafebabe"],
+			"metadata": {
+				"balance_updates": [],
+				"operation_result": {
+					"status": "applied",
+					"consumed_milligas": "1002777"
+				}
+			}
+		}
+	]`)
+	if len(ops) != 2 {
+		t.Fatalf("expected 2 ops, got %d", len(ops))
+	}
+	o, ok := ops[0].(*SmartRollupOriginateOp)
+	if !ok {
+		t.Fatalf("expected *SmartRollupOriginateOp, got %T", ops[0])
+	}
+	if o.OpKind() != tezos.OpTypeSmartRollupOriginate || o.PvmKind != "wasm_2_0_0" || o.Fee != 1587 {
+		t.Errorf("unexpected originate %#v", o)
+	}
+	if o.ParamType.OpCode.String() != "bytes" {
+		t.Errorf("unexpected parameter type %s", o.ParamType.Dump())
+	}
+	res := o.Metadata.Result
+	if !res.Status.IsSuccess() || res.Size != 6552 || res.Address == nil {
+		t.Fatalf("unexpected originate result %#v", res)
+	}
+	if res.Address.Type != tezos.AddressTypeSmartRollup || res.Address.String() != "sr1Ghq66tYK9y3r8CC1Tf8i8m5nxh8nTvZEf" {
+		t.Errorf("rollup address mismatch got=%s", res.Address)
+	}
+
+	m, ok := ops[1].(*SmartRollupAddMessagesOp)
+	if !ok {
+		t.Fatalf("expected *SmartRollupAddMessagesOp, got %T", ops[1])
+	}
+	if len(m.Messages) != 2 || m.Messages[1] != "cafebabe" || m.Counter != 52 {
+		t.Errorf("unexpected add_messages %#v", m)
+	}
+	if m.Metadata.Result.ConsumedMilliGas != 1002777 {
+		t.Errorf("unexpected add_messages result %#v", m.Metadata.Result)
+	}
+	if tag := tezos.OpTypeSmartRollupAddMessages.Tag(&tezos.Params{OperationTagsVersion: 1}); tag != 201 {
+		t.Errorf("tag mismatch got=%d", tag)
+	}
+}
+
+func TestDecodeDalOps(t *testing.T) {
+	// DAL operations from a v019 block, the last using an older kind name
+	ops := decodeOps(t, `[
+		{
+			"kind": "dal_publish_commitment",
+			"source": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+			"fee": "513",
+			"counter": "1806",
+			"gas_limit": "2169",
+			"storage_limit": "0",
+			"slot_header": {
+				"slot_index": 3,
+				"commitment": "sh1u3tr3YKPDYUp2wWKCfmV5KZb82FREhv8GtDeR3EJccsBerWGwJYKufsDNH8rk4XqGrXdooZ",
+				"commitment_proof": "8b1b5b4d4e8e2b1a"
+			},
+			"metadata": {
+				"balance_updates": [],
+				"operation_result": {
+					"status": "applied",
+					"slot_header": {
+						"version": "0",
+						"level": 6437011,
+						"index": 3,
+						"commitment": "sh1u3tr3YKPDYUp2wWKCfmV5KZb82FREhv8GtDeR3EJccsBerWGwJYKufsDNH8rk4XqGrXdooZ"
+					},
+					"consumed_milligas": "2068324"
+				}
+			}
+		},
+		{
+			"kind": "dal_attestation",
+			"attestor": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+			"attestation": "0",
+			"level": 6437012
+		},
+		{
+			"kind": "dal_publish_slot_header",
+			"source": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+			"fee": "500",
+			"counter": "12",
+			"gas_limit": "2000",
+			"storage_limit": "0",
+			"slot_header": {
+				"slot_index": 1,
+				"commitment": "sh1u3tr3YKPDYUp2wWKCfmV5KZb82FREhv8GtDeR3EJccsBerWGwJYKufsDNH8rk4XqGrXdooZ"
+			}
+		}
+	]`)
+	if len(ops) != 3 {
+		t.Fatalf("expected 3 ops, got %d", len(ops))
+	}
+	d, ok := ops[0].(*DalPublishCommitmentOp)
+	if !ok {
+		t.Fatalf("expected *DalPublishCommitmentOp, got %T", ops[0])
+	}
+	if d.OpKind() != tezos.OpTypeDalPublishCommitment || d.Fee != 513 || d.SlotHeader.GetIndex() != 3 {
+		t.Errorf("unexpected op %#v", d)
+	}
+	if d.Metadata == nil || !d.Metadata.Result.Status.IsSuccess() {
+		t.Fatalf("unexpected metadata %#v", d.Metadata)
+	}
+	if h := d.Metadata.Result.SlotHeader; h.GetIndex() != 3 || h.Level != 6437011 || h.Commitment != d.SlotHeader.Commitment {
+		t.Errorf("unexpected result slot header %#v", h)
+	}
+	if _, ok := ops[1].(*GenericOp); !ok {
+		t.Errorf("expected unknown DAL op to fall back to *GenericOp, got %T", ops[1])
+	}
+	if d, ok := ops[2].(*DalPublishCommitmentOp); !ok || d.SlotHeader.GetIndex() != 1 {
+		t.Errorf("expected legacy slot header op, got %#v", ops[2])
+	}
+	if tag := tezos.OpTypeDalPublishCommitment.Tag(&tezos.Params{OperationTagsVersion: 1}); tag != 230 || tezos.ParseOpTag(tag) != tezos.OpTypeDalPublishCommitment {
+		t.Errorf("tag mismatch got=%d", tag)
+	}
+}
+
+func TestDecodeStakingOps(t *testing.T) {
+	// staking operations from a v019 block
+	ops := decodeOps(t, `[
+		{
+			"kind": "transaction",
+			"source": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+			"fee": "705",
+			"counter": "8451",
+			"gas_limit": "3645",
+			"storage_limit": "0",
+			"amount": "900000000",
+			"destination": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+			"parameters": {"entrypoint": "stake", "value": {"prim": "Unit"}}
+		},
+		{
+			"kind": "transaction",
+			"source": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+			"fee": "541",
+			"counter": "8452",
+			"gas_limit": "1001",
+			"storage_limit": "0",
+			"amount": "0",
+			"destination": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+			"parameters": {
+				"entrypoint": "set_delegate_parameters",
+				"value": {"prim": "Pair", "args": [{"int": "5000000"}, {"prim": "Pair", "args": [{"int": "100000000"}, {"prim": "Unit"}]}]}
+			}
+		},
+		{
+			"kind": "transaction",
+			"source": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+			"fee": "541",
+			"counter": "8453",
+			"gas_limit": "1001",
+			"storage_limit": "0",
+			"amount": "0",
+			"destination": "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu",
+			"parameters": {"entrypoint": "stake", "value": {"prim": "Unit"}}
+		}
+	]`)
+	if len(ops) != 3 {
+		t.Fatalf("expected 3 ops, got %d", len(ops))
+	}
+	tx := ops[0].(*TransactionOp)
+	stake, err := NewStakingOp(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stake.OpKind() != tezos.OpTypeStake || stake.Amount != 900000000 || stake.DelegateParameters != nil {
+		t.Errorf("unexpected stake op %#v", stake)
+	}
+
+	params, err := NewStakingOp(ops[1].(*TransactionOp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.OpKind() != tezos.OpTypeSetDelegateParameters || params.OpKind().ListId() != 3 {
+		t.Errorf("unexpected kind %s", params.OpKind())
+	}
+	if p := params.DelegateParameters; p == nil || p.LimitOfStakingOverBaking != 5000000 || p.EdgeOfBakingOverStaking != 100000000 {
+		t.Errorf("unexpected delegate parameters %#v", p)
+	}
+
+	// calls to a contract entrypoint named stake are regular transactions
+	if tx := ops[2].(*TransactionOp); tx.IsStaking() {
+		t.Errorf("expected contract call not to be a staking op")
+	}
+	if _, err := NewStakingOp(ops[2].(*TransactionOp)); err == nil {
+		t.Errorf("expected error for non-staking transaction")
+	}
+
+	// malformed parameters
+	tx.Parameters.Entrypoint = "set_delegate_parameters"
+	if _, err := NewStakingOp(tx); err == nil {
+		t.Errorf("expected error for invalid delegate parameters")
+	}
+	tx.Parameters.Value = micheline.NewPairValue(micheline.Prim{Type: micheline.PrimInt}, micheline.NewInt64(0))
+	if _, err := NewStakingOp(tx); err == nil {
+		t.Errorf("expected error for missing staking limit")
+	}
+	tx.Parameters.Value = micheline.NewPairValue(micheline.NewInt64(0), micheline.NewBig(new(big.Int).Lsh(big.NewInt(1), 64)))
+	if _, err := NewStakingOp(tx); err == nil {
+		t.Errorf("expected error for baking edge overflow")
+	}
+	if tezos.ParseOpType("finalize_unstake") != tezos.OpTypeFinalizeUnstake || tezos.OpTypeUnstake.String() != "unstake" {
+		t.Errorf("staking op type names mismatch")
+	}
+}
+
+func TestDecodeOrigination(t *testing.T) {
+	ops := decodeOps(t, `[
+		{
+			"kind": "origination",
+			"source": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+			"fee": "1299",
+			"counter": "2",
+			"gas_limit": "1870",
+			"storage_limit": "10000",
+			"balance": "5000000",
+			"delegate": "tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs",
+			"script": {
+				"code": [{"prim":"parameter","args":[{"prim":"unit"}]},{"prim":"storage","args":[{"prim":"unit"}]},{"prim":"code","args":[[{"prim":"CDR"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]]}],
+				"storage": {"prim":"Unit"}
+			},
+			"metadata": {
+				"balance_updates": [],
+				"operation_result": {
+					"status": "applied",
+					"originated_contracts": ["KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"],
+					"consumed_milligas": "1769150",
+					"storage_size": "38",
+					"paid_storage_size_diff": "38"
+				}
+			}
+		},
+		{
+			"kind": "origination",
+			"source": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+			"fee": "1299",
+			"counter": "3",
+			"gas_limit": "1870",
+			"storage_limit": "10000",
+			"balance": "0",
+			"script": {
+				"code": [{"prim":"parameter","args":[{"prim":"unit"}]},{"prim":"storage","args":[{"prim":"unit"}]},{"prim":"code","args":[[{"prim":"CDR"},{"prim":"NIL","args":[{"prim":"operation"}]},{"prim":"PAIR"}]]}],
+				"storage": {"prim":"Unit"}
+			}
+		}
+	]`)
+	o, ok := ops[0].(*OriginationOp)
+	if !ok {
+		t.Fatalf("expected *OriginationOp, got %T", ops[0])
+	}
+	if d, ok := o.DelegateAddress(); !ok || d.String() != "tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs" {
+		t.Errorf("delegate mismatch got=%s ok=%t", d, ok)
+	}
+	if c, ok := o.OriginatedContract(); !ok || c.String() != "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu" {
+		t.Errorf("contract mismatch got=%s ok=%t", c, ok)
+	}
+
+	o = ops[1].(*OriginationOp)
+	if _, ok := o.DelegateAddress(); ok {
+		t.Errorf("expected no delegate")
+	}
+	if _, ok := o.OriginatedContract(); ok {
+		t.Errorf("expected no originated contract without metadata")
+	}
+}
+
+func TestDecodeGovernanceOps(t *testing.T) {
+	ops := decodeOps(t, `[
+		{
+			"kind": "proposals",
+			"source": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+			"period": 84,
+			"proposals": [
+				"PtKathmankSpLLDALzWw7CGD2j2MtyveTwboEYokqUCP4a1LxMg",
+				"PtLimaPtLMwfNinJi9rCfDPWea8dFgTZ1MeJ9f1m2SRic6ayiwW"
+			],
+			"metadata": {}
+		},
+		{
+			"kind": "ballot",
+			"source": "tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs",
+			"period": 85,
+			"proposal": "PtLimaPtLMwfNinJi9rCfDPWea8dFgTZ1MeJ9f1m2SRic6ayiwW",
+			"ballot": "nay",
+			"metadata": {}
+		}
+	]`)
+	if len(ops) != 2 {
+		t.Fatalf("expected 2 ops, got %d", len(ops))
+	}
+
+	p, ok := ops[0].(*ProposalsOp)
+	if !ok {
+		t.Fatalf("expected *ProposalsOp, got %T", ops[0])
+	}
+	if p.OpKind() != tezos.OpTypeProposals || p.Source.String() != "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb" || p.Period != 84 {
+		t.Errorf("unexpected proposals op %#v", p)
+	}
+	if len(p.Proposals) != 2 || p.Proposals[1].String() != "PtLimaPtLMwfNinJi9rCfDPWea8dFgTZ1MeJ9f1m2SRic6ayiwW" {
+		t.Errorf("unexpected proposals %v", p.Proposals)
+	}
+
+	b, ok := ops[1].(*BallotOp)
+	if !ok {
+		t.Fatalf("expected *BallotOp, got %T", ops[1])
+	}
+	if b.OpKind() != tezos.OpTypeBallot || b.Source.String() != "tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs" || b.Period != 85 {
+		t.Errorf("unexpected ballot op %#v", b)
+	}
+	if b.Ballot != tezos.BallotVoteNay || b.Proposal.String() != "PtLimaPtLMwfNinJi9rCfDPWea8dFgTZ1MeJ9f1m2SRic6ayiwW" {
+		t.Errorf("unexpected ballot %s for %s", b.Ballot, b.Proposal)
+	}
+}
@@ -26,6 +26,24 @@ type OriginationOp struct {
 	Metadata       *OriginationOpMetadata `json:"metadata"`
 }
 
+// DelegateAddress returns the baker the new contract was delegated to at
+// origination.
+func (o OriginationOp) DelegateAddress() (tezos.Address, bool) {
+	if o.Delegate == nil || !o.Delegate.IsValid() {
+		return tezos.InvalidAddress, false
+	}
+	return *o.Delegate, true
+}
+
+// OriginatedContract returns the address of the new contract. It is only
+// available for applied operations with metadata.
+func (o OriginationOp) OriginatedContract() (tezos.Address, bool) {
+	if o.Metadata == nil || len(o.Metadata.Result.OriginatedContracts) == 0 {
+		return tezos.InvalidAddress, false
+	}
+	return o.Metadata.Result.OriginatedContracts[0], true
+}
+
 func (o OriginationOp) Manager() tezos.Address {
 	if o.ManagerPubkey2.IsValid() {
 		return o.ManagerPubkey2
@@ -0,0 +1,100 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"io"
+)
+
+// PageFunc fetches at most limit items of a list endpoint starting at offset.
+// An empty page or io.EOF signals the end of the list.
+type PageFunc func(ctx context.Context, offset, limit int) ([]interface{}, error)
+
+// PageIterator yields the items of an offset/length paginated list endpoint
+// one at a time or page by page. A page shorter than limit is treated as the
+// last page and ends iteration without another request.
+//
+//	it := NewPageIterator(fetch, 100)
+//	for it.Next(ctx) {
+//	    item := it.Item().(T)
+//	}
+//	if err := it.Err(); err != nil { ... }
+type PageIterator struct {
+	fetch  PageFunc
+	offset int
+	limit  int
+	page   []interface{}
+	item   interface{}
+	err    error
+	done   bool
+}
+
+// NewPageIterator returns an iterator which calls fetch to load pages of
+// limit items. A limit <= 0 selects 1000.
+func NewPageIterator(fetch PageFunc, limit int) *PageIterator {
+	if limit <= 0 {
+		limit = 1000
+	}
+	return &PageIterator{fetch: fetch, limit: limit}
+}
+
+// Next advances to the next item and reports whether one is available. It
+// returns false at the end of the list or on error, see Err.
+func (it *PageIterator) Next(ctx context.Context) bool {
+	if len(it.page) == 0 && !it.load(ctx) {
+		it.item = nil
+		return false
+	}
+	it.item, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Item returns the current item.
+func (it *PageIterator) Item() interface{} {
+	return it.item
+}
+
+// Err returns the first error that stopped iteration, if any.
+func (it *PageIterator) Err() error {
+	return it.err
+}
+
+// NextPage returns all remaining items of the current page or the next
+// fetched page. It returns io.EOF at the end of the list.
+func (it *PageIterator) NextPage(ctx context.Context) ([]interface{}, error) {
+	if len(it.page) == 0 && !it.load(ctx) {
+		if it.err != nil {
+			return nil, it.err
+		}
+		return nil, io.EOF
+	}
+	page := it.page
+	it.page = nil
+	return page, nil
+}
+
+func (it *PageIterator) load(ctx context.Context) bool {
+	if it.done || it.err != nil {
+		return false
+	}
+	page, err := it.fetch(ctx, it.offset, it.limit)
+	switch {
+	case err == io.EOF:
+		it.done = true
+		return false
+	case err != nil:
+		it.err = err
+		return false
+	case len(page) == 0:
+		it.done = true
+		return false
+	}
+	it.offset += len(page)
+	if len(page) < it.limit {
+		it.done = true
+	}
+	it.page = page
+	return true
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestPageIterator(t *testing.T) {
+	const total = 7
+	var calls int
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		length, _ := strconv.Atoi(r.URL.Query().Get("length"))
+		page := []int{}
+		for i := offset; i < total && i < offset+length; i++ {
+			page = append(page, i)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(page)
+	})
+	fetch := func(ctx context.Context, offset, limit int) ([]interface{}, error) {
+		var ids []int
+		if err := c.Get(ctx, "list?offset="+strconv.Itoa(offset)+"&length="+strconv.Itoa(limit), &ids); err != nil {
+			return nil, err
+		}
+		page := make([]interface{}, len(ids))
+		for i, v := range ids {
+			page[i] = v
+		}
+		return page, nil
+	}
+
+	ctx := context.Background()
+	it := NewPageIterator(fetch, 3)
+	var n int
+	for it.Next(ctx) {
+		if got := it.Item().(int); got != n {
+			t.Errorf("item %d: got=%d", n, got)
+		}
+		n++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != total || calls != 3 {
+		t.Errorf("expected %d items in 3 calls, got %d in %d", total, n, calls)
+	}
+	if it.Next(ctx) || it.Item() != nil {
+		t.Errorf("expected exhausted iterator")
+	}
+
+	// an exact multiple of the limit needs one extra empty page
+	calls = 0
+	it = NewPageIterator(fetch, 7)
+	for it.Next(ctx) {
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+
+	// mixed item and page reads
+	it = NewPageIterator(fetch, 4)
+	it.Next(ctx)
+	if page, err := it.NextPage(ctx); err != nil || len(page) != 3 || page[0].(int) != 1 {
+		t.Errorf("unexpected rest of page %v %v", page, err)
+	}
+	if page, err := it.NextPage(ctx); err != nil || len(page) != 3 {
+		t.Errorf("unexpected last page %v %v", page, err)
+	}
+	if _, err := it.NextPage(ctx); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+
+	// errors stop iteration
+	fail := errors.New("boom")
+	it = NewPageIterator(func(ctx context.Context, offset, limit int) ([]interface{}, error) {
+		if offset > 0 {
+			return nil, fail
+		}
+		return []interface{}{"a", "b"}, nil
+	}, 2)
+	n = 0
+	for it.Next(ctx) {
+		n++
+	}
+	if n != 2 || it.Err() != fail {
+		t.Errorf("expected 2 items and error, got %d %v", n, it.Err())
+	}
+	if _, err := it.NextPage(ctx); err != fail {
+		t.Errorf("expected sticky error, got %v", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strconv"
 	"time"
 
@@ -20,6 +21,7 @@ type BakingRight struct {
 	Delegate      tezos.Address `json:"delegate"`
 	Level         int64         `json:"level"`
 	Priority      int           `json:"priority"`
+	Round         int           `json:"round"` // v012+
 	EstimatedTime time.Time     `json:"estimated_time"`
 }
 
@@ -29,16 +31,43 @@ func (r BakingRight) Address() tezos.Address {
 
 // EndorsingRight holds information about the right to endorse a specific Tezos block
 type EndorsingRight struct {
-	Delegate      tezos.Address `json:"delegate"`
-	Level         int64         `json:"level"`
-	EstimatedTime time.Time     `json:"estimated_time"`
-	Slots         []int         `json:"slots"`
+	Delegate       tezos.Address `json:"delegate"`
+	Level          int64         `json:"level"`
+	EstimatedTime  time.Time     `json:"estimated_time"`
+	Slots          []int         `json:"slots"`
+	FirstSlot      int           `json:"first_slot"`      // v012+
+	EndorsingPower int           `json:"endorsing_power"` // v012+
 }
 
 func (r EndorsingRight) Address() tezos.Address {
 	return r.Delegate
 }
 
+// endorsingRightsLevel is the per-level response format used by Tenderbake
+// nodes (v012+) which nests all delegates with rights at a level.
+type endorsingRightsLevel struct {
+	EndorsingRight
+	Delegates []EndorsingRight `json:"delegates"`
+}
+
+// flattenEndorsingRights converts Tenderbake per-level rights into one
+// EndorsingRight per delegate. Entries in the pre-v012 format are kept as is.
+func flattenEndorsingRights(levels []endorsingRightsLevel) []EndorsingRight {
+	rights := make([]EndorsingRight, 0, len(levels))
+	for _, l := range levels {
+		if len(l.Delegates) == 0 {
+			rights = append(rights, l.EndorsingRight)
+			continue
+		}
+		for _, d := range l.Delegates {
+			d.Level = l.Level
+			d.EstimatedTime = l.EstimatedTime
+			rights = append(rights, d)
+		}
+	}
+	return rights
+}
+
 type SnapshotIndex struct {
 	LastRoll     []string `json:"last_roll"`
 	Nonces       []string `json:"nonces"`
@@ -104,11 +133,67 @@ type SnapshotOwners struct {
 	Rolls  []SnapshotRoll `json:"rolls"`
 }
 
-// GetBakingRights returns information about a Tezos block baking rights
+// RightsOptions defines query filters for baking and endorsing rights.
+// Unset (zero) fields are not sent. MaxRound is used by Tenderbake
+// nodes (v012+) and MaxPriority by earlier protocols.
+type RightsOptions struct {
+	Delegate    tezos.Address // limit to a single delegate
+	Level       int64         // limit to a single block level
+	Cycle       int64         // limit to an entire cycle
+	MaxRound    int           // baking rights only, v012+
+	MaxPriority int           // baking rights only, before v012
+	All         bool          // include rights of all delegates (pre v012)
+}
+
+// DefaultBakingRightsOptions reproduces the query used before options existed.
+var DefaultBakingRightsOptions = &RightsOptions{All: true, MaxPriority: 63}
+
+// DefaultEndorsingRightsOptions reproduces the query used before options existed.
+var DefaultEndorsingRightsOptions = &RightsOptions{All: true}
+
+// Query returns the URL query string for options.
+func (o RightsOptions) Query() string {
+	q := url.Values{}
+	if o.All {
+		q.Set("all", "true")
+	}
+	if o.Delegate.IsValid() {
+		q.Set("delegate", o.Delegate.String())
+	}
+	if o.Level > 0 {
+		q.Set("level", strconv.FormatInt(o.Level, 10))
+	}
+	if o.Cycle > 0 {
+		q.Set("cycle", strconv.FormatInt(o.Cycle, 10))
+	}
+	if o.MaxRound > 0 {
+		q.Set("max_round", strconv.Itoa(o.MaxRound))
+	}
+	if o.MaxPriority > 0 {
+		q.Set("max_priority", strconv.Itoa(o.MaxPriority))
+	}
+	return q.Encode()
+}
+
+func rightsUrl(path string, opts *RightsOptions) string {
+	if opts == nil {
+		return path
+	}
+	if q := opts.Query(); q != "" {
+		return path + "?" + q
+	}
+	return path
+}
+
+// GetBakingRights returns information about a Tezos block baking rights filtered
+// by opts. When opts is nil DefaultBakingRightsOptions are used.
 // https://tezos.gitlab.io/mainnet/api/rpc.html#get-block-id-helpers-baking-rights
-func (c *Client) GetBakingRights(ctx context.Context, blockID tezos.BlockHash) ([]BakingRight, error) {
+func (c *Client) GetBakingRights(ctx context.Context, blockID tezos.BlockHash, opts *RightsOptions) ([]BakingRight, error) {
+	if opts == nil {
+		opts = DefaultBakingRightsOptions
+	}
 	rights := make([]BakingRight, 0, 64)
-	u := fmt.Sprintf("chains/%s/blocks/%s/helpers/baking_rights?all=true&max_priority=63", c.ChainID, blockID)
+	u := rightsUrl(fmt.Sprintf("chains/%s/blocks/%s/helpers/baking_rights", c.ChainID, blockID), opts)
 	if err := c.Get(ctx, u, &rights); err != nil {
 		return nil, err
 	}
@@ -137,37 +222,41 @@ func (c *Client) GetBakingRightsCycle(ctx context.Context, height, cycle int64)
 	return rights, nil
 }
 
-// GetEndorsingRights returns information about a Tezos block endorsing rights
+// GetEndorsingRights returns information about a Tezos block endorsing rights filtered
+// by opts. When opts is nil DefaultEndorsingRightsOptions are used.
 // https://tezos.gitlab.io/mainnet/api/rpc.html#get-block-id-helpers-endorsing-rights
-func (c *Client) GetEndorsingRights(ctx context.Context, blockID tezos.BlockHash) ([]EndorsingRight, error) {
-	rights := make([]EndorsingRight, 0, 32)
-	u := fmt.Sprintf("chains/%s/blocks/%s/helpers/endorsing_rights?all=true", c.ChainID, blockID)
+func (c *Client) GetEndorsingRights(ctx context.Context, blockID tezos.BlockHash, opts *RightsOptions) ([]EndorsingRight, error) {
+	if opts == nil {
+		opts = DefaultEndorsingRightsOptions
+	}
+	rights := make([]endorsingRightsLevel, 0, 32)
+	u := rightsUrl(fmt.Sprintf("chains/%s/blocks/%s/helpers/endorsing_rights", c.ChainID, blockID), opts)
 	if err := c.Get(ctx, u, &rights); err != nil {
 		return nil, err
 	}
-	return rights, nil
+	return flattenEndorsingRights(rights), nil
 }
 
 // GetEndorsingRightsHeight returns information about a Tezos block endorsing rights
 // https://tezos.gitlab.io/mainnet/api/rpc.html#get-block-id-helpers-endorsing-rights
 func (c *Client) GetEndorsingRightsHeight(ctx context.Context, height int64) ([]EndorsingRight, error) {
-	rights := make([]EndorsingRight, 0, 32)
+	rights := make([]endorsingRightsLevel, 0, 32)
 	u := fmt.Sprintf("chains/%s/blocks/%d/helpers/endorsing_rights?all=true&level=%d", c.ChainID, height, height)
 	if err := c.Get(ctx, u, &rights); err != nil {
 		return nil, err
 	}
-	return rights, nil
+	return flattenEndorsingRights(rights), nil
 }
 
 // GetEndorsingRightsCycle returns information about a Tezos endorsing rights for an entire cycle
 // https://tezos.gitlab.io/mainnet/api/rpc.html#get-block-id-helpers-endorsing-rights
 func (c *Client) GetEndorsingRightsCycle(ctx context.Context, height, cycle int64) ([]EndorsingRight, error) {
-	rights := make([]EndorsingRight, 0, 32*4096)
+	rights := make([]endorsingRightsLevel, 0, 32*4096)
 	u := fmt.Sprintf("chains/%s/blocks/%d/helpers/endorsing_rights?all=true&cycle=%d", c.ChainID, height, cycle)
 	if err := c.Get(ctx, u, &rights); err != nil {
 		return nil, err
 	}
-	return rights, nil
+	return flattenEndorsingRights(rights), nil
 }
 
 // GetSnapshotIndexCycle returns information about a Tezos roll snapshot
@@ -0,0 +1,135 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+const (
+	testBlock    = "BKjcyGqv8uF9jjHCHFNgZs8wFCe8vrDPmCZU1nbS9wM7ebcPQJT"
+	testDelegate = "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"
+)
+
+func TestGetBakingRights(t *testing.T) {
+	body := `[
+		{"level":1523393,"delegate":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb","round":0,"estimated_time":"2021-06-28T12:00:00Z"},
+		{"level":1523393,"delegate":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb","round":1,"estimated_time":"2021-06-28T12:00:30Z"}
+	]`
+	c := newTestClient(t, serveJSON(t,
+		"/chains/main/blocks/"+testBlock+"/helpers/baking_rights",
+		"cycle=372&delegate="+testDelegate+"&level=1523393&max_round=1",
+		body,
+	))
+	rights, err := c.GetBakingRights(context.Background(), tezos.MustParseBlockHash(testBlock), &RightsOptions{
+		Delegate: tezos.MustParseAddress(testDelegate),
+		Level:    1523393,
+		Cycle:    372,
+		MaxRound: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rights) != 2 {
+		t.Fatalf("expected 2 rights, got %d", len(rights))
+	}
+	for i, r := range rights {
+		if r.Level != 1523393 {
+			t.Errorf("right %d: level mismatch got=%d", i, r.Level)
+		}
+		if r.Round != i {
+			t.Errorf("right %d: round mismatch got=%d", i, r.Round)
+		}
+		if r.Delegate.String() != testDelegate {
+			t.Errorf("right %d: delegate mismatch got=%s", i, r.Delegate)
+		}
+		if r.EstimatedTime.IsZero() {
+			t.Errorf("right %d: missing estimated time", i)
+		}
+	}
+}
+
+func TestGetBakingRightsDefault(t *testing.T) {
+	c := newTestClient(t, serveJSON(t,
+		"/chains/main/blocks/"+testBlock+"/helpers/baking_rights",
+		"all=true&max_priority=63",
+		`[{"level":1,"delegate":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb","priority":5}]`,
+	))
+	rights, err := c.GetBakingRights(context.Background(), tezos.MustParseBlockHash(testBlock), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rights) != 1 || rights[0].Priority != 5 {
+		t.Errorf("unexpected rights %#v", rights)
+	}
+}
+
+func TestGetEndorsingRights(t *testing.T) {
+	body := `[
+		{"level":1523393,"delegate":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb","slots":[3,17,21],"estimated_time":"2021-06-28T12:00:00Z"}
+	]`
+	c := newTestClient(t, serveJSON(t,
+		"/chains/main/blocks/"+testBlock+"/helpers/endorsing_rights",
+		"delegate="+testDelegate+"&level=1523393",
+		body,
+	))
+	rights, err := c.GetEndorsingRights(context.Background(), tezos.MustParseBlockHash(testBlock), &RightsOptions{
+		Delegate: tezos.MustParseAddress(testDelegate),
+		Level:    1523393,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rights) != 1 {
+		t.Fatalf("expected 1 right, got %d", len(rights))
+	}
+	r := rights[0]
+	if r.Level != 1523393 || r.Delegate.String() != testDelegate {
+		t.Errorf("unexpected right %#v", r)
+	}
+	if len(r.Slots) != 3 || r.Slots[0] != 3 || r.Slots[2] != 21 {
+		t.Errorf("slots mismatch got=%v", r.Slots)
+	}
+}
+
+func TestGetEndorsingRightsTenderbake(t *testing.T) {
+	body := `[
+		{"level":2244609,"delegates":[
+			{"delegate":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb","first_slot":11,"endorsing_power":156},
+			{"delegate":"tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs","first_slot":0,"endorsing_power":204}
+		],"estimated_time":"2022-04-01T12:00:00Z"}
+	]`
+	c := newTestClient(t, serveJSON(t,
+		"/chains/main/blocks/"+testBlock+"/helpers/endorsing_rights",
+		"cycle=468",
+		body,
+	))
+	rights, err := c.GetEndorsingRights(context.Background(), tezos.MustParseBlockHash(testBlock), &RightsOptions{Cycle: 468})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rights) != 2 {
+		t.Fatalf("expected 2 rights, got %d", len(rights))
+	}
+	for i, want := range []struct {
+		Delegate string
+		Slot     int
+		Power    int
+	}{
+		{testDelegate, 11, 156},
+		{"tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs", 0, 204},
+	} {
+		r := rights[i]
+		if r.Delegate.String() != want.Delegate || r.FirstSlot != want.Slot || r.EndorsingPower != want.Power {
+			t.Errorf("right %d: unexpected %#v", i, r)
+		}
+		if r.Level != 2244609 || r.EstimatedTime.IsZero() {
+			t.Errorf("right %d: missing level or time", i)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"encoding/json"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// SmartRollupOriginateOp represents a smart rollup origination
+type SmartRollupOriginateOp struct {
+	GenericOp
+	Source       tezos.Address          `json:"source"`
+	Fee          int64                  `json:"fee,string"`
+	Counter      int64                  `json:"counter,string"`
+	GasLimit     int64                  `json:"gas_limit,string"`
+	StorageLimit int64                  `json:"storage_limit,string"`
+	PvmKind      string                 `json:"pvm_kind"`
+	Kernel       string                 `json:"kernel"` // hex encoded
+	ParamType    micheline.Prim         `json:"parameters_ty"`
+	Metadata     *SmartRollupOpMetadata `json:"metadata"`
+}
+
+// SmartRollupAddMessagesOp represents a batch of messages sent to the shared
+// smart rollup inbox
+type SmartRollupAddMessagesOp struct {
+	GenericOp
+	Source       tezos.Address          `json:"source"`
+	Fee          int64                  `json:"fee,string"`
+	Counter      int64                  `json:"counter,string"`
+	GasLimit     int64                  `json:"gas_limit,string"`
+	StorageLimit int64                  `json:"storage_limit,string"`
+	Messages     []string               `json:"message"` // hex encoded
+	Metadata     *SmartRollupOpMetadata `json:"metadata"`
+}
+
+// SmartRollupCementOp represents a smart rollup commitment cementation
+type SmartRollupCementOp struct {
+	GenericOp
+	Source       tezos.Address          `json:"source"`
+	Fee          int64                  `json:"fee,string"`
+	Counter      int64                  `json:"counter,string"`
+	GasLimit     int64                  `json:"gas_limit,string"`
+	StorageLimit int64                  `json:"storage_limit,string"`
+	Rollup       tezos.Address          `json:"rollup"`
+	Commitment   string                 `json:"commitment,omitempty"` // v016 only
+	Metadata     *SmartRollupOpMetadata `json:"metadata"`
+}
+
+// SmartRollupPublishOp represents a smart rollup commitment publication
+type SmartRollupPublishOp struct {
+	GenericOp
+	Source       tezos.Address          `json:"source"`
+	Fee          int64                  `json:"fee,string"`
+	Counter      int64                  `json:"counter,string"`
+	GasLimit     int64                  `json:"gas_limit,string"`
+	StorageLimit int64                  `json:"storage_limit,string"`
+	Rollup       tezos.Address          `json:"rollup"`
+	Commitment   SmartRollupCommitment  `json:"commitment"`
+	Metadata     *SmartRollupOpMetadata `json:"metadata"`
+}
+
+// SmartRollupCommitment represents a commitment to a rollup state
+type SmartRollupCommitment struct {
+	CompressedState string `json:"compressed_state"`
+	InboxLevel      int64  `json:"inbox_level"`
+	Predecessor     string `json:"predecessor"`
+	NumberOfTicks   int64  `json:"number_of_ticks,string"`
+}
+
+// SmartRollupRefuteOp represents a move in a refutation game
+type SmartRollupRefuteOp struct {
+	GenericOp
+	Source       tezos.Address          `json:"source"`
+	Fee          int64                  `json:"fee,string"`
+	Counter      int64                  `json:"counter,string"`
+	GasLimit     int64                  `json:"gas_limit,string"`
+	StorageLimit int64                  `json:"storage_limit,string"`
+	Rollup       tezos.Address          `json:"rollup"`
+	Opponent     tezos.Address          `json:"opponent"`
+	Refutation   json.RawMessage        `json:"refutation"`
+	Metadata     *SmartRollupOpMetadata `json:"metadata"`
+}
+
+// SmartRollupTimeoutOp represents a timeout claim in a refutation game
+type SmartRollupTimeoutOp struct {
+	GenericOp
+	Source       tezos.Address          `json:"source"`
+	Fee          int64                  `json:"fee,string"`
+	Counter      int64                  `json:"counter,string"`
+	GasLimit     int64                  `json:"gas_limit,string"`
+	StorageLimit int64                  `json:"storage_limit,string"`
+	Rollup       tezos.Address          `json:"rollup"`
+	Stakers      SmartRollupStakers     `json:"stakers"`
+	Metadata     *SmartRollupOpMetadata `json:"metadata"`
+}
+
+// SmartRollupStakers identifies both players of a refutation game
+type SmartRollupStakers struct {
+	Alice tezos.Address `json:"alice"`
+	Bob   tezos.Address `json:"bob"`
+}
+
+// SmartRollupExecuteOutboxMessageOp represents the execution of a rollup
+// outbox message on L1
+type SmartRollupExecuteOutboxMessageOp struct {
+	GenericOp
+	Source             tezos.Address          `json:"source"`
+	Fee                int64                  `json:"fee,string"`
+	Counter            int64                  `json:"counter,string"`
+	GasLimit           int64                  `json:"gas_limit,string"`
+	StorageLimit       int64                  `json:"storage_limit,string"`
+	Rollup             tezos.Address          `json:"rollup"`
+	CementedCommitment string                 `json:"cemented_commitment"`
+	OutputProof        string                 `json:"output_proof"` // hex encoded
+	Metadata           *SmartRollupOpMetadata `json:"metadata"`
+}
+
+// SmartRollupRecoverBondOp represents a staker's bond recovery
+type SmartRollupRecoverBondOp struct {
+	GenericOp
+	Source       tezos.Address          `json:"source"`
+	Fee          int64                  `json:"fee,string"`
+	Counter      int64                  `json:"counter,string"`
+	GasLimit     int64                  `json:"gas_limit,string"`
+	StorageLimit int64                  `json:"storage_limit,string"`
+	Rollup       tezos.Address          `json:"rollup"`
+	Staker       tezos.Address          `json:"staker"`
+	Metadata     *SmartRollupOpMetadata `json:"metadata"`
+}
+
+// SmartRollupOpMetadata represents smart rollup operation metadata
+type SmartRollupOpMetadata struct {
+	BalanceUpdates BalanceUpdates    `json:"balance_updates"` // fee-related
+	Result         SmartRollupResult `json:"operation_result"`
+}
+
+// SmartRollupResult represents the result of any smart rollup operation,
+// fields are only present for the operation kinds noted.
+type SmartRollupResult struct {
+	BalanceUpdates   BalanceUpdates   `json:"balance_updates"`
+	ConsumedMilliGas int64            `json:"consumed_milligas,string"`
+	Status           tezos.OpStatus   `json:"status"`
+	Errors           []OperationError `json:"errors,omitempty"`
+
+	// originate
+	Address               *tezos.Address `json:"address,omitempty"`
+	GenesisCommitmentHash string         `json:"genesis_commitment_hash,omitempty"`
+	Size                  int64          `json:"size,string"`
+
+	// cement
+	InboxLevel     int64  `json:"inbox_level"`
+	CommitmentHash string `json:"commitment_hash,omitempty"`
+
+	// publish
+	StakedHash       string `json:"staked_hash,omitempty"`
+	PublishedAtLevel int64  `json:"published_at_level"`
+
+	// refute, timeout
+	GameStatus json.RawMessage `json:"game_status,omitempty"`
+
+	// execute outbox message
+	PaidStorageSizeDiff int64          `json:"paid_storage_size_diff,string"`
+	TicketUpdatesList   []TicketUpdate `json:"ticket_updates,omitempty"`
+}
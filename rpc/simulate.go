@@ -0,0 +1,238 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// simulationSignature is the all-zero signature run_operation accepts
+// in place of a real signature.
+var simulationSignature = tezos.NewSignature(tezos.SignatureTypeGeneric, make([]byte, 64))
+
+// runOperationRequest is the request body of run_operation.
+type runOperationRequest struct {
+	Operation struct {
+		Branch    tezos.BlockHash   `json:"branch"`
+		Contents  []json.RawMessage `json:"contents"`
+		Signature string            `json:"signature"`
+	} `json:"operation"`
+	ChainId tezos.ChainIdHash `json:"chain_id"`
+}
+
+// runOperationResponse holds the results of run_operation in the order of
+// the submitted contents.
+type runOperationResponse struct {
+	Contents []struct {
+		Metadata struct {
+			Result          OperationResult `json:"operation_result"`
+			InternalResults []struct {
+				Result OperationResult `json:"result"`
+			} `json:"internal_operation_results"`
+		} `json:"metadata"`
+	} `json:"contents"`
+}
+
+// OperationFailedError is returned by RunOperation when the node simulated an
+// operation group and one of its contents did not apply. It is distinct from
+// transport and RPC errors which prevent the simulation from running at all.
+type OperationFailedError struct {
+	Index  int              // position of the first failed content
+	Kind   tezos.OpType     // kind of the failed content
+	Status tezos.OpStatus   // failed, backtracked or skipped
+	Errors []OperationError // errors of the content and its internal results
+}
+
+func (e *OperationFailedError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("rpc: %s operation %d %s", e.Kind, e.Index, e.Status)
+	}
+	return fmt.Sprintf("rpc: %s operation %d %s: %s", e.Kind, e.Index, e.Status, DescribeError(e.Errors[len(e.Errors)-1]))
+}
+
+// RunOperation simulates the operation group op on top of block blockID
+// without checking its signature and returns the contents with metadata,
+// i.e. consumed gas, storage size, balance updates and internal operation
+// results. A missing branch defaults to blockID. When a content fails to
+// apply the simulated contents are returned together with an
+// *OperationFailedError.
+// https://tezos.gitlab.io/active/rpc.html#post-block-id-helpers-scripts-run-operation
+func (c *Client) RunOperation(ctx context.Context, blockID tezos.BlockHash, op *OperationHeader) (Operations, error) {
+	req := runOperationRequest{ChainId: op.ChainID}
+	req.Operation.Branch = op.Branch
+	if !req.Operation.Branch.IsValid() {
+		req.Operation.Branch = blockID
+	}
+	req.Operation.Signature = op.Signature
+	if req.Operation.Signature == "" {
+		req.Operation.Signature = simulationSignature.String()
+	}
+	for _, v := range op.Contents {
+		buf, err := simulationContents(v)
+		if err != nil {
+			return nil, err
+		}
+		req.Operation.Contents = append(req.Operation.Contents, buf)
+	}
+	raw, err := c.runOperation(ctx, blockID, &req)
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Contents Operations `json:"contents"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, fmt.Errorf("rpc: decoding run_operation result: %w", err)
+	}
+	var status runOperationResponse
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return nil, fmt.Errorf("rpc: decoding run_operation result: %w", err)
+	}
+	for i, v := range status.Contents {
+		r := v.Metadata.Result
+		if r.Status.IsSuccess() {
+			continue
+		}
+		ferr := &OperationFailedError{Index: i, Status: r.Status}
+		if i < len(res.Contents) && res.Contents[i] != nil {
+			ferr.Kind = res.Contents[i].OpKind()
+		}
+		ferr.Errors = append(ferr.Errors, r.Errors...)
+		for _, ir := range v.Metadata.InternalResults {
+			ferr.Errors = append(ferr.Errors, ir.Result.Errors...)
+		}
+		return res.Contents, ferr
+	}
+	return res.Contents, nil
+}
+
+// runOperation posts req to run_operation and returns the raw result. A
+// missing chain id is looked up first.
+func (c *Client) runOperation(ctx context.Context, blockID tezos.BlockHash, req *runOperationRequest) (json.RawMessage, error) {
+	if !req.ChainId.IsValid() {
+		if err := c.Get(ctx, fmt.Sprintf("chains/%s/chain_id", c.ChainID), &req.ChainId); err != nil {
+			return nil, err
+		}
+	}
+	var raw json.RawMessage
+	u := fmt.Sprintf("chains/%s/blocks/%s/helpers/scripts/run_operation", c.ChainID, blockID)
+	if err := c.Post(ctx, u, req, &raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// SimulateOperations dry-runs manager operations on top of block blockID and
+// returns one result per operation in input order, including consumed gas
+// and errors. Operations of the same source are run as a single batch so that
+// each operation sees the effects of the ones before it. Operations with a
+// zero counter are assigned consecutive counters following the current
+// counter of their source, the assigned counters are written back to ops.
+// https://tezos.gitlab.io/active/rpc.html#post-block-id-helpers-scripts-run-operation
+func (c *Client) SimulateOperations(ctx context.Context, ops []Operation, blockID tezos.BlockHash) ([]OperationResult, error) {
+	// batch operations by source, keep the order sources first appear in
+	var (
+		sources []tezos.Address
+		bySrc   = make(map[string][]int)
+	)
+	for i, op := range ops {
+		src, _, _, ok := managerLimits(op)
+		if !ok {
+			return nil, fmt.Errorf("rpc: simulate: unsupported operation kind %s", op.OpKind())
+		}
+		key := src.String()
+		if _, ok := bySrc[key]; !ok {
+			sources = append(sources, src)
+		}
+		bySrc[key] = append(bySrc[key], i)
+	}
+
+	var chainId tezos.ChainIdHash
+	res := make([]OperationResult, len(ops))
+	for _, src := range sources {
+		idx := bySrc[src.String()]
+		if err := c.fillCounters(ctx, src, ops, idx, blockID); err != nil {
+			return nil, err
+		}
+		req := runOperationRequest{ChainId: chainId}
+		req.Operation.Branch = blockID
+		req.Operation.Signature = simulationSignature.String()
+		for _, i := range idx {
+			buf, err := simulationContents(ops[i])
+			if err != nil {
+				return nil, err
+			}
+			req.Operation.Contents = append(req.Operation.Contents, buf)
+		}
+		raw, err := c.runOperation(ctx, blockID, &req)
+		if err != nil {
+			return nil, err
+		}
+		chainId = req.ChainId
+		var resp runOperationResponse
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			return nil, fmt.Errorf("rpc: decoding run_operation result: %w", err)
+		}
+		if len(resp.Contents) != len(idx) {
+			return nil, fmt.Errorf("rpc: simulate: got %d results for %d operations", len(resp.Contents), len(idx))
+		}
+		for k, i := range idx {
+			res[i] = resp.Contents[k].Metadata.Result
+		}
+	}
+	return res, nil
+}
+
+// fillCounters assigns counters to operations at idx which have none.
+func (c *Client) fillCounters(ctx context.Context, src tezos.Address, ops []Operation, idx []int, blockID tezos.BlockHash) error {
+	var next int64
+	for _, i := range idx {
+		_, counter, _, _ := managerLimits(ops[i])
+		if counter == 0 {
+			if next == 0 {
+				last, err := c.GetContractCounter(ctx, src, blockID)
+				if err != nil {
+					return err
+				}
+				next = last + 1
+			}
+			counter = next
+			setManagerCounter(ops[i], counter)
+		}
+		next = counter + 1
+	}
+	return nil
+}
+
+// setManagerCounter sets the counter of a manager operation.
+func setManagerCounter(op Operation, counter int64) {
+	switch o := op.(type) {
+	case *TransactionOp:
+		o.Counter = counter
+	case *RevelationOp:
+		o.Counter = counter
+	case *DelegationOp:
+		o.Counter = counter
+	case *OriginationOp:
+		o.Counter = counter
+	}
+}
+
+// simulationContents returns the JSON encoding of op without metadata.
+func simulationContents(op Operation) (json.RawMessage, error) {
+	buf, err := json.Marshal(op)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return nil, err
+	}
+	delete(m, "metadata")
+	return json.Marshal(m)
+}
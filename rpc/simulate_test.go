@@ -0,0 +1,188 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func TestSimulateOperations(t *testing.T) {
+	var runs int
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/chains/main/chain_id":
+			w.Write([]byte(`"NetXdQprcVkpaWU"`))
+		case "/chains/main/blocks/" + testBlock + "/context/contracts/" + testDelegate + "/counter":
+			w.Write([]byte(`"41"`))
+		case "/chains/main/blocks/" + testBlock + "/helpers/scripts/run_operation":
+			runs++
+			if r.Method != http.MethodPost {
+				t.Errorf("unexpected method %s", r.Method)
+			}
+			var req struct {
+				Operation struct {
+					Branch    string                   `json:"branch"`
+					Contents  []map[string]interface{} `json:"contents"`
+					Signature string                   `json:"signature"`
+				} `json:"operation"`
+				ChainId string `json:"chain_id"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatal(err)
+			}
+			if req.Operation.Branch != testBlock || req.ChainId != "NetXdQprcVkpaWU" || req.Operation.Signature == "" {
+				t.Errorf("unexpected request %#v", req)
+			}
+			if len(req.Operation.Contents) != 2 {
+				t.Fatalf("expected two operations in one batch, got %d", len(req.Operation.Contents))
+			}
+			for i, op := range req.Operation.Contents {
+				if op["counter"] != []string{"42", "43"}[i] {
+					t.Errorf("op %d: unexpected counter %v", i, op["counter"])
+				}
+				if _, ok := op["metadata"]; ok {
+					t.Errorf("op %d: unexpected metadata", i)
+				}
+			}
+			w.Write([]byte(`{"contents":[
+				{"kind":"transaction","metadata":{"operation_result":{"status":"applied","consumed_milligas":"1000000"}}},
+				{"kind":"transaction","metadata":{"operation_result":{"status":"failed","consumed_milligas":"1200000","errors":[{"kind":"temporary","id":"proto.balance_too_low"}]}}}
+			]}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	src := tezos.MustParseAddress(testDelegate)
+	dst := tezos.MustParseAddress("tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs")
+	ops := []Operation{
+		&TransactionOp{GenericOp: GenericOp{Kind: tezos.OpTypeTransaction}, Source: src, Destination: dst, Amount: 1000000, GasLimit: 1500, StorageLimit: 300},
+		&TransactionOp{GenericOp: GenericOp{Kind: tezos.OpTypeTransaction}, Source: src, Destination: dst, Amount: 2000000, GasLimit: 1500, StorageLimit: 300},
+	}
+	res, err := c.SimulateOperations(context.Background(), ops, tezos.MustParseBlockHash(testBlock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs != 1 {
+		t.Errorf("expected a single run_operation call, got %d", runs)
+	}
+	if len(res) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(res))
+	}
+	if !res[0].IsSuccess() || res[0].ConsumedMilliGas != 1000000 {
+		t.Errorf("unexpected first result %#v", res[0])
+	}
+	if res[1].IsSuccess() || res[1].ConsumedMilliGas != 1200000 || len(res[1].Errors) != 1 {
+		t.Errorf("unexpected second result %#v", res[1])
+	}
+	if ops[0].(*TransactionOp).Counter != 42 || ops[1].(*TransactionOp).Counter != 43 {
+		t.Errorf("counters not assigned")
+	}
+
+	// non-manager operations cannot be simulated
+	if _, err := c.SimulateOperations(context.Background(), []Operation{&BallotOp{}}, tezos.MustParseBlockHash(testBlock)); err == nil {
+		t.Errorf("expected error for non-manager operation")
+	}
+}
+
+func TestRunOperation(t *testing.T) {
+	var result string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/chains/main/chain_id":
+			w.Write([]byte(`"NetXdQprcVkpaWU"`))
+		case "/chains/main/blocks/" + testBlock + "/helpers/scripts/run_operation":
+			var req runOperationRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatal(err)
+			}
+			if req.Operation.Branch.String() != testBlock || !req.ChainId.IsValid() || req.Operation.Signature == "" {
+				t.Errorf("unexpected request %#v", req)
+			}
+			if result == "" {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`[{"kind":"permanent","id":"failure"}]`))
+				return
+			}
+			w.Write([]byte(result))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	op := &OperationHeader{
+		Contents: Operations{
+			&TransactionOp{
+				GenericOp:    GenericOp{Kind: tezos.OpTypeTransaction},
+				Source:       tezos.MustParseAddress(testDelegate),
+				Destination:  tezos.MustParseAddress(testContract),
+				Counter:      42,
+				GasLimit:     10000,
+				StorageLimit: 300,
+			},
+		},
+	}
+	block := tezos.MustParseBlockHash(testBlock)
+
+	// applied with internal result
+	result = `{"contents":[{"kind":"transaction","source":"` + testDelegate + `","destination":"` + testContract + `","counter":"42","metadata":{
+		"operation_result":{"status":"applied","consumed_milligas":"2500000","storage_size":"1234"},
+		"internal_operation_results":[{"kind":"transaction","source":"` + testContract + `","nonce":0,"destination":"tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs","amount":"10","result":{"status":"applied","consumed_milligas":"100000"}}]
+	}}]}`
+	ops, err := c.RunOperation(context.Background(), block, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(ops))
+	}
+	tx, ok := ops[0].(*TransactionOp)
+	if !ok || tx.Metadata == nil || tx.Metadata.Result == nil {
+		t.Fatalf("unexpected result %#v", ops[0])
+	}
+	if tx.Metadata.Result.ConsumedMilliGas != 2500000 || tx.Metadata.Result.StorageSize != 1234 {
+		t.Errorf("unexpected operation result %#v", tx.Metadata.Result)
+	}
+	if len(tx.Metadata.InternalResults) != 1 || tx.Metadata.InternalResults[0].Result.ConsumedMilliGas != 100000 {
+		t.Errorf("missing internal result")
+	}
+
+	// script failure is reported as operation error
+	result = `{"contents":[{"kind":"transaction","source":"` + testDelegate + `","destination":"` + testContract + `","counter":"42","metadata":{
+		"operation_result":{"status":"backtracked","consumed_milligas":"500000"},
+		"internal_operation_results":[{"kind":"transaction","source":"` + testContract + `","nonce":0,"destination":"tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs","amount":"10","result":{"status":"failed","errors":[{"kind":"temporary","id":"proto.015-PtLimaPt.michelson_v1.script_rejected"}]}}]
+	}}]}`
+	ops, err = c.RunOperation(context.Background(), block, op)
+	var ferr *OperationFailedError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("expected operation failure, got %v", err)
+	}
+	if ferr.Index != 0 || ferr.Kind != tezos.OpTypeTransaction || len(ferr.Errors) != 1 {
+		t.Errorf("unexpected failure %#v", ferr)
+	}
+	if len(ops) != 1 {
+		t.Errorf("expected results along with failure")
+	}
+
+	// transport errors are passed through unchanged
+	result = ""
+	_, err = c.RunOperation(context.Background(), block, op)
+	if err == nil || errors.As(err, &ferr) {
+		t.Errorf("expected rpc error, got %v", err)
+	}
+	if _, ok := err.(HTTPError); !ok {
+		t.Errorf("expected http error, got %T", err)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// groupOverhead is the size of branch and signature forged with every group.
+const groupOverhead = 32 + 64
+
+// SplitOperations greedily partitions manager operations into groups whose
+// summed gas limit stays below maxGas and whose forged size including branch
+// and signature stays below maxSize. A zero limit is ignored. Operations are
+// grouped by source in order of first appearance and sorted by counter, so
+// groups must be injected in the returned order. An operation which exceeds a
+// limit on its own ends up in a group of its own, as does every non-manager
+// operation. With a size limit, an error is returned when a manager operation
+// cannot be forged.
+func SplitOperations(ops []Operation, maxGas, maxSize int64) ([][]Operation, error) {
+	// group by source, keep the order sources first appear in
+	var (
+		sources []tezos.Address
+		bySrc   = make(map[string][]Operation)
+		other   []Operation
+	)
+	for _, op := range ops {
+		src, _, _, ok := managerLimits(op)
+		if !ok {
+			other = append(other, op)
+			continue
+		}
+		key := src.String()
+		if _, ok := bySrc[key]; !ok {
+			sources = append(sources, src)
+		}
+		bySrc[key] = append(bySrc[key], op)
+	}
+
+	res := make([][]Operation, 0)
+	for _, src := range sources {
+		list := bySrc[src.String()]
+		sort.SliceStable(list, func(i, j int) bool {
+			_, ci, _, _ := managerLimits(list[i])
+			_, cj, _, _ := managerLimits(list[j])
+			return ci < cj
+		})
+		var (
+			group     []Operation
+			gas, size int64
+		)
+		for _, op := range list {
+			_, _, g, _ := managerLimits(op)
+			var sz int64
+			if maxSize > 0 {
+				var err error
+				if sz, err = forgedSize(op); err != nil {
+					return nil, err
+				}
+			}
+			if len(group) > 0 && ((maxGas > 0 && gas+g > maxGas) || (maxSize > 0 && groupOverhead+size+sz > maxSize)) {
+				res = append(res, group)
+				group, gas, size = nil, 0, 0
+			}
+			group = append(group, op)
+			gas += g
+			size += sz
+		}
+		if len(group) > 0 {
+			res = append(res, group)
+		}
+	}
+	for _, op := range other {
+		res = append(res, []Operation{op})
+	}
+	return res, nil
+}
+
+// managerLimits returns source, counter and gas limit of manager operations.
+func managerLimits(op Operation) (tezos.Address, int64, int64, bool) {
+	switch o := op.(type) {
+	case *TransactionOp:
+		return o.Source, o.Counter, o.GasLimit, true
+	case *RevelationOp:
+		return o.Source, o.Counter, o.GasLimit, true
+	case *DelegationOp:
+		return o.Source, o.Counter, o.GasLimit, true
+	case *OriginationOp:
+		return o.Source, o.Counter, o.GasLimit, true
+	default:
+		return tezos.Address{}, 0, 0, false
+	}
+}
+
+// forgedSize returns the binary size of op.
+func forgedSize(op Operation) (int64, error) {
+	f, ok := op.(forger)
+	if !ok {
+		return 0, fmt.Errorf("rpc: forge: unsupported operation kind %s", op.OpKind())
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := f.EncodeBuffer(buf); err != nil {
+		return 0, err
+	}
+	return int64(buf.Len()), nil
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package rpc
+
+import (
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func TestSplitOperations(t *testing.T) {
+	src := tezos.MustParseAddress("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb")
+	dst := tezos.MustParseAddress("KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu")
+	newTx := func(counter int64) *TransactionOp {
+		return &TransactionOp{
+			GenericOp:   GenericOp{Kind: tezos.OpTypeTransaction},
+			Source:      src,
+			Destination: dst,
+			Fee:         1000,
+			Counter:     counter,
+			GasLimit:    10000,
+			Amount:      1,
+		}
+	}
+	// out of counter order on purpose
+	ops := []Operation{newTx(5), newTx(1), newTx(3), newTx(2), newTx(4)}
+	sz, err := forgedSize(ops[0])
+	if err != nil {
+		t.Fatalf("transaction cannot be forged: %v", err)
+	}
+
+	check := func(name string, maxGas, maxSize int64, sizes ...int) {
+		t.Helper()
+		groups, err := SplitOperations(ops, maxGas, maxSize)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(groups) != len(sizes) {
+			t.Fatalf("%s: expected %d groups, got %d", name, len(sizes), len(groups))
+		}
+		var counter int64
+		for i, g := range groups {
+			if len(g) != sizes[i] {
+				t.Errorf("%s: group %d: expected %d ops, got %d", name, i, sizes[i], len(g))
+			}
+			for _, op := range g {
+				_, c, _, _ := managerLimits(op)
+				if c != counter+1 {
+					t.Errorf("%s: group %d: counter out of order %d after %d", name, i, c, counter)
+				}
+				counter = c
+			}
+		}
+	}
+
+	// gas limit exactly fits two transactions
+	check("gas", 20000, 0, 2, 2, 1)
+	// size limit fits three transactions
+	check("size", 0, groupOverhead+3*sz, 3, 2)
+	// both limits, gas is stricter
+	check("both", 40000, groupOverhead+3*sz, 3, 2)
+	// no limits
+	check("none", 0, 0, 5)
+	// a single transaction above the limit stays alone
+	check("oversize", 5000, 0, 1, 1, 1, 1, 1)
+
+	// sources are split into separate groups, other ops become singletons
+	other := *newTx(1)
+	other.Source = tezos.MustParseAddress("tz28Pw51UCaNFZB8bZkW9pfBA44ezqyQn4Fs")
+	mixed := []Operation{newTx(1), &other, &GenericOp{Kind: tezos.OpTypeEndorsement}, newTx(2)}
+	groups, err := SplitOperations(mixed, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 3 || len(groups[0]) != 2 || groups[1][0] != &other || groups[2][0].OpKind() != tezos.OpTypeEndorsement {
+		t.Errorf("unexpected mixed grouping %v", groups)
+	}
+
+	// operations which cannot be forged fail a size limited split
+	bad := newTx(1)
+	bad.Destination = tezos.Address{}
+	if _, err := SplitOperations([]Operation{bad}, 0, 1000); err == nil {
+		t.Errorf("expected forge error")
+	}
+	if _, err := SplitOperations([]Operation{bad}, 20000, 0); err != nil {
+		t.Errorf("unexpected error without size limit: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"fmt"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// stakingEntrypoints maps the pseudo-entrypoints introduced with adaptive
+// issuance (v018) to their pseudo operation type.
+var stakingEntrypoints = map[string]tezos.OpType{
+	"stake":                   tezos.OpTypeStake,
+	"unstake":                 tezos.OpTypeUnstake,
+	"finalize_unstake":        tezos.OpTypeFinalizeUnstake,
+	"set_delegate_parameters": tezos.OpTypeSetDelegateParameters,
+}
+
+// StakingOp represents a staking operation. On the wire staking operations
+// are transactions from an implicit account to itself that call one of the
+// staking pseudo-entrypoints.
+type StakingOp struct {
+	*TransactionOp
+	Kind               tezos.OpType        `json:"kind"`
+	DelegateParameters *DelegateParameters `json:"delegate_parameters,omitempty"`
+}
+
+// OpKind returns the staking pseudo operation type.
+func (o *StakingOp) OpKind() tezos.OpType {
+	return o.Kind
+}
+
+// DelegateParameters holds the staking parameters a delegate accepts as set
+// with set_delegate_parameters.
+type DelegateParameters struct {
+	LimitOfStakingOverBaking int64 `json:"limit_of_staking_over_baking_millionth"`
+	EdgeOfBakingOverStaking  int64 `json:"edge_of_baking_over_staking_billionth"`
+}
+
+// IsStaking returns true when the transaction is a staking operation.
+func (o *TransactionOp) IsStaking() bool {
+	if o.Parameters == nil || !o.Source.IsImplicit() || !o.Source.Equal(o.Destination) {
+		return false
+	}
+	_, ok := stakingEntrypoints[o.Parameters.Entrypoint]
+	return ok
+}
+
+// NewStakingOp decodes a staking operation from transaction o. It fails when
+// o is not a staking operation or its parameters are malformed.
+func NewStakingOp(o *TransactionOp) (*StakingOp, error) {
+	if !o.IsStaking() {
+		return nil, fmt.Errorf("rpc: transaction is not a staking operation")
+	}
+	op := &StakingOp{
+		TransactionOp: o,
+		Kind:          stakingEntrypoints[o.Parameters.Entrypoint],
+	}
+	if op.Kind == tezos.OpTypeSetDelegateParameters {
+		p, err := decodeDelegateParameters(o.Parameters.Value)
+		if err != nil {
+			return nil, err
+		}
+		op.DelegateParameters = p
+	}
+	return op, nil
+}
+
+// decodeDelegateParameters decodes the set_delegate_parameters argument, a
+// right comb of the staking limit, the baking edge and unit.
+func decodeDelegateParameters(val micheline.Prim) (*DelegateParameters, error) {
+	orig := val
+	args := make([]micheline.Prim, 0, 3)
+	for val.OpCode == micheline.D_PAIR && len(val.Args) > 1 {
+		args = append(args, val.Args[:len(val.Args)-1]...)
+		val = val.Args[len(val.Args)-1]
+	}
+	args = append(args, val)
+	if len(args) < 2 || !isInt64Prim(args[0]) || !isInt64Prim(args[1]) {
+		return nil, fmt.Errorf("rpc: invalid delegate parameters %s", orig.DumpLimit(64))
+	}
+	return &DelegateParameters{
+		LimitOfStakingOverBaking: args[0].Int.Int64(),
+		EdgeOfBakingOverStaking:  args[1].Int.Int64(),
+	}, nil
+}
+
+// isInt64Prim reports whether p is an integer that fits into int64.
+func isInt64Prim(p micheline.Prim) bool {
+	return p.Type == micheline.PrimInt && p.Int != nil && p.Int.IsInt64()
+}
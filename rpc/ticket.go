@@ -0,0 +1,28 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package rpc
+
+import (
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+// TicketToken identifies a ticket by its ticketer and content
+type TicketToken struct {
+	Ticketer    tezos.Address  `json:"ticketer"`
+	ContentType micheline.Prim `json:"content_type"`
+	Content     micheline.Prim `json:"content"`
+}
+
+// TicketBalanceUpdate is a change of ticket balance for an account
+type TicketBalanceUpdate struct {
+	Account tezos.Address `json:"account"`
+	Amount  tezos.Z       `json:"amount"` // arbitrary precision, tickets are not bound to int64
+}
+
+// TicketUpdate lists all balance changes for a single ticket (v015+)
+type TicketUpdate struct {
+	Ticket  TicketToken           `json:"ticket_token"`
+	Updates []TicketBalanceUpdate `json:"updates"`
+}
@@ -4,6 +4,8 @@
 package rpc
 
 import (
+	"math/big"
+
 	"blockwatch.cc/tzgo/micheline"
 	"blockwatch.cc/tzgo/tezos"
 )
@@ -22,15 +24,37 @@ type TransactionOp struct {
 	Metadata     *TransactionOpMetadata `json:"metadata"`
 }
 
+// BigmapUpdates returns all bigmap changes of the transaction and its
+// internal operations in execution order. Both the legacy big_map_diff and
+// the lazy_storage_diff format of v008+ are supported.
+func (o *TransactionOp) BigmapUpdates() []BigmapUpdate {
+	res := make([]BigmapUpdate, 0)
+	if o.Metadata == nil {
+		return res
+	}
+	if o.Metadata.Result != nil {
+		res = append(res, newBigmapUpdates(o.Metadata.Result.BigmapUpdates())...)
+	}
+	for _, v := range o.Metadata.InternalResults {
+		if v.Result != nil {
+			res = append(res, newBigmapUpdates(v.Result.BigmapUpdates())...)
+		}
+	}
+	return res
+}
+
 // TransactionOpMetadata represents a transaction operation metadata
 type TransactionOpMetadata struct {
-	BalanceUpdates  BalanceUpdates     `json:"balance_updates"` // fee-related
-	Result          *TransactionResult `json:"operation_result"`
-	InternalResults []*InternalResult  `json:"internal_operation_results,omitempty"`
+	BalanceUpdates  BalanceUpdates    `json:"balance_updates"` // fee-related
+	Result          *OperationResult  `json:"operation_result"`
+	InternalResults []*InternalResult `json:"internal_operation_results,omitempty"`
 }
 
-// TransactionResult represents a transaction result
-type TransactionResult struct {
+// TransactionResult is kept for compatibility, use OperationResult instead.
+type TransactionResult = OperationResult
+
+// OperationResult represents a manager operation result
+type OperationResult struct {
 	BalanceUpdates      BalanceUpdates   `json:"balance_updates"` // tx or contract related
 	ConsumedGas         int64            `json:"consumed_gas,string"`
 	ConsumedMilliGas    int64            `json:"consumed_milligas,string"`
@@ -49,13 +73,94 @@ type TransactionResult struct {
 
 	// when reused as internal origination result
 	OriginatedContracts []tezos.Address `json:"originated_contracts,omitempty"`
+
+	// v015
+	TicketUpdatesList []TicketUpdate `json:"ticket_updates,omitempty"`
+
+	// v016
+	TicketReceipt []TicketUpdate `json:"ticket_receipt,omitempty"`
+}
+
+// IsSuccess returns true when the operation was applied.
+func (r OperationResult) IsSuccess() bool {
+	return r.Status.IsSuccess()
+}
+
+// BigmapUpdates returns all bigmap changes of the result. Lazy storage
+// diffs (v008+) are converted to the legacy big_map_diff format, on older
+// protocols big_map_diff is returned as is.
+func (r OperationResult) BigmapUpdates() micheline.BigmapDiff {
+	if len(r.LazyStorageDiff) == 0 {
+		return r.BigmapDiff
+	}
+	res := make(micheline.BigmapDiff, 0)
+	for _, v := range r.LazyStorageDiff {
+		if d, ok := v.(*LazyBigMapDiff); ok {
+			res = append(res, d.BigmapDiff()...)
+		}
+	}
+	return res
+}
+
+// BigmapUpdate is a single bigmap change of an operation. Alloc and copy
+// actions carry the new bigmap types or source id, update and remove actions
+// the key and, on update, the new value. A remove action without key hash
+// deletes the entire bigmap. BigmapId is the bigmap that changed, on copy
+// that is the destination.
+type BigmapUpdate struct {
+	Action    micheline.DiffAction
+	BigmapId  int64
+	SourceId  int64          // copy
+	KeyType   micheline.Prim // alloc
+	ValueType micheline.Prim // alloc
+	KeyHash   tezos.ExprHash // update, remove
+	Key       micheline.Prim // update, remove
+	Value     micheline.Prim // update
+}
+
+// newBigmapUpdates converts bigmap diff elements into bigmap updates.
+func newBigmapUpdates(diff micheline.BigmapDiff) []BigmapUpdate {
+	res := make([]BigmapUpdate, 0, len(diff))
+	for _, v := range diff {
+		u := BigmapUpdate{
+			Action:    v.Action,
+			BigmapId:  v.Id,
+			KeyHash:   v.KeyHash,
+			Key:       v.Key,
+			Value:     v.Value,
+			KeyType:   v.KeyType,
+			ValueType: v.ValueType,
+		}
+		if v.Action == micheline.DiffActionCopy {
+			// legacy big_map_diff only sets source and destination
+			u.SourceId = v.SourceId
+			if v.DestId != 0 {
+				u.BigmapId = v.DestId
+			}
+		}
+		res = append(res, u)
+	}
+	return res
+}
+
+// TicketUpdates returns all ticket balance changes of the result. Unlike
+// bigmaps, ticket changes are not part of the lazy storage diff,
+// the node reports them in `ticket_updates` (transactions) and
+// `ticket_receipt` (internal transfers).
+func (r OperationResult) TicketUpdates() []TicketUpdate {
+	if len(r.TicketReceipt) == 0 {
+		return r.TicketUpdatesList
+	}
+	res := make([]TicketUpdate, 0, len(r.TicketUpdatesList)+len(r.TicketReceipt))
+	res = append(res, r.TicketUpdatesList...)
+	return append(res, r.TicketReceipt...)
 }
 
 type InternalResult struct {
 	GenericOp
 	Source      tezos.Address         `json:"source"`
 	Nonce       int64                 `json:"nonce"`
-	Result      *TransactionResult    `json:"result"`
+	Result      *OperationResult      `json:"result"`
 	Destination *tezos.Address        `json:"destination,omitempty"` // transaction
 	Delegate    *tezos.Address        `json:"delegate,omitempty"`    // delegation
 	Parameters  *micheline.Parameters `json:"parameters,omitempty"`  // transaction
@@ -64,6 +169,23 @@ type InternalResult struct {
 	Script      *micheline.Script     `json:"script,omitempty"`      // origination
 }
 
+// StorageBurn returns the amount of mutez burned for storage by an operation
+// result. Burn is computed from paid storage size diff, and, when new accounts
+// or contracts were allocated, their origination size times cost per byte.
+func StorageBurn(result OperationResult, constants *Constants) tezos.Z {
+	if constants == nil {
+		return tezos.NewZ(0)
+	}
+	size := result.PaidStorageSizeDiff
+	if result.Allocated {
+		size += constants.OriginationSize
+	}
+	size += int64(len(result.OriginatedContracts)) * constants.OriginationSize
+	burn := tezos.NewZ(size)
+	burn.Big().Mul(burn.Big(), big.NewInt(constants.CostPerByte))
+	return burn
+}
+
 // found in block metadata from v010+
 type ImplicitResult struct {
 	Kind                tezos.OpType      `json:"kind"`
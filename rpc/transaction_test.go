@@ -0,0 +1,263 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package rpc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"blockwatch.cc/tzgo/micheline"
+	"blockwatch.cc/tzgo/tezos"
+)
+
+func TestStorageBurn(t *testing.T) {
+	// transfer to a new implicit account that also paid for storage
+	// in the destination contract
+	buf := []byte(`{
+		"status": "applied",
+		"consumed_gas": "10207",
+		"allocated_destination_contract": true,
+		"paid_storage_size_diff": "67"
+	}`)
+	var res OperationResult
+	if err := json.Unmarshal(buf, &res); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	c := &Constants{
+		CostPerByte:     250,
+		OriginationSize: 257,
+	}
+	burn := StorageBurn(res, c)
+	if got, want := burn.Int64(), int64((67+257)*250); got != want {
+		t.Errorf("burn mismatch want=%d got=%d", want, got)
+	}
+
+	// same result without allocation only pays for storage
+	res.Allocated = false
+	burn = StorageBurn(res, c)
+	if got, want := burn.Int64(), int64(67*250); got != want {
+		t.Errorf("burn mismatch want=%d got=%d", want, got)
+	}
+
+	// missing constants
+	burn = StorageBurn(res, nil)
+	if got := burn.Int64(); got != 0 {
+		t.Errorf("expected zero burn without constants, got=%d", got)
+	}
+}
+
+func TestResultUpdates(t *testing.T) {
+	buf := []byte(`{
+		"status": "applied",
+		"lazy_storage_diff": [
+			{
+				"kind": "big_map",
+				"id": "17",
+				"diff": {
+					"action": "update",
+					"updates": [
+						{
+							"key_hash": "exprv6n4YrvfCD2N6JmSF9aZxtcrcDCDV5YAFpaJDhJU6bhmNHz3YK",
+							"key": {"int": "352"},
+							"value": {"string": "hello"}
+						},
+						{
+							"key_hash": "exprv6n4YrvfCD2N6JmSF9aZxtcrcDCDV5YAFpaJDhJU6bhmNHz3YK",
+							"key": {"int": "353"}
+						}
+					]
+				}
+			},
+			{
+				"kind": "big_map",
+				"id": "-3",
+				"diff": {
+					"action": "alloc",
+					"updates": [],
+					"key_type": {"prim": "nat"},
+					"value_type": {"prim": "string"}
+				}
+			},
+			{
+				"kind": "sapling_state",
+				"id": "5",
+				"diff": {
+					"action": "update",
+					"updates": {"commitments_and_ciphertexts": [], "nullifiers": []}
+				}
+			}
+		],
+		"ticket_updates": [
+			{
+				"ticket_token": {
+					"ticketer": "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu",
+					"content_type": {"prim": "string"},
+					"content": {"string": "ticket"}
+				},
+				"updates": [
+					{"account": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb", "amount": "-10"},
+					{"account": "KT195Mo1aBBLvzMVGQhiSWRDngp5PoQANavy", "amount": "100000000000000000000000"}
+				]
+			}
+		]
+	}`)
+	var res OperationResult
+	if err := json.Unmarshal(buf, &res); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if len(res.LazyStorageDiff) != 3 {
+		t.Fatalf("expected 3 lazy diffs, got %d", len(res.LazyStorageDiff))
+	}
+
+	// the full lazy diff is kept next to the legacy diff element
+	lazy := res.LazyStorageDiff[0].(*LazyBigMapDiff)
+	if lazy.Lazy.Action != micheline.DiffActionUpdate || len(lazy.Lazy.Updates) != 2 {
+		t.Errorf("unexpected lazy bigmap diff %#v", lazy)
+	}
+
+	bigmaps := res.BigmapUpdates()
+	if len(bigmaps) != 3 {
+		t.Fatalf("expected 3 bigmap updates, got %d", len(bigmaps))
+	}
+	for i, v := range []struct {
+		Id     int64
+		Action micheline.DiffAction
+	}{
+		{17, micheline.DiffActionUpdate},
+		{17, micheline.DiffActionRemove},
+		{-3, micheline.DiffActionAlloc},
+	} {
+		if bigmaps[i].Id != v.Id || bigmaps[i].Action != v.Action {
+			t.Errorf("update %d: want %d/%s got %d/%s", i, v.Id, v.Action, bigmaps[i].Id, bigmaps[i].Action)
+		}
+	}
+	if bigmaps[0].Key.Int.Int64() != 352 || bigmaps[0].Value.String != "hello" {
+		t.Errorf("unexpected key/value %s/%s", bigmaps[0].Key.Dump(), bigmaps[0].Value.Dump())
+	}
+	if bigmaps[2].KeyType.OpCode != micheline.T_NAT || bigmaps[2].ValueType.OpCode != micheline.T_STRING {
+		t.Errorf("unexpected alloc types %s/%s", bigmaps[2].KeyType.Dump(), bigmaps[2].ValueType.Dump())
+	}
+
+	tickets := res.TicketUpdates()
+	if len(tickets) != 1 {
+		t.Fatalf("expected 1 ticket update, got %d", len(tickets))
+	}
+	tk := tickets[0]
+	if tk.Ticket.Ticketer.String() != "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu" || tk.Ticket.Content.String != "ticket" {
+		t.Errorf("unexpected ticket %#v", tk.Ticket)
+	}
+	if len(tk.Updates) != 2 || tk.Updates[0].Amount.String() != "-10" || tk.Updates[1].Amount.String() != "100000000000000000000000" {
+		t.Errorf("unexpected ticket balance updates %#v", tk.Updates)
+	}
+}
+
+func TestOperationResultStatus(t *testing.T) {
+	for _, test := range []struct {
+		Status  string
+		Want    tezos.OpStatus
+		Success bool
+	}{
+		{Status: "applied", Want: tezos.OpStatusApplied, Success: true},
+		{Status: "failed", Want: tezos.OpStatusFailed},
+		{Status: "skipped", Want: tezos.OpStatusSkipped},
+		{Status: "backtracked", Want: tezos.OpStatusBacktracked},
+	} {
+		var res OperationResult
+		if err := json.Unmarshal([]byte(`{"status":"`+test.Status+`"}`), &res); err != nil {
+			t.Errorf("%s: unmarshal result: %v", test.Status, err)
+			continue
+		}
+		if res.Status != test.Want {
+			t.Errorf("%s: status mismatch got=%s", test.Status, res.Status)
+		}
+		if res.IsSuccess() != test.Success {
+			t.Errorf("%s: success mismatch got=%t", test.Status, res.IsSuccess())
+		}
+		buf, err := json.Marshal(res.Status)
+		if err != nil || string(buf) != `"`+test.Status+`"` {
+			t.Errorf("%s: marshal mismatch got=%s err=%v", test.Status, buf, err)
+		}
+	}
+	var res OperationResult
+	if err := json.Unmarshal([]byte(`{"status":"unknown"}`), &res); err == nil {
+		t.Errorf("expected error for unknown status")
+	}
+}
+
+func TestTransactionBigmapUpdates(t *testing.T) {
+	ops := decodeOps(t, `[{
+		"kind": "transaction",
+		"source": "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb",
+		"destination": "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu",
+		"fee": "1000", "counter": "1", "gas_limit": "10000", "storage_limit": "100", "amount": "0",
+		"metadata": {
+			"balance_updates": [],
+			"operation_result": {
+				"status": "applied",
+				"lazy_storage_diff": [
+					{"kind": "big_map", "id": "17", "diff": {"action": "update", "updates": [
+						{"key_hash": "exprv6n4YrvfCD2N6JmSF9aZxtcrcDCDV5YAFpaJDhJU6bhmNHz3YK", "key": {"int": "1"}, "value": {"int": "5"}},
+						{"key_hash": "exprv6n4YrvfCD2N6JmSF9aZxtcrcDCDV5YAFpaJDhJU6bhmNHz3YK", "key": {"int": "2"}}
+					]}},
+					{"kind": "big_map", "id": "18", "diff": {"action": "copy", "source": "17", "updates": []}},
+					{"kind": "big_map", "id": "16", "diff": {"action": "remove"}}
+				]
+			},
+			"internal_operation_results": [{
+				"kind": "transaction",
+				"source": "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu",
+				"nonce": 0,
+				"amount": "0",
+				"destination": "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu",
+				"result": {
+					"status": "applied",
+					"big_map_diff": [
+						{"action": "alloc", "big_map": "19", "key_type": {"prim": "address"}, "value_type": {"prim": "nat"}},
+						{"action": "copy", "source_big_map": "17", "destination_big_map": "20"}
+					]
+				}
+			}]
+		}
+	}]`)
+	tx, ok := ops[0].(*TransactionOp)
+	if !ok {
+		t.Fatalf("expected *TransactionOp, got %T", ops[0])
+	}
+	updates := tx.BigmapUpdates()
+	want := []struct {
+		Action micheline.DiffAction
+		Id     int64
+		Source int64
+	}{
+		{micheline.DiffActionUpdate, 17, 0},
+		{micheline.DiffActionRemove, 17, 0},
+		{micheline.DiffActionCopy, 18, 17},
+		{micheline.DiffActionRemove, 16, 0},
+		{micheline.DiffActionAlloc, 19, 0},
+		{micheline.DiffActionCopy, 20, 17},
+	}
+	if len(updates) != len(want) {
+		t.Fatalf("want %d updates, got %d", len(want), len(updates))
+	}
+	for i, w := range want {
+		u := updates[i]
+		if u.Action != w.Action || u.BigmapId != w.Id || u.SourceId != w.Source {
+			t.Errorf("update %d: want %s/%d/%d got %s/%d/%d", i, w.Action, w.Id, w.Source, u.Action, u.BigmapId, u.SourceId)
+		}
+	}
+	if u := updates[0]; u.Key.Int.Int64() != 1 || u.Value.Int.Int64() != 5 || !u.KeyHash.IsValid() {
+		t.Errorf("unexpected update %s => %s", u.Key.Dump(), u.Value.Dump())
+	}
+	if u := updates[1]; u.Key.Int.Int64() != 2 || u.Value.IsValid() {
+		t.Errorf("unexpected removal %s => %s", u.Key.Dump(), u.Value.Dump())
+	}
+	if u := updates[4]; u.KeyType.OpCode != micheline.T_ADDRESS || u.ValueType.OpCode != micheline.T_NAT {
+		t.Errorf("unexpected alloc types %s/%s", u.KeyType.Dump(), u.ValueType.Dump())
+	}
+
+	if n := len((&TransactionOp{}).BigmapUpdates()); n != 0 {
+		t.Errorf("expected no updates without metadata, got %d", n)
+	}
+}
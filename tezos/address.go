@@ -5,8 +5,13 @@ package tezos
 
 import (
 	"bytes"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"blockwatch.cc/tzgo/base58"
@@ -40,6 +45,9 @@ const (
 	AddressTypeContract
 	AddressTypeBlinded
 	AddressTypeBaker
+	AddressTypeBls12381
+	AddressTypeTxRollup
+	AddressTypeSmartRollup
 )
 
 func ParseAddressType(s string) AddressType {
@@ -50,6 +58,12 @@ func ParseAddressType(s string) AddressType {
 		return AddressTypeSecp256k1
 	case "p256", P256_PUBLIC_KEY_HASH_PREFIX:
 		return AddressTypeP256
+	case "bls12_381", BLS12_381_PUBLIC_KEY_HASH_PREFIX:
+		return AddressTypeBls12381
+	case "tx_rollup", TX_ROLLUP_ADDRESS_PREFIX:
+		return AddressTypeTxRollup
+	case "smart_rollup", SMART_ROLLUP_ADDRESS_PREFIX:
+		return AddressTypeSmartRollup
 	case "contract", NOCURVE_PUBLIC_KEY_HASH_PREFIX:
 		return AddressTypeContract
 	case "blinded", BLINDED_PUBLIC_KEY_HASH_PREFIX:
@@ -79,6 +93,12 @@ func (t AddressType) String() string {
 		return "blinded"
 	case AddressTypeBaker:
 		return "baker"
+	case AddressTypeBls12381:
+		return "bls12_381"
+	case AddressTypeTxRollup:
+		return "tx_rollup"
+	case AddressTypeSmartRollup:
+		return "smart_rollup"
 	default:
 		return "invalid"
 	}
@@ -98,11 +118,19 @@ func (t AddressType) Prefix() string {
 		return BLINDED_PUBLIC_KEY_HASH_PREFIX
 	case AddressTypeBaker:
 		return BAKER_PUBLIC_KEY_HASH_PREFIX
+	case AddressTypeBls12381:
+		return BLS12_381_PUBLIC_KEY_HASH_PREFIX
+	case AddressTypeTxRollup:
+		return TX_ROLLUP_ADDRESS_PREFIX
+	case AddressTypeSmartRollup:
+		return SMART_ROLLUP_ADDRESS_PREFIX
 	default:
 		return ""
 	}
 }
 
+// Tag returns the binary public key hash tag. Blinded addresses have no
+// protocol tag and use 4 which is unassigned in the protocol.
 func (t AddressType) Tag() byte {
 	switch t {
 	case AddressTypeEd25519:
@@ -111,8 +139,10 @@ func (t AddressType) Tag() byte {
 		return 1
 	case AddressTypeP256:
 		return 2
-	case AddressTypeBlinded:
+	case AddressTypeBls12381:
 		return 3
+	case AddressTypeBlinded:
+		return 4
 	default:
 		return 255
 	}
@@ -127,6 +157,8 @@ func ParseAddressTag(b byte) AddressType {
 	case 2:
 		return AddressTypeP256
 	case 3:
+		return AddressTypeBls12381
+	case 4:
 		return AddressTypeBlinded
 	default:
 		return AddressTypeInvalid
@@ -151,9 +183,12 @@ func HasAddressPrefix(s string) bool {
 		ED25519_PUBLIC_KEY_HASH_PREFIX,
 		SECP256K1_PUBLIC_KEY_HASH_PREFIX,
 		P256_PUBLIC_KEY_HASH_PREFIX,
+		BLS12_381_PUBLIC_KEY_HASH_PREFIX,
 		NOCURVE_PUBLIC_KEY_HASH_PREFIX,
 		BLINDED_PUBLIC_KEY_HASH_PREFIX,
 		BAKER_PUBLIC_KEY_HASH_PREFIX,
+		TX_ROLLUP_ADDRESS_PREFIX,
+		SMART_ROLLUP_ADDRESS_PREFIX,
 	} {
 		if strings.HasPrefix(s, prefix) {
 			return true
@@ -176,6 +211,12 @@ func (t AddressType) HashType() HashType {
 		return HashTypePkhBlinded
 	case AddressTypeBaker:
 		return HashTypePkhBaker
+	case AddressTypeBls12381:
+		return HashTypePkhBls12381
+	case AddressTypeTxRollup:
+		return HashTypeTxRollupAddress
+	case AddressTypeSmartRollup:
+		return HashTypeSmartRollupAddress
 	default:
 		return HashTypeInvalid
 	}
@@ -212,10 +253,58 @@ func (a Address) IsValid() bool {
 	return a.Type != AddressTypeInvalid && len(a.Hash) == a.Type.HashType().Len()
 }
 
+// IsContract returns true for originated contract (KT1) addresses.
+func (a Address) IsContract() bool {
+	return a.Type == AddressTypeContract
+}
+
+// IsImplicit returns true for implicit account addresses controlled by
+// a key (tz1, tz2, tz3, tz4).
+func (a Address) IsImplicit() bool {
+	switch a.Type {
+	case AddressTypeEd25519, AddressTypeSecp256k1, AddressTypeP256, AddressTypeBls12381:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRollup returns true for smart rollup (sr1) and transaction rollup
+// (txr1) addresses.
+func (a Address) IsRollup() bool {
+	return a.Type == AddressTypeSmartRollup || a.Type == AddressTypeTxRollup
+}
+
+// Equal reports whether both addresses have the same type and hash. It is
+// the general-purpose comparison, use EqualConstantTime when comparing
+// untrusted input against secret or access-controlling addresses.
 func (a Address) Equal(b Address) bool {
 	return a.Type == b.Type && bytes.Compare(a.Hash, b.Hash) == 0
 }
 
+// EqualConstantTime is like Equal, but compares hashes in constant time so
+// that the time taken does not depend on the position of the first differing
+// byte. The address type is not secret and is compared first.
+func (a Address) EqualConstantTime(b Address) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	return subtle.ConstantTimeCompare(a.Hash, b.Hash) == 1
+}
+
+// Compare orders addresses the way Michelson compares them in map keys and
+// sets: by their binary encoding, i.e. implicit accounts by key type tag
+// first, followed by contracts and rollups, then by hash bytes. It returns
+// -1, 0 or +1.
+func (a Address) Compare(b Address) int {
+	return bytes.Compare(a.Bytes22(), b.Bytes22())
+}
+
+// SortAddresses sorts a slice of addresses in Michelson order (see Compare).
+func SortAddresses(l []Address) {
+	sort.Slice(l, func(i, j int) bool { return l[i].Compare(l[j]) < 0 })
+}
+
 func (a Address) Clone() Address {
 	x := Address{
 		Type: a.Type,
@@ -231,6 +320,18 @@ func (a Address) String() string {
 	return s
 }
 
+// Bucket returns a stable bucket index in [0, n) computed from the first
+// 8 bytes of the address hash. Use it to shard addresses without encoding
+// them to base58 first. Returns 0 when n <= 0.
+func (a Address) Bucket(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	var buf [8]byte
+	copy(buf[:], a.Hash)
+	return int(binary.BigEndian.Uint64(buf[:]) % uint64(n))
+}
+
 func (a Address) Short() string {
 	s := a.String()
 	if len(s) < 12 {
@@ -253,59 +354,151 @@ func (a Address) MarshalText() ([]byte, error) {
 	return []byte(a.String()), nil
 }
 
-// output the 21 (implicit) or 22 byte (contract) version
+// MarshalJSON encodes the address as base58 string. Use AddressObject to
+// encode the object form.
+func (a Address) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON decodes an address from a base58 string or from the object
+// form {"type":"ed25519","hash":"..."} produced by AddressObject. In object
+// form the hash may be hex or base58 encoded.
+func (a *Address) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if data[0] != '{' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return a.UnmarshalText([]byte(s))
+	}
+	var obj addressObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	typ := ParseAddressType(obj.Type)
+	if !typ.IsValid() {
+		return fmt.Errorf("invalid address type '%s'", obj.Type)
+	}
+	if buf, err := hex.DecodeString(obj.Hash); err == nil {
+		if len(buf) != typ.HashType().Len() {
+			return fmt.Errorf("invalid %s address hash length %d", typ, len(buf))
+		}
+		*a = NewAddress(typ, buf)
+		return nil
+	}
+	addr, err := ParseAddress(obj.Hash)
+	if err != nil {
+		return err
+	}
+	if addr.Type != typ {
+		return fmt.Errorf("address type mismatch: %s is not %s", addr, typ)
+	}
+	*a = addr
+	return nil
+}
+
+// AddressObject wraps an address to encode it in JSON object form with its
+// type name and hex encoded hash, e.g.
+// {"type":"ed25519","hash":"5c56bbc501ad676afc27ae3d660232287a15b5e2"}.
+type AddressObject struct {
+	Address
+}
+
+type addressObject struct {
+	Type string `json:"type"`
+	Hash string `json:"hash"`
+}
+
+func (o AddressObject) MarshalJSON() ([]byte, error) {
+	return json.Marshal(addressObject{
+		Type: o.Type.String(),
+		Hash: hex.EncodeToString(o.Hash),
+	})
+}
+
+// contractTag returns the binary contract id tag for originated contracts
+// and rollups or 0 for implicit accounts.
+func (t AddressType) contractTag() byte {
+	switch t {
+	case AddressTypeContract:
+		return 1
+	case AddressTypeTxRollup:
+		return 2
+	case AddressTypeSmartRollup:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// output the 21 (implicit) or 22 byte (contract, rollup) version
 func (a Address) Bytes() []byte {
 	if !a.Type.IsValid() {
 		return nil
 	}
-	if a.Type == AddressTypeContract {
-		buf := append([]byte{01}, a.Hash...)
+	if tag := a.Type.contractTag(); tag > 0 {
+		buf := append([]byte{tag}, a.Hash...)
 		buf = append(buf, byte(0)) // padding
 		return buf
 	}
 	return append([]byte{a.Type.Tag()}, a.Hash...)
 }
 
-// Tezos compatible binary encoding with padding for contracts and
+// Tezos compatible binary encoding with padding for contracts and rollups and
 // leading 0-byte for EOAs
 func (a Address) Bytes22() []byte {
 	if !a.Type.IsValid() {
 		return nil
 	}
-	if a.Type == AddressTypeContract {
-		buf := append([]byte{01}, a.Hash...)
+	if tag := a.Type.contractTag(); tag > 0 {
+		buf := append([]byte{tag}, a.Hash...)
 		buf = append(buf, byte(0)) // padding
 		return buf
 	}
 	return append([]byte{00, a.Type.Tag()}, a.Hash...)
 }
 
+// BytesWithEntrypoint returns the binary encoding of a `contract` or `address`
+// value, i.e. the 22 byte address followed by the entrypoint name. The name
+// is omitted for the default entrypoint.
+func (a Address) BytesWithEntrypoint(ep string) []byte {
+	buf := a.Bytes22()
+	if buf == nil || ep == "" || ep == "default" {
+		return buf
+	}
+	return append(buf, ep...)
+}
+
 // output the 22 byte version
 func (a Address) MarshalBinary() ([]byte, error) {
 	if !a.Type.IsValid() {
 		return nil, ErrUnknownAddressType
 	}
-	if a.Type == AddressTypeContract {
-		buf := append([]byte{01}, a.Hash...)
-		buf = append(buf, byte(0)) // padding
-		return buf, nil
-	}
-	return append([]byte{00, a.Type.Tag()}, a.Hash...), nil
+	return a.Bytes22(), nil
 }
 
 // support both the 21 byte and 22 byte versions
 // be resilient to longer byte strings with extra padding
 func (a *Address) UnmarshalBinary(b []byte) error {
 	switch true {
-	case len(b) >= 22 && (b[0] == 0 || b[0] == 1):
-		if b[0] == 0 {
+	case len(b) >= 22 && b[0] <= 3:
+		switch b[0] {
+		case 0:
 			a.Type = ParseAddressTag(b[1])
 			b = b[2:22]
-		} else if b[0] == 1 {
+		case 1:
 			a.Type = AddressTypeContract
 			b = b[1:21]
-		} else {
-			return fmt.Errorf("invalid binary address prefix %x", b[0])
+		case 2:
+			a.Type = AddressTypeTxRollup
+			b = b[1:21]
+		case 3:
+			a.Type = AddressTypeSmartRollup
+			b = b[1:21]
 		}
 	case len(b) >= 21:
 		a.Type = ParseAddressTag(b[0])
@@ -325,12 +518,33 @@ func (a *Address) UnmarshalBinary(b []byte) error {
 	return nil
 }
 
+// DecodeAddressAndEntrypoint decodes the binary form produced by
+// BytesWithEntrypoint and returns the address and entrypoint name. The name
+// is empty for the default entrypoint.
+func DecodeAddressAndEntrypoint(b []byte) (Address, string, error) {
+	if len(b) < 22 {
+		return InvalidAddress, "", fmt.Errorf("invalid binary address length %d", len(b))
+	}
+	var a Address
+	if err := a.UnmarshalBinary(b[:22]); err != nil {
+		return InvalidAddress, "", err
+	}
+	ep := string(b[22:])
+	if ep == "default" {
+		ep = ""
+	}
+	if len(ep) > 31 {
+		return InvalidAddress, "", fmt.Errorf("entrypoint name too long (%d bytes)", len(ep))
+	}
+	return a, ep, nil
+}
+
 func IsAddressBytes(b []byte) bool {
 	if len(b) < 21 {
 		return false
 	}
 	switch true {
-	case len(b) == 22 && (b[0] == 0 || b[0] == 1):
+	case len(b) == 22 && b[0] <= 3:
 		return true
 	case len(b) == 21:
 		return ParseAddressTag(b[0]) != AddressTypeInvalid
@@ -366,10 +580,24 @@ func ParseAddress(addr string) (Address, error) {
 		return InvalidAddress, nil
 	}
 	a := Address{}
-	// check for blinded address first
+	// check for blinded and tx rollup addresses first, both use
+	// 4 byte version prefixes
 	if strings.HasPrefix(addr, BLINDED_PUBLIC_KEY_HASH_PREFIX) {
 		return DecodeBlindedAddress(addr)
 	}
+	if strings.HasPrefix(addr, TX_ROLLUP_ADDRESS_PREFIX) {
+		decoded, version, err := base58.CheckDecode(addr, 4, nil)
+		if err != nil {
+			if err == base58.ErrChecksum {
+				return a, ErrChecksumMismatch
+			}
+			return a, fmt.Errorf("decoded address is of unknown format: %w", err)
+		}
+		if len(decoded) != 20 || bytes.Compare(version, TX_ROLLUP_ADDRESS_ID) != 0 {
+			return a, fmt.Errorf("decoded address %s is of unknown type %x", addr, version)
+		}
+		return Address{Type: AddressTypeTxRollup, Hash: decoded}, nil
+	}
 	decoded, version, err := base58.CheckDecode(addr, 3, nil)
 	if err != nil {
 		if err == base58.ErrChecksum {
@@ -389,13 +617,35 @@ func ParseAddress(addr string) (Address, error) {
 		return Address{Type: AddressTypeSecp256k1, Hash: decoded}, nil
 	case bytes.Compare(version, P256_PUBLIC_KEY_HASH_ID) == 0:
 		return Address{Type: AddressTypeP256, Hash: decoded}, nil
+	case bytes.Compare(version, BLS12_381_PUBLIC_KEY_HASH_ID) == 0:
+		return Address{Type: AddressTypeBls12381, Hash: decoded}, nil
 	case bytes.Compare(version, NOCURVE_PUBLIC_KEY_HASH_ID) == 0:
 		return Address{Type: AddressTypeContract, Hash: decoded}, nil
+	case bytes.Compare(version, SMART_ROLLUP_ADDRESS_ID) == 0:
+		return Address{Type: AddressTypeSmartRollup, Hash: decoded}, nil
 	default:
 		return a, fmt.Errorf("decoded address %s is of unknown type %x", addr, version)
 	}
 }
 
+// ParseAddressAndEntrypoint parses a contract call target in the form
+// KT1...%entrypoint and returns the address and the entrypoint name. The
+// entrypoint is empty when s has no % suffix.
+func ParseAddressAndEntrypoint(s string) (Address, string, error) {
+	var entrypoint string
+	if i := strings.IndexByte(s, '%'); i >= 0 {
+		s, entrypoint = s[:i], s[i+1:]
+		if len(entrypoint) == 0 {
+			return InvalidAddress, "", fmt.Errorf("empty entrypoint in address %s%%", s)
+		}
+	}
+	a, err := ParseAddress(s)
+	if err != nil {
+		return InvalidAddress, "", err
+	}
+	return a, entrypoint, nil
+}
+
 func EncodeAddress(typ AddressType, addrhash []byte) (string, error) {
 	if len(addrhash) != 20 {
 		return "", fmt.Errorf("invalid address hash")
@@ -409,10 +659,16 @@ func EncodeAddress(typ AddressType, addrhash []byte) (string, error) {
 		return base58.CheckEncode(addrhash, SECP256K1_PUBLIC_KEY_HASH_ID), nil
 	case AddressTypeP256:
 		return base58.CheckEncode(addrhash, P256_PUBLIC_KEY_HASH_ID), nil
+	case AddressTypeBls12381:
+		return base58.CheckEncode(addrhash, BLS12_381_PUBLIC_KEY_HASH_ID), nil
 	case AddressTypeContract:
 		return base58.CheckEncode(addrhash, NOCURVE_PUBLIC_KEY_HASH_ID), nil
 	case AddressTypeBlinded:
 		return base58.CheckEncode(addrhash, BLINDED_PUBLIC_KEY_HASH_ID), nil
+	case AddressTypeTxRollup:
+		return base58.CheckEncode(addrhash, TX_ROLLUP_ADDRESS_ID), nil
+	case AddressTypeSmartRollup:
+		return base58.CheckEncode(addrhash, SMART_ROLLUP_ADDRESS_ID), nil
 	default:
 		return "", fmt.Errorf("unknown address type %s for hash=%x\n", typ, addrhash)
 	}
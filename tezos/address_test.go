@@ -0,0 +1,356 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package tezos
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestAddressBls12381(t *testing.T) {
+	hash := bytes.Repeat([]byte{0xab}, 20)
+	s, err := EncodeAddress(AddressTypeBls12381, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(s, "tz4") || len(s) != HashTypePkhBls12381.Base58Len() {
+		t.Fatalf("unexpected encoding %s", s)
+	}
+	if !HasAddressPrefix(s) {
+		t.Errorf("tz4 prefix not recognized")
+	}
+	if typ := ParseHashType(s); typ != HashTypePkhBls12381 {
+		t.Errorf("hash type mismatch got=%s", typ)
+	}
+
+	// string round-trip
+	a, err := ParseAddress(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Type != AddressTypeBls12381 || !bytes.Equal(a.Hash, hash) {
+		t.Errorf("parse mismatch got=%s %x", a.Type, a.Hash)
+	}
+	if a.String() != s {
+		t.Errorf("string mismatch got=%s", a.String())
+	}
+	if !a.IsValid() {
+		t.Errorf("address should be valid")
+	}
+
+	// binary round-trip uses the protocol tag
+	buf := a.Bytes22()
+	if buf[0] != 0 || buf[1] != 3 {
+		t.Errorf("binary tag mismatch got=%x", buf[:2])
+	}
+	var b Address
+	if err := b.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !b.Equal(a) {
+		t.Errorf("binary mismatch got=%s", b)
+	}
+	if typ := ParseAddressType("tz4"); typ != AddressTypeBls12381 {
+		t.Errorf("address type mismatch got=%s", typ)
+	}
+}
+
+func TestAddressRollups(t *testing.T) {
+	for _, test := range []struct {
+		In   string
+		Type AddressType
+		Tag  byte
+	}{
+		{In: "sr1Ghq66tYK9y3r8CC1Tf8i8m5nxh8nTvZEf", Type: AddressTypeSmartRollup, Tag: 3},
+		{In: "txr1YNMEtkj5Vkqsbdmt7xaxBTMRZjzS96UAi", Type: AddressTypeTxRollup, Tag: 2},
+	} {
+		if !HasAddressPrefix(test.In) {
+			t.Errorf("%s: prefix not recognized", test.In)
+		}
+		a, err := ParseAddress(test.In)
+		if err != nil {
+			t.Errorf("%s: %v", test.In, err)
+			continue
+		}
+		if a.Type != test.Type {
+			t.Errorf("%s: type mismatch want=%s got=%s", test.In, test.Type, a.Type)
+		}
+		if s := a.String(); s != test.In {
+			t.Errorf("%s: re-encoding mismatch got=%s", test.In, s)
+		}
+
+		// binary form is tag, hash and padding
+		buf := a.Bytes22()
+		if len(buf) != 22 || buf[0] != test.Tag || buf[21] != 0 {
+			t.Errorf("%s: unexpected binary encoding %x", test.In, buf)
+		}
+		if !IsAddressBytes(buf) {
+			t.Errorf("%s: binary form not detected", test.In)
+		}
+		var b Address
+		if err := b.UnmarshalBinary(buf); err != nil {
+			t.Errorf("%s: %v", test.In, err)
+			continue
+		}
+		if !b.Equal(a) {
+			t.Errorf("%s: binary round-trip mismatch got=%s", test.In, b)
+		}
+	}
+}
+
+func TestAddressBucket(t *testing.T) {
+	a := MustParseAddress("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb")
+	if b1, b2 := a.Bucket(16), a.Clone().Bucket(16); b1 != b2 {
+		t.Errorf("bucket not deterministic %d != %d", b1, b2)
+	}
+	if b := a.Bucket(0); b != 0 {
+		t.Errorf("expected bucket 0 for n=0, got %d", b)
+	}
+	if b := InvalidAddress.Bucket(16); b != 0 {
+		t.Errorf("expected bucket 0 for invalid address, got %d", b)
+	}
+
+	// hashes are uniformly distributed, so should be the buckets
+	const n, count = 16, 16000
+	buckets := make([]int, n)
+	for i := 0; i < count; i++ {
+		h, _ := blake2b.New(20, nil)
+		h.Write([]byte{byte(i), byte(i >> 8)})
+		addr := NewAddress(AddressTypeEd25519, h.Sum(nil))
+		b := addr.Bucket(n)
+		if b < 0 || b >= n {
+			t.Fatalf("bucket %d out of range", b)
+		}
+		buckets[b]++
+	}
+	for i, c := range buckets {
+		if c < count/n*8/10 || c > count/n*12/10 {
+			t.Errorf("bucket %d has %d entries, expected about %d", i, c, count/n)
+		}
+	}
+}
+
+func TestAddressPredicates(t *testing.T) {
+	for _, test := range []struct {
+		In       string
+		Contract bool
+		Implicit bool
+		Rollup   bool
+	}{
+		{In: "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb", Implicit: true},
+		{In: "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu", Contract: true},
+		{In: "sr1Ghq66tYK9y3r8CC1Tf8i8m5nxh8nTvZEf", Rollup: true},
+		{In: "txr1YNMEtkj5Vkqsbdmt7xaxBTMRZjzS96UAi", Rollup: true},
+		{In: ""},
+	} {
+		a, err := ParseAddress(test.In)
+		if err != nil {
+			t.Fatalf("%q: %v", test.In, err)
+		}
+		if got := a.IsContract(); got != test.Contract {
+			t.Errorf("%q: IsContract want=%t got=%t", test.In, test.Contract, got)
+		}
+		if got := a.IsImplicit(); got != test.Implicit {
+			t.Errorf("%q: IsImplicit want=%t got=%t", test.In, test.Implicit, got)
+		}
+		if got := a.IsRollup(); got != test.Rollup {
+			t.Errorf("%q: IsRollup want=%t got=%t", test.In, test.Rollup, got)
+		}
+	}
+	if !NewAddress(AddressTypeBls12381, make([]byte, 20)).IsImplicit() {
+		t.Errorf("tz4 should be implicit")
+	}
+}
+
+func TestAddressCompare(t *testing.T) {
+	hash := func(b byte) []byte { return bytes.Repeat([]byte{b}, 20) }
+	want := []Address{
+		NewAddress(AddressTypeEd25519, hash(1)),
+		NewAddress(AddressTypeEd25519, hash(2)),
+		NewAddress(AddressTypeSecp256k1, hash(0)),
+		NewAddress(AddressTypeP256, hash(0)),
+		NewAddress(AddressTypeContract, hash(0)),
+		NewAddress(AddressTypeContract, hash(9)),
+		NewAddress(AddressTypeSmartRollup, hash(0)),
+	}
+	l := []Address{want[5], want[2], want[6], want[0], want[4], want[3], want[1]}
+	SortAddresses(l)
+	for i := range want {
+		if !l[i].Equal(want[i]) {
+			t.Errorf("pos %d: want=%s got=%s", i, want[i], l[i])
+		}
+	}
+	if c := want[0].Compare(want[0].Clone()); c != 0 {
+		t.Errorf("equal addresses compare as %d", c)
+	}
+	if c := want[4].Compare(want[1]); c != 1 {
+		t.Errorf("contract should sort after implicit, got %d", c)
+	}
+}
+
+func TestAddressJSON(t *testing.T) {
+	addr := MustParseAddress("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb")
+	buf, err := json.Marshal(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != `"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"` {
+		t.Errorf("string form mismatch got=%s", buf)
+	}
+	buf, err = json.Marshal(AddressObject{addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"type":"ed25519","hash":"5c56bbc501ad676afc27ae3d660232287a15b5e2"}`; string(buf) != want {
+		t.Errorf("object form mismatch\n  want=%s\n  got= %s", want, buf)
+	}
+
+	for _, in := range []string{
+		`"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"`,
+		`{"type":"ed25519","hash":"5c56bbc501ad676afc27ae3d660232287a15b5e2"}`,
+		`{"type":"ed25519","hash":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"}`,
+		`{"hash":"5c56bbc501ad676afc27ae3d660232287a15b5e2","type":"tz1"}`,
+	} {
+		var a Address
+		if err := json.Unmarshal([]byte(in), &a); err != nil {
+			t.Errorf("%s: %v", in, err)
+			continue
+		}
+		if !a.Equal(addr) {
+			t.Errorf("%s: decoded %s", in, a)
+		}
+		var o AddressObject
+		if err := json.Unmarshal([]byte(in), &o); err != nil || !o.Equal(addr) {
+			t.Errorf("%s: object decode mismatch %s %v", in, o.Address, err)
+		}
+	}
+
+	for _, in := range []string{
+		`{"type":"p256","hash":"tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb"}`,
+		`{"type":"ed25519","hash":"5c56"}`,
+		`{"type":"nope","hash":"5c56bbc501ad676afc27ae3d660232287a15b5e2"}`,
+		`"tz1invalid"`,
+		`42`,
+	} {
+		var a Address
+		if err := json.Unmarshal([]byte(in), &a); err == nil {
+			t.Errorf("%s: expected error", in)
+		}
+	}
+
+	// struct fields and null
+	var s struct {
+		A Address  `json:"a"`
+		B *Address `json:"b"`
+	}
+	if err := json.Unmarshal([]byte(`{"a":{"type":"contract","hash":"KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"},"b":null}`), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.A.String() != "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu" || s.B != nil {
+		t.Errorf("unexpected struct %#v", s)
+	}
+}
+
+func TestAddressEqualConstantTime(t *testing.T) {
+	a := MustParseAddress("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb")
+	b := NewAddress(AddressTypeSecp256k1, a.Hash) // same hash, other type
+	c := a.Clone()
+	c.Hash[19] ^= 1
+	for _, test := range []struct {
+		X, Y Address
+		Want bool
+	}{
+		{a, a.Clone(), true},
+		{a, b, false},
+		{a, c, false},
+		{a, NewAddress(AddressTypeEd25519, a.Hash[:10]), false},
+		{InvalidAddress, InvalidAddress, true},
+	} {
+		if got := test.X.EqualConstantTime(test.Y); got != test.Want {
+			t.Errorf("%s vs %s: want=%t got=%t", test.X, test.Y, test.Want, got)
+		}
+		if got := test.X.Equal(test.Y); got != test.Want {
+			t.Errorf("%s vs %s: Equal disagrees, got=%t", test.X, test.Y, got)
+		}
+	}
+}
+
+func TestParseAddressAndEntrypoint(t *testing.T) {
+	for _, test := range []struct {
+		In    string
+		Addr  string
+		Entry string
+		Err   bool
+	}{
+		{In: "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu%mint", Addr: "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu", Entry: "mint"},
+		{In: "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu", Addr: "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu"},
+		{In: "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb%default", Addr: "tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb", Entry: "default"},
+		{In: "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu%", Err: true},
+		{In: "KT1invalid%mint", Err: true},
+	} {
+		a, ep, err := ParseAddressAndEntrypoint(test.In)
+		if test.Err {
+			if err == nil {
+				t.Errorf("%s: expected error", test.In)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.In, err)
+			continue
+		}
+		if a.String() != test.Addr || ep != test.Entry {
+			t.Errorf("%s: want=%s %q got=%s %q", test.In, test.Addr, test.Entry, a, ep)
+		}
+	}
+
+	// UnmarshalText keeps dropping the entrypoint
+	var a Address
+	if err := a.UnmarshalText([]byte("KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu%mint")); err != nil || a.String() != "KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu" {
+		t.Errorf("unexpected UnmarshalText result %s err=%v", a, err)
+	}
+}
+
+func TestAddressBytesWithEntrypoint(t *testing.T) {
+	kt := MustParseAddress("KT18zL7LB7Ng3nCN8pJwkcZudmMw4YGf9wFu")
+	tz := MustParseAddress("tz1U4Gm7dMYjvAG9UYH3vgb99MV4BbY7qkAb")
+	for _, test := range []struct {
+		Addr  Address
+		Entry string
+		Want  string
+		Len   int
+	}{
+		{Addr: kt, Entry: "", Len: 22},
+		{Addr: kt, Entry: "default", Len: 22},
+		{Addr: kt, Entry: "mint", Want: "mint", Len: 26},
+		{Addr: tz, Entry: "", Len: 22},
+	} {
+		buf := test.Addr.BytesWithEntrypoint(test.Entry)
+		if len(buf) != test.Len || !bytes.Equal(buf[:22], test.Addr.Bytes22()) {
+			t.Errorf("%s%%%s: unexpected encoding %x", test.Addr, test.Entry, buf)
+		}
+		a, ep, err := DecodeAddressAndEntrypoint(buf)
+		if err != nil {
+			t.Errorf("%s%%%s: %v", test.Addr, test.Entry, err)
+			continue
+		}
+		if !a.Equal(test.Addr) || ep != test.Want {
+			t.Errorf("%s%%%s: round-trip got %s %q", test.Addr, test.Entry, a, ep)
+		}
+	}
+	if _, _, err := DecodeAddressAndEntrypoint(tz.Bytes()); err == nil {
+		t.Errorf("expected error for short input")
+	}
+	if _, _, err := DecodeAddressAndEntrypoint(kt.BytesWithEntrypoint(strings.Repeat("x", 32))); err == nil {
+		t.Errorf("expected error for long entrypoint")
+	}
+	if buf := InvalidAddress.BytesWithEntrypoint("mint"); buf != nil {
+		t.Errorf("expected nil for invalid address, got %x", buf)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// ecdsaCurve wraps the ECDSA operations of a curve. Secp256k1 is backed by
+// the constant time implementation of dcrd, P-256 by the standard library.
+type ecdsaCurve struct {
+	N         *big.Int
+	publicKey func(d []byte) ([]byte, error)         // compressed public key of scalar d
+	sign      func(d, digest []byte) ([]byte, error) // 64 byte r|s signature of digest
+	verify    func(pub, digest, sig []byte) bool     // check r|s sig with compressed key pub
+}
+
+var (
+	p256Curve = ecdsaCurve{
+		N:         elliptic.P256().Params().N,
+		publicKey: p256PublicKey,
+		sign:      p256Sign,
+		verify:    p256Verify,
+	}
+	secp256k1Curve = ecdsaCurve{
+		N:         secp256k1.S256().N,
+		publicKey: secp256k1PublicKey,
+		sign:      secp256k1Sign,
+		verify:    secp256k1Verify,
+	}
+)
+
+var errInvalidScalar = errors.New("invalid private key scalar")
+
+// secp256k1Scalar parses d as a private scalar in [1, N-1].
+func secp256k1Scalar(d []byte) (*secp256k1.PrivateKey, error) {
+	var k secp256k1.ModNScalar
+	if len(d) != 32 || k.SetByteSlice(d) || k.IsZero() {
+		return nil, errInvalidScalar
+	}
+	return secp256k1.NewPrivateKey(&k), nil
+}
+
+func secp256k1PublicKey(d []byte) ([]byte, error) {
+	k, err := secp256k1Scalar(d)
+	if err != nil {
+		return nil, err
+	}
+	defer k.Zero()
+	return k.PubKey().SerializeCompressed(), nil
+}
+
+// secp256k1Sign signs with a deterministic RFC 6979 nonce and returns a low-S
+// signature as libsecp256k1 requires.
+func secp256k1Sign(d, digest []byte) ([]byte, error) {
+	k, err := secp256k1Scalar(d)
+	if err != nil {
+		return nil, err
+	}
+	defer k.Zero()
+	// compact signatures are a recovery code followed by r|s
+	return secpecdsa.SignCompact(k, digest, true)[1:], nil
+}
+
+func secp256k1Verify(pub, digest, sig []byte) bool {
+	if len(sig) != 64 {
+		return false
+	}
+	key, err := secp256k1.ParsePubKey(pub)
+	if err != nil || len(pub) != 33 {
+		return false
+	}
+	var r, s secp256k1.ModNScalar
+	if r.SetByteSlice(sig[:32]) || s.SetByteSlice(sig[32:]) || r.IsZero() || s.IsZero() {
+		return false
+	}
+	return secpecdsa.NewSignature(&r, &s).Verify(digest, key)
+}
+
+// p256Key parses d as a private scalar in [1, N-1].
+func p256Key(d []byte) (*ecdsa.PrivateKey, error) {
+	c := elliptic.P256()
+	k := new(big.Int).SetBytes(d)
+	if len(d) != 32 || k.Sign() == 0 || k.Cmp(c.Params().N) >= 0 {
+		return nil, errInvalidScalar
+	}
+	x, y := c.ScalarBaseMult(d)
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: c, X: x, Y: y},
+		D:         k,
+	}, nil
+}
+
+func p256PublicKey(d []byte) ([]byte, error) {
+	k, err := p256Key(d)
+	if err != nil {
+		return nil, err
+	}
+	return elliptic.MarshalCompressed(k.Curve, k.X, k.Y), nil
+}
+
+// p256Sign signs with a random nonce, so signatures are not deterministic.
+func p256Sign(d, digest []byte) ([]byte, error) {
+	k, err := p256Key(d)
+	if err != nil {
+		return nil, err
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, k, digest)
+	if err != nil {
+		return nil, err
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return sig, nil
+}
+
+func p256Verify(pub, digest, sig []byte) bool {
+	if len(sig) != 64 {
+		return false
+	}
+	x, y := elliptic.UnmarshalCompressed(elliptic.P256(), pub)
+	if x == nil {
+		return false
+	}
+	key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	return ecdsa.Verify(key, digest, r, s)
+}
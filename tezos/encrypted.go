@@ -0,0 +1,102 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"io"
+
+	"blockwatch.cc/tzgo/base58"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// ErrInvalidPassphrase is returned when an encrypted private key cannot be
+// decrypted with the given passphrase.
+var ErrInvalidPassphrase = errors.New("invalid passphrase")
+
+// octez-client derives the secretbox key from the passphrase with
+// PBKDF2-HMAC-SHA512 over a random 8 byte salt and uses an all-zero nonce.
+const (
+	encryptedKeySaltSize   = 8
+	encryptedKeyIterations = 32768
+)
+
+// ParseEncryptedPrivateKey decrypts a base58 encoded encrypted secret key
+// (edesk, spesk or p2esk) as exported by octez-client. A wrong passphrase
+// results in ErrInvalidPassphrase, malformed strings in other errors.
+func ParseEncryptedPrivateKey(s string, passphrase []byte) (PrivateKey, error) {
+	decoded, version, err := base58.CheckDecode(s, 5, nil)
+	if err != nil {
+		if err == base58.ErrChecksum {
+			return PrivateKey{}, ErrChecksumMismatch
+		}
+		return PrivateKey{}, fmt.Errorf("unknown format for encrypted private key: %w", err)
+	}
+	var typ KeyType
+	switch true {
+	case bytes.Compare(version, ED25519_ENCRYPTED_SEED_ID) == 0:
+		typ = KeyTypeEd25519
+	case bytes.Compare(version, SECP256K1_ENCRYPTED_SECRET_KEY_ID) == 0:
+		typ = KeyTypeSecp256k1
+	case bytes.Compare(version, P256_ENCRYPTED_SECRET_KEY_ID) == 0:
+		typ = KeyTypeP256
+	default:
+		return PrivateKey{}, fmt.Errorf("unknown version %x for encrypted private key", version)
+	}
+	if len(decoded) != encryptedKeySaltSize+secretbox.Overhead+32 {
+		return PrivateKey{}, fmt.Errorf("invalid length %d for encrypted private key", len(decoded))
+	}
+	salt, box := decoded[:encryptedKeySaltSize], decoded[encryptedKeySaltSize:]
+	var nonce [24]byte
+	key := encryptionKey(passphrase, salt)
+	plain, ok := secretbox.Open(nil, box, &nonce, &key)
+	if !ok {
+		return PrivateKey{}, ErrInvalidPassphrase
+	}
+	return NewPrivateKey(typ, plain)
+}
+
+// Encrypt returns the base58 encoded secret key encrypted with passphrase in
+// the format octez-client uses. Ed25519 keys are stored as 32 byte seed.
+func (k PrivateKey) Encrypt(passphrase []byte) (string, error) {
+	salt := make([]byte, encryptedKeySaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", err
+	}
+	return k.encrypt(passphrase, salt)
+}
+
+func (k PrivateKey) encrypt(passphrase, salt []byte) (string, error) {
+	if !k.IsValid() {
+		return "", ErrUnknownKeyType
+	}
+	var (
+		version []byte
+		plain   = k.Data
+	)
+	switch k.Type {
+	case KeyTypeEd25519:
+		version, plain = ED25519_ENCRYPTED_SEED_ID, k.Data[:32]
+	case KeyTypeSecp256k1:
+		version = SECP256K1_ENCRYPTED_SECRET_KEY_ID
+	case KeyTypeP256:
+		version = P256_ENCRYPTED_SECRET_KEY_ID
+	}
+	var nonce [24]byte
+	key := encryptionKey(passphrase, salt)
+	buf := secretbox.Seal(append([]byte{}, salt...), plain, &nonce, &key)
+	return base58.CheckEncode(buf, version), nil
+}
+
+func encryptionKey(passphrase, salt []byte) [32]byte {
+	var key [32]byte
+	copy(key[:], pbkdf2.Key(passphrase, salt, encryptedKeyIterations, 32, sha512.New))
+	return key
+}
@@ -9,6 +9,9 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
 )
 
 var (
@@ -61,9 +64,19 @@ const (
 	HashTypeSigSecp256k1
 	HashTypeSigP256
 	HashTypeSigGeneric
+	HashTypePkhBls12381
+	HashTypeTxRollupAddress
+	HashTypeSmartRollupAddress
 )
 
 func ParseHashType(s string) HashType {
+	if t := parseBuiltinHashType(s); t.IsValid() {
+		return t
+	}
+	return parseCustomHashType(s)
+}
+
+func parseBuiltinHashType(s string) HashType {
 	switch len(s) {
 	case 15:
 		if strings.HasPrefix(s, CHAIN_ID_PREFIX) {
@@ -81,12 +94,23 @@ func ParseHashType(s string) HashType {
 			return HashTypePkhSecp256k1
 		case strings.HasPrefix(s, P256_PUBLIC_KEY_HASH_PREFIX):
 			return HashTypePkhP256
+		case strings.HasPrefix(s, BLS12_381_PUBLIC_KEY_HASH_PREFIX):
+			return HashTypePkhBls12381
 		case strings.HasPrefix(s, NOCURVE_PUBLIC_KEY_HASH_PREFIX):
 			return HashTypePkhNocurve
 		case strings.HasPrefix(s, BLINDED_PUBLIC_KEY_HASH_PREFIX):
 			return HashTypePkhBlinded
 		case strings.HasPrefix(s, BAKER_PUBLIC_KEY_HASH_PREFIX):
 			return HashTypePkhBaker
+		case strings.HasPrefix(s, SMART_ROLLUP_ADDRESS_PREFIX):
+			return HashTypeSmartRollupAddress
+		}
+	case 37:
+		switch true {
+		case strings.HasPrefix(s, BLINDED_PUBLIC_KEY_HASH_PREFIX):
+			return HashTypePkhBlinded
+		case strings.HasPrefix(s, TX_ROLLUP_ADDRESS_PREFIX):
+			return HashTypeTxRollupAddress
 		}
 	case 51:
 		switch true {
@@ -190,12 +214,18 @@ func (t HashType) Prefix() string {
 		return SECP256K1_PUBLIC_KEY_HASH_PREFIX
 	case HashTypePkhP256:
 		return P256_PUBLIC_KEY_HASH_PREFIX
+	case HashTypePkhBls12381:
+		return BLS12_381_PUBLIC_KEY_HASH_PREFIX
 	case HashTypePkhNocurve:
 		return NOCURVE_PUBLIC_KEY_HASH_PREFIX
 	case HashTypePkhBlinded:
 		return BLINDED_PUBLIC_KEY_HASH_PREFIX
 	case HashTypePkhBaker:
 		return BAKER_PUBLIC_KEY_HASH_PREFIX
+	case HashTypeTxRollupAddress:
+		return TX_ROLLUP_ADDRESS_PREFIX
+	case HashTypeSmartRollupAddress:
+		return SMART_ROLLUP_ADDRESS_PREFIX
 	case HashTypeBlock:
 		return BLOCK_HASH_PREFIX
 	case HashTypeOperation:
@@ -245,6 +275,9 @@ func (t HashType) Prefix() string {
 	case HashTypeSigGeneric:
 		return GENERIC_SIGNATURE_PREFIX
 	default:
+		if c, ok := lookupCustomHashType(t); ok {
+			return c.prefix
+		}
 		return ""
 	}
 }
@@ -261,12 +294,18 @@ func (t HashType) PrefixBytes() []byte {
 		return SECP256K1_PUBLIC_KEY_HASH_ID
 	case HashTypePkhP256:
 		return P256_PUBLIC_KEY_HASH_ID
+	case HashTypePkhBls12381:
+		return BLS12_381_PUBLIC_KEY_HASH_ID
 	case HashTypePkhNocurve:
 		return NOCURVE_PUBLIC_KEY_HASH_ID
 	case HashTypePkhBlinded:
 		return BLINDED_PUBLIC_KEY_HASH_ID
 	case HashTypePkhBaker:
 		return BAKER_PUBLIC_KEY_HASH_ID
+	case HashTypeTxRollupAddress:
+		return TX_ROLLUP_ADDRESS_ID
+	case HashTypeSmartRollupAddress:
+		return SMART_ROLLUP_ADDRESS_ID
 	case HashTypeBlock:
 		return BLOCK_HASH_ID
 	case HashTypeOperation:
@@ -316,6 +355,9 @@ func (t HashType) PrefixBytes() []byte {
 	case HashTypeSigGeneric:
 		return GENERIC_SIGNATURE_ID
 	default:
+		if c, ok := lookupCustomHashType(t); ok {
+			return c.id
+		}
 		return nil
 	}
 }
@@ -329,9 +371,12 @@ func (t HashType) Len() int {
 	case HashTypePkhEd25519,
 		HashTypePkhSecp256k1,
 		HashTypePkhP256,
+		HashTypePkhBls12381,
 		HashTypePkhNocurve,
 		HashTypePkhBlinded,
-		HashTypePkhBaker:
+		HashTypePkhBaker,
+		HashTypeTxRollupAddress,
+		HashTypeSmartRollupAddress:
 		return 20
 	case HashTypeBlock,
 		HashTypeOperation,
@@ -362,6 +407,9 @@ func (t HashType) Len() int {
 		HashTypeSigGeneric:
 		return 64
 	default:
+		if c, ok := lookupCustomHashType(t); ok {
+			return c.len
+		}
 		return 0
 	}
 }
@@ -374,10 +422,13 @@ func (t HashType) Base58Len() int {
 		HashTypePkhEd25519,
 		HashTypePkhSecp256k1,
 		HashTypePkhP256,
+		HashTypePkhBls12381,
 		HashTypePkhNocurve,
-		HashTypePkhBaker:
+		HashTypePkhBaker,
+		HashTypeSmartRollupAddress:
 		return 36
-	case HashTypePkhBlinded:
+	case HashTypePkhBlinded,
+		HashTypeTxRollupAddress:
 		return 37
 	case HashTypeBlock,
 		HashTypeOperation,
@@ -413,6 +464,9 @@ func (t HashType) Base58Len() int {
 		HashTypeSigSecp256k1:
 		return 99
 	default:
+		if c, ok := lookupCustomHashType(t); ok {
+			return c.b58len
+		}
 		return 0
 	}
 }
@@ -500,6 +554,28 @@ func NewChainIdHash(buf []byte) ChainIdHash {
 	return ChainIdHash{Hash: NewHash(HashTypeChainId, b)}
 }
 
+// ChainId identifies a Tezos network. It is the type of Michelson chain_id
+// values and an alias of ChainIdHash.
+type ChainId = ChainIdHash
+
+// ParseChainId parses a chain id in base58 form, e.g. NetXdQprcVkpaWU.
+func ParseChainId(s string) (ChainId, error) {
+	return ParseChainIdHash(s)
+}
+
+// DecodeChainId decodes a chain id from its 4 byte binary form.
+func DecodeChainId(buf []byte) (ChainId, error) {
+	if len(buf) != HashTypeChainId.Len() {
+		return ChainId{}, fmt.Errorf("invalid len %d for chain id hash", len(buf))
+	}
+	return NewChainIdHash(buf), nil
+}
+
+// Bytes returns the 4 byte binary form of the chain id.
+func (h ChainIdHash) Bytes() []byte {
+	return h.Hash.Hash
+}
+
 func (h ChainIdHash) Equal(h2 ChainIdHash) bool {
 	return h.Hash.Equal(h2.Hash)
 }
@@ -617,6 +693,13 @@ func ParseBlockHash(s string) (BlockHash, error) {
 	return h, nil
 }
 
+// ComputeBlockHash returns the hash of a block from the binary encoding of
+// its header, i.e. the shell header followed by the protocol data.
+func ComputeBlockHash(header []byte) BlockHash {
+	h := blake2b.Sum256(header)
+	return NewBlockHash(h[:])
+}
+
 // ProtocolHash
 type ProtocolHash struct {
 	Hash
@@ -751,6 +834,15 @@ func ParseOpHash(s string) (OpHash, error) {
 	return h, nil
 }
 
+// ComputeOpHash returns the hash of a signed operation, i.e. forged operation
+// bytes (which start with the branch) followed by the raw signature. The
+// node reports the same hash on injection, so it can be used to find the
+// operation in blocks before injection returns.
+func ComputeOpHash(signed []byte) OpHash {
+	h := blake2b.Sum256(signed)
+	return NewOpHash(h[:])
+}
+
 // ExprHash
 type ExprHash struct {
 	Hash
@@ -919,3 +1011,77 @@ func encodeHash(typ HashType, h []byte) (string, error) {
 	}
 	return base58.CheckEncode(h, typ.PrefixBytes()), nil
 }
+
+// custom hash types registered at runtime
+type customHashType struct {
+	prefix string
+	id     []byte
+	len    int
+	b58len int
+}
+
+var (
+	customHashMu    sync.RWMutex
+	customHashTypes = make(map[HashType]customHashType)
+	nextHashType    = HashTypeSmartRollupAddress + 1
+)
+
+// RegisterHashPrefix registers a new hash type with base58 prefix, binary
+// version bytes and decoded payload length so that ParseHash can decode
+// identifiers introduced by future protocols. It returns HashTypeInvalid when
+// prefix and version bytes don't match or collide with a known hash type.
+// Registering the same prefix again returns the existing type.
+func RegisterHashPrefix(prefix string, versionBytes []byte, length int) HashType {
+	if prefix == "" || len(versionBytes) == 0 || length <= 0 {
+		return HashTypeInvalid
+	}
+	// all encodings of a valid prefix start with prefix and have equal length
+	lo := base58.CheckEncode(make([]byte, length), versionBytes)
+	hi := base58.CheckEncode(bytes.Repeat([]byte{0xff}, length), versionBytes)
+	if len(lo) != len(hi) || !strings.HasPrefix(lo, prefix) || !strings.HasPrefix(hi, prefix) {
+		return HashTypeInvalid
+	}
+	if parseBuiltinHashType(lo).IsValid() {
+		return HashTypeInvalid
+	}
+	customHashMu.Lock()
+	defer customHashMu.Unlock()
+	for t, c := range customHashTypes {
+		if c.prefix == prefix && c.len == length && bytes.Equal(c.id, versionBytes) {
+			return t
+		}
+		if c.b58len == len(lo) && (strings.HasPrefix(lo, c.prefix) || strings.HasPrefix(c.prefix, prefix)) {
+			return HashTypeInvalid
+		}
+	}
+	if nextHashType == HashTypeInvalid {
+		return HashTypeInvalid
+	}
+	t := nextHashType
+	nextHashType++
+	customHashTypes[t] = customHashType{
+		prefix: prefix,
+		id:     append([]byte(nil), versionBytes...),
+		len:    length,
+		b58len: len(lo),
+	}
+	return t
+}
+
+func lookupCustomHashType(t HashType) (customHashType, bool) {
+	customHashMu.RLock()
+	defer customHashMu.RUnlock()
+	c, ok := customHashTypes[t]
+	return c, ok
+}
+
+func parseCustomHashType(s string) HashType {
+	customHashMu.RLock()
+	defer customHashMu.RUnlock()
+	for t, c := range customHashTypes {
+		if len(s) == c.b58len && strings.HasPrefix(s, c.prefix) {
+			return t
+		}
+	}
+	return HashTypeInvalid
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package tezos
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"blockwatch.cc/tzgo/base58"
+)
+
+func TestRegisterHashPrefix(t *testing.T) {
+	// smart rollup commitment hash
+	prefix, id := "src1", []byte{17, 165, 134, 138}
+	typ := RegisterHashPrefix(prefix, id, 32)
+	if !typ.IsValid() {
+		t.Fatalf("registration failed")
+	}
+	if typ.Prefix() != prefix || !bytes.Equal(typ.PrefixBytes(), id) || typ.Len() != 32 {
+		t.Errorf("unexpected type properties %s %x %d", typ.Prefix(), typ.PrefixBytes(), typ.Len())
+	}
+	if again := RegisterHashPrefix(prefix, id, 32); again != typ {
+		t.Errorf("re-registration returned different type %d != %d", again, typ)
+	}
+
+	payload := bytes.Repeat([]byte{0x42}, 32)
+	s := base58.CheckEncode(payload, id)
+	if len(s) != typ.Base58Len() {
+		t.Errorf("base58 length mismatch want=%d got=%d", typ.Base58Len(), len(s))
+	}
+	h, err := ParseHash(s)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if h.Type != typ || !bytes.Equal(h.Hash, payload) {
+		t.Errorf("decoded hash mismatch %d %x", h.Type, h.Hash)
+	}
+	if h.String() != s {
+		t.Errorf("encoded hash mismatch want=%s got=%s", s, h.String())
+	}
+
+	// prefix does not match version bytes
+	if typ := RegisterHashPrefix("xyz", id, 32); typ.IsValid() {
+		t.Errorf("expected mismatched prefix to fail")
+	}
+	// builtin prefixes cannot be replaced
+	if typ := RegisterHashPrefix(BLOCK_HASH_PREFIX, BLOCK_HASH_ID, 32); typ.IsValid() {
+		t.Errorf("expected builtin prefix to fail")
+	}
+}
+
+func TestComputeHash(t *testing.T) {
+	// BLAKE2b-256 of the empty input
+	want, _ := hex.DecodeString("0e5751c026e543b2e8ab2eb06099daa1d1e5df47778f7787faab45cdf12fe3a8")
+
+	oh := ComputeOpHash(nil)
+	if !bytes.Equal(oh.Hash.Hash, want) {
+		t.Errorf("op hash mismatch %x", oh.Hash.Hash)
+	}
+	if s := oh.String(); !strings.HasPrefix(s, OPERATION_HASH_PREFIX) || len(s) != 51 {
+		t.Errorf("unexpected op hash %s", s)
+	}
+	if _, err := ParseOpHash(oh.String()); err != nil {
+		t.Errorf("op hash does not parse: %v", err)
+	}
+
+	bh := ComputeBlockHash(nil)
+	if !bytes.Equal(bh.Hash.Hash, want) {
+		t.Errorf("block hash mismatch %x", bh.Hash.Hash)
+	}
+	if s := bh.String(); !strings.HasPrefix(s, BLOCK_HASH_PREFIX) || len(s) != 51 {
+		t.Errorf("unexpected block hash %s", s)
+	}
+
+	// a transaction forged on the mainnet genesis branch and signed by the
+	// flextesa alice key, expected hashes computed with Python's hashlib
+	// blake2b and an independent base58check encoder
+	sk := MustParsePrivateKey("edsk3QoqBuvdamxouPhin7swCvkQNgq4jP5KZPbwWNnwdZpSpJiEbq")
+	op, _ := hex.DecodeString("8fcf233671b6a04fcf679d2a381c2544ea6c1ea29ba6157776ed8424c7ccd00b6c005c56bbc501ad676afc27ae3d660232287a15b5e28c0b0be852ac02c0843d010474bbd2d5084f3c0cbe182d38323b4804cf01b500ffff087472616e73666572000000020001")
+	sig, err := sk.SignOperation(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(sig.Data), "9d9a51f7a4fe99696d3184cbbcb8f07be199bfdfeb152c61f985315537d39f3e7338e80069ad4e38f9e32e44ff80b286e1d437735b77b30c0782616e7662d905"; got != want {
+		t.Fatalf("signature mismatch\n  want=%s\n  got= %s", want, got)
+	}
+	if got, want := ComputeOpHash(append(op, sig.Data...)).String(), "ooTZuRox8SxXH1hhqKfk6o7QDPu18kTobcv4AcqyBMWWU61Ftom"; got != want {
+		t.Errorf("op hash mismatch want=%s got=%s", want, got)
+	}
+	if got, want := ComputeBlockHash(op).String(), "BLG2ppuav3jjTxysojYqugfBY4i6Pe53e7y17fBbAX8t8UaCVnk"; got != want {
+		t.Errorf("block hash mismatch want=%s got=%s", want, got)
+	}
+}
+
+func TestChainId(t *testing.T) {
+	c, err := ParseChainId("NetXdQprcVkpaWU")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(c.Bytes()) != "7a06a770" {
+		t.Errorf("unexpected bytes %x", c.Bytes())
+	}
+	d, err := DecodeChainId(c.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Equal(c) || d.String() != "NetXdQprcVkpaWU" {
+		t.Errorf("roundtrip mismatch %s", d)
+	}
+	if _, err := DecodeChainId([]byte{1, 2, 3}); err == nil {
+		t.Errorf("expected length error")
+	}
+	if _, err := ParseChainId("tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"); err == nil {
+		t.Errorf("expected prefix error")
+	}
+}
@@ -17,9 +17,12 @@ const (
 	ED25519_PUBLIC_KEY_HASH_PREFIX   = "tz1"
 	SECP256K1_PUBLIC_KEY_HASH_PREFIX = "tz2"
 	P256_PUBLIC_KEY_HASH_PREFIX      = "tz3"
+	BLS12_381_PUBLIC_KEY_HASH_PREFIX = "tz4"
 	NOCURVE_PUBLIC_KEY_HASH_PREFIX   = "KT1"  // originated contract identifier
 	BAKER_PUBLIC_KEY_HASH_PREFIX     = "SG1"  // baker contract in v008
 	BLINDED_PUBLIC_KEY_HASH_PREFIX   = "btz1" // blinded tz1
+	TX_ROLLUP_ADDRESS_PREFIX         = "txr1" // transaction rollup (deprecated)
+	SMART_ROLLUP_ADDRESS_PREFIX      = "sr1"  // smart rollup
 
 	// base58 prefixes for 32 byte hash magics
 	BLOCK_HASH_PREFIX               = "B"
@@ -67,9 +70,12 @@ var (
 	ED25519_PUBLIC_KEY_HASH_ID   = []byte{0x06, 0xA1, 0x9F}       // "\006\161\159" (* tz1(36) *)
 	SECP256K1_PUBLIC_KEY_HASH_ID = []byte{0x06, 0xA1, 0xA1}       // "\006\161\161" (* tz2(36) *)
 	P256_PUBLIC_KEY_HASH_ID      = []byte{0x06, 0xA1, 0xA4}       // "\006\161\164" (* tz3(36) *)
+	BLS12_381_PUBLIC_KEY_HASH_ID = []byte{0x06, 0xA1, 0xA6}       // "\006\161\166" (* tz4(36) *)
 	NOCURVE_PUBLIC_KEY_HASH_ID   = []byte{0x02, 0x5A, 0x79}       // "\002\090\121" (* KT1(36) *)
 	BAKER_PUBLIC_KEY_HASH_ID     = []byte{0x03, 0x38, 0xE2}       // "\003\056\226" (* SG1(36) *)
 	BLINDED_PUBLIC_KEY_HASH_ID   = []byte{0x01, 0x02, 0x31, 0xDF} // "\002\090\121" (* btz1(37) *)
+	TX_ROLLUP_ADDRESS_ID         = []byte{0x01, 0x80, 0x78, 0x1F} // "\001\128\120\031" (* txr1(37) *)
+	SMART_ROLLUP_ADDRESS_ID      = []byte{0x06, 0x7C, 0x75}       // "\006\124\117" (* sr1(36) *)
 
 	// 32 byte hash magics
 	BLOCK_HASH_ID               = []byte{0x01, 0x34}       // "\001\052" (* B(51) *)
@@ -82,7 +88,7 @@ var (
 
 	ED25519_SEED_ID         = []byte{0x0D, 0x0F, 0x3A, 0x07} // "\013\015\058\007" (* edsk(54) *)
 	ED25519_PUBLIC_KEY_ID   = []byte{0x0D, 0x0F, 0x25, 0xD9} // "\013\015\037\217" (* edpk(54) *)
-	SECP256K1_SECRET_KEY_ID = []byte{0x11, 0xA2, 0xE0, 0xD2} // "\017\162\224\201" (* spsk(54) *)
+	SECP256K1_SECRET_KEY_ID = []byte{0x11, 0xA2, 0xE0, 0xC9} // "\017\162\224\201" (* spsk(54) *)
 	P256_SECRET_KEY_ID      = []byte{0x10, 0x51, 0xEE, 0xBD} // "\016\081\238\189" (* p2sk(54) *)
 
 	// 33 byte hash magics
@@ -0,0 +1,147 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tyler-smith/go-bip39/wordlists"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultDerivationPath is the BIP44 path used by Tezos wallets like Temple
+// and Kukai for the first account.
+const DefaultDerivationPath = "m/44'/1729'/0'/0'"
+
+// hardenedOffset marks a hardened derivation index.
+const hardenedOffset = 0x80000000
+
+// ErrInvalidMnemonic is returned for phrases that are not valid English BIP39
+// mnemonics.
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+// mnemonicWords maps English BIP39 words to their 11 bit index.
+var mnemonicWords = func() map[string]int {
+	m := make(map[string]int, len(wordlists.English))
+	for i, w := range wordlists.English {
+		m[w] = i
+	}
+	return m
+}()
+
+// MnemonicToSeed returns the 64 byte BIP39 seed of a mnemonic phrase and an
+// optional passphrase. Words may be separated by any white space. The phrase
+// must consist of 12 to 24 words from the English wordlist with a valid
+// checksum. Mnemonic and passphrase are converted to Unicode NFKD form
+// before the seed is derived.
+func MnemonicToSeed(mnemonic, passphrase string) ([]byte, error) {
+	words := strings.Fields(norm.NFKD.String(mnemonic))
+	if err := checkMnemonic(words); err != nil {
+		return nil, err
+	}
+	salt := "mnemonic" + norm.NFKD.String(passphrase)
+	return pbkdf2.Key([]byte(strings.Join(words, " ")), []byte(salt), 2048, 64, sha512.New), nil
+}
+
+// checkMnemonic verifies the word count, words and checksum of a phrase. Each
+// word encodes 11 bits, the last len(words)/3 bits are the leading bits of
+// the SHA256 hash of the entropy.
+func checkMnemonic(words []string) error {
+	n := len(words)
+	if n%3 != 0 || n < 12 || n > 24 {
+		return fmt.Errorf("%w: %d words", ErrInvalidMnemonic, n)
+	}
+	buf := make([]byte, (n*11+7)/8)
+	for i, w := range words {
+		idx, ok := mnemonicWords[w]
+		if !ok {
+			return fmt.Errorf("%w: unknown word %q", ErrInvalidMnemonic, w)
+		}
+		for j := 0; j < 11; j++ {
+			if idx&(1<<(10-j)) != 0 {
+				bit := i*11 + j
+				buf[bit/8] |= 0x80 >> (bit % 8)
+			}
+		}
+	}
+	cs := n / 3
+	entropy := buf[:n*4/3]
+	want := sha256.Sum256(entropy)
+	if buf[len(entropy)]>>(8-cs) != want[0]>>(8-cs) {
+		return fmt.Errorf("%w: checksum mismatch", ErrInvalidMnemonic)
+	}
+	return nil
+}
+
+// ParseDerivationPath parses a BIP32 path like m/44'/1729'/0'/0' into child
+// indexes. Hardened indexes are marked with ' or h.
+func ParseDerivationPath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if len(parts) == 0 || parts[0] != "m" {
+		return nil, fmt.Errorf("invalid derivation path %q", path)
+	}
+	res := make([]uint32, 0, len(parts)-1)
+	for _, v := range parts[1:] {
+		var offset uint32
+		if strings.HasSuffix(v, "'") || strings.HasSuffix(v, "h") {
+			v, offset = v[:len(v)-1], hardenedOffset
+		}
+		i, err := strconv.ParseUint(v, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid derivation path %q: %v", path, err)
+		}
+		res = append(res, uint32(i)+offset)
+	}
+	return res, nil
+}
+
+// DeriveEd25519Key derives an ed25519 key from a BIP39 seed along path using
+// SLIP-10. Ed25519 only supports hardened derivation.
+//
+//	seed, err := MnemonicToSeed(words, "")
+//	if err != nil {
+//		return err
+//	}
+//	sk, err := DeriveEd25519Key(seed, DefaultDerivationPath)
+func DeriveEd25519Key(seed []byte, path string) (PrivateKey, error) {
+	idx, err := ParseDerivationPath(path)
+	if err != nil {
+		return PrivateKey{}, err
+	}
+	key, chain := slip10Master(seed)
+	for _, i := range idx {
+		if i < hardenedOffset {
+			return PrivateKey{}, fmt.Errorf("ed25519 derivation requires hardened index in %q", path)
+		}
+		key, chain = slip10Child(key, chain, i)
+	}
+	return NewPrivateKey(KeyTypeEd25519, key)
+}
+
+// slip10Master returns the ed25519 master key and chain code of seed.
+func slip10Master(seed []byte) ([]byte, []byte) {
+	m := hmac.New(sha512.New, []byte("ed25519 seed"))
+	m.Write(seed)
+	sum := m.Sum(nil)
+	return sum[:32], sum[32:]
+}
+
+// slip10Child returns the hardened ed25519 child key i of key.
+func slip10Child(key, chain []byte, i uint32) ([]byte, []byte) {
+	var buf [37]byte
+	copy(buf[1:], key)
+	binary.BigEndian.PutUint32(buf[33:], i)
+	m := hmac.New(sha512.New, chain)
+	m.Write(buf[:])
+	sum := m.Sum(nil)
+	return sum[:32], sum[32:]
+}
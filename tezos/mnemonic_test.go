@@ -0,0 +1,121 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package tezos
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func TestMnemonicToSeed(t *testing.T) {
+	// BIP39 reference vectors (Trezor)
+	for _, test := range []struct {
+		Mnemonic string
+		Seed     string
+	}{
+		{
+			Mnemonic: testMnemonic,
+			Seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			Mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+			Seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+		{
+			Mnemonic: strings.Repeat("zoo ", 11) + "wrong",
+			Seed:     "ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+		},
+	} {
+		seed, err := MnemonicToSeed(test.Mnemonic, "TREZOR")
+		if err != nil {
+			t.Errorf("%s: %v", test.Mnemonic, err)
+			continue
+		}
+		if got := hex.EncodeToString(seed); got != test.Seed {
+			t.Errorf("seed mismatch\n  want=%s\n  got= %s", test.Seed, got)
+		}
+	}
+
+	// extra white space is ignored
+	seed, err := MnemonicToSeed("  abandon abandon abandon abandon abandon abandon\n abandon abandon abandon abandon abandon  about ", "TREZOR")
+	if err != nil || !strings.HasPrefix(hex.EncodeToString(seed), "c55257c3") {
+		t.Errorf("seed mismatch for unnormalized white space: %v", err)
+	}
+
+	// passphrases are NFKD normalized
+	composed, _ := MnemonicToSeed(testMnemonic, "caf\u00e9")
+	decomposed, _ := MnemonicToSeed(testMnemonic, "cafe\u0301")
+	if !bytes.Equal(composed, decomposed) {
+		t.Errorf("seed differs for composed and decomposed passphrase")
+	}
+
+	for _, m := range []string{
+		"",
+		strings.Repeat("abandon ", 12),          // bad checksum
+		strings.Repeat("abandon ", 11) + "abou", // unknown word
+		strings.Repeat("abandon ", 8) + "about", // too few words
+		strings.Repeat("abandon ", 12) + "abandon abandon about", // bad checksum for 15 words
+	} {
+		if _, err := MnemonicToSeed(m, ""); !errors.Is(err, ErrInvalidMnemonic) {
+			t.Errorf("%q: expected ErrInvalidMnemonic, got %v", m, err)
+		}
+	}
+}
+
+// mustSeed returns the seed of a mnemonic known to be valid.
+func mustSeed(t *testing.T, mnemonic string) []byte {
+	t.Helper()
+	seed, err := MnemonicToSeed(mnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return seed
+}
+
+func TestSlip10Ed25519(t *testing.T) {
+	// SLIP-10 test vector 1 for ed25519
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	key, chain := slip10Master(seed)
+	if hex.EncodeToString(key) != "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7" ||
+		hex.EncodeToString(chain) != "90046a93de5380a72b5e45010748567d5ea02bbf6522f979e05c0d8d8ca9fffb" {
+		t.Errorf("master mismatch key=%x chain=%x", key, chain)
+	}
+	key, chain = slip10Child(key, chain, hardenedOffset)
+	if hex.EncodeToString(key) != "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3" ||
+		hex.EncodeToString(chain) != "8b59aa11380b624e81507a27fedda59fea6d0b779a778918a2fd3590e16e9c69" {
+		t.Errorf("m/0' mismatch key=%x chain=%x", key, chain)
+	}
+}
+
+func TestDeriveEd25519Key(t *testing.T) {
+	sk, err := DeriveEd25519Key(mustSeed(t, testMnemonic), DefaultDerivationPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sk.Address().String(); got != "tz1VQA4RP4fLjEEMW2FR4pE9kAg5abb5h5GL" {
+		t.Errorf("address mismatch got=%s", got)
+	}
+	if got := sk.Public().String(); got != "edpku4US3ZykcZifjzSGFCmFr3zRgCKndE82estE4irj4d5oqDNDvf" {
+		t.Errorf("public key mismatch got=%s", got)
+	}
+	if _, err := sk.SignOperation([]byte{1, 2, 3}); err != nil {
+		t.Errorf("sign: %v", err)
+	}
+
+	for _, path := range []string{"", "44'/1729'", "m/44'/x'", "m/44'/1729'/0'/0"} {
+		if _, err := DeriveEd25519Key(mustSeed(t, testMnemonic), path); err == nil {
+			t.Errorf("%q: expected error", path)
+		}
+	}
+	idx, err := ParseDerivationPath("m/44h/1729'/3'")
+	if err != nil || len(idx) != 3 || idx[0] != 44+hardenedOffset || idx[2] != 3+hardenedOffset {
+		t.Errorf("unexpected path %v %v", idx, err)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrTezPrecision is returned when a tez amount has more than 6 fractional
+	// digits and cannot be represented in mutez without loss of precision.
+	ErrTezPrecision = errors.New("tez amount exceeds mutez precision")
+
+	// ErrTezOverflow is returned when a tez amount does not fit into mutez.
+	ErrTezOverflow = errors.New("tez amount overflows mutez")
+)
+
+// Mutez represents an amount of tez in atomic units (1 tez = 1,000,000 mutez).
+type Mutez int64
+
+const MutezPerTez Mutez = 1000000
+
+func (m Mutez) Int64() int64 {
+	return int64(m)
+}
+
+// Tez returns the amount as float. Use for display only, values may be rounded.
+func (m Mutez) Tez() float64 {
+	return float64(m) / float64(MutezPerTez)
+}
+
+// String returns the exact decimal tez representation, e.g. "1.5".
+func (m Mutez) String() string {
+	var sign string
+	u := uint64(m)
+	if m < 0 {
+		sign = "-"
+		u = uint64(-m)
+	}
+	i, f := u/uint64(MutezPerTez), u%uint64(MutezPerTez)
+	if f == 0 {
+		return sign + strconv.FormatUint(i, 10)
+	}
+	frac := strings.TrimRight(fmt.Sprintf("%06d", f), "0")
+	return sign + strconv.FormatUint(i, 10) + "." + frac
+}
+
+// ParseTez parses a decimal tez string like "1.5", "-0.000001" or "1.5e3" into
+// an exact amount of mutez. Parsing uses integer arithmetic only so that
+// values delivered as JSON floats do not suffer float rounding. Amounts with
+// more than 6 significant fractional digits are rejected with ErrTezPrecision,
+// trailing zeros like in "1.0000000" are accepted.
+func ParseTez(s string) (Mutez, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return 0, fmt.Errorf("invalid tez amount ''")
+	}
+	orig := s
+
+	// sign
+	var neg bool
+	switch s[0] {
+	case '-':
+		neg = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	// optional exponent
+	var exp int
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return 0, fmt.Errorf("invalid tez amount '%s'", orig)
+		}
+		switch {
+		case e > maxTezExponent:
+			return 0, fmt.Errorf("%w: '%s'", ErrTezOverflow, orig)
+		case e < -maxTezExponent:
+			return 0, fmt.Errorf("%w: '%s'", ErrTezPrecision, orig)
+		}
+		exp = e
+		s = s[:i]
+	}
+
+	// integer and fractional digits
+	ipart, fpart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		ipart, fpart = s[:i], s[i+1:]
+	}
+	if len(ipart)+len(fpart) == 0 || !isDigits(ipart) || !isDigits(fpart) {
+		return 0, fmt.Errorf("invalid tez amount '%s'", orig)
+	}
+	// trailing zeros do not add precision
+	fpart = strings.TrimRight(fpart, "0")
+	digits := strings.TrimLeft(ipart+fpart, "0")
+	if len(digits) == 0 {
+		return 0, nil
+	}
+
+	// number of fractional digits after applying the exponent; on mutez
+	// scale at most 6 fractional digits are allowed
+	shift := 6 - (len(fpart) - exp)
+	if shift < 0 {
+		return 0, fmt.Errorf("%w: '%s'", ErrTezPrecision, orig)
+	}
+	if len(digits)+shift > 19 {
+		return 0, fmt.Errorf("%w: '%s'", ErrTezOverflow, orig)
+	}
+	z, _ := new(big.Int).SetString(digits, 10)
+	z.Mul(z, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(shift)), nil))
+	if neg {
+		z.Neg(z)
+	}
+	if !z.IsInt64() {
+		return 0, fmt.Errorf("%w: '%s'", ErrTezOverflow, orig)
+	}
+	return Mutez(z.Int64()), nil
+}
+
+// ParseTezLoose parses human-entered tez amounts like "1,000.5" or
+// "1 000 000". Grouping separators (comma, space, underscore or apostrophe)
+// are only accepted in the integer part, must be used consistently and must
+// separate groups of three digits. Ambiguous inputs like "1,5" or "1.000,5"
+// are rejected. The cleaned amount is parsed exactly like ParseTez.
+func ParseTezLoose(s string) (Mutez, error) {
+	s = strings.TrimSpace(s)
+	orig := s
+	var sign string
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		sign, s = s[:1], s[1:]
+	}
+	ipart, rest := s, ""
+	if i := strings.IndexAny(s, ".eE"); i >= 0 {
+		ipart, rest = s[:i], s[i:]
+	}
+	if strings.ContainsAny(rest, tezGroupSeparators) {
+		return 0, fmt.Errorf("invalid tez amount '%s'", orig)
+	}
+	if i := strings.IndexAny(ipart, tezGroupSeparators); i >= 0 {
+		groups := strings.Split(ipart, ipart[i:i+1])
+		for k, g := range groups {
+			if !isDigits(g) || len(g) > 3 || len(g) == 0 || (k > 0 && len(g) != 3) {
+				return 0, fmt.Errorf("invalid tez amount '%s'", orig)
+			}
+		}
+		ipart = strings.Join(groups, "")
+	}
+	return ParseTez(sign + ipart + rest)
+}
+
+const tezGroupSeparators = ", _'"
+
+// maxTezExponent bounds exponents accepted by ParseTez. Larger exponents can
+// never yield a valid mutez amount and would only cost arithmetic.
+const maxTezExponent = 40
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package tezos
+
+import (
+	"errors"
+	"testing"
+)
+
+type tezTest struct {
+	In   string
+	Want Mutez
+	Err  error
+}
+
+var tezInfo = []tezTest{
+	tezTest{In: "0", Want: 0},
+	tezTest{In: "1", Want: 1000000},
+	tezTest{In: "1.5", Want: 1500000},
+	tezTest{In: "+1.5", Want: 1500000},
+	tezTest{In: "-1.5", Want: -1500000},
+	tezTest{In: ".5", Want: 500000},
+	tezTest{In: "5.", Want: 5000000},
+	tezTest{In: "0.000001", Want: 1},
+	tezTest{In: "-0.000001", Want: -1},
+	tezTest{In: "0.1", Want: 100000},
+	tezTest{In: "0.3", Want: 300000},
+	tezTest{In: "1234.567891", Want: 1234567891},
+	tezTest{In: "1e-6", Want: 1},
+	tezTest{In: "1.5e3", Want: 1500000000},
+	tezTest{In: "1500E-3", Want: 1500000},
+	tezTest{In: "0.00000000", Want: 0},
+	// trailing zeros beyond mutez precision
+	tezTest{In: "0.0000000", Want: 0},
+	tezTest{In: "1.0000000", Want: 1000000},
+	tezTest{In: "1.0000010", Want: 1000001},
+	tezTest{In: "1.50e-5", Want: 15},
+	tezTest{In: "9223372036854.775807", Want: 9223372036854775807},
+	tezTest{In: "-9223372036854.775808", Want: -9223372036854775808},
+	// over-precise
+	tezTest{In: "0.0000001", Err: ErrTezPrecision},
+	tezTest{In: "1.1234567", Err: ErrTezPrecision},
+	tezTest{In: "1e-7", Err: ErrTezPrecision},
+	tezTest{In: "1e-9223372036854775801", Err: ErrTezPrecision},
+	// overflow
+	tezTest{In: "9223372036854.775808", Err: ErrTezOverflow},
+	tezTest{In: "1e100", Err: ErrTezOverflow},
+	tezTest{In: "1e9223372036854775801", Err: ErrTezOverflow},
+	tezTest{In: "0e9223372036854775801", Err: ErrTezOverflow},
+	tezTest{In: "0.1e9223372036854775807", Err: ErrTezOverflow},
+	// malformed
+	tezTest{In: "", Err: errInvalid},
+	tezTest{In: "-", Err: errInvalid},
+	tezTest{In: ".", Err: errInvalid},
+	tezTest{In: "1.2.3", Err: errInvalid},
+	tezTest{In: "abc", Err: errInvalid},
+	tezTest{In: "1e", Err: errInvalid},
+	tezTest{In: "--1", Err: errInvalid},
+}
+
+var errInvalid = errors.New("invalid")
+
+func TestParseTez(t *testing.T) {
+	for _, test := range tezInfo {
+		m, err := ParseTez(test.In)
+		switch {
+		case test.Err == nil:
+			if err != nil {
+				t.Errorf("%q: unexpected error: %v", test.In, err)
+				continue
+			}
+			if m != test.Want {
+				t.Errorf("%q: mismatch want=%d got=%d", test.In, test.Want, m)
+			}
+		case test.Err == errInvalid:
+			if err == nil {
+				t.Errorf("%q: expected error, got %d", test.In, m)
+			}
+		default:
+			if !errors.Is(err, test.Err) {
+				t.Errorf("%q: expected error %v, got %v", test.In, test.Err, err)
+			}
+		}
+	}
+}
+
+func TestMutezString(t *testing.T) {
+	for _, v := range []string{"0", "1", "1.5", "-1.5", "0.000001", "1234.567891"} {
+		m, err := ParseTez(v)
+		if err != nil {
+			t.Fatalf("%q: %v", v, err)
+		}
+		if got := m.String(); got != v {
+			t.Errorf("%q: round-trip mismatch got=%s", v, got)
+		}
+	}
+}
+
+var tezLooseInfo = []tezTest{
+	// grouped
+	tezTest{In: "1,000", Want: 1000000000},
+	tezTest{In: "1,000.5", Want: 1000500000},
+	tezTest{In: "-12,345,678.000001", Want: -12345678000001},
+	tezTest{In: "1 000 000", Want: 1000000000000},
+	tezTest{In: "1_000", Want: 1000000000},
+	tezTest{In: "1'000.25", Want: 1000250000},
+	tezTest{In: "  2,500  ", Want: 2500000000},
+	// plain
+	tezTest{In: "0", Want: 0},
+	tezTest{In: "1.5", Want: 1500000},
+	tezTest{In: "1000", Want: 1000000000},
+	tezTest{In: "1.5e3", Want: 1500000000},
+	// ambiguous or malformed
+	tezTest{In: "1,5", Err: errInvalid},
+	tezTest{In: "1,0000", Err: errInvalid},
+	tezTest{In: "1000,000", Err: errInvalid},
+	tezTest{In: "1.000,5", Err: errInvalid},
+	tezTest{In: "1,000 000", Err: errInvalid},
+	tezTest{In: ",100", Err: errInvalid},
+	tezTest{In: "100,", Err: errInvalid},
+	tezTest{In: "1,,000", Err: errInvalid},
+	tezTest{In: "1.000 5", Err: errInvalid},
+	tezTest{In: "1e1,000", Err: errInvalid},
+	tezTest{In: "1,000e9223372036854775801", Err: ErrTezOverflow},
+	tezTest{In: "- 1", Err: errInvalid},
+	tezTest{In: "", Err: errInvalid},
+	tezTest{In: "1,000.0000001", Err: ErrTezPrecision},
+}
+
+func TestParseTezLoose(t *testing.T) {
+	for _, test := range tezLooseInfo {
+		m, err := ParseTezLoose(test.In)
+		switch {
+		case test.Err == nil:
+			if err != nil {
+				t.Errorf("%q: unexpected error: %v", test.In, err)
+				continue
+			}
+			if m != test.Want {
+				t.Errorf("%q: mismatch want=%d got=%d", test.In, test.Want, m)
+			}
+		case test.Err == errInvalid:
+			if err == nil {
+				t.Errorf("%q: expected error, got %d", test.In, m)
+			}
+		default:
+			if !errors.Is(err, test.Err) {
+				t.Errorf("%q: expected error %v, got %v", test.In, test.Err, err)
+			}
+		}
+	}
+}
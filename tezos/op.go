@@ -34,7 +34,7 @@ func (t *OpStatus) UnmarshalText(data []byte) error {
 	return nil
 }
 
-func (t *OpStatus) MarshalText() ([]byte, error) {
+func (t OpStatus) MarshalText() ([]byte, error) {
 	return []byte(t.String()), nil
 }
 
@@ -71,26 +71,42 @@ func (t OpStatus) String() string {
 type OpType byte
 
 const (
-	OpTypeBake                      OpType = iota // 0
-	OpTypeActivateAccount                         // 1
-	OpTypeDoubleBakingEvidence                    // 2
-	OpTypeDoubleEndorsementEvidence               // 3
-	OpTypeSeedNonceRevelation                     // 4
-	OpTypeTransaction                             // 5
-	OpTypeOrigination                             // 6
-	OpTypeDelegation                              // 7
-	OpTypeReveal                                  // 8
-	OpTypeEndorsement                             // 9
-	OpTypeProposals                               // 10
-	OpTypeBallot                                  // 11
-	OpTypeUnfreeze                                // 12 indexer only
-	OpTypeInvoice                                 // 13 indexer only
-	OpTypeAirdrop                                 // 14 indexer only
-	OpTypeSeedSlash                               // 15 indexer only
-	OpTypeMigration                               // 16 indexer only
-	OpTypeFailingNoop                             // 17 v009
-	OpTypeBatch                     = 254         // indexer only, output-only
-	OpTypeInvalid                   = 255
+	OpTypeBake                            OpType = iota // 0
+	OpTypeActivateAccount                               // 1
+	OpTypeDoubleBakingEvidence                          // 2
+	OpTypeDoubleEndorsementEvidence                     // 3
+	OpTypeSeedNonceRevelation                           // 4
+	OpTypeTransaction                                   // 5
+	OpTypeOrigination                                   // 6
+	OpTypeDelegation                                    // 7
+	OpTypeReveal                                        // 8
+	OpTypeEndorsement                                   // 9
+	OpTypeProposals                                     // 10
+	OpTypeBallot                                        // 11
+	OpTypeUnfreeze                                      // 12 indexer only
+	OpTypeInvoice                                       // 13 indexer only
+	OpTypeAirdrop                                       // 14 indexer only
+	OpTypeSeedSlash                                     // 15 indexer only
+	OpTypeMigration                                     // 16 indexer only
+	OpTypeFailingNoop                                   // 17 v009
+	OpTypePreendorsement                                // 18 v012
+	OpTypeAttestation                                   // 19 v018 (renamed endorsement)
+	OpTypePreattestation                                // 20 v018 (renamed preendorsement)
+	OpTypeSmartRollupOriginate                          // 21 v016
+	OpTypeSmartRollupAddMessages                        // 22 v016
+	OpTypeSmartRollupCement                             // 23 v016
+	OpTypeSmartRollupPublish                            // 24 v016
+	OpTypeSmartRollupRefute                             // 25 v016
+	OpTypeSmartRollupTimeout                            // 26 v016
+	OpTypeSmartRollupExecuteOutboxMessage               // 27 v016
+	OpTypeSmartRollupRecoverBond                        // 28 v016
+	OpTypeDalPublishCommitment                          // 29 v016 (renamed v019)
+	OpTypeStake                                         // 30 v018 pseudo op
+	OpTypeUnstake                                       // 31 v018 pseudo op
+	OpTypeFinalizeUnstake                               // 32 v018 pseudo op
+	OpTypeSetDelegateParameters                         // 33 v018 pseudo op
+	OpTypeBatch                           = 254         // indexer only, output-only
+	OpTypeInvalid                         = 255
 )
 
 func (t OpType) IsValid() bool {
@@ -150,6 +166,38 @@ func ParseOpType(s string) OpType {
 		return OpTypeBatch
 	case "failing_noop":
 		return OpTypeFailingNoop
+	case "preendorsement":
+		return OpTypePreendorsement
+	case "attestation", "attestation_with_dal":
+		return OpTypeAttestation
+	case "preattestation":
+		return OpTypePreattestation
+	case "smart_rollup_originate":
+		return OpTypeSmartRollupOriginate
+	case "smart_rollup_add_messages":
+		return OpTypeSmartRollupAddMessages
+	case "smart_rollup_cement":
+		return OpTypeSmartRollupCement
+	case "smart_rollup_publish":
+		return OpTypeSmartRollupPublish
+	case "smart_rollup_refute":
+		return OpTypeSmartRollupRefute
+	case "smart_rollup_timeout":
+		return OpTypeSmartRollupTimeout
+	case "smart_rollup_execute_outbox_message":
+		return OpTypeSmartRollupExecuteOutboxMessage
+	case "smart_rollup_recover_bond":
+		return OpTypeSmartRollupRecoverBond
+	case "dal_publish_commitment", "dal_publish_slot_header":
+		return OpTypeDalPublishCommitment
+	case "stake":
+		return OpTypeStake
+	case "unstake":
+		return OpTypeUnstake
+	case "finalize_unstake":
+		return OpTypeFinalizeUnstake
+	case "set_delegate_parameters":
+		return OpTypeSetDelegateParameters
 	default:
 		return OpTypeInvalid
 	}
@@ -195,6 +243,38 @@ func (t OpType) String() string {
 		return "batch"
 	case OpTypeFailingNoop:
 		return "failing_noop"
+	case OpTypePreendorsement:
+		return "preendorsement"
+	case OpTypeAttestation:
+		return "attestation"
+	case OpTypePreattestation:
+		return "preattestation"
+	case OpTypeSmartRollupOriginate:
+		return "smart_rollup_originate"
+	case OpTypeSmartRollupAddMessages:
+		return "smart_rollup_add_messages"
+	case OpTypeSmartRollupCement:
+		return "smart_rollup_cement"
+	case OpTypeSmartRollupPublish:
+		return "smart_rollup_publish"
+	case OpTypeSmartRollupRefute:
+		return "smart_rollup_refute"
+	case OpTypeSmartRollupTimeout:
+		return "smart_rollup_timeout"
+	case OpTypeSmartRollupExecuteOutboxMessage:
+		return "smart_rollup_execute_outbox_message"
+	case OpTypeSmartRollupRecoverBond:
+		return "smart_rollup_recover_bond"
+	case OpTypeDalPublishCommitment:
+		return "dal_publish_commitment"
+	case OpTypeStake:
+		return "stake"
+	case OpTypeUnstake:
+		return "unstake"
+	case OpTypeFinalizeUnstake:
+		return "finalize_unstake"
+	case OpTypeSetDelegateParameters:
+		return "set_delegate_parameters"
 	default:
 		return ""
 	}
@@ -229,6 +309,19 @@ var (
 		OpTypeOrigination:               109, // v005
 		OpTypeDelegation:                110, // v005
 		OpTypeFailingNoop:               17,  // v009
+		OpTypePreendorsement:            20,  // v012
+		OpTypePreattestation:            20,  // v018
+		OpTypeAttestation:               21,  // v018
+
+		OpTypeSmartRollupOriginate:            200, // v016
+		OpTypeSmartRollupAddMessages:          201, // v016
+		OpTypeSmartRollupCement:               202, // v016
+		OpTypeSmartRollupPublish:              203, // v016
+		OpTypeSmartRollupRefute:               204, // v016
+		OpTypeSmartRollupTimeout:              205, // v016
+		OpTypeSmartRollupExecuteOutboxMessage: 206, // v016
+		OpTypeSmartRollupRecoverBond:          207, // v016
+		OpTypeDalPublishCommitment:            230, // v016
 	}
 )
 
@@ -257,7 +350,7 @@ func (t OpType) Tag(p *Params) byte {
 
 func (t OpType) ListId() int {
 	switch t {
-	case OpTypeEndorsement:
+	case OpTypeEndorsement, OpTypePreendorsement, OpTypeAttestation, OpTypePreattestation:
 		return 0
 	case OpTypeProposals, OpTypeBallot:
 		return 1
@@ -270,6 +363,19 @@ func (t OpType) ListId() int {
 		OpTypeOrigination,
 		OpTypeDelegation,
 		OpTypeReveal,
+		OpTypeSmartRollupOriginate,
+		OpTypeSmartRollupAddMessages,
+		OpTypeSmartRollupCement,
+		OpTypeSmartRollupPublish,
+		OpTypeSmartRollupRefute,
+		OpTypeSmartRollupTimeout,
+		OpTypeSmartRollupExecuteOutboxMessage,
+		OpTypeSmartRollupRecoverBond,
+		OpTypeDalPublishCommitment,
+		OpTypeStake, // sent as transactions
+		OpTypeUnstake,
+		OpTypeFinalizeUnstake,
+		OpTypeSetDelegateParameters,
 		OpTypeBatch: // custom, indexer only
 		return 3
 	case OpTypeBake, OpTypeUnfreeze, OpTypeSeedSlash:
@@ -307,6 +413,28 @@ func ParseOpTag(t byte) OpType {
 		return OpTypeDelegation
 	case 17:
 		return OpTypeFailingNoop
+	case 20:
+		return OpTypePreattestation
+	case 21:
+		return OpTypeAttestation
+	case 200:
+		return OpTypeSmartRollupOriginate
+	case 201:
+		return OpTypeSmartRollupAddMessages
+	case 202:
+		return OpTypeSmartRollupCement
+	case 203:
+		return OpTypeSmartRollupPublish
+	case 204:
+		return OpTypeSmartRollupRefute
+	case 205:
+		return OpTypeSmartRollupTimeout
+	case 206:
+		return OpTypeSmartRollupExecuteOutboxMessage
+	case 207:
+		return OpTypeSmartRollupRecoverBond
+	case 230:
+		return OpTypeDalPublishCommitment
 	default:
 		return OpTypeInvalid
 	}
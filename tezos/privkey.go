@@ -0,0 +1,276 @@
+// Copyright (c) 2020-2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+
+package tezos
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"blockwatch.cc/tzgo/base58"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// OperationWatermark is prepended to forged operations before signing.
+const OperationWatermark = 0x03
+
+// ErrInvalidSignature is returned when a signature does not verify.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// PrivateKey is a secret key which can sign messages and operations.
+// Type is the type of the matching public key. Data holds the 64 byte
+// ed25519 secret key or the 32 byte secp256k1 or P-256 scalar.
+type PrivateKey struct {
+	Type KeyType
+	Data []byte
+}
+
+// GenerateKey returns a new random private key of curve typ.
+func GenerateKey(typ KeyType) (PrivateKey, error) {
+	switch typ {
+	case KeyTypeEd25519:
+		_, sk, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return PrivateKey{}, err
+		}
+		return PrivateKey{Type: typ, Data: sk}, nil
+	case KeyTypeSecp256k1, KeyTypeP256:
+		c := typ.curve()
+		buf := make([]byte, 32)
+		for {
+			if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+				return PrivateKey{}, err
+			}
+			if k := new(big.Int).SetBytes(buf); k.Sign() > 0 && k.Cmp(c.N) < 0 {
+				return PrivateKey{Type: typ, Data: buf}, nil
+			}
+		}
+	default:
+		return PrivateKey{}, ErrUnknownKeyType
+	}
+}
+
+// NewPrivateKey returns a private key of curve typ from raw key material.
+// Ed25519 keys accept a 32 byte seed or a 64 byte secret key.
+func NewPrivateKey(typ KeyType, data []byte) (PrivateKey, error) {
+	switch typ {
+	case KeyTypeEd25519:
+		switch len(data) {
+		case ed25519.SeedSize:
+			return PrivateKey{Type: typ, Data: ed25519.NewKeyFromSeed(data)}, nil
+		case ed25519.PrivateKeySize:
+			return PrivateKey{Type: typ, Data: data}, nil
+		}
+	case KeyTypeSecp256k1, KeyTypeP256:
+		if len(data) == 32 {
+			if _, err := typ.curve().publicKey(data); err != nil {
+				return PrivateKey{}, err
+			}
+			return PrivateKey{Type: typ, Data: data}, nil
+		}
+	default:
+		return PrivateKey{}, ErrUnknownKeyType
+	}
+	return PrivateKey{}, fmt.Errorf("invalid length %d for %s private key", len(data), typ.Prefix())
+}
+
+// ParsePrivateKey decodes an unencrypted base58 secret key (edsk, spsk or
+// p2sk). Both the 32 byte seed and 64 byte forms of edsk are accepted.
+func ParsePrivateKey(s string) (PrivateKey, error) {
+	decoded, version, err := base58.CheckDecode(s, 4, nil)
+	if err != nil {
+		if err == base58.ErrChecksum {
+			return PrivateKey{}, ErrChecksumMismatch
+		}
+		return PrivateKey{}, fmt.Errorf("unknown format for private key: %w", err)
+	}
+	var typ KeyType
+	switch true {
+	case bytes.Compare(version, ED25519_SEED_ID) == 0, bytes.Compare(version, ED25519_SECRET_KEY_ID) == 0:
+		typ = KeyTypeEd25519
+	case bytes.Compare(version, SECP256K1_SECRET_KEY_ID) == 0:
+		typ = KeyTypeSecp256k1
+	case bytes.Compare(version, P256_SECRET_KEY_ID) == 0:
+		typ = KeyTypeP256
+	default:
+		return PrivateKey{}, fmt.Errorf("unknown version %x for private key", version)
+	}
+	return NewPrivateKey(typ, decoded)
+}
+
+func MustParsePrivateKey(s string) PrivateKey {
+	k, err := ParsePrivateKey(s)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+func (k PrivateKey) IsValid() bool {
+	switch k.Type {
+	case KeyTypeEd25519:
+		return len(k.Data) == ed25519.PrivateKeySize
+	case KeyTypeSecp256k1, KeyTypeP256:
+		return len(k.Data) == 32
+	default:
+		return false
+	}
+}
+
+// String returns the base58 encoded secret key. Ed25519 keys use the 64 byte
+// form like octez-client.
+func (k PrivateKey) String() string {
+	typ := k.Type
+	switch typ {
+	case KeyTypeEd25519:
+		typ = KeyTypeEd25519Sec
+	case KeyTypeSecp256k1:
+		typ = KeyTypeSecp256k1Sec
+	case KeyTypeP256:
+		typ = KeyTypeP256Sec
+	}
+	if !k.IsValid() {
+		return ""
+	}
+	return base58.CheckEncode(k.Data, typ.PrefixBytes())
+}
+
+func (k PrivateKey) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+func (k *PrivateKey) UnmarshalText(data []byte) error {
+	key, err := ParsePrivateKey(string(data))
+	if err != nil {
+		return err
+	}
+	*k = key
+	return nil
+}
+
+// Public returns the public key of k.
+func (k PrivateKey) Public() Key {
+	if !k.IsValid() {
+		return InvalidKey
+	}
+	switch k.Type {
+	case KeyTypeEd25519:
+		pk := ed25519.PrivateKey(k.Data).Public().(ed25519.PublicKey)
+		return NewKey(k.Type, []byte(pk))
+	default:
+		pk, err := k.Type.curve().publicKey(k.Data)
+		if err != nil {
+			return InvalidKey
+		}
+		return NewKey(k.Type, pk)
+	}
+}
+
+// Address returns the implicit account address of k.
+func (k PrivateKey) Address() Address {
+	return k.Public().Address()
+}
+
+// Sign returns the signature of msg. Like octez-client, the BLAKE2b-256
+// digest of msg is signed rather than msg itself. Secp256k1 signatures use a
+// deterministic nonce (RFC 6979), P-256 signatures are randomized.
+func (k PrivateKey) Sign(msg []byte) (Signature, error) {
+	if !k.IsValid() {
+		return InvalidSignature, ErrUnknownKeyType
+	}
+	digest := blake2b.Sum256(msg)
+	switch k.Type {
+	case KeyTypeEd25519:
+		sig := ed25519.Sign(ed25519.PrivateKey(k.Data), digest[:])
+		return NewSignature(SignatureTypeEd25519, sig), nil
+	default:
+		sig, err := k.Type.curve().sign(k.Data, digest[:])
+		if err != nil {
+			return InvalidSignature, err
+		}
+		return NewSignature(k.Type.SignatureType(), sig), nil
+	}
+}
+
+// SignOperation signs forged operation bytes as produced by the forge
+// endpoint, prefixed with the generic operation watermark.
+func (k PrivateKey) SignOperation(op []byte) (Signature, error) {
+	return k.Sign(append([]byte{OperationWatermark}, op...))
+}
+
+// Verify checks that sig is a signature of msg by k as produced by
+// PrivateKey.Sign. Generic signatures are checked with the curve of k.
+func (k Key) Verify(msg []byte, sig Signature) error {
+	if !k.IsValid() {
+		return ErrUnknownKeyType
+	}
+	if sig.Type != SignatureTypeGeneric && sig.Type != k.Type.SignatureType() {
+		return fmt.Errorf("%s signature for %s key", sig.Type.Prefix(), k.Type.Prefix())
+	}
+	digest := blake2b.Sum256(msg)
+	var ok bool
+	switch k.Type {
+	case KeyTypeEd25519:
+		ok = len(sig.Data) == ed25519.SignatureSize && ed25519.Verify(ed25519.PublicKey(k.Data), digest[:], sig.Data)
+	case KeyTypeSecp256k1, KeyTypeP256:
+		ok = k.Type.curve().verify(k.Data, digest[:], sig.Data)
+	}
+	if !ok {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// SignatureType returns the signature type produced by keys of type t.
+func (t KeyType) SignatureType() SignatureType {
+	switch t {
+	case KeyTypeEd25519, KeyTypeEd25519Sec:
+		return SignatureTypeEd25519
+	case KeyTypeSecp256k1, KeyTypeSecp256k1Sec:
+		return SignatureTypeSecp256k1
+	case KeyTypeP256, KeyTypeP256Sec:
+		return SignatureTypeP256
+	default:
+		return SignatureTypeInvalid
+	}
+}
+
+func (t KeyType) curve() ecdsaCurve {
+	if t == KeyTypeSecp256k1 || t == KeyTypeSecp256k1Sec {
+		return secp256k1Curve
+	}
+	return p256Curve
+}
+
+// MessageWatermark is prepended to PACKed Michelson data before signing.
+const MessageWatermark = 0x05
+
+// SignedMessageBytes returns the preimage wallets sign for a human-readable
+// message: the PACKed Michelson string msg, that is MessageWatermark, the
+// string tag 0x01, the big-endian 4 byte length of msg and the UTF-8 bytes
+// of msg. As with operations, the BLAKE2b-256 digest of the preimage is
+// what is actually signed. Wallets following tzip-32 expect msg to start
+// with "Tezos Signed Message: ".
+func SignedMessageBytes(msg string) []byte {
+	buf := make([]byte, 6, 6+len(msg))
+	buf[0], buf[1] = MessageWatermark, 0x01
+	binary.BigEndian.PutUint32(buf[2:], uint32(len(msg)))
+	return append(buf, msg...)
+}
+
+// SignMessage signs msg the way wallets do for off-chain messages.
+func (k PrivateKey) SignMessage(msg string) (Signature, error) {
+	return k.Sign(SignedMessageBytes(msg))
+}
+
+// VerifySignedMessage checks that sig is a wallet signature of msg by key.
+func VerifySignedMessage(msg string, sig Signature, key Key) error {
+	return key.Verify(SignedMessageBytes(msg), sig)
+}
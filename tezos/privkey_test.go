@@ -0,0 +1,309 @@
+// Copyright (c) 2021 Blockwatch Data Inc.
+// Author: alex@blockwatch.cc
+//
+
+package tezos
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestECDSAVectors(t *testing.T) {
+	for _, test := range []struct {
+		Name          string
+		Curve         ecdsaCurve
+		Key           string
+		Msg           string
+		Public        string
+		Sig           string
+		Deterministic bool
+	}{
+		{
+			// widely used RFC 6979 vector for secp256k1 with low-S
+			Name:          "secp256k1",
+			Curve:         secp256k1Curve,
+			Key:           "0000000000000000000000000000000000000000000000000000000000000001",
+			Msg:           "Satoshi Nakamoto",
+			Public:        "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			Sig:           "934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d82442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5",
+			Deterministic: true,
+		},
+		{
+			// RFC 6979 A.2.5, P-256 with SHA-256, message "sample"
+			Name:   "p256",
+			Curve:  p256Curve,
+			Key:    "c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721",
+			Msg:    "sample",
+			Public: "0360fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6",
+			Sig:    "efd48b2aacb6a8fd1140dd9cd45e81d69d2c877b56aaf991c34d0ea84eaf3716f7cb1c942d657c41d436c7a1b6e29f65f3e900dbb9aff4064dc4ab2f843acda8",
+		},
+	} {
+		d, _ := hex.DecodeString(test.Key)
+		digest := sha256.Sum256([]byte(test.Msg))
+		pub, err := test.Curve.publicKey(d)
+		if err != nil {
+			t.Fatalf("%s: %v", test.Name, err)
+		}
+		if got := hex.EncodeToString(pub); got != test.Public {
+			t.Errorf("%s: public key mismatch\n  want=%s\n  got= %s", test.Name, test.Public, got)
+		}
+		want, _ := hex.DecodeString(test.Sig)
+		if !test.Curve.verify(pub, digest[:], want) {
+			t.Errorf("%s: reference signature does not verify", test.Name)
+		}
+		sig, err := test.Curve.sign(d, digest[:])
+		if err != nil {
+			t.Fatalf("%s: %v", test.Name, err)
+		}
+		// only secp256k1 signs with a deterministic nonce
+		if got := hex.EncodeToString(sig); test.Deterministic && got != test.Sig {
+			t.Errorf("%s: signature mismatch\n  want=%s\n  got= %s", test.Name, test.Sig, got)
+		}
+		if !test.Curve.verify(pub, digest[:], sig) {
+			t.Errorf("%s: signature does not verify", test.Name)
+		}
+		digest[0] ^= 1
+		if test.Curve.verify(pub, digest[:], sig) || test.Curve.verify(pub, digest[:], want) {
+			t.Errorf("%s: signature verifies for wrong digest", test.Name)
+		}
+	}
+}
+
+func TestPrivateKeySignOperation(t *testing.T) {
+	op, _ := hex.DecodeString("a3c13aa1ae1b97e7a0d1a7f1b1cee83e418d1d3dbdbcaad8db97916bf8fe4c9d6c00")
+	for _, test := range []struct {
+		Type    KeyType
+		Key     string
+		Secret  string
+		Public  string
+		Address string
+		Sig     string
+	}{
+		{
+			// RFC 8032 test 1 seed
+			Type:    KeyTypeEd25519,
+			Key:     "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+			Secret:  "edskRxbzm4vq4ivncG4kaQH6dLNiZn57NVxfyg1bnsazDdcDRacLQmSQc8RLs8KEBjoQnGRnzVhG96mvJJ2khmhhc2LxZB6gs8",
+			Public:  "edpkvH4rzbmfvAEgiJQU1TKYfrTvBbpVJGHmQByh9Nph4BzvRh8aXP",
+			Address: "tz1N7tYGMGs3GGjeJAJKtbycAWcvoPNSUYgu",
+			Sig:     "edsigtupW1ErvVDpwy2vuHApkzaktQTmhqPCdPTKJo2qbYnkxvCpwTGsTZHBcuHf3KNuxjSucV8GA6cseWZWX8kTrNScmPMZxsq",
+		},
+		{
+			Type:    KeyTypeSecp256k1,
+			Key:     "0000000000000000000000000000000000000000000000000000000000000001",
+			Secret:  "spsk1RZgUW68mN4kVJsjdEUCsDMhEGDXkp5yryy2Ca6uS4vwskkyHu",
+			Public:  "sppk7aEFdrScsCDxdaQ7Ev1JxpWZESrEK6UsWRhr79JfGKkPYGTsudN",
+			Address: "tz2BCeQSi5ETyKJsob61pWCoQvoGtsrJBEt2",
+			Sig:     "spsig17ZRrHZmn1rXT54MC2N3gmb8YzREnBHex2oYZe22eGe862BjBGiqTrSnDANMorPiEc6e3mCA4zWfqqBd8hiNZNAEFudr5b",
+		},
+		{
+			Type:    KeyTypeP256,
+			Key:     "c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721",
+			Secret:  "p2sk3sccH1AApz4Yw1gufgkeN91Rn9KnzPe2cv5WiaNmxusAZZ7Na2",
+			Public:  "p2pk67FdBTd2tRMwkL24Nncky3jeCkjmTavgvEBj28PxRcAsotmaoDv",
+			Address: "tz3eXwkF6GKTcLNsrzpngmsEka3EyPo2VP1X",
+			Sig:     "p2sigj16c35WLuz3RLawsT8PYGxrTrE8vVfciMeYJH8S6sqMtTaVh9NHAQb4u7o6fhUcqnA1jC5dTE8xRBadXibC8KMrutLhoo",
+		},
+	} {
+		name := test.Type.String()
+		d, _ := hex.DecodeString(test.Key)
+		sk, err := NewPrivateKey(test.Type, d)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := sk.String(); got != test.Secret {
+			t.Errorf("%s: secret key mismatch want=%s got=%s", name, test.Secret, got)
+		}
+		if got := sk.Public().String(); got != test.Public {
+			t.Errorf("%s: public key mismatch want=%s got=%s", name, test.Public, got)
+		}
+		if got := sk.Address().String(); got != test.Address {
+			t.Errorf("%s: address mismatch want=%s got=%s", name, test.Address, got)
+		}
+		sk2, err := ParsePrivateKey(test.Secret)
+		if err != nil || !sk2.Public().IsEqual(sk.Public()) {
+			t.Errorf("%s: parse secret key: %v", name, err)
+		}
+
+		sig, err := sk.SignOperation(op)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		sig2, err := ParseSignature(test.Sig)
+		if err != nil {
+			t.Errorf("%s: parse signature: %v", name, err)
+		}
+		if err := sk.Public().Verify(append([]byte{OperationWatermark}, op...), sig2); err != nil {
+			t.Errorf("%s: verify reference signature: %v", name, err)
+		}
+		// P-256 signatures use a random nonce
+		if got := sig.String(); test.Type != KeyTypeP256 && got != test.Sig {
+			t.Errorf("%s: signature mismatch\n  want=%s\n  got= %s", name, test.Sig, got)
+		}
+		if err := sk.Public().Verify(append([]byte{OperationWatermark}, op...), sig); err != nil {
+			t.Errorf("%s: verify: %v", name, err)
+		}
+		if err := sk.Public().Verify(op, sig); err == nil {
+			t.Errorf("%s: signature verifies without watermark", name)
+		}
+	}
+
+	// the 32 byte edsk seed form decodes to the same key
+	seed, _ := hex.DecodeString("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+	sk := MustParsePrivateKey("edskRxbzm4vq4ivncG4kaQH6dLNiZn57NVxfyg1bnsazDdcDRacLQmSQc8RLs8KEBjoQnGRnzVhG96mvJJ2khmhhc2LxZB6gs8")
+	sk2, err := NewPrivateKey(KeyTypeEd25519, seed)
+	if err != nil || sk2.String() != sk.String() {
+		t.Errorf("seed mismatch: %v", err)
+	}
+
+	for _, typ := range []KeyType{KeyTypeEd25519, KeyTypeSecp256k1, KeyTypeP256} {
+		sk, err := GenerateKey(typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := sk.Sign([]byte("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sk.Public().Verify([]byte("hello"), sig); err != nil {
+			t.Errorf("%s: generated key: %v", typ, err)
+		}
+	}
+
+	if _, err := NewPrivateKey(KeyTypeSecp256k1, make([]byte, 32)); err == nil {
+		t.Errorf("expected error for zero scalar")
+	}
+}
+
+func TestEncryptedPrivateKey(t *testing.T) {
+	pass := []byte("correct horse")
+	salt := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	for _, test := range []struct {
+		Type   KeyType
+		Prefix string
+	}{
+		{KeyTypeEd25519, ED25519_ENCRYPTED_SEED_PREFIX},
+		{KeyTypeSecp256k1, SECP256K1_ENCRYPTED_SECRET_KEY_PREFIX},
+		{KeyTypeP256, P256_ENCRYPTED_SECRET_KEY_PREFIX},
+	} {
+		sk, err := NewPrivateKey(test.Type, bytes.Repeat([]byte{0x42}, 32))
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc, err := sk.encrypt(pass, salt)
+		if err != nil {
+			t.Fatalf("%s: %v", test.Prefix, err)
+		}
+		if !strings.HasPrefix(enc, test.Prefix) || len(enc) != 88 {
+			t.Errorf("%s: unexpected encoding %s", test.Prefix, enc)
+		}
+		dec, err := ParseEncryptedPrivateKey(enc, pass)
+		if err != nil {
+			t.Fatalf("%s: %v", test.Prefix, err)
+		}
+		if dec.Type != sk.Type || !bytes.Equal(dec.Data, sk.Data) {
+			t.Errorf("%s: round trip mismatch %s != %s", test.Prefix, dec, sk)
+		}
+		if _, err := ParseEncryptedPrivateKey(enc, []byte("wrong")); err != ErrInvalidPassphrase {
+			t.Errorf("%s: expected ErrInvalidPassphrase, got %v", test.Prefix, err)
+		}
+	}
+
+	// random salt
+	sk := MustParsePrivateKey("edskRxbzm4vq4ivncG4kaQH6dLNiZn57NVxfyg1bnsazDdcDRacLQmSQc8RLs8KEBjoQnGRnzVhG96mvJJ2khmhhc2LxZB6gs8")
+	enc, err := sk.Encrypt(pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec, err := ParseEncryptedPrivateKey(enc, pass); err != nil || dec.String() != sk.String() {
+		t.Errorf("round trip failed: %v", err)
+	}
+
+	// malformed strings are not reported as wrong passphrase
+	for _, s := range []string{enc[:40], sk.String(), "edesk"} {
+		if _, err := ParseEncryptedPrivateKey(s, pass); err == nil || err == ErrInvalidPassphrase {
+			t.Errorf("%q: expected format error, got %v", s, err)
+		}
+	}
+}
+
+func TestEncryptedPrivateKeyVectors(t *testing.T) {
+	// externally encrypted keys from the Taquito signer test suite, all use
+	// the passphrase "test"
+	for _, test := range []struct {
+		Encrypted string
+		Key       string
+		Address   string
+	}{
+		{
+			Encrypted: "edesk1GXwWmGjXiLHBKxGBxwmNvG21vKBh6FBxc4CyJ8adQQE2avP5vBB57ZUZ93Anm7i4k8RmsHaPzVAvpnHkFF",
+			Key:       "edskRk1hRPhBCsGRDfqRBKDY5ecPKLfBhQDC4MvmWwa8i8dXUiGEyWJ7vUDjFo1k59PHfRrQKSEM9ieJNH3FbqrrDFg18ZZorh",
+			Address:   "tz1QkYxSbPu1nFVxYv2D3p7nHxeHsLMB2Uh2",
+		},
+		{
+			Encrypted: "spesk24UQkAiJk8X6AufNtRv1WWPp2BAssEgmijCTQPMgUXweSKPmLdbyAjPmCG1pR2dC9P5UZZVeZcb7zVodUHZ",
+			Key:       "spsk2MXVuq9SXAfvfcwmTkUxoa5efPcGZbshJRsGWp7ox36jSrsoS8",
+			Address:   "tz2HT7VLPySSMUm9bPtDDTSQJczuZxAgt1yj",
+		},
+		{
+			Encrypted: "p2esk28hoUE2J88QNFj2aDX2pjzL7wcVh2g8tkEwtWWguby9M3FHUgSbzvF2Sd7wQ4Kd8crFwvto6gF3otcBuo4T",
+			Key:       "p2sk3KN4pCCJigciQSJQEhKCiBFYqYwS4pyCG22hnhaYAZY3h8AGjN",
+			Address:   "tz3be5v4ZWL3zQYUZoLWJQy8P3H6RJryVVXn",
+		},
+	} {
+		sk, err := ParseEncryptedPrivateKey(test.Encrypted, []byte("test"))
+		if err != nil {
+			t.Errorf("%s: %v", test.Encrypted[:5], err)
+			continue
+		}
+		if got := sk.String(); got != test.Key {
+			t.Errorf("%s: key mismatch\n  want=%s\n  got= %s", test.Encrypted[:5], test.Key, got)
+		}
+		if got := sk.Address().String(); got != test.Address {
+			t.Errorf("%s: address mismatch want=%s got=%s", test.Encrypted[:5], test.Address, got)
+		}
+		if _, err := ParseEncryptedPrivateKey(test.Encrypted, []byte("tset")); err != ErrInvalidPassphrase {
+			t.Errorf("%s: expected ErrInvalidPassphrase, got %v", test.Encrypted[:5], err)
+		}
+	}
+}
+
+func TestVerifySignedMessage(t *testing.T) {
+	// signed by tz1VQA4RP4fLjEEMW2FR4pE9kAg5abb5h5GL, the first account of the
+	// "abandon ... about" test mnemonic, ed25519 signatures are deterministic
+	msg := "Tezos Signed Message: example.com 2021-07-01T00:00:00Z Hello Tezos"
+	key := MustParseKey("edpku4US3ZykcZifjzSGFCmFr3zRgCKndE82estE4irj4d5oqDNDvf")
+	sig := MustParseSignature("edsigu3Trs9WM3kvnY6miwCzB9uV9qSgkGaaxaMzMe3uDKHj7B77z2Da4xczuZDAhqdrWdjEV25HyenVjwxEJFnTsKEYh6XkMNV")
+
+	if got, want := hex.EncodeToString(SignedMessageBytes("abc")), "050100000003616263"; got != want {
+		t.Errorf("preimage mismatch\n  want=%s\n  got= %s", want, got)
+	}
+	if err := VerifySignedMessage(msg, sig, key); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+	if err := VerifySignedMessage(msg+".", sig, key); err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature for modified message, got %v", err)
+	}
+	other := MustParseKey("edpkuBknW28nW72KG6RoHtYW7p12T6GKc7nAbwYX5m8Wd9sDVC9yav")
+	if err := VerifySignedMessage(msg, sig, other); err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature for other key, got %v", err)
+	}
+
+	// a signature produced outside tzgo (Taquito verifySignature test suite)
+	// over watermarked bytes, which checks the digest and encoding against
+	// another implementation
+	data, _ := hex.DecodeString("03d0c10e3ed11d7c6e3357f6ef335bab9e8f2bd54d0ce20c482e241191a6e4b8ce6c01be917311d9ac46959750e405d57e268e2ed9e174a80794fbd504e12a4a000141eb3781afed2f69679ff2bbe1c5375950b0e40d00ff000000005e05050505050507070100000024747a32526773486e74516b72794670707352466261313652546656503539684b72654a4d07070100000024747a315a6672455263414c42776d4171776f6e525859565142445439426a4e6a42484a750001")
+	extKey := MustParseKey("sppk7c7hkPj47yjYFEHX85q46sFJGw6RBrqoVSHwAJAT4e14KJwzoey")
+	extSig := MustParseSignature("spsig1cdLkp1RLgUHAp13aRFkZ6MQDPp7xCnjAExGL3MBSdMDmT6JgQSX8cufyDgJRM3sinFtiCzLbsyP6d365EHoNevxhT47nx")
+	if err := extKey.Verify(data, extSig); err != nil {
+		t.Errorf("verify external signature: %v", err)
+	}
+	data[len(data)-1] ^= 1
+	if err := extKey.Verify(data, extSig); err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature for modified data, got %v", err)
+	}
+}
@@ -256,3 +256,11 @@ func ParseSignature(s string) (Signature, error) {
 		Data: dec[:typ.Len()],
 	}, nil
 }
+
+func MustParseSignature(s string) Signature {
+	sig, err := ParseSignature(s)
+	if err != nil {
+		panic(err)
+	}
+	return sig
+}